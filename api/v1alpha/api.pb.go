@@ -35,6 +35,21 @@ It has these top-level messages:
 	ListenEventsResponse
 	GetLogsRequest
 	GetLogsResponse
+	FetchImageRequest
+	FetchImageResponse
+	PreparePodRequest
+	PreparePodResponse
+	RunPodRequest
+	RunPodResponse
+	StopPodRequest
+	StopPodResponse
+	RemovePodRequest
+	RemovePodResponse
+	AppStats
+	NetworkStats
+	PodStats
+	GetPodStatsRequest
+	GetPodStatsResponse
 */
 package v1alpha
 
@@ -788,6 +803,228 @@ func (m *GetLogsResponse) String() string            { return proto.CompactTextS
 func (*GetLogsResponse) ProtoMessage()               {}
 func (*GetLogsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
 
+// Request for FetchImage().
+type FetchImageRequest struct {
+	// Image to fetch, in any form accepted by 'rkt fetch': an image
+	// name, a hash, a URL or a path on the host. Required.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// If true, only the images in the store are used, optional.
+	StoreOnly bool `protobuf:"varint,2,opt,name=store_only" json:"store_only,omitempty"`
+	// If true, the image is fetched even if it is in the store, optional.
+	NoStore bool `protobuf:"varint,3,opt,name=no_store" json:"no_store,omitempty"`
+}
+
+func (m *FetchImageRequest) Reset()                    { *m = FetchImageRequest{} }
+func (m *FetchImageRequest) String() string            { return proto.CompactTextString(m) }
+func (*FetchImageRequest) ProtoMessage()               {}
+func (*FetchImageRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+// Response for FetchImage().
+type FetchImageResponse struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *FetchImageResponse) Reset()                    { *m = FetchImageResponse{} }
+func (m *FetchImageResponse) String() string            { return proto.CompactTextString(m) }
+func (*FetchImageResponse) ProtoMessage()               {}
+func (*FetchImageResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+// Request for PreparePod().
+type PreparePodRequest struct {
+	// JSON encoded pod manifest of the pod to prepare, the images
+	// of its apps must be in the store. Required.
+	PodManifest []byte `protobuf:"bytes,1,opt,name=pod_manifest,proto3" json:"pod_manifest,omitempty"`
+	// If true, the overlay filesystem is not used, optional.
+	NoOverlay bool `protobuf:"varint,2,opt,name=no_overlay" json:"no_overlay,omitempty"`
+}
+
+func (m *PreparePodRequest) Reset()                    { *m = PreparePodRequest{} }
+func (m *PreparePodRequest) String() string            { return proto.CompactTextString(m) }
+func (*PreparePodRequest) ProtoMessage()               {}
+func (*PreparePodRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+// Response for PreparePod().
+type PreparePodResponse struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *PreparePodResponse) Reset()                    { *m = PreparePodResponse{} }
+func (m *PreparePodResponse) String() string            { return proto.CompactTextString(m) }
+func (*PreparePodResponse) ProtoMessage()               {}
+func (*PreparePodResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+// Request for RunPod().
+type RunPodRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// Networks to join, with the syntax of the '--net' flag of
+	// 'rkt run-prepared', optional.
+	Networks []string `protobuf:"bytes,2,rep,name=networks" json:"networks,omitempty"`
+}
+
+func (m *RunPodRequest) Reset()                    { *m = RunPodRequest{} }
+func (m *RunPodRequest) String() string            { return proto.CompactTextString(m) }
+func (*RunPodRequest) ProtoMessage()               {}
+func (*RunPodRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+// Response for RunPod().
+type RunPodResponse struct {
+}
+
+func (m *RunPodResponse) Reset()                    { *m = RunPodResponse{} }
+func (m *RunPodResponse) String() string            { return proto.CompactTextString(m) }
+func (*RunPodResponse) ProtoMessage()               {}
+func (*RunPodResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+// Request for StopPod().
+type StopPodRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// If true, the pod is killed instead of being asked to shut down, optional.
+	Force bool `protobuf:"varint,2,opt,name=force" json:"force,omitempty"`
+	// Number of seconds to wait for the pod to exit before killing it,
+	// optional, the default is 10 seconds.
+	Timeout int64 `protobuf:"varint,3,opt,name=timeout" json:"timeout,omitempty"`
+}
+
+func (m *StopPodRequest) Reset()                    { *m = StopPodRequest{} }
+func (m *StopPodRequest) String() string            { return proto.CompactTextString(m) }
+func (*StopPodRequest) ProtoMessage()               {}
+func (*StopPodRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+// Response for StopPod().
+type StopPodResponse struct {
+}
+
+func (m *StopPodResponse) Reset()                    { *m = StopPodResponse{} }
+func (m *StopPodResponse) String() string            { return proto.CompactTextString(m) }
+func (*StopPodResponse) ProtoMessage()               {}
+func (*StopPodResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+// Request for RemovePod().
+type RemovePodRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *RemovePodRequest) Reset()                    { *m = RemovePodRequest{} }
+func (m *RemovePodRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemovePodRequest) ProtoMessage()               {}
+func (*RemovePodRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+// Response for RemovePod().
+type RemovePodResponse struct {
+}
+
+func (m *RemovePodResponse) Reset()                    { *m = RemovePodResponse{} }
+func (m *RemovePodResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemovePodResponse) ProtoMessage()               {}
+func (*RemovePodResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+// AppStats contains the resource usage of an app in a pod.
+type AppStats struct {
+	// Name of the app.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// CPU time consumed by the app since it started, in nanoseconds.
+	CpuUsage uint64 `protobuf:"varint,2,opt,name=cpu_usage" json:"cpu_usage,omitempty"`
+	// Memory used by the app, in bytes.
+	MemoryUsage uint64 `protobuf:"varint,3,opt,name=memory_usage" json:"memory_usage,omitempty"`
+}
+
+func (m *AppStats) Reset()                    { *m = AppStats{} }
+func (m *AppStats) String() string            { return proto.CompactTextString(m) }
+func (*AppStats) ProtoMessage()               {}
+func (*AppStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+// NetworkStats contains the counters of the interface of a pod in a network.
+type NetworkStats struct {
+	// Name of the network.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Name of the interface in the pod's network namespace.
+	Interface string `protobuf:"bytes,2,opt,name=interface" json:"interface,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,3,opt,name=rx_bytes" json:"rx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,4,opt,name=rx_packets" json:"rx_packets,omitempty"`
+	RxErrors  uint64 `protobuf:"varint,5,opt,name=rx_errors" json:"rx_errors,omitempty"`
+	RxDropped uint64 `protobuf:"varint,6,opt,name=rx_dropped" json:"rx_dropped,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,7,opt,name=tx_bytes" json:"tx_bytes,omitempty"`
+	TxPackets uint64 `protobuf:"varint,8,opt,name=tx_packets" json:"tx_packets,omitempty"`
+	TxErrors  uint64 `protobuf:"varint,9,opt,name=tx_errors" json:"tx_errors,omitempty"`
+	TxDropped uint64 `protobuf:"varint,10,opt,name=tx_dropped" json:"tx_dropped,omitempty"`
+}
+
+func (m *NetworkStats) Reset()                    { *m = NetworkStats{} }
+func (m *NetworkStats) String() string            { return proto.CompactTextString(m) }
+func (*NetworkStats) ProtoMessage()               {}
+func (*NetworkStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+// PodStats is a sample of the resource usage of a running pod.
+type PodStats struct {
+	// ID of the pod, in the form of a UUID.
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// Timestamp of the sample, nanoseconds since epoch.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	// CPU time consumed by the pod since it started, in nanoseconds.
+	CpuUsage uint64 `protobuf:"varint,3,opt,name=cpu_usage" json:"cpu_usage,omitempty"`
+	// Memory used by the pod, in bytes.
+	MemoryUsage uint64 `protobuf:"varint,4,opt,name=memory_usage" json:"memory_usage,omitempty"`
+	// Resource usage of the apps in the pod. Apps whose usage
+	// cannot be accounted separately (e.g. in the kvm and fly
+	// flavors) are omitted.
+	Apps []*AppStats `protobuf:"bytes,5,rep,name=apps" json:"apps,omitempty"`
+	// Counters of the pod's interfaces, one per network.
+	Networks []*NetworkStats `protobuf:"bytes,6,rep,name=networks" json:"networks,omitempty"`
+}
+
+func (m *PodStats) Reset()                    { *m = PodStats{} }
+func (m *PodStats) String() string            { return proto.CompactTextString(m) }
+func (*PodStats) ProtoMessage()               {}
+func (*PodStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *PodStats) GetApps() []*AppStats {
+	if m != nil {
+		return m.Apps
+	}
+	return nil
+}
+
+func (m *PodStats) GetNetworks() []*NetworkStats {
+	if m != nil {
+		return m.Networks
+	}
+	return nil
+}
+
+// Request for GetPodStats().
+type GetPodStatsRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// If true, then a response stream will not be closed, and a
+	// new sample will be sent every interval until the pod exits,
+	// default is false.
+	Follow bool `protobuf:"varint,2,opt,name=follow" json:"follow,omitempty"`
+	// Number of seconds between two samples when following,
+	// optional, the default is 1 second.
+	Interval int64 `protobuf:"varint,3,opt,name=interval" json:"interval,omitempty"`
+}
+
+func (m *GetPodStatsRequest) Reset()                    { *m = GetPodStatsRequest{} }
+func (m *GetPodStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetPodStatsRequest) ProtoMessage()               {}
+func (*GetPodStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+// Response for GetPodStats().
+type GetPodStatsResponse struct {
+	Stats *PodStats `protobuf:"bytes,1,opt,name=stats" json:"stats,omitempty"`
+}
+
+func (m *GetPodStatsResponse) Reset()                    { *m = GetPodStatsResponse{} }
+func (m *GetPodStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetPodStatsResponse) ProtoMessage()               {}
+func (*GetPodStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+func (m *GetPodStatsResponse) GetStats() *PodStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ImageFormat)(nil), "v1alpha.ImageFormat")
 	proto.RegisterType((*Image)(nil), "v1alpha.Image")
@@ -815,6 +1052,21 @@ func init() {
 	proto.RegisterType((*ListenEventsResponse)(nil), "v1alpha.ListenEventsResponse")
 	proto.RegisterType((*GetLogsRequest)(nil), "v1alpha.GetLogsRequest")
 	proto.RegisterType((*GetLogsResponse)(nil), "v1alpha.GetLogsResponse")
+	proto.RegisterType((*FetchImageRequest)(nil), "v1alpha.FetchImageRequest")
+	proto.RegisterType((*FetchImageResponse)(nil), "v1alpha.FetchImageResponse")
+	proto.RegisterType((*PreparePodRequest)(nil), "v1alpha.PreparePodRequest")
+	proto.RegisterType((*PreparePodResponse)(nil), "v1alpha.PreparePodResponse")
+	proto.RegisterType((*RunPodRequest)(nil), "v1alpha.RunPodRequest")
+	proto.RegisterType((*RunPodResponse)(nil), "v1alpha.RunPodResponse")
+	proto.RegisterType((*StopPodRequest)(nil), "v1alpha.StopPodRequest")
+	proto.RegisterType((*StopPodResponse)(nil), "v1alpha.StopPodResponse")
+	proto.RegisterType((*RemovePodRequest)(nil), "v1alpha.RemovePodRequest")
+	proto.RegisterType((*RemovePodResponse)(nil), "v1alpha.RemovePodResponse")
+	proto.RegisterType((*AppStats)(nil), "v1alpha.AppStats")
+	proto.RegisterType((*NetworkStats)(nil), "v1alpha.NetworkStats")
+	proto.RegisterType((*PodStats)(nil), "v1alpha.PodStats")
+	proto.RegisterType((*GetPodStatsRequest)(nil), "v1alpha.GetPodStatsRequest")
+	proto.RegisterType((*GetPodStatsResponse)(nil), "v1alpha.GetPodStatsResponse")
 	proto.RegisterEnum("v1alpha.ImageType", ImageType_name, ImageType_value)
 	proto.RegisterEnum("v1alpha.AppState", AppState_name, AppState_value)
 	proto.RegisterEnum("v1alpha.PodState", PodState_name, PodState_value)
@@ -848,6 +1100,10 @@ type PublicAPIClient interface {
 	// will not be closed after the first response, the future logs will be sent via
 	// the stream.
 	GetLogs(ctx context.Context, in *GetLogsRequest, opts ...grpc.CallOption) (PublicAPI_GetLogsClient, error)
+	// GetPodStats gets the resource usage of a running pod.
+	// If follow is set, the stream is kept open and a new sample
+	// is sent periodically until the pod exits.
+	GetPodStats(ctx context.Context, in *GetPodStatsRequest, opts ...grpc.CallOption) (PublicAPI_GetPodStatsClient, error)
 }
 
 type publicAPIClient struct {
@@ -967,6 +1223,38 @@ func (x *publicAPIGetLogsClient) Recv() (*GetLogsResponse, error) {
 	return m, nil
 }
 
+func (c *publicAPIClient) GetPodStats(ctx context.Context, in *GetPodStatsRequest, opts ...grpc.CallOption) (PublicAPI_GetPodStatsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_PublicAPI_serviceDesc.Streams[2], c.cc, "/v1alpha.PublicAPI/GetPodStats", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &publicAPIGetPodStatsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PublicAPI_GetPodStatsClient interface {
+	Recv() (*GetPodStatsResponse, error)
+	grpc.ClientStream
+}
+
+type publicAPIGetPodStatsClient struct {
+	grpc.ClientStream
+}
+
+func (x *publicAPIGetPodStatsClient) Recv() (*GetPodStatsResponse, error) {
+	m := new(GetPodStatsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for PublicAPI service
 
 type PublicAPIServer interface {
@@ -990,6 +1278,10 @@ type PublicAPIServer interface {
 	// will not be closed after the first response, the future logs will be sent via
 	// the stream.
 	GetLogs(*GetLogsRequest, PublicAPI_GetLogsServer) error
+	// GetPodStats gets the resource usage of a running pod.
+	// If follow is set, the stream is kept open and a new sample
+	// is sent periodically until the pod exits.
+	GetPodStats(*GetPodStatsRequest, PublicAPI_GetPodStatsServer) error
 }
 
 func RegisterPublicAPIServer(s *grpc.Server, srv PublicAPIServer) {
@@ -1098,6 +1390,27 @@ func (x *publicAPIGetLogsServer) Send(m *GetLogsResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _PublicAPI_GetPodStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetPodStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PublicAPIServer).GetPodStats(m, &publicAPIGetPodStatsServer{stream})
+}
+
+type PublicAPI_GetPodStatsServer interface {
+	Send(*GetPodStatsResponse) error
+	grpc.ServerStream
+}
+
+type publicAPIGetPodStatsServer struct {
+	grpc.ServerStream
+}
+
+func (x *publicAPIGetPodStatsServer) Send(m *GetPodStatsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _PublicAPI_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "v1alpha.PublicAPI",
 	HandlerType: (*PublicAPIServer)(nil),
@@ -1134,102 +1447,311 @@ var _PublicAPI_serviceDesc = grpc.ServiceDesc{
 			Handler:       _PublicAPI_GetLogs_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetPodStats",
+			Handler:       _PublicAPI_GetPodStats_Handler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// Client API for MutatingAPI service
+
+type MutatingAPIClient interface {
+	// FetchImage fetches an image into the store.
+	FetchImage(ctx context.Context, in *FetchImageRequest, opts ...grpc.CallOption) (*FetchImageResponse, error)
+	// PreparePod prepares a pod from a pod manifest.
+	PreparePod(ctx context.Context, in *PreparePodRequest, opts ...grpc.CallOption) (*PreparePodResponse, error)
+	// RunPod runs a prepared pod. It returns once the pod is started.
+	RunPod(ctx context.Context, in *RunPodRequest, opts ...grpc.CallOption) (*RunPodResponse, error)
+	// StopPod stops a running pod. It returns once the pod exited.
+	StopPod(ctx context.Context, in *StopPodRequest, opts ...grpc.CallOption) (*StopPodResponse, error)
+	// RemovePod removes a pod that is not running.
+	RemovePod(ctx context.Context, in *RemovePodRequest, opts ...grpc.CallOption) (*RemovePodResponse, error)
+}
+
+type mutatingAPIClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMutatingAPIClient(cc *grpc.ClientConn) MutatingAPIClient {
+	return &mutatingAPIClient{cc}
+}
+
+func (c *mutatingAPIClient) FetchImage(ctx context.Context, in *FetchImageRequest, opts ...grpc.CallOption) (*FetchImageResponse, error) {
+	out := new(FetchImageResponse)
+	err := grpc.Invoke(ctx, "/v1alpha.MutatingAPI/FetchImage", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mutatingAPIClient) PreparePod(ctx context.Context, in *PreparePodRequest, opts ...grpc.CallOption) (*PreparePodResponse, error) {
+	out := new(PreparePodResponse)
+	err := grpc.Invoke(ctx, "/v1alpha.MutatingAPI/PreparePod", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mutatingAPIClient) RunPod(ctx context.Context, in *RunPodRequest, opts ...grpc.CallOption) (*RunPodResponse, error) {
+	out := new(RunPodResponse)
+	err := grpc.Invoke(ctx, "/v1alpha.MutatingAPI/RunPod", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mutatingAPIClient) StopPod(ctx context.Context, in *StopPodRequest, opts ...grpc.CallOption) (*StopPodResponse, error) {
+	out := new(StopPodResponse)
+	err := grpc.Invoke(ctx, "/v1alpha.MutatingAPI/StopPod", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mutatingAPIClient) RemovePod(ctx context.Context, in *RemovePodRequest, opts ...grpc.CallOption) (*RemovePodResponse, error) {
+	out := new(RemovePodResponse)
+	err := grpc.Invoke(ctx, "/v1alpha.MutatingAPI/RemovePod", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for MutatingAPI service
+
+type MutatingAPIServer interface {
+	// FetchImage fetches an image into the store.
+	FetchImage(context.Context, *FetchImageRequest) (*FetchImageResponse, error)
+	// PreparePod prepares a pod from a pod manifest.
+	PreparePod(context.Context, *PreparePodRequest) (*PreparePodResponse, error)
+	// RunPod runs a prepared pod. It returns once the pod is started.
+	RunPod(context.Context, *RunPodRequest) (*RunPodResponse, error)
+	// StopPod stops a running pod. It returns once the pod exited.
+	StopPod(context.Context, *StopPodRequest) (*StopPodResponse, error)
+	// RemovePod removes a pod that is not running.
+	RemovePod(context.Context, *RemovePodRequest) (*RemovePodResponse, error)
+}
+
+func RegisterMutatingAPIServer(s *grpc.Server, srv MutatingAPIServer) {
+	s.RegisterService(&_MutatingAPI_serviceDesc, srv)
+}
+
+func _MutatingAPI_FetchImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(FetchImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(MutatingAPIServer).FetchImage(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _MutatingAPI_PreparePod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(PreparePodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(MutatingAPIServer).PreparePod(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _MutatingAPI_RunPod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(RunPodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(MutatingAPIServer).RunPod(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _MutatingAPI_StopPod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StopPodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(MutatingAPIServer).StopPod(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _MutatingAPI_RemovePod_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(RemovePodRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(MutatingAPIServer).RemovePod(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var _MutatingAPI_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha.MutatingAPI",
+	HandlerType: (*MutatingAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FetchImage",
+			Handler:    _MutatingAPI_FetchImage_Handler,
+		},
+		{
+			MethodName: "PreparePod",
+			Handler:    _MutatingAPI_PreparePod_Handler,
+		},
+		{
+			MethodName: "RunPod",
+			Handler:    _MutatingAPI_RunPod_Handler,
+		},
+		{
+			MethodName: "StopPod",
+			Handler:    _MutatingAPI_StopPod_Handler,
+		},
+		{
+			MethodName: "RemovePod",
+			Handler:    _MutatingAPI_RemovePod_Handler,
+		},
 	},
+	Streams: []grpc.StreamDesc{},
 }
 
 var fileDescriptor0 = []byte{
-	// 1481 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x57, 0xcd, 0x72, 0xdb, 0x46,
-	0x12, 0x36, 0x7f, 0xc0, 0x9f, 0x26, 0x4d, 0x91, 0x23, 0xd9, 0xa2, 0xe8, 0x3f, 0x19, 0xbb, 0xeb,
-	0xf2, 0xea, 0xa0, 0xdd, 0xc8, 0x8e, 0x2f, 0xa9, 0x4a, 0x99, 0x96, 0x20, 0x15, 0x63, 0x89, 0x64,
-	0xd1, 0xb4, 0x2b, 0x3e, 0xa1, 0x20, 0x72, 0x28, 0xa3, 0x04, 0x02, 0x08, 0x00, 0xca, 0x56, 0x8e,
-	0x39, 0xe5, 0x96, 0x47, 0xc8, 0x33, 0xe4, 0x94, 0x63, 0xde, 0x25, 0x55, 0x79, 0x8f, 0xf4, 0x0c,
-	0x06, 0xc0, 0x00, 0x04, 0x0f, 0xb9, 0x89, 0xdd, 0x3d, 0x5f, 0x7f, 0xdd, 0xd3, 0xfd, 0x0d, 0x04,
-	0x75, 0xc3, 0x35, 0x0f, 0x5d, 0xcf, 0x09, 0x1c, 0x52, 0xbd, 0xf9, 0xca, 0xb0, 0xdc, 0x4f, 0x86,
-	0xfa, 0x1a, 0x1a, 0x83, 0xa5, 0x71, 0x45, 0x4f, 0x1d, 0x6f, 0x69, 0x04, 0x64, 0x1f, 0xca, 0xc1,
-	0xad, 0x4b, 0xbb, 0x85, 0xfd, 0xc2, 0xf3, 0xd6, 0x11, 0x39, 0x14, 0x61, 0x87, 0x3c, 0x66, 0x8a,
-	0x1e, 0xb2, 0x05, 0xd5, 0x1b, 0xea, 0xf9, 0xa6, 0x63, 0x77, 0x8b, 0x18, 0x54, 0x57, 0xff, 0x28,
-	0x80, 0xc2, 0xdd, 0xe4, 0xbf, 0xd0, 0xb8, 0x34, 0x7c, 0xaa, 0x2f, 0x38, 0x16, 0xc7, 0x68, 0x1c,
-	0xed, 0xa4, 0x31, 0x44, 0x1e, 0x80, 0xa2, 0x39, 0x0f, 0x01, 0x48, 0x13, 0xca, 0xb6, 0xb1, 0xa4,
-	0xdd, 0x12, 0xff, 0x25, 0xe1, 0x97, 0xb9, 0xa1, 0x0b, 0x6d, 0x73, 0xe9, 0x3a, 0x5e, 0xa0, 0x07,
-	0xe6, 0x92, 0xfa, 0x81, 0xb1, 0x74, 0xbb, 0x0a, 0x7a, 0x4a, 0xa4, 0x0d, 0xb5, 0xa5, 0x61, 0x9b,
-	0x0b, 0x34, 0x76, 0x2b, 0x68, 0x69, 0x32, 0x28, 0xdf, 0xfc, 0x91, 0x76, 0xab, 0xdc, 0xff, 0x0c,
-	0x1a, 0x86, 0x6d, 0x3b, 0x81, 0x11, 0x20, 0x9a, 0xdf, 0xad, 0xed, 0x97, 0x90, 0x4f, 0x27, 0xe6,
-	0xf3, 0x96, 0xde, 0x7e, 0x30, 0xac, 0x15, 0x55, 0x5f, 0x40, 0x75, 0x48, 0x83, 0xcf, 0x8e, 0x77,
-	0x1d, 0x73, 0x29, 0x44, 0xcc, 0x4c, 0xf7, 0xe6, 0x65, 0xc2, 0x13, 0x7f, 0xbd, 0x0a, 0x79, 0xaa,
-	0xbf, 0x14, 0xa0, 0xd4, 0x77, 0xdd, 0xcc, 0x89, 0x47, 0xa0, 0x98, 0xac, 0x4c, 0x7e, 0xa4, 0x71,
-	0xd4, 0x4a, 0x17, 0x8f, 0xed, 0x55, 0xb0, 0x80, 0x20, 0xac, 0xb5, 0x25, 0x71, 0x41, 0xa4, 0x77,
-	0xcc, 0x41, 0x3a, 0x50, 0xa7, 0x5f, 0xcc, 0x40, 0x9f, 0x39, 0x73, 0xca, 0x1b, 0xd0, 0xc9, 0x96,
-	0xa1, 0x6c, 0x2a, 0xe3, 0xe7, 0x22, 0x94, 0xc6, 0xce, 0x5c, 0xf4, 0x36, 0xe4, 0xd3, 0x80, 0x92,
-	0x2b, 0x1a, 0xdd, 0xd9, 0x9c, 0x1d, 0x4f, 0x85, 0xd9, 0x7b, 0x50, 0x36, 0x5c, 0xd7, 0xc7, 0xc4,
-	0x2c, 0x47, 0x53, 0xa6, 0x47, 0x54, 0xa8, 0xd9, 0x61, 0x97, 0x22, 0x0e, 0xed, 0xd8, 0x1f, 0xb5,
-	0x6f, 0xfd, 0x46, 0x32, 0xe4, 0xab, 0x1b, 0xc8, 0x93, 0x16, 0x54, 0x66, 0x57, 0x9e, 0xb3, 0x72,
-	0xf1, 0x9a, 0x18, 0x71, 0xac, 0x62, 0xe6, 0x51, 0xe4, 0x34, 0xd7, 0x71, 0x94, 0xea, 0xfc, 0x3e,
-	0xd1, 0x86, 0xfc, 0x3d, 0x61, 0x03, 0x6e, 0xdb, 0x81, 0xe6, 0xd5, 0x4c, 0x5f, 0x1a, 0xde, 0x75,
-	0x68, 0x6d, 0x30, 0xab, 0xfa, 0x0c, 0x6a, 0x31, 0x32, 0xb6, 0x00, 0xff, 0x16, 0xfd, 0xb8, 0x0b,
-	0xca, 0x0d, 0xb3, 0x8a, 0xd9, 0xfd, 0xad, 0x00, 0x75, 0x2c, 0xfe, 0xd4, 0xb4, 0x02, 0xea, 0xb1,
-	0x48, 0x73, 0xee, 0x63, 0x64, 0x09, 0x23, 0x9f, 0x42, 0x85, 0x37, 0xcb, 0xc7, 0xd0, 0x52, 0x7e,
-	0xb7, 0x3a, 0x6c, 0xa3, 0x5c, 0x9d, 0x5d, 0xbf, 0x8f, 0x3d, 0x65, 0xa7, 0xd0, 0xc4, 0xef, 0x5f,
-	0x67, 0x40, 0x65, 0x6e, 0xba, 0x07, 0x77, 0x45, 0xdf, 0x44, 0xa4, 0xc2, 0xcd, 0x99, 0xc6, 0x54,
-	0x36, 0x35, 0x06, 0xf7, 0x21, 0x6c, 0x4c, 0xd8, 0xbc, 0xba, 0xfa, 0x67, 0x21, 0x5a, 0xd9, 0x1c,
-	0xd6, 0x78, 0x01, 0xae, 0x47, 0x17, 0xe6, 0x17, 0xc1, 0x9b, 0x37, 0x92, 0x2f, 0xa5, 0xcc, 0x12,
-	0xa3, 0xae, 0xe9, 0x2d, 0x52, 0x8a, 0x49, 0x62, 0xb5, 0x96, 0x71, 0x49, 0xad, 0xcd, 0xe3, 0x45,
-	0xee, 0x43, 0x2b, 0xdc, 0x43, 0xd6, 0xe8, 0x05, 0x66, 0xe6, 0x37, 0x5c, 0x22, 0xbb, 0xb0, 0x15,
-	0xdb, 0x2f, 0x29, 0xee, 0xfe, 0x3f, 0x5c, 0x3f, 0xc6, 0x70, 0xb1, 0xb2, 0x2c, 0xc1, 0xb0, 0xce,
-	0x8b, 0xfc, 0x15, 0x8b, 0x3c, 0xb3, 0x9c, 0x4b, 0xc3, 0x3a, 0xb5, 0x8c, 0x2b, 0x9f, 0x15, 0x39,
-	0x37, 0x3d, 0x71, 0x89, 0x7b, 0xd0, 0xf1, 0x6f, 0xfd, 0x80, 0x2e, 0x71, 0x4b, 0xec, 0x85, 0x79,
-	0xa5, 0x33, 0x57, 0x31, 0x12, 0x0b, 0xcb, 0x99, 0x19, 0x96, 0xec, 0x09, 0x75, 0x05, 0x69, 0xae,
-	0x7c, 0xea, 0xc9, 0x8e, 0x50, 0x5f, 0x58, 0x5d, 0xb6, 0x4f, 0x67, 0x2b, 0x0f, 0x95, 0x8b, 0x25,
-	0xe3, 0xea, 0xc2, 0x56, 0xf9, 0x5e, 0xe0, 0xad, 0xfc, 0x40, 0xc7, 0x56, 0xf9, 0xfa, 0xc2, 0x73,
-	0x96, 0xfa, 0xa7, 0x20, 0x70, 0x7d, 0x5e, 0x76, 0x4d, 0xf5, 0xa0, 0x3c, 0xb0, 0x17, 0x0e, 0xd9,
-	0x86, 0x86, 0x77, 0x1d, 0xe8, 0x91, 0x66, 0x85, 0x0c, 0x71, 0x2a, 0x71, 0x32, 0x66, 0x7a, 0x4a,
-	0x29, 0x59, 0x28, 0x2a, 0x70, 0x6c, 0x0c, 0x79, 0x1d, 0xe0, 0x00, 0xf3, 0x42, 0x45, 0xf2, 0x72,
-	0x46, 0x35, 0xa5, 0x2e, 0x60, 0x4e, 0x45, 0xbb, 0xa1, 0xf6, 0x66, 0x99, 0xe6, 0x5e, 0x2e, 0xd3,
-	0x19, 0x81, 0x65, 0xf4, 0x45, 0x42, 0xfc, 0xc5, 0x84, 0x94, 0x27, 0x2a, 0x91, 0x27, 0x50, 0x9e,
-	0x1b, 0x81, 0xb1, 0x59, 0x55, 0x02, 0x68, 0x70, 0x54, 0x31, 0x6d, 0x4f, 0x41, 0x61, 0x99, 0xc3,
-	0x79, 0xcb, 0x4f, 0x2d, 0x06, 0x32, 0x1c, 0x3f, 0x5c, 0x38, 0x79, 0xf2, 0xd8, 0x0a, 0x9b, 0xf6,
-	0x8c, 0xea, 0x12, 0x05, 0xb4, 0xad, 0xec, 0xc0, 0xb4, 0x42, 0x1b, 0x97, 0x76, 0xb5, 0x0d, 0xad,
-	0x33, 0x1a, 0xb0, 0x06, 0x4f, 0xe8, 0x0f, 0x2b, 0x94, 0x13, 0xf5, 0x10, 0xb6, 0x62, 0x8b, 0xef,
-	0xe2, 0x40, 0x51, 0xf2, 0x00, 0x05, 0x19, 0x7f, 0x8b, 0x87, 0xe6, 0x6e, 0xa2, 0xb5, 0x68, 0x54,
-	0x4f, 0x61, 0xeb, 0xdc, 0xf4, 0x03, 0x5c, 0x56, 0x5f, 0x40, 0x90, 0x7f, 0x41, 0x75, 0xc1, 0xab,
-	0x08, 0xd9, 0x37, 0x24, 0xf6, 0x89, 0x08, 0xa0, 0x10, 0xcd, 0x69, 0x60, 0x98, 0x16, 0x6f, 0x5e,
-	0x0d, 0xf3, 0xb6, 0x13, 0x1c, 0x91, 0x18, 0x65, 0xd2, 0x75, 0xe6, 0x11, 0x4a, 0x53, 0x46, 0x51,
-	0x9f, 0x40, 0x67, 0x60, 0xfb, 0x2e, 0x9d, 0xb1, 0x23, 0x51, 0x66, 0x49, 0x92, 0xd5, 0xff, 0x01,
-	0x91, 0x03, 0x04, 0xe4, 0x1e, 0x0a, 0xb5, 0x33, 0x17, 0xa5, 0xa4, 0x11, 0xbf, 0x83, 0x0e, 0x63,
-	0xc0, 0x77, 0x3e, 0xae, 0xe5, 0x3f, 0xd9, 0x5a, 0xb2, 0xef, 0x6c, 0x7e, 0x35, 0x2f, 0x81, 0xc8,
-	0x58, 0x22, 0xf9, 0x63, 0xa8, 0x70, 0xd5, 0x8a, 0xb0, 0x32, 0xcf, 0x96, 0xfa, 0x14, 0xb6, 0x05,
-	0x65, 0xfe, 0x3b, 0xaf, 0xaa, 0xaf, 0x61, 0x27, 0x1d, 0x22, 0xa0, 0xe3, 0x07, 0xb1, 0x90, 0xf7,
-	0x20, 0xaa, 0xdf, 0xc0, 0x36, 0xe3, 0x43, 0x6d, 0x3e, 0x3e, 0x71, 0x75, 0xff, 0x86, 0x4a, 0x58,
-	0xdd, 0xda, 0x47, 0x84, 0x34, 0x8b, 0xea, 0x2b, 0xd8, 0x49, 0x1f, 0x4e, 0xca, 0xa1, 0xdc, 0xb2,
-	0x56, 0x0e, 0x0f, 0x54, 0x6f, 0xf9, 0x70, 0x9d, 0x3b, 0x57, 0x71, 0x3e, 0x6c, 0x13, 0x76, 0x5f,
-	0x8f, 0x9f, 0x4d, 0x14, 0xc8, 0x48, 0xd9, 0xc5, 0x0e, 0xe1, 0x1c, 0x5b, 0xa6, 0xcd, 0xe7, 0xb8,
-	0xf0, 0x5c, 0x61, 0x07, 0x16, 0x8e, 0x65, 0x39, 0x9f, 0xf9, 0x0c, 0xd7, 0x32, 0x73, 0xad, 0xe4,
-	0xcc, 0x35, 0x17, 0x4b, 0x75, 0x9f, 0x4f, 0x71, 0x98, 0x5a, 0xb0, 0x8d, 0x91, 0xb9, 0x82, 0x1f,
-	0x50, 0xa8, 0x27, 0x1f, 0x5b, 0x5d, 0xec, 0xea, 0x45, 0xff, 0x4c, 0xd3, 0xa7, 0x1f, 0xc7, 0x9a,
-	0xfe, 0x7e, 0x78, 0xa2, 0x9d, 0x0e, 0x86, 0xda, 0x49, 0xfb, 0x0e, 0x6a, 0xc9, 0x96, 0xe4, 0xe9,
-	0x8f, 0xc7, 0xc7, 0xed, 0x02, 0x3e, 0x35, 0x1d, 0xc9, 0x78, 0x32, 0x3a, 0x7e, 0xab, 0x4d, 0xda,
-	0x45, 0x24, 0xd2, 0x92, 0xcc, 0xa3, 0xe3, 0x41, 0xbb, 0x74, 0x30, 0x86, 0x5a, 0xfc, 0xcd, 0xb1,
-	0x0b, 0xdb, 0x08, 0xa0, 0xbf, 0x9b, 0xf6, 0xa7, 0xe9, 0x24, 0x88, 0x97, 0x38, 0x26, 0xef, 0x87,
-	0xc3, 0xc1, 0xf0, 0x0c, 0xd3, 0xec, 0x40, 0x3b, 0x31, 0x6b, 0xdf, 0x0f, 0xa6, 0x18, 0x5c, 0x3c,
-	0xf8, 0xab, 0x00, 0xb5, 0xf8, 0x69, 0x44, 0xc8, 0xf1, 0xe8, 0x24, 0x07, 0x12, 0xcf, 0x26, 0x0e,
-	0xed, 0xe2, 0xcd, 0xe4, 0xe3, 0x08, 0x11, 0x53, 0xe1, 0xe3, 0x89, 0x36, 0xee, 0x4f, 0x58, 0xaa,
-	0x22, 0x8a, 0x33, 0xc9, 0x3a, 0x10, 0xa6, 0xc4, 0x98, 0x25, 0xf6, 0x88, 0x59, 0x19, 0xa7, 0x6d,
-	0x2f, 0x31, 0xf7, 0xdf, 0x8c, 0x26, 0x48, 0x2d, 0x3a, 0xd6, 0x56, 0x32, 0xc9, 0x43, 0xe2, 0x95,
-	0x74, 0x8e, 0x13, 0xed, 0x5c, 0x9b, 0x32, 0xb0, 0x6a, 0x3a, 0xc7, 0x59, 0x7f, 0xf2, 0x06, 0x5b,
-	0xd8, 0xae, 0x1d, 0xfc, 0x5e, 0x84, 0x7a, 0x22, 0x76, 0x78, 0x43, 0xda, 0x07, 0x6d, 0x38, 0x5d,
-	0xbf, 0xa1, 0x07, 0xb0, 0x2b, 0x79, 0x18, 0x52, 0xcc, 0xbf, 0x80, 0x1f, 0x53, 0x8f, 0xf3, 0x9d,
-	0x11, 0x6b, 0xac, 0xbd, 0x07, 0xf7, 0x33, 0x31, 0x48, 0x85, 0xfb, 0x4a, 0x28, 0x17, 0xf7, 0x32,
-	0x3e, 0x51, 0x4e, 0x19, 0x77, 0x67, 0x3f, 0xe3, 0x12, 0xdc, 0xf5, 0xe3, 0xd1, 0xf9, 0xb9, 0x76,
-	0xcc, 0xa2, 0x94, 0x0c, 0xb8, 0xb8, 0xce, 0x49, 0xd8, 0x90, 0x34, 0x38, 0xf3, 0x09, 0xf0, 0x2a,
-	0x6b, 0xb0, 0xe4, 0x0a, 0xa7, 0x6a, 0x70, 0x31, 0x0e, 0x29, 0xd7, 0xc8, 0x43, 0xe8, 0xae, 0xb9,
-	0x27, 0xda, 0xc5, 0xe8, 0x03, 0x7a, 0xeb, 0x47, 0x3f, 0x95, 0xf1, 0x6b, 0x6b, 0x75, 0x69, 0x99,
-	0xb3, 0xfe, 0x78, 0x40, 0xbe, 0x85, 0xaa, 0x10, 0x74, 0xb2, 0x9b, 0xbc, 0x76, 0x29, 0xd1, 0xef,
-	0x75, 0xd7, 0x1d, 0xe1, 0xd6, 0xa8, 0x77, 0x48, 0x1f, 0x6a, 0x91, 0x30, 0x93, 0x24, 0x2e, 0xa3,
-	0xf9, 0xbd, 0xbd, 0x1c, 0x4f, 0x0c, 0x71, 0x06, 0x90, 0x48, 0x31, 0xe9, 0x49, 0x0f, 0x48, 0x46,
-	0xc0, 0x7b, 0x0f, 0x72, 0x7d, 0x32, 0x50, 0x22, 0xab, 0x12, 0xd0, 0x9a, 0x6e, 0x4b, 0x40, 0xeb,
-	0x3a, 0x8c, 0x40, 0x17, 0xd0, 0x94, 0x65, 0x94, 0x3c, 0xcc, 0xe6, 0x95, 0x05, 0xb8, 0xf7, 0x68,
-	0x83, 0x37, 0x86, 0x1b, 0x41, 0x53, 0x56, 0x48, 0x09, 0x2e, 0x47, 0x75, 0x25, 0xb8, 0x3c, 0x59,
-	0x55, 0xef, 0xfc, 0xbf, 0x40, 0x5e, 0xf3, 0x4b, 0x63, 0xfa, 0x95, 0xbe, 0x34, 0x49, 0x4c, 0xd3,
-	0x97, 0x26, 0x4b, 0x1d, 0x43, 0xb8, 0xac, 0xf0, 0x7f, 0x40, 0x5f, 0xfc, 0x1d, 0x00, 0x00, 0xff,
-	0xff, 0x4d, 0xcd, 0xc6, 0xd5, 0x8d, 0x0e, 0x00, 0x00,
+	// 1950 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x58, 0xcd, 0x6e, 0xe3, 0xc8,
+	0x11, 0x5e, 0xea, 0x97, 0x2a, 0xc9, 0x12, 0xd5, 0x96, 0xc7, 0x1c, 0x79, 0x7e, 0x34, 0xcc, 0x66,
+	0xe3, 0x18, 0x88, 0x93, 0x78, 0x37, 0x9b, 0xc3, 0x26, 0x8b, 0xd5, 0x58, 0xb4, 0xa0, 0x8c, 0x7f,
+	0x04, 0x8d, 0x77, 0x90, 0x3d, 0x11, 0xb4, 0xd4, 0xf2, 0x10, 0xa6, 0xd8, 0x0c, 0xd9, 0xf2, 0x58,
+	0x79, 0x82, 0xdc, 0xf2, 0x02, 0x01, 0xf2, 0x0c, 0x39, 0xe5, 0x98, 0x73, 0x6e, 0x79, 0x86, 0x05,
+	0xf2, 0x1e, 0x41, 0x37, 0x9b, 0x64, 0x93, 0xa2, 0x0e, 0x39, 0xba, 0xaa, 0xfa, 0xab, 0xaf, 0xaa,
+	0xab, 0x8b, 0x9f, 0x0c, 0x0d, 0xdb, 0x77, 0x4e, 0xfd, 0x80, 0x50, 0x82, 0xea, 0x8f, 0xbf, 0xb6,
+	0x5d, 0xff, 0xa3, 0x6d, 0x7c, 0x07, 0xcd, 0xc9, 0xca, 0xbe, 0xc7, 0x17, 0x24, 0x58, 0xd9, 0x14,
+	0x0d, 0xa0, 0x42, 0x37, 0x3e, 0xd6, 0x95, 0x81, 0x72, 0xdc, 0x3e, 0x43, 0xa7, 0x22, 0xec, 0x94,
+	0xc7, 0xdc, 0x6e, 0x7c, 0x8c, 0x3a, 0x50, 0x7f, 0xc4, 0x41, 0xe8, 0x10, 0x4f, 0x2f, 0x0d, 0x94,
+	0xe3, 0x86, 0xf1, 0x2f, 0x05, 0xaa, 0xdc, 0x8d, 0x7e, 0x0e, 0xcd, 0x3b, 0x3b, 0xc4, 0xd6, 0x92,
+	0x63, 0x71, 0x8c, 0xe6, 0x59, 0x2f, 0x8b, 0x21, 0xf2, 0x00, 0x94, 0x9c, 0x45, 0x04, 0x80, 0x5a,
+	0x50, 0xf1, 0xec, 0x15, 0xd6, 0xcb, 0xfc, 0x2f, 0x09, 0xbf, 0xc2, 0x0d, 0x3a, 0x68, 0xce, 0xca,
+	0x27, 0x01, 0xb5, 0xa8, 0xb3, 0xc2, 0x21, 0xb5, 0x57, 0xbe, 0x5e, 0x1d, 0x28, 0xc7, 0x65, 0xa4,
+	0x81, 0xba, 0xb2, 0x3d, 0x67, 0x89, 0x43, 0xaa, 0xd7, 0x06, 0xca, 0x71, 0x8b, 0x41, 0x85, 0xce,
+	0x9f, 0xb1, 0x5e, 0xe7, 0xfe, 0x2f, 0xa0, 0x69, 0x7b, 0x1e, 0xa1, 0x36, 0x75, 0x88, 0x17, 0xea,
+	0xea, 0xa0, 0x7c, 0xdc, 0x3c, 0xeb, 0x26, 0x7c, 0xde, 0xe1, 0xcd, 0x07, 0xdb, 0x5d, 0x63, 0xe3,
+	0x4b, 0xa8, 0x5f, 0x63, 0xfa, 0x89, 0x04, 0x0f, 0x09, 0x17, 0x25, 0x66, 0xe6, 0xf8, 0x8f, 0x5f,
+	0xa5, 0x3c, 0x1d, 0xff, 0xf1, 0xeb, 0x88, 0xa7, 0xf1, 0x57, 0x05, 0xca, 0x43, 0xdf, 0xcf, 0x9d,
+	0x78, 0x09, 0x55, 0x87, 0x95, 0xc9, 0x8f, 0x34, 0xcf, 0xda, 0xd9, 0xe2, 0xd1, 0x00, 0xaa, 0x21,
+	0xb5, 0x69, 0x54, 0x6b, 0x5b, 0xe2, 0x32, 0xf4, 0xfd, 0xf7, 0xcc, 0x81, 0xba, 0xd0, 0xc0, 0x4f,
+	0x0e, 0xb5, 0xe6, 0x64, 0x81, 0x79, 0x03, 0xba, 0xf9, 0x32, 0xaa, 0xbb, 0xca, 0xf8, 0x4b, 0x09,
+	0xca, 0x53, 0xb2, 0x10, 0xbd, 0x8d, 0xf8, 0x34, 0xa1, 0xec, 0x8b, 0x46, 0x77, 0x77, 0x67, 0x9f,
+	0x92, 0x45, 0x94, 0xbd, 0x0f, 0x15, 0xdb, 0xf7, 0x43, 0xbd, 0xc2, 0x73, 0xb4, 0x64, 0x7a, 0xc8,
+	0x00, 0xd5, 0x8b, 0xba, 0x14, 0x73, 0xd0, 0x12, 0x7f, 0xdc, 0xbe, 0xed, 0x1b, 0xc9, 0x91, 0xaf,
+	0xef, 0x20, 0x8f, 0xda, 0x50, 0x9b, 0xdf, 0x07, 0x64, 0xed, 0xeb, 0x2a, 0x27, 0x8e, 0x00, 0xe6,
+	0x01, 0xb6, 0x29, 0x5e, 0x58, 0x36, 0xd5, 0x1b, 0xfc, 0x3e, 0x11, 0x40, 0x48, 0xed, 0x40, 0xd8,
+	0x80, 0xdb, 0x7a, 0xd0, 0xba, 0x9f, 0x5b, 0x2b, 0x3b, 0x78, 0x88, 0xac, 0x4d, 0x66, 0x35, 0xbe,
+	0x00, 0x35, 0x41, 0x6e, 0x42, 0xf9, 0x1d, 0xde, 0x88, 0x7e, 0xec, 0x41, 0xf5, 0x91, 0x59, 0xc5,
+	0xec, 0xfe, 0x43, 0x81, 0xc6, 0x94, 0x2c, 0x2e, 0x1c, 0x97, 0xe2, 0x80, 0x45, 0x3a, 0x8b, 0x50,
+	0x57, 0x06, 0xe5, 0xe3, 0x06, 0x7a, 0x03, 0x35, 0xde, 0xac, 0x50, 0x2f, 0x0d, 0xca, 0xc5, 0xdd,
+	0xea, 0xb2, 0x17, 0xe5, 0x5b, 0xec, 0xfa, 0x43, 0xbd, 0xcc, 0x4f, 0x75, 0xa1, 0xc1, 0xef, 0xdf,
+	0x72, 0x16, 0x51, 0x17, 0x1b, 0xe8, 0x00, 0xf6, 0x44, 0xdf, 0x44, 0x64, 0x95, 0x9b, 0x73, 0x8d,
+	0xa9, 0xed, 0x6a, 0x4c, 0x07, 0xea, 0x51, 0x63, 0xa2, 0xe6, 0x35, 0x8c, 0x1f, 0x95, 0xf8, 0xc9,
+	0x16, 0xb0, 0xd6, 0x40, 0xf5, 0x03, 0xbc, 0x74, 0x9e, 0x04, 0x6f, 0xde, 0x48, 0xfe, 0x28, 0x65,
+	0x96, 0x1a, 0xa8, 0x0f, 0x78, 0xf3, 0x89, 0x04, 0x09, 0xc9, 0x37, 0x50, 0x73, 0xed, 0x3b, 0xec,
+	0xee, 0x1e, 0x2f, 0xf4, 0x0c, 0xda, 0xd1, 0x3b, 0x64, 0x8d, 0x5e, 0x52, 0x1c, 0xf0, 0x1b, 0x2e,
+	0xa3, 0x43, 0xe8, 0x24, 0xf6, 0x3b, 0xbc, 0x24, 0xc1, 0xff, 0xf9, 0xfc, 0x18, 0xc3, 0xe5, 0xda,
+	0x75, 0x05, 0xc3, 0x06, 0x2f, 0xf2, 0xef, 0x0a, 0x34, 0xc7, 0x2e, 0xb9, 0xb3, 0xdd, 0x0b, 0xd7,
+	0xbe, 0x0f, 0x59, 0x91, 0x0b, 0x27, 0x10, 0x97, 0xf8, 0x1c, 0xba, 0xe1, 0x26, 0xa4, 0x78, 0x65,
+	0xcd, 0x89, 0xb7, 0x74, 0xee, 0x2d, 0xe6, 0x2a, 0xc5, 0xcb, 0xc2, 0x25, 0x73, 0xdb, 0x95, 0x3d,
+	0xd1, 0x5e, 0x39, 0x84, 0xce, 0x3a, 0xc4, 0x81, 0xec, 0x88, 0xf6, 0x0b, 0xab, 0xcb, 0x0b, 0xf1,
+	0x7c, 0x1d, 0x60, 0x6b, 0xc9, 0x92, 0xe9, 0x55, 0xf1, 0x94, 0x0f, 0x68, 0xb0, 0x0e, 0xa9, 0xf5,
+	0x80, 0x37, 0xa1, 0xb5, 0x0c, 0xc8, 0xca, 0xfa, 0x48, 0xa9, 0x1f, 0xf2, 0xb2, 0x55, 0x23, 0x80,
+	0xca, 0xc4, 0x5b, 0x12, 0xb4, 0x0f, 0xcd, 0xe0, 0x81, 0x5a, 0xf1, 0xce, 0x8a, 0x18, 0xf6, 0xa0,
+	0x65, 0xfb, 0xfe, 0xdc, 0xca, 0x6c, 0x4a, 0x16, 0x6a, 0xfb, 0x4e, 0x62, 0x8c, 0x78, 0x9d, 0x40,
+	0xeb, 0x9e, 0x17, 0x2a, 0x92, 0x57, 0x72, 0x5b, 0x53, 0xea, 0x82, 0x11, 0x40, 0xd5, 0x7c, 0xc4,
+	0xde, 0xee, 0x35, 0xcd, 0xbd, 0x7c, 0x4d, 0xe7, 0x16, 0x2c, 0xa3, 0x2f, 0x12, 0xb6, 0xa0, 0xc2,
+	0x16, 0x29, 0x4f, 0x54, 0x46, 0xaf, 0xa1, 0xb2, 0xb0, 0xa9, 0xbd, 0x7b, 0xab, 0x50, 0x68, 0x72,
+	0x54, 0x31, 0x6d, 0x6f, 0xa0, 0xca, 0x32, 0x47, 0xf3, 0x56, 0x9c, 0x5a, 0x0c, 0x64, 0x34, 0x7e,
+	0x7b, 0x50, 0x95, 0x27, 0x8f, 0x3d, 0x61, 0xc7, 0x9b, 0x63, 0x4b, 0xa2, 0x80, 0x00, 0xd6, 0x1e,
+	0x75, 0xdc, 0xc8, 0xc6, 0x57, 0xbb, 0xa1, 0x41, 0x7b, 0x8c, 0x29, 0x6b, 0xf0, 0x0c, 0xff, 0x69,
+	0x8d, 0x43, 0x6a, 0x9c, 0x42, 0x27, 0xb1, 0x84, 0x3e, 0xf1, 0x42, 0x8c, 0x8e, 0xa0, 0xe2, 0x78,
+	0x4b, 0x22, 0x3e, 0x34, 0x7b, 0xe9, 0xae, 0xf5, 0x96, 0xc4, 0xb8, 0x80, 0xce, 0xa5, 0x13, 0xd2,
+	0x29, 0x59, 0x84, 0x02, 0x02, 0xfd, 0x04, 0xea, 0x4b, 0x5e, 0x45, 0xc4, 0xbe, 0x29, 0xb1, 0x4f,
+	0x97, 0x40, 0x1b, 0x6a, 0x0b, 0x4c, 0x6d, 0xc7, 0xe5, 0xcd, 0x53, 0x8d, 0x53, 0xd0, 0x52, 0x1c,
+	0x91, 0xb8, 0x0f, 0x15, 0x9f, 0x2c, 0x62, 0x94, 0x96, 0x8c, 0x62, 0xbc, 0x86, 0xee, 0xc4, 0x0b,
+	0x7d, 0x3c, 0x67, 0x47, 0xe2, 0xcc, 0xd2, 0x4a, 0x36, 0x7e, 0x09, 0x48, 0x0e, 0x10, 0x90, 0xcf,
+	0xa1, 0xec, 0x93, 0x85, 0x28, 0x25, 0x8b, 0xf8, 0x07, 0xe8, 0x32, 0x06, 0xfc, 0xcd, 0x27, 0xb5,
+	0xfc, 0x34, 0x5f, 0x4b, 0xfe, 0x3b, 0x5b, 0x5c, 0xcd, 0x57, 0x80, 0x64, 0x2c, 0x91, 0xfc, 0x15,
+	0xd4, 0xf8, 0xd6, 0x8a, 0xb1, 0x72, 0x9f, 0x2d, 0xe3, 0x0d, 0xec, 0x0b, 0xca, 0xfc, 0xef, 0xa2,
+	0xaa, 0x7e, 0x03, 0xbd, 0x6c, 0x88, 0x80, 0x4e, 0x3e, 0x88, 0x4a, 0xd1, 0x07, 0xd1, 0xf8, 0x06,
+	0xf6, 0x19, 0x1f, 0xec, 0xf1, 0xf1, 0x49, 0xaa, 0xfb, 0x1c, 0x6a, 0x51, 0x75, 0x5b, 0x22, 0x42,
+	0x9a, 0x45, 0xe3, 0x6b, 0xe8, 0x65, 0x0f, 0xa7, 0xe5, 0x60, 0x6e, 0xd9, 0x2a, 0x87, 0x07, 0x1a,
+	0x1b, 0x3e, 0x5c, 0x97, 0xe4, 0x3e, 0xc9, 0xd7, 0x86, 0x9a, 0x4f, 0x16, 0x56, 0xf2, 0xd9, 0xd4,
+	0x40, 0x8d, 0x37, 0xbb, 0x78, 0x43, 0x7b, 0x50, 0x75, 0x1d, 0x8f, 0xcf, 0xb1, 0x72, 0x5c, 0x65,
+	0x07, 0x96, 0xc4, 0x75, 0xc9, 0x27, 0x3e, 0xc3, 0x6a, 0x6e, 0xae, 0xab, 0x05, 0x73, 0xcd, 0x97,
+	0xa5, 0x31, 0x80, 0x4e, 0x92, 0x5a, 0xb0, 0x4d, 0x90, 0xf9, 0x06, 0x37, 0xc6, 0xd0, 0xbd, 0xc0,
+	0x74, 0xfe, 0x31, 0xd3, 0xe9, 0xac, 0xc8, 0xe0, 0xdf, 0x41, 0x12, 0x60, 0x8b, 0x78, 0xee, 0x26,
+	0xba, 0x58, 0xc6, 0xd8, 0x23, 0x16, 0x37, 0x73, 0x8a, 0xaa, 0x31, 0x00, 0x24, 0x03, 0x89, 0x6c,
+	0xf2, 0x9d, 0xfd, 0x1e, 0xba, 0xd3, 0x00, 0xfb, 0x76, 0x80, 0xa5, 0x51, 0xed, 0x41, 0x8b, 0xb5,
+	0x22, 0xf9, 0x8c, 0x2b, 0xfc, 0x33, 0x8e, 0x00, 0x3c, 0x62, 0x91, 0x47, 0x1c, 0xb8, 0xb6, 0x48,
+	0xc9, 0x12, 0xc8, 0xc7, 0x0b, 0x12, 0xfc, 0x02, 0xf6, 0x66, 0x6b, 0xaf, 0xf8, 0x1d, 0x70, 0xc6,
+	0xb1, 0x9e, 0xe0, 0xbb, 0x82, 0x3d, 0xfa, 0x38, 0x3c, 0x02, 0x33, 0x7e, 0x07, 0xed, 0xf7, 0x94,
+	0xf8, 0x3b, 0x10, 0xf6, 0xa0, 0xba, 0x24, 0xc1, 0x1c, 0x8b, 0x16, 0x74, 0xa0, 0xce, 0x3a, 0x4d,
+	0xd6, 0x94, 0x77, 0xa0, 0x6c, 0x74, 0xa1, 0x93, 0x9c, 0x16, 0x80, 0xaf, 0x40, 0x9b, 0xe1, 0x15,
+	0x79, 0xc4, 0x3b, 0x1e, 0xe7, 0x3e, 0x74, 0x25, 0xbf, 0x38, 0x34, 0x04, 0x55, 0xe8, 0xb3, 0x30,
+	0x77, 0x13, 0x5d, 0x68, 0xcc, 0xfd, 0xb5, 0xb5, 0x0e, 0x63, 0xc9, 0x57, 0x61, 0xfd, 0x5b, 0xe1,
+	0x15, 0x09, 0x36, 0xc2, 0xca, 0xa8, 0x54, 0x8c, 0x7f, 0x2b, 0xd0, 0x12, 0x22, 0x69, 0x07, 0x8e,
+	0xe3, 0x51, 0x1c, 0x2c, 0xed, 0x79, 0x3c, 0x70, 0x1a, 0xa8, 0xc1, 0x93, 0x75, 0xb7, 0xa1, 0x62,
+	0xe6, 0x2a, 0xec, 0x0e, 0x82, 0x27, 0xcb, 0xb7, 0xe7, 0x0f, 0x98, 0x46, 0xdf, 0x89, 0x0a, 0x3b,
+	0x18, 0x3c, 0x59, 0x38, 0x08, 0x48, 0x10, 0x7d, 0xb7, 0xe2, 0xb0, 0x45, 0x40, 0x7c, 0x1f, 0x2f,
+	0xf8, 0xd8, 0x55, 0x18, 0x18, 0x8d, 0xc1, 0xea, 0x71, 0x14, 0x4d, 0xc1, 0xd4, 0x18, 0x8c, 0x26,
+	0x60, 0x0d, 0x29, 0x2c, 0x06, 0x03, 0x5e, 0xcb, 0xdf, 0x14, 0x50, 0x85, 0x06, 0x0a, 0x33, 0xf7,
+	0xc1, 0xce, 0x27, 0x12, 0xbd, 0xc4, 0xdf, 0x40, 0xa6, 0x41, 0xe5, 0xc2, 0x06, 0x45, 0x85, 0xbc,
+	0x16, 0xca, 0x33, 0xff, 0x1d, 0x4a, 0x1a, 0xff, 0x33, 0x69, 0x5c, 0x22, 0xb1, 0x74, 0x90, 0x97,
+	0x9f, 0x3c, 0xd0, 0x78, 0x0b, 0x68, 0x8c, 0x69, 0x4c, 0xb0, 0x68, 0x6e, 0xd2, 0xc7, 0x9b, 0xbc,
+	0x1d, 0xde, 0xfd, 0x47, 0xdb, 0x15, 0x93, 0xf3, 0x5b, 0xd8, 0xcf, 0x60, 0x88, 0xd9, 0x16, 0x02,
+	0x3a, 0x14, 0x5b, 0x69, 0x4b, 0x12, 0x86, 0x27, 0x18, 0x1a, 0xe9, 0x4f, 0x25, 0x1d, 0x7a, 0x93,
+	0xab, 0xe1, 0xd8, 0xb4, 0x6e, 0x7f, 0x98, 0x9a, 0xd6, 0xf7, 0xd7, 0x23, 0xf3, 0x62, 0x72, 0x6d,
+	0x8e, 0xb4, 0xcf, 0xd0, 0x3e, 0x74, 0x24, 0xcf, 0x70, 0x3a, 0x3d, 0xd7, 0x14, 0x74, 0x00, 0x5d,
+	0xc9, 0x38, 0xba, 0x39, 0x7f, 0x67, 0xce, 0xb4, 0x12, 0x42, 0xd0, 0x96, 0xcc, 0x37, 0xe7, 0x13,
+	0xad, 0x7c, 0x32, 0x4d, 0x26, 0x12, 0xa3, 0x43, 0xd8, 0x1f, 0x4e, 0xa7, 0xd6, 0xfb, 0xdb, 0xe1,
+	0x6d, 0x36, 0xc9, 0x01, 0x74, 0x53, 0xc7, 0xec, 0xfb, 0xeb, 0xeb, 0xc9, 0xf5, 0x58, 0x53, 0x50,
+	0x0f, 0xb4, 0xd4, 0x6c, 0xfe, 0x71, 0x72, 0x6b, 0x8e, 0xb4, 0xd2, 0xc9, 0x7f, 0xd3, 0x4b, 0xe5,
+	0x90, 0xd3, 0x9b, 0x51, 0x01, 0x64, 0x0f, 0xb4, 0xd4, 0x61, 0x5e, 0xbd, 0x9d, 0xfd, 0x70, 0xa3,
+	0x29, 0xd9, 0xf0, 0xe9, 0xcc, 0x9c, 0x0e, 0x67, 0x2c, 0x55, 0x09, 0x3d, 0x03, 0x94, 0x77, 0x98,
+	0x23, 0xad, 0xcc, 0x98, 0xa5, 0xf6, 0x98, 0x59, 0x05, 0xbd, 0x84, 0xe7, 0xa9, 0x79, 0xf8, 0xf6,
+	0x66, 0x76, 0x6b, 0x8e, 0xe2, 0x63, 0x5a, 0x35, 0x97, 0x3c, 0x22, 0x5e, 0xcb, 0xe6, 0x18, 0x99,
+	0x97, 0xe6, 0x2d, 0x03, 0xab, 0x67, 0x73, 0x8c, 0x87, 0xb3, 0xb7, 0xc3, 0xb1, 0xa9, 0xa9, 0x27,
+	0xff, 0x2c, 0x41, 0x23, 0x95, 0x2a, 0x3a, 0xf4, 0xcc, 0x0f, 0xe6, 0xf5, 0xed, 0xf6, 0x0d, 0x1d,
+	0xc1, 0xa1, 0xe4, 0x99, 0xde, 0x24, 0x44, 0x46, 0x9a, 0x82, 0x0c, 0x78, 0x55, 0xec, 0x8c, 0x59,
+	0x6b, 0x25, 0xd4, 0x87, 0x67, 0xb9, 0x98, 0xf7, 0xb7, 0x43, 0xee, 0x2b, 0xa3, 0xe7, 0x70, 0x90,
+	0xf3, 0x89, 0x72, 0x2a, 0xe8, 0x73, 0x18, 0xe4, 0x5c, 0x82, 0xbb, 0x75, 0x7e, 0x73, 0x79, 0x69,
+	0x9e, 0xb3, 0xa8, 0x6a, 0x0e, 0x5c, 0x5c, 0xe7, 0x2c, 0x6a, 0x48, 0x16, 0x9c, 0xf9, 0x04, 0x78,
+	0x9d, 0x35, 0x58, 0x72, 0x45, 0x53, 0x35, 0xb9, 0x9a, 0x46, 0x94, 0x55, 0xf4, 0x02, 0xf4, 0x2d,
+	0xf7, 0xcc, 0xbc, 0xba, 0xf9, 0x60, 0x8e, 0xb4, 0xc6, 0xd9, 0x7f, 0x2a, 0xd0, 0x98, 0xae, 0xef,
+	0x5c, 0x67, 0x3e, 0x9c, 0x4e, 0xd0, 0xb7, 0x50, 0x17, 0x72, 0x0c, 0x1d, 0xa6, 0x5a, 0x35, 0x23,
+	0xd9, 0xfa, 0xfa, 0xb6, 0x43, 0x6c, 0xd4, 0xcf, 0xd0, 0x10, 0xd4, 0x58, 0x56, 0xa1, 0x34, 0x2e,
+	0xa7, 0xd8, 0xfa, 0xcf, 0x0b, 0x3c, 0x09, 0xc4, 0x18, 0x20, 0x15, 0x52, 0xa8, 0x2f, 0xc9, 0xbf,
+	0x9c, 0xfc, 0xea, 0x1f, 0x15, 0xfa, 0x64, 0xa0, 0x54, 0x14, 0x49, 0x40, 0x5b, 0xaa, 0xab, 0x7f,
+	0x54, 0xe8, 0x4b, 0x80, 0xae, 0xa0, 0x25, 0x8b, 0x20, 0xf4, 0x22, 0x9f, 0x57, 0xfe, 0xa8, 0xf7,
+	0x5f, 0xee, 0xf0, 0x26, 0x70, 0x37, 0xd0, 0x92, 0xf5, 0x8d, 0x04, 0x57, 0xa0, 0x99, 0xfa, 0x2f,
+	0x77, 0x78, 0x63, 0xb8, 0x5f, 0x29, 0xe8, 0x3b, 0xa8, 0x0b, 0xf5, 0x91, 0xbd, 0x34, 0x49, 0x0a,
+	0xf5, 0xf5, 0x6d, 0x87, 0x84, 0x70, 0x09, 0x4d, 0x69, 0x31, 0xa2, 0x23, 0x39, 0x38, 0xb7, 0x72,
+	0xfb, 0x2f, 0x8a, 0x9d, 0x29, 0xda, 0xd9, 0x8f, 0x25, 0x68, 0x5e, 0xad, 0xd9, 0x0f, 0x44, 0xef,
+	0x9e, 0x0d, 0xd5, 0x18, 0x20, 0x95, 0x2c, 0xd2, 0x45, 0x6c, 0x09, 0xa2, 0xfe, 0x51, 0xa1, 0x4f,
+	0xbe, 0xd1, 0x54, 0x9a, 0x48, 0x40, 0x5b, 0x72, 0xa7, 0x7f, 0x54, 0xe8, 0x4b, 0x80, 0xbe, 0x81,
+	0x5a, 0x24, 0x49, 0xd0, 0xb3, 0x24, 0x30, 0x23, 0x69, 0xfa, 0x87, 0x5b, 0xf6, 0xe4, 0xf0, 0xb7,
+	0x50, 0x17, 0xfa, 0x43, 0x6a, 0x77, 0x56, 0xcf, 0xf4, 0xf5, 0x6d, 0x47, 0x72, 0x7e, 0x04, 0x8d,
+	0x44, 0x8c, 0xa0, 0xf4, 0x29, 0xe4, 0x05, 0x4c, 0xbf, 0x5f, 0xe4, 0x8a, 0x51, 0xee, 0x6a, 0xfc,
+	0x3f, 0x7e, 0x5f, 0xfe, 0x6f, 0x00, 0xbc, 0xe6, 0xd9, 0x93, 0xfe, 0x13, 0x00, 0x00,
 }
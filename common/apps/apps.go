@@ -24,9 +24,9 @@ import (
 )
 
 // AppImageType describes a type of an image reference. The reference
-// can either be guessed or be a hash, a URL, a path, or a name. The
-// first option means that the application will have to deduce the
-// actual type (one of the last four).
+// can either be guessed or be a hash, a URL, a path, a name, or an OCI
+// image layout reference. The first option means that the application
+// will have to deduce the actual type (one of the last five).
 type AppImageType int
 
 const (
@@ -35,6 +35,7 @@ const (
 	AppImageURL                       // image URL with a scheme
 	AppImagePath                      // absolute or relative path
 	AppImageName                      // image name
+	AppImageOCI                       // image in an OCI image layout directory or archive
 )
 
 type App struct {
@@ -226,6 +226,33 @@ func GetCgroupPathByPid(pid int, controller string) (string, error) {
 	return parts[2], nil
 }
 
+// GetCPUUsage returns the CPU time consumed by the tasks of the cgroup with
+// the given path in the cpuacct hierarchy, in nanoseconds.
+func GetCPUUsage(cgroupPath string) (uint64, error) {
+	return readUintFile(filepath.Join("/sys/fs/cgroup/cpuacct", cgroupPath, "cpuacct.usage"))
+}
+
+// GetMemoryUsage returns the memory used by the tasks of the cgroup with the
+// given path in the memory hierarchy, in bytes.
+func GetMemoryUsage(cgroupPath string) (uint64, error) {
+	return readUintFile(filepath.Join("/sys/fs/cgroup/memory", cgroupPath, "memory.usage_in_bytes"))
+}
+
+// readUintFile reads a cgroup file containing a single unsigned integer.
+// The errors of reading the file are returned as is, so that callers can
+// check them with os.IsNotExist.
+func readUintFile(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("error parsing %q", path), err)
+	}
+	return v, nil
+}
+
 // JoinCgroup makes the calling process join the subcgroup hierarchy on a
 // particular controller
 func JoinSubcgroup(controller string, subcgroup string) error {
@@ -18,6 +18,9 @@ package cgroup
 
 import (
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -110,3 +113,46 @@ net_prio	6	432	1`
 		}
 	}
 }
+
+func TestReadUintFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		content string
+		value   uint64
+		err     bool
+	}{
+		{"123456789\n", 123456789, false},
+		{"18446744073709551615\n", 18446744073709551615, false},
+		{"", 0, true},
+		{"-1\n", 0, true},
+	}
+
+	path := filepath.Join(dir, "cpuacct.usage")
+	for i, tt := range tests {
+		if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+		v, err := readUintFile(path)
+		if tt.err {
+			if err == nil {
+				t.Errorf("#%d: expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error `%v`", i, err)
+		}
+		if v != tt.value {
+			t.Errorf("#%d: expected `%d` got `%d`", i, tt.value, v)
+		}
+	}
+
+	if _, err := readUintFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got `%v`", err)
+	}
+}
@@ -72,6 +72,18 @@ func Stage1ManifestPath(root string) string {
 	return filepath.Join(Stage1ImagePath(root), aci.ManifestFile)
 }
 
+// PortNetworkAnnotation returns the name of the pod annotation holding the
+// network on which the exposed port is forwarded.
+func PortNetworkAnnotation(port types.ACName) types.ACIdentifier {
+	return types.ACIdentifier("coreos.com/rkt/port-network/" + port.String())
+}
+
+// PortHostIPAnnotation returns the name of the pod annotation holding the
+// host IP on which the exposed port is forwarded.
+func PortHostIPAnnotation(port types.ACName) types.ACIdentifier {
+	return types.ACIdentifier("coreos.com/rkt/port-hostip/" + port.String())
+}
+
 // PodManifestPath returns the path in root to the Pod Manifest
 func PodManifestPath(root string) string {
 	return filepath.Join(root, "pod")
@@ -192,7 +204,9 @@ func (l *NetList) Set(value string) error {
 		l.mapping = make(map[string]string)
 	}
 	for _, s := range strings.Split(value, ",") {
-		netArgsPair := strings.Split(s, ":")
+		// the arguments may hold IPv6 addresses, only the first
+		// colon separates them from the network name
+		netArgsPair := strings.SplitN(s, ":", 2)
 		netName := netArgsPair[0]
 
 		if netName == "" {
@@ -212,8 +226,6 @@ func (l *NetList) Set(value string) error {
 				return fmt.Errorf("arguments are not supported by special netname %q", netName)
 			}
 			l.mapping[netName] = netArgsPair[1]
-		case len(netArgsPair) > 2:
-			return fmt.Errorf("network %q provided with invalid arguments: %v", netName, netArgsPair[1:])
 		default:
 			return fmt.Errorf("unexpected case when processing network %q", s)
 		}
@@ -134,10 +134,6 @@ func setupMacVTapDevice(podID types.UUID, config MacVTapNetConf, interfaceNumber
 // in result it updates activeNet.runtime configuration
 func kvmSetupNetAddressing(network *Networking, n activeNet, ifName string) error {
 	// TODO: very ugly hack, that go through upper plugin, down to ipam plugin
-	if err := ip.EnableIP4Forward(); err != nil {
-		return errwrap.Wrap(errors.New("failed to enable forwarding"), err)
-	}
-
 	// patch plugin type only for single IPAM run time, then revert this change
 	original_type := n.conf.Type
 	n.conf.Type = n.conf.IPAM.Type
@@ -152,25 +148,48 @@ func kvmSetupNetAddressing(network *Networking, n activeNet, ifName string) erro
 		return errwrap.Wrap(fmt.Errorf("error parsing %q result", n.conf.Name), err)
 	}
 
-	if result.IP4 == nil {
-		return fmt.Errorf("net-plugin returned no IPv4 configuration")
+	if result.IP4 == nil && result.IP6 == nil {
+		return fmt.Errorf("net-plugin returned no IP configuration")
 	}
 
-	n.runtime.IP, n.runtime.Mask, n.runtime.HostIP, n.runtime.IP4 = result.IP4.IP.IP, net.IP(result.IP4.IP.Mask), result.IP4.Gateway, result.IP4
+	n.runtime.SetResult(&result)
+
+	if result.IP4 != nil {
+		if err := ip.EnableIP4Forward(); err != nil {
+			return errwrap.Wrap(errors.New("failed to enable forwarding"), err)
+		}
+	}
+	if result.IP6 != nil {
+		if err := ip.EnableIP6Forward(); err != nil {
+			return errwrap.Wrap(errors.New("failed to enable IPv6 forwarding"), err)
+		}
+	}
 
 	return nil
 }
 
 func ensureHasAddr(link netlink.Link, ipn *net.IPNet) error {
-	addrs, err := netlink.AddrList(link, syscall.AF_INET)
+	family := syscall.AF_INET
+	if ipn.IP.To4() == nil {
+		family = syscall.AF_INET6
+	}
+	addrs, err := netlink.AddrList(link, family)
 	if err != nil && err != syscall.ENOENT {
 		return errwrap.Wrap(errors.New("could not get list of IP addresses"), err)
 	}
 
+	// the IPv6 link-local addresses are added by the kernel
+	var globalAddrs []netlink.Addr
+	for _, a := range addrs {
+		if !a.IP.IsLinkLocalUnicast() {
+			globalAddrs = append(globalAddrs, a)
+		}
+	}
+
 	// if there're no addresses on the interface, it's ok -- we'll add one
-	if len(addrs) > 0 {
+	if len(globalAddrs) > 0 {
 		ipnStr := ipn.String()
-		for _, a := range addrs {
+		for _, a := range globalAddrs {
 			// string comp is actually easiest for doing IPNet comps
 			if a.IPNet.String() == ipnStr {
 				return nil
@@ -226,24 +245,33 @@ func ensureBridgeIsUp(brName string, mtu int) (*netlink.Bridge, error) {
 }
 
 func addRoute(link netlink.Link, podIP net.IP) error {
+	mask := net.CIDRMask(32, 32)
+	if podIP.To4() == nil {
+		mask = net.CIDRMask(128, 128)
+	}
 	route := netlink.Route{
 		LinkIndex: link.Attrs().Index,
 		Scope:     netlink.SCOPE_LINK,
 		Dst: &net.IPNet{
 			IP:   podIP,
-			Mask: net.IPv4Mask(0xff, 0xff, 0xff, 0xff),
+			Mask: mask,
 		},
 	}
 	return netlink.RouteAdd(&route)
 }
 
-func removeAllRoutesOnLink(link netlink.Link) error {
-	routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+// removeAllRoutesOnLink removes the routes of the family on the link,
+// except the IPv6 link-local ones
+func removeAllRoutesOnLink(link netlink.Link, family int) error {
+	routes, err := netlink.RouteList(link, family)
 	if err != nil {
 		return errwrap.Wrap(fmt.Errorf("cannot list routes on link %q", link.Attrs().Name), err)
 	}
 
 	for _, route := range routes {
+		if route.Dst != nil && route.Dst.IP.IsLinkLocalUnicast() {
+			continue
+		}
 		if err := netlink.RouteDel(&route); err != nil {
 			return errwrap.Wrap(fmt.Errorf("error in time of route removal for route %q", route), err)
 		}
@@ -450,23 +478,50 @@ func kvmSetup(podRoot string, podID types.UUID, fps []ForwardedPort, netList com
 			}
 
 			// add address to host tap device
-			err = ensureHasAddr(
-				link,
-				&net.IPNet{
-					IP:   n.runtime.IP4.Gateway,
-					Mask: net.IPMask(n.runtime.Mask),
-				},
-			)
-			if err != nil {
-				return nil, errwrap.Wrap(fmt.Errorf("cannot add address to host tap device %q", ifName), err)
+			if n.runtime.IP4 != nil {
+				err = ensureHasAddr(
+					link,
+					&net.IPNet{
+						IP:   n.runtime.IP4.Gateway,
+						Mask: net.IPMask(n.runtime.Mask),
+					},
+				)
+				if err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot add address to host tap device %q", ifName), err)
+				}
 			}
 
-			if err := removeAllRoutesOnLink(link); err != nil {
-				return nil, errwrap.Wrap(fmt.Errorf("cannot remove route on host tap device %q", ifName), err)
+			if n.runtime.IP6 != nil {
+				err = ensureHasAddr(
+					link,
+					&net.IPNet{
+						IP:   n.runtime.IP6.Gateway,
+						Mask: n.runtime.IP6.IP.Mask,
+					},
+				)
+				if err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot add IPv6 address to host tap device %q", ifName), err)
+				}
 			}
 
-			if err := addRoute(link, n.runtime.IP); err != nil {
-				return nil, errwrap.Wrap(errors.New("cannot add on host direct route to pod"), err)
+			if n.runtime.IP4 != nil {
+				if err := removeAllRoutesOnLink(link, netlink.FAMILY_V4); err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot remove route on host tap device %q", ifName), err)
+				}
+
+				if err := addRoute(link, n.runtime.IP); err != nil {
+					return nil, errwrap.Wrap(errors.New("cannot add on host direct route to pod"), err)
+				}
+			}
+
+			if n.runtime.IP6 != nil {
+				if err := removeAllRoutesOnLink(link, netlink.FAMILY_V6); err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot remove IPv6 route on host tap device %q", ifName), err)
+				}
+
+				if err := addRoute(link, n.runtime.IP6.IP.IP); err != nil {
+					return nil, errwrap.Wrap(errors.New("cannot add on host direct IPv6 route to pod"), err)
+				}
 			}
 
 		case "bridge":
@@ -505,7 +560,7 @@ func kvmSetup(podRoot string, podID types.UUID, fps []ForwardedPort, netList com
 				return nil, err
 			}
 
-			if config.IsGw {
+			if config.IsGw && n.runtime.IP4 != nil {
 				err = ensureHasAddr(
 					br,
 					&net.IPNet{
@@ -519,6 +574,19 @@ func kvmSetup(podRoot string, podID types.UUID, fps []ForwardedPort, netList com
 				}
 			}
 
+			if config.IsGw && n.runtime.IP6 != nil {
+				err = ensureHasAddr(
+					br,
+					&net.IPNet{
+						IP:   n.runtime.IP6.Gateway,
+						Mask: n.runtime.IP6.IP.Mask,
+					},
+				)
+				if err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot add IPv6 address to host bridge device %q", br.Name), err)
+				}
+			}
+
 		case "macvlan":
 			config := MacVTapNetConf{}
 			if err := json.Unmarshal(n.confBytes, &config); err != nil {
@@ -540,7 +608,8 @@ func kvmSetup(podRoot string, podID types.UUID, fps []ForwardedPort, netList com
 			return nil, fmt.Errorf("network %q have unsupported type: %q", n.conf.Name, n.conf.Type)
 		}
 
-		if n.conf.IPMasq {
+		// the masquerading is only done for IPv4
+		if n.conf.IPMasq && n.runtime.IP != nil {
 			chain := getChainName(podID.String(), n.conf.Name)
 			if err := ip.SetupIPMasq(&net.IPNet{
 				IP:   n.runtime.IP,
@@ -551,7 +620,7 @@ func kvmSetup(podRoot string, podID types.UUID, fps []ForwardedPort, netList com
 		}
 		network.nets[i] = n
 	}
-	err := network.forwardPorts(fps, network.GetDefaultIP())
+	err := network.forwardPorts(fps)
 	if err != nil {
 		return nil, err
 	}
@@ -597,7 +666,7 @@ func (n *Networking) teardownKvmNets() {
 			stderr.PrintE("error executing network plugin", err)
 		}
 		// remove masquerading if it was prepared
-		if an.conf.IPMasq {
+		if an.conf.IPMasq && an.runtime.IP != nil {
 			chain := getChainName(n.podID.String(), an.conf.Name)
 			err := ip.TeardownIPMasq(&net.IPNet{
 				IP:   an.runtime.IP,
@@ -652,11 +721,41 @@ func (an activeNet) IPMasq() bool {
 	return an.conf.IPMasq
 }
 func (an activeNet) Gateway() net.IP {
+	if an.runtime.IP4 == nil {
+		return nil
+	}
 	return an.runtime.IP4.Gateway
 }
 func (an activeNet) Routes() []cnitypes.Route {
+	if an.runtime.IP4 == nil {
+		return nil
+	}
 	return an.runtime.IP4.Routes
 }
+func (an activeNet) GuestIP6() net.IP {
+	if an.runtime.IP6 == nil {
+		return nil
+	}
+	return an.runtime.IP6.IP.IP
+}
+func (an activeNet) Mask6() net.IP {
+	if an.runtime.IP6 == nil {
+		return nil
+	}
+	return net.IP(an.runtime.IP6.IP.Mask)
+}
+func (an activeNet) Gateway6() net.IP {
+	if an.runtime.IP6 == nil {
+		return nil
+	}
+	return an.runtime.IP6.Gateway
+}
+func (an activeNet) Routes6() []cnitypes.Route {
+	if an.runtime.IP6 == nil {
+		return nil
+	}
+	return an.runtime.IP6.Routes
+}
 
 // GetActiveNetworks returns activeNets to be used as NetDescriptors
 // by plugins, which are required for stage1 executor to run (only for KVM)
@@ -18,7 +18,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -50,23 +49,22 @@ func pluginErr(err error, output []byte) error {
 	return err
 }
 
-func (e *podEnv) netPluginAdd(n *activeNet, netns string) (ip, hostIP net.IP, err error) {
+// netPluginAdd adds the pod to the network and sets the addresses of the
+// pod on the network in its runtime info
+func (e *podEnv) netPluginAdd(n *activeNet, netns string) error {
 	output, err := e.execNetPlugin("ADD", n, netns)
 	if err != nil {
-		return nil, nil, pluginErr(err, output)
+		return pluginErr(err, output)
 	}
 
 	pr := cnitypes.Result{}
 	if err = json.Unmarshal(output, &pr); err != nil {
 		err = errwrap.Wrap(fmt.Errorf("parsing %q", string(output)), err)
-		return nil, nil, errwrap.Wrap(fmt.Errorf("error parsing %q result", n.conf.Name), err)
-	}
-
-	if pr.IP4 == nil {
-		return nil, nil, nil
+		return errwrap.Wrap(fmt.Errorf("error parsing %q result", n.conf.Name), err)
 	}
 
-	return pr.IP4.IP.IP, pr.IP4.Gateway, nil
+	n.runtime.SetResult(&pr)
+	return nil
 }
 
 func (e *podEnv) netPluginDel(n *activeNet, netns string) error {
@@ -127,9 +125,10 @@ func (e *podEnv) execNetPlugin(cmd string, n *activeNet, netns string) ([]byte,
 	stdin := bytes.NewBuffer(n.confBytes)
 	stdout := &bytes.Buffer{}
 
+	pluginPath := e.podPath(n.runtime.PluginPath)
 	c := exec.Cmd{
-		Path:   n.runtime.PluginPath,
-		Args:   []string{n.runtime.PluginPath},
+		Path:   pluginPath,
+		Args:   []string{pluginPath},
 		Env:    envVars(vars),
 		Stdin:  stdin,
 		Stdout: stdout,
@@ -31,11 +31,56 @@ type NetInfo struct {
 	ConfPath   string          `json:"netConf"`
 	PluginPath string          `json:"pluginPath"`
 	IfName     string          `json:"ifName"`
-	IP         net.IP          `json:"ip"`
+	IP         net.IP          `json:"ip"` // the IPv4 address, see Addresses for all of them
 	Args       string          `json:"args"`
 	Mask       net.IP          `json:"mask"` // we used IP instead of IPMask because support for json serialization (we don't need specific functionalities)
+	Addresses  []Address       `json:"addresses,omitempty"`
 	HostIP     net.IP          `json:"-"`
+	HostIP6    net.IP          `json:"-"`
 	IP4        *types.IPConfig `json:"-"`
+	IP6        *types.IPConfig `json:"-"`
+}
+
+// Address is an IPv4 or IPv6 address of the pod on a network
+type Address struct {
+	IP   net.IP `json:"ip"`
+	Mask net.IP `json:"mask"`
+}
+
+// SetResult sets the addresses of the pod on the network from the result
+// of the network plugin
+func (ni *NetInfo) SetResult(r *types.Result) {
+	ni.IP, ni.Mask, ni.HostIP, ni.IP4 = nil, nil, nil, r.IP4
+	ni.HostIP6, ni.IP6 = nil, r.IP6
+	ni.Addresses = nil
+	if r.IP4 != nil {
+		ni.IP, ni.Mask, ni.HostIP = r.IP4.IP.IP, net.IP(r.IP4.IP.Mask), r.IP4.Gateway
+		ni.Addresses = append(ni.Addresses, Address{IP: ni.IP, Mask: ni.Mask})
+	}
+	if r.IP6 != nil {
+		ni.HostIP6 = r.IP6.Gateway
+		ni.Addresses = append(ni.Addresses, Address{IP: r.IP6.IP.IP, Mask: net.IP(r.IP6.IP.Mask)})
+	}
+}
+
+// IP6Addr returns the first IPv6 address of the pod on the network, or nil
+// if it has none
+func (ni *NetInfo) IP6Addr() net.IP {
+	for _, a := range ni.Addresses {
+		if a.IP.To4() == nil {
+			return a.IP
+		}
+	}
+	return nil
+}
+
+// IPs returns all the addresses of the pod on the network
+func (ni *NetInfo) IPs() []net.IP {
+	var ips []net.IP
+	for _, a := range ni.Addresses {
+		ips = append(ips, a.IP)
+	}
+	return ips
 }
 
 func LoadAt(cdirfd int) ([]NetInfo, error) {
@@ -47,8 +92,16 @@ func LoadAt(cdirfd int) ([]NetInfo, error) {
 	f := os.NewFile(uintptr(fd), filename)
 
 	var info []NetInfo
-	err = json.NewDecoder(f).Decode(&info)
-	return info, err
+	if err := json.NewDecoder(f).Decode(&info); err != nil {
+		return nil, err
+	}
+	// the files written by older versions only have the IPv4 address
+	for i, ni := range info {
+		if len(ni.Addresses) == 0 && ni.IP != nil {
+			info[i].Addresses = []Address{{IP: ni.IP, Mask: ni.Mask}}
+		}
+	}
+	return info, nil
 }
 
 func Save(root string, info []NetInfo) error {
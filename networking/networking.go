@@ -44,8 +44,10 @@ const (
 // forwarded (mapped) from the host to the pod
 type ForwardedPort struct {
 	Protocol string
+	HostIP   net.IP // only forward the connections to this host IP, if set
 	HostPort uint
 	PodPort  uint
+	Network  string // forward to the pod IP on this network, the default one if empty
 }
 
 // Networking describes the networking details of a pod.
@@ -62,9 +64,13 @@ type NetConf struct {
 	cnitypes.NetConf
 	IPMasq bool `json:"ipMasq"`
 	MTU    int  `json:"mtu"`
+	// PortForwarder is the backend forwarding the ports of the pod
+	// when this network is the default one: "iptables", "nftables",
+	// "userspace" or "auto".
+	PortForwarder string `json:"portForwarder"`
 }
 
-var stderr *log.Logger
+var stderr = log.New(os.Stderr, "networking", false)
 
 // Setup creates a new networking namespace and executes network plugins to
 // set up networking. It returns in the new pod namespace
@@ -121,7 +127,7 @@ func Setup(podRoot string, podID types.UUID, fps []ForwardedPort, netList common
 			if err = n.enableDefaultLocalnetRouting(); err != nil {
 				return err
 			}
-			if err := n.forwardPorts(fps, n.GetDefaultIP()); err != nil {
+			if err := n.forwardPorts(fps); err != nil {
 				n.unforwardPorts()
 				return err
 			}
@@ -146,12 +152,16 @@ func (n *Networking) enableDefaultLocalnetRouting() error {
 		return err
 	}
 
+	// there is no route_localnet for IPv6, connections to ::1 are
+	// never routed out of the loopback interface
+	if defaultHostIP == nil && n.nets[len(n.nets)-1].runtime.HostIP6 != nil {
+		return nil
+	}
+
 	defaultHostIPstring := defaultHostIP.String()
 	switch {
 	case strings.Contains(defaultHostIPstring, "."):
 		routeLocalnetFormat = "/proc/sys/net/ipv4/conf/%s/route_localnet"
-	case strings.Contains(defaultHostIPstring, ":"):
-		return fmt.Errorf("unexpected IPv6 Address returned for default host interface: %q", defaultHostIPstring)
 	default:
 		return fmt.Errorf("unknown type for default Host IP: %q", defaultHostIPstring)
 	}
@@ -203,9 +213,14 @@ func Load(podRoot string, podID *types.UUID) (*Networking, error) {
 		return nil, err
 	}
 
+	e := podEnv{
+		podRoot: podRoot,
+		podID:   *podID,
+	}
+
 	var nets []activeNet
 	for _, ni := range nis {
-		n, err := loadNet(ni.ConfPath)
+		n, err := loadNet(e.podPath(ni.ConfPath))
 		if err != nil {
 			if !os.IsNotExist(err) {
 				stderr.PrintE(fmt.Sprintf("error loading %q; ignoring", ni.ConfPath), err)
@@ -220,15 +235,102 @@ func Load(podRoot string, podID *types.UUID) (*Networking, error) {
 	}
 
 	return &Networking{
-		podEnv: podEnv{
-			podRoot: podRoot,
-			podID:   *podID,
-		},
+		podEnv: e,
 		hostNS: hostNS,
 		nets:   nets,
 	}, nil
 }
 
+// Attach adds the running pod to the network named in netList, which must
+// be a single network configured in localConfig or one of the default
+// networks. The network is inserted before the default one, the last one,
+// so the forwarded ports and the metadata service are left untouched. The
+// saved networking state is updated.
+// Assumes the current netns is that of the host.
+func (n *Networking) Attach(netList common.NetList, localConfig string, debug bool) (*netinfo.NetInfo, error) {
+	stderr = log.New(os.Stderr, "networking", debug)
+
+	names := netList.StringsOnlyNames()
+	if len(names) != 1 {
+		return nil, fmt.Errorf("expected a single network, got %q", netList.String())
+	}
+	name := names[0]
+	if netExists(n.nets, name) {
+		return nil, fmt.Errorf("pod is already attached to network %q", name)
+	}
+
+	n.localConfig = localConfig
+	an, err := n.loadNet(name, netList.SpecificArgs(name))
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error loading network %q", name), err)
+	}
+	an.runtime.IfName = n.freeIfName()
+	if err := n.attachNet(an); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error attaching network %q", name), err)
+	}
+
+	i := len(n.nets)
+	if i > 0 {
+		i--
+	}
+	n.nets = append(n.nets, activeNet{})
+	copy(n.nets[i+1:], n.nets[i:])
+	n.nets[i] = *an
+
+	if err := n.Save(); err != nil {
+		return nil, errwrap.Wrap(errors.New("error saving networking state"), err)
+	}
+	return an.runtime, nil
+}
+
+// freeIfName returns the first interface name not used by the nets.
+func (n *Networking) freeIfName() string {
+	used := make(map[string]bool)
+	for _, an := range n.nets {
+		used[an.runtime.IfName] = true
+	}
+	for i := 0; ; i++ {
+		ifName := fmt.Sprintf(IfNamePattern, i)
+		if !used[ifName] {
+			return ifName
+		}
+	}
+}
+
+// Detach removes the running pod from the network. The default network
+// cannot be detached. The saved networking state is updated.
+// Assumes the current netns is that of the host.
+func (n *Networking) Detach(name string, debug bool) error {
+	stderr = log.New(os.Stderr, "networking", debug)
+
+	i := -1
+	for j, an := range n.nets {
+		if an.conf.Name == name {
+			i = j
+			break
+		}
+	}
+	switch {
+	case i < 0:
+		return fmt.Errorf("pod is not attached to network %q", name)
+	case i == len(n.nets)-1:
+		return fmt.Errorf("network %q is the default network of the pod and cannot be detached", name)
+	}
+
+	if err := n.netPluginDel(&n.nets[i], n.podNSPath()); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error deleting network %q", name), err)
+	}
+	if err := os.Remove(n.podPath(n.nets[i].runtime.ConfPath)); err != nil {
+		stderr.PrintE(fmt.Sprintf("error deleting %q", n.nets[i].runtime.ConfPath), err)
+	}
+	n.nets = append(n.nets[:i], n.nets[i+1:]...)
+
+	if err := n.Save(); err != nil {
+		return errwrap.Wrap(errors.New("error saving networking state"), err)
+	}
+	return nil
+}
+
 func (n *Networking) GetDefaultIP() net.IP {
 	if len(n.nets) == 0 {
 		return nil
@@ -336,15 +438,19 @@ func (n *Networking) enterHostNS() error {
 	return ns.SetNS(n.hostNS, syscall.CLONE_NEWNET)
 }
 
-// Save writes out the info about active nets
-// for "rkt list" and friends to display
-func (e *Networking) Save() error {
+// NetInfos returns the info about active nets
+func (e *Networking) NetInfos() []netinfo.NetInfo {
 	var nis []netinfo.NetInfo
 	for _, n := range e.nets {
 		nis = append(nis, *n.runtime)
 	}
+	return nis
+}
 
-	return netinfo.Save(e.podRoot, nis)
+// Save writes out the info about active nets
+// for "rkt list" and friends to display
+func (e *Networking) Save() error {
+	return netinfo.Save(e.podRoot, e.NetInfos())
 }
 
 func newNetNS() (hostNS, childNS *os.File, err error) {
@@ -99,6 +99,31 @@ func (e *podEnv) netDir() string {
 	return filepath.Join(e.podRoot, "net")
 }
 
+// podPath returns the path of a file saved relative to the pod root.
+func (e *podEnv) podPath(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(e.podRoot, p)
+}
+
+// relPodPath returns the path relative to the pod root if the file is in
+// the pod directory, and the path itself otherwise.
+func (e *podEnv) relPodPath(p string) string {
+	if p == "" || !filepath.IsAbs(p) {
+		return p
+	}
+	root, err := filepath.Abs(e.podRoot)
+	if err != nil {
+		return p
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return p
+	}
+	return rel
+}
+
 func (e *podEnv) setupNets(nets []activeNet) error {
 	err := os.MkdirAll(e.netDir(), 0755)
 	if err != nil {
@@ -123,14 +148,70 @@ func (e *podEnv) setupNets(nets []activeNet) error {
 			return errwrap.Wrap(fmt.Errorf("error copying %q to %q", n.runtime.ConfPath, e.netDir()), err)
 		}
 
-		n.runtime.IP, n.runtime.HostIP, err = e.netPluginAdd(&n, nspath)
-		if err != nil {
+		if err = e.netPluginAdd(&n, nspath); err != nil {
 			return errwrap.Wrap(fmt.Errorf("error adding network %q", n.conf.Name), err)
 		}
 	}
 	return nil
 }
 
+// loadNet loads the network named name, from the user configuration or,
+// for the default networks, from stage1, with the plugin arguments.
+func (e *podEnv) loadNet(name, args string) (*activeNet, error) {
+	var netList common.NetList
+	if err := netList.Set(name); err != nil {
+		return nil, err
+	}
+	nets, err := loadUserNets(e.localConfig, netList)
+	if err != nil {
+		return nil, err
+	}
+
+	var n *activeNet
+	switch {
+	case len(nets) > 0:
+		n = &nets[0]
+	case name == "default":
+		n, err = loadNet(path.Join(common.Stage1RootfsPath(e.podRoot), DefaultNetPath))
+	case name == "default-restricted":
+		n, err = loadNet(path.Join(common.Stage1RootfsPath(e.podRoot), DefaultRestrictedNetPath))
+	default:
+		return nil, fmt.Errorf("network %q not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.runtime.Args = args
+	return n, nil
+}
+
+// attachNet adds the pod to the net. The paths in the runtime info of the
+// net are relative to the pod root when they are in the pod directory, so
+// they stay valid when the pod directory is moved to be garbage collected.
+func (e *podEnv) attachNet(n *activeNet) error {
+	if err := os.MkdirAll(e.netDir(), 0755); err != nil {
+		return err
+	}
+	confPath, err := copyFileToDir(n.runtime.ConfPath, e.netDir())
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("error copying %q to %q", n.runtime.ConfPath, e.netDir()), err)
+	}
+	n.runtime.ConfPath = e.relPodPath(confPath)
+	n.runtime.PluginPath = e.relPodPath(e.findNetPlugin(n.conf.Type))
+
+	if err := e.netPluginAdd(n, e.podNSPath()); err != nil {
+		// the plugin may have left some state behind
+		if err := e.netPluginDel(n, e.podNSPath()); err != nil {
+			stderr.PrintE(fmt.Sprintf("error deleting %q", n.conf.Name), err)
+		}
+		if err := os.Remove(confPath); err != nil {
+			stderr.PrintE(fmt.Sprintf("error deleting %q", confPath), err)
+		}
+		return err
+	}
+	return nil
+}
+
 func (e *podEnv) teardownNets(nets []activeNet) {
 	nspath := e.podNSPath()
 
@@ -144,7 +225,7 @@ func (e *podEnv) teardownNets(nets []activeNet) {
 
 		// Delete the conf file to signal that the network was
 		// torn down (or at least attempted to)
-		if err = os.Remove(nets[i].runtime.ConfPath); err != nil {
+		if err = os.Remove(e.podPath(nets[i].runtime.ConfPath)); err != nil {
 			stderr.PrintE(fmt.Sprintf("error deleting %q", nets[i].runtime.ConfPath), err)
 		}
 	}
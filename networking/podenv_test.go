@@ -0,0 +1,50 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"testing"
+)
+
+func TestPodPaths(t *testing.T) {
+	e := podEnv{podRoot: "/var/lib/rkt/pods/run/a6f4a4a0"}
+
+	tests := []struct {
+		path string
+		rel  string
+	}{
+		{"/var/lib/rkt/pods/run/a6f4a4a0/net/10-attached.conf", "net/10-attached.conf"},
+		{"/var/lib/rkt/pods/run/a6f4a4a0/stage1/rootfs/usr/lib/rkt/plugins/net/ptp", "stage1/rootfs/usr/lib/rkt/plugins/net/ptp"},
+		{"/usr/lib/rkt/plugins/net/ptp", "/usr/lib/rkt/plugins/net/ptp"},
+		{"/var/lib/rkt/pods/run/a6f4a4a0b", "/var/lib/rkt/pods/run/a6f4a4a0b"},
+		{"net/10-attached.conf", "net/10-attached.conf"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		rel := e.relPodPath(tt.path)
+		if rel != tt.rel {
+			t.Errorf("%q: expected relative path %q, got %q", tt.path, tt.rel, rel)
+		}
+		if tt.path != "" && e.podPath(rel) != e.podPath(tt.path) {
+			t.Errorf("%q: expected %q to resolve to %q, got %q", tt.path, rel, e.podPath(tt.path), e.podPath(rel))
+		}
+	}
+
+	// stage1 runs in the pod directory
+	e = podEnv{podRoot: "."}
+	if p := e.podPath("net/10-attached.conf"); p != "net/10-attached.conf" {
+		t.Errorf("expected %q, got %q", "net/10-attached.conf", p)
+	}
+}
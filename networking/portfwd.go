@@ -17,142 +17,172 @@ package networking
 import (
 	"fmt"
 	"net"
-	"strconv"
 
-	"github.com/coreos/go-iptables/iptables"
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
 )
 
-func (e *podEnv) forwardPorts(fps []ForwardedPort, defIP net.IP) error {
-	if len(fps) == 0 {
-		return nil
-	}
+// The port forwarding backends, set with the portForwarder field of
+// the network configuration.
+const (
+	portForwarderAuto      = "auto"
+	portForwarderIptables  = "iptables"
+	portForwarderNftables  = "nftables"
+	portForwarderUserspace = "userspace"
+)
 
-	ipt, err := iptables.New()
-	if err != nil {
-		return err
-	}
+// portForwarder is a backend setting up on the host the rules
+// forwarding ports to a pod.
+type portForwarder interface {
+	// Name returns the name of the backend.
+	Name() string
+	// Available tells whether the backend can be used on the host.
+	Available() bool
+	// ForwardPorts forwards the ports from the host to the pod.
+	ForwardPorts(pfs []portForwarding) error
+	// UnforwardPorts removes the port forwarding rules of the pod.
+	// It does not fail if there are no rules, so it can be called
+	// again to clean up after a crash.
+	UnforwardPorts() error
+}
 
-	// Create a separate chain for this pod. This helps with debugging
-	// and makes it easier to cleanup
-	chainDNAT := e.portFwdChain("DNAT")
-	chainSNAT := e.portFwdChain("SNAT")
+// portForwarding is a forwarded port with the IP of the pod on the
+// network the port is forwarded on.
+type portForwarding struct {
+	ForwardedPort
+	podIP net.IP
+}
 
-	if err = ipt.NewChain("nat", chainDNAT); err != nil {
-		return err
+// splitPortForwardings splits the port forwardings to IPv4 and IPv6 pod
+// addresses.
+func splitPortForwardings(pfs []portForwarding) (pfs4, pfs6 []portForwarding) {
+	for _, p := range pfs {
+		if p.podIP.To4() != nil {
+			pfs4 = append(pfs4, p)
+		} else {
+			pfs6 = append(pfs6, p)
+		}
 	}
+	return pfs4, pfs6
+}
 
-	if err = ipt.NewChain("nat", chainSNAT); err != nil {
-		return err
+func newPortForwarders(podID types.UUID) []portForwarder {
+	return []portForwarder{
+		newIptablesPortForwarder(podID),
+		newNftablesPortForwarder(podID),
+		newUserspacePortForwarder(),
 	}
+}
 
-	chainRuleDNAT := e.portFwdChainRuleSpec(chainDNAT, "DNAT")
-	chainRuleSNAT := e.portFwdChainRuleSpec(chainSNAT, "SNAT")
+// getPortForwarder returns the port forwarding backend set in the
+// configuration of the default network. The nftables backend is picked
+// automatically if iptables is not available, or if it is only a
+// compatibility layer over nftables, and the userspace proxy if neither
+// of them is available.
+func (n *Networking) getPortForwarder() (portForwarder, error) {
+	name := ""
+	if len(n.nets) > 0 {
+		name = n.nets[len(n.nets)-1].conf.PortForwarder
+	}
+	ipt := newIptablesPortForwarder(n.podID)
+	nft := newNftablesPortForwarder(n.podID)
+	switch name {
+	case "", portForwarderAuto:
+		switch {
+		case nft.Available() && (!ipt.Available() || ipt.usesNftables()):
+			return nft, nil
+		case ipt.Available():
+			return ipt, nil
+		default:
+			return newUserspacePortForwarder(), nil
+		}
+	case portForwarderIptables:
+		return ipt, nil
+	case portForwarderNftables:
+		return nft, nil
+	case portForwarderUserspace:
+		return newUserspacePortForwarder(), nil
+	default:
+		return nil, fmt.Errorf("unknown port forwarder %q, expected %q, %q, %q or %q", name, portForwarderAuto, portForwarderIptables, portForwarderNftables, portForwarderUserspace)
+	}
+}
 
-	for _, entry := range []struct {
-		chain           string
-		customChainRule []string
-	}{
-		{"POSTROUTING", chainRuleSNAT}, // traffic originating from this host
-		{"PREROUTING", chainRuleDNAT},  // outside traffic hitting this host
-		{"OUTPUT", chainRuleDNAT},      // traffic originating from this host
-	} {
-		exists, err := ipt.Exists("nat", entry.chain, entry.customChainRule...)
+func (n *Networking) forwardPorts(fps []ForwardedPort) error {
+	if len(fps) == 0 {
+		return nil
+	}
+	var pfs []portForwarding
+	for _, fp := range fps {
+		podIPs, err := n.getPodIPs(fp.Network)
 		if err != nil {
-			return err
+			return errwrap.Wrap(fmt.Errorf("cannot forward port %d/%s", fp.HostPort, fp.Protocol), err)
 		}
-		if !exists {
-			err = ipt.Insert("nat", entry.chain, 1, entry.customChainRule...)
-			if err != nil {
-				return err
+		// forward to the pod address of the family of the host IP,
+		// or to all the pod addresses
+		found := false
+		for _, podIP := range podIPs {
+			if fp.HostIP != nil && (fp.HostIP.To4() == nil) != (podIP.To4() == nil) {
+				continue
 			}
+			pfs = append(pfs, portForwarding{ForwardedPort: fp, podIP: podIP})
+			found = true
 		}
-	}
-
-	for _, p := range fps {
-
-		dst := fmt.Sprintf("%v:%v", defIP, p.PodPort)
-		dstIP := fmt.Sprintf("%v", defIP)
-		dport := strconv.Itoa(int(p.HostPort))
-
-		for _, r := range []struct {
-			chain string
-			rule  []string
-		}{
-			{ // Rewrite the destination
-				chainDNAT,
-				[]string{
-					"-p", p.Protocol,
-					"--dport", dport,
-					"-j", "DNAT",
-					"--to-destination", dst,
-				},
-			},
-			{ // Rewrite the source for connections to localhost on the host
-				chainSNAT,
-				[]string{
-					"-p", p.Protocol,
-					"-s", "127.0.0.1",
-					"-d", dstIP,
-					"--dport", dport,
-					"-j", "MASQUERADE",
-				},
-			},
-		} {
-			if err := ipt.AppendUnique("nat", r.chain, r.rule...); err != nil {
-				return err
-			}
+		if !found {
+			return fmt.Errorf("cannot forward port %d/%s: the pod has no address of the family of host IP %v", fp.HostPort, fp.Protocol, fp.HostIP)
 		}
 	}
-	return nil
-}
-
-func (e *podEnv) unforwardPorts() error {
-	ipt, err := iptables.New()
+	pf, err := n.getPortForwarder()
 	if err != nil {
 		return err
 	}
-
-	chainDNAT := e.portFwdChain("DNAT")
-	chainSNAT := e.portFwdChain("SNAT")
-
-	chainRuleDNAT := e.portFwdChainRuleSpec(chainDNAT, "DNAT")
-	chainRuleSNAT := e.portFwdChainRuleSpec(chainSNAT, "SNAT")
-
-	// There's no clean way now to test if a chain exists or
-	// even if a rule exists if the chain is not present.
-	// So we swallow the errors for now :(
-	// TODO(eyakubovich): move to using libiptc for iptable
-	// manipulation
-
-	for _, entry := range []struct {
-		chain           string
-		customChainRule []string
-	}{
-		{"POSTROUTING", chainRuleSNAT}, // traffic originating on this host
-		{"PREROUTING", chainRuleDNAT},  // outside traffic hitting this host
-		{"OUTPUT", chainRuleDNAT},      // traffic originating on this host
-	} {
-		ipt.Delete("nat", entry.chain, entry.customChainRule...)
+	if !pf.Available() {
+		return fmt.Errorf("port forwarder %q is not available on the host", pf.Name())
 	}
-
-	for _, entry := range []string{chainDNAT, chainSNAT} {
-		ipt.ClearChain("nat", entry)
-		ipt.DeleteChain("nat", entry)
+	stderr.Printf("forwarding ports with %s", pf.Name())
+	if err := pf.ForwardPorts(pfs); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error forwarding ports with %s", pf.Name()), err)
 	}
 	return nil
 }
 
-func (e *podEnv) portFwdChain(name string) string {
-	return fmt.Sprintf("RKT-PFWD-%s-%s", name, e.podID.String()[0:8])
+// getPodIPs returns the IPv4 and IPv6 addresses of the pod on the named
+// network, or on the default network if the name is empty.
+func (n *Networking) getPodIPs(network string) ([]net.IP, error) {
+	var an *activeNet
+	switch {
+	case network != "":
+		for i := range n.nets {
+			if n.nets[i].conf.Name == network {
+				an = &n.nets[i]
+			}
+		}
+		if an == nil {
+			return nil, fmt.Errorf("the pod is not in network %q", network)
+		}
+	case len(n.nets) > 0:
+		an = &n.nets[len(n.nets)-1]
+	default:
+		return nil, fmt.Errorf("no default network")
+	}
+	ips := an.runtime.IPs()
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("the pod has no address on network %q", an.conf.Name)
+	}
+	return ips, nil
 }
 
-func (e *podEnv) portFwdChainRuleSpec(chain string, name string) []string {
-	switch name {
-	case "SNAT":
-		return []string{"-s", "127.0.0.1", "!", "-d", "127.0.0.1", "-j", chain}
-	case "DNAT":
-		return []string{"-m", "addrtype", "--dst-type", "LOCAL", "-j", chain}
-	default:
-		return nil
+// unforwardPorts removes the port forwarding rules of the pod. The
+// backend which set them up may be unknown after a crash, so the rules
+// of all the available backends are removed.
+func (n *Networking) unforwardPorts() error {
+	var firstErr error
+	for _, pf := range newPortForwarders(n.podID) {
+		if !pf.Available() {
+			continue
+		}
+		if err := pf.UnforwardPorts(); err != nil && firstErr == nil {
+			firstErr = errwrap.Wrap(fmt.Errorf("error removing forwarded ports with %s", pf.Name()), err)
+		}
 	}
+	return firstErr
 }
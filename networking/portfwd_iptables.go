@@ -0,0 +1,228 @@
+// Copyright 2015 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/hashicorp/errwrap"
+)
+
+// iptablesPortForwarder forwards the ports with iptables rules, in
+// per-pod chains of the nat table.
+type iptablesPortForwarder struct {
+	podID types.UUID
+}
+
+func newIptablesPortForwarder(podID types.UUID) *iptablesPortForwarder {
+	return &iptablesPortForwarder{podID: podID}
+}
+
+func (f *iptablesPortForwarder) Name() string {
+	return portForwarderIptables
+}
+
+func (f *iptablesPortForwarder) Available() bool {
+	_, err := exec.LookPath("iptables")
+	return err == nil
+}
+
+// usesNftables tells whether iptables is the compatibility layer over
+// nftables.
+func (f *iptablesPortForwarder) usesNftables() bool {
+	out, err := exec.Command("iptables", "--version").Output()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(out), "nf_tables")
+}
+
+func (f *iptablesPortForwarder) ForwardPorts(pfs []portForwarding) error {
+	pfs4, pfs6 := splitPortForwardings(pfs)
+	if len(pfs4) > 0 {
+		if err := f.forwardPorts(iptables.ProtocolIPv4, pfs4); err != nil {
+			return err
+		}
+	}
+	if len(pfs6) > 0 {
+		if err := f.forwardPorts(iptables.ProtocolIPv6, pfs6); err != nil {
+			return errwrap.Wrap(errors.New("error forwarding IPv6 ports"), err)
+		}
+	}
+	return nil
+}
+
+// forwardPorts forwards the ports of a single family, with iptables or
+// ip6tables. IPv6 connections to localhost cannot be forwarded, as
+// the kernel never routes ::1 out of the loopback interface.
+func (f *iptablesPortForwarder) forwardPorts(proto iptables.Protocol, pfs []portForwarding) error {
+	ipt, err := iptables.NewWithProtocol(proto)
+	if err != nil {
+		return err
+	}
+	ipv4 := proto == iptables.ProtocolIPv4
+
+	// Create a separate chain for this pod. This helps with debugging
+	// and makes it easier to cleanup
+	chainDNAT := f.portFwdChain("DNAT")
+	chainSNAT := f.portFwdChain("SNAT")
+
+	if err = ipt.NewChain("nat", chainDNAT); err != nil {
+		return err
+	}
+
+	if ipv4 {
+		if err = ipt.NewChain("nat", chainSNAT); err != nil {
+			return err
+		}
+	}
+
+	chainRuleDNAT := f.portFwdChainRuleSpec(chainDNAT, "DNAT")
+	chainRuleSNAT := f.portFwdChainRuleSpec(chainSNAT, "SNAT")
+
+	type chainRule struct {
+		chain string
+		rule  []string
+	}
+
+	entries := []chainRule{
+		{"PREROUTING", chainRuleDNAT}, // outside traffic hitting this host
+		{"OUTPUT", chainRuleDNAT},     // traffic originating from this host
+	}
+	if ipv4 {
+		entries = append(entries, chainRule{"POSTROUTING", chainRuleSNAT}) // traffic originating from this host
+	}
+	for _, entry := range entries {
+		exists, err := ipt.Exists("nat", entry.chain, entry.rule...)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			err = ipt.Insert("nat", entry.chain, 1, entry.rule...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range pfs {
+
+		dst := net.JoinHostPort(p.podIP.String(), strconv.Itoa(int(p.PodPort)))
+		dstIP := fmt.Sprintf("%v", p.podIP)
+		dport := strconv.Itoa(int(p.HostPort))
+
+		ruleDNAT := []string{"-p", p.Protocol}
+		if p.HostIP != nil {
+			ruleDNAT = append(ruleDNAT, "-d", p.HostIP.String())
+		}
+		ruleDNAT = append(ruleDNAT,
+			"--dport", dport,
+			"-j", "DNAT",
+			"--to-destination", dst,
+		)
+
+		rules := []chainRule{
+			{ // Rewrite the destination
+				chainDNAT,
+				ruleDNAT,
+			},
+		}
+		if ipv4 {
+			rules = append(rules, chainRule{ // Rewrite the source for connections to localhost on the host
+				chainSNAT,
+				[]string{
+					"-p", p.Protocol,
+					"-s", "127.0.0.1",
+					"-d", dstIP,
+					"--dport", dport,
+					"-j", "MASQUERADE",
+				},
+			})
+		}
+		for _, r := range rules {
+			if err := ipt.AppendUnique("nat", r.chain, r.rule...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *iptablesPortForwarder) UnforwardPorts() error {
+	for _, proto := range []iptables.Protocol{iptables.ProtocolIPv4, iptables.ProtocolIPv6} {
+		ipt, err := iptables.NewWithProtocol(proto)
+		if err != nil {
+			if proto == iptables.ProtocolIPv4 {
+				return err
+			}
+			// no ip6tables, so no IPv6 ports were forwarded
+			continue
+		}
+		f.unforwardPorts(ipt)
+	}
+	return nil
+}
+
+func (f *iptablesPortForwarder) unforwardPorts(ipt *iptables.IPTables) {
+	chainDNAT := f.portFwdChain("DNAT")
+	chainSNAT := f.portFwdChain("SNAT")
+
+	chainRuleDNAT := f.portFwdChainRuleSpec(chainDNAT, "DNAT")
+	chainRuleSNAT := f.portFwdChainRuleSpec(chainSNAT, "SNAT")
+
+	// There's no clean way now to test if a chain exists or
+	// even if a rule exists if the chain is not present.
+	// So we swallow the errors for now :(
+	// TODO(eyakubovich): move to using libiptc for iptable
+	// manipulation
+
+	for _, entry := range []struct {
+		chain           string
+		customChainRule []string
+	}{
+		{"POSTROUTING", chainRuleSNAT}, // traffic originating on this host
+		{"PREROUTING", chainRuleDNAT},  // outside traffic hitting this host
+		{"OUTPUT", chainRuleDNAT},      // traffic originating on this host
+	} {
+		ipt.Delete("nat", entry.chain, entry.customChainRule...)
+	}
+
+	for _, entry := range []string{chainDNAT, chainSNAT} {
+		ipt.ClearChain("nat", entry)
+		ipt.DeleteChain("nat", entry)
+	}
+}
+
+func (f *iptablesPortForwarder) portFwdChain(name string) string {
+	return fmt.Sprintf("RKT-PFWD-%s-%s", name, f.podID.String()[0:8])
+}
+
+func (f *iptablesPortForwarder) portFwdChainRuleSpec(chain string, name string) []string {
+	switch name {
+	case "SNAT":
+		return []string{"-s", "127.0.0.1", "!", "-d", "127.0.0.1", "-j", chain}
+	case "DNAT":
+		return []string{"-m", "addrtype", "--dst-type", "LOCAL", "-j", chain}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+)
+
+// nftablesPortForwarder forwards the ports with nftables rules, in
+// per-pod tables. The tables are replaced and deleted atomically, so
+// setting up and removing the rules are idempotent.
+type nftablesPortForwarder struct {
+	podID types.UUID
+}
+
+func newNftablesPortForwarder(podID types.UUID) *nftablesPortForwarder {
+	return &nftablesPortForwarder{podID: podID}
+}
+
+func (f *nftablesPortForwarder) Name() string {
+	return portForwarderNftables
+}
+
+func (f *nftablesPortForwarder) Available() bool {
+	_, err := exec.LookPath("nft")
+	return err == nil
+}
+
+func (f *nftablesPortForwarder) ForwardPorts(pfs []portForwarding) error {
+	return f.run(f.ruleset(pfs))
+}
+
+func (f *nftablesPortForwarder) UnforwardPorts() error {
+	return f.run(f.deleteTables())
+}
+
+func (f *nftablesPortForwarder) table() string {
+	return fmt.Sprintf("rkt_pfwd_%s", f.podID.String()[0:8])
+}
+
+// deleteTables returns the commands deleting the IPv4 and IPv6 tables of
+// the pod. The tables are added first, so deleting them does not fail
+// if they do not exist.
+func (f *nftablesPortForwarder) deleteTables() string {
+	var b bytes.Buffer
+	for _, family := range []string{"ip", "ip6"} {
+		fmt.Fprintf(&b, "add table %s %s\ndelete table %s %s\n", family, f.table(), family, f.table())
+	}
+	return b.String()
+}
+
+// ruleset returns the commands replacing the tables of the pod with ones
+// forwarding the ports, with the same rules as the iptables backend.
+func (f *nftablesPortForwarder) ruleset(pfs []portForwarding) string {
+	var b bytes.Buffer
+	b.WriteString(f.deleteTables())
+	pfs4, pfs6 := splitPortForwardings(pfs)
+	if len(pfs4) > 0 {
+		f.writeTable(&b, "ip", pfs4)
+	}
+	if len(pfs6) > 0 {
+		f.writeTable(&b, "ip6", pfs6)
+	}
+	return b.String()
+}
+
+// writeTable writes the table of a family. IPv6 connections to localhost
+// cannot be forwarded, as the kernel never routes ::1 out of the loopback
+// interface.
+func (f *nftablesPortForwarder) writeTable(b *bytes.Buffer, family string, pfs []portForwarding) {
+	fmt.Fprintf(b, "table %s %s {\n", family, f.table())
+	// outside traffic hitting this host, and traffic originating
+	// from this host
+	for _, hook := range []string{"prerouting", "output"} {
+		fmt.Fprintf(b, "\tchain %s {\n", hook)
+		fmt.Fprintf(b, "\t\ttype nat hook %s priority -100;\n", hook)
+		b.WriteString("\t\tfib daddr type local jump pfwd_dnat\n")
+		b.WriteString("\t}\n")
+	}
+	if family == "ip" {
+		// traffic originating from this host to localhost
+		b.WriteString("\tchain postrouting {\n")
+		b.WriteString("\t\ttype nat hook postrouting priority 100;\n")
+		b.WriteString("\t\tip saddr 127.0.0.1 ip daddr != 127.0.0.1 jump pfwd_snat\n")
+		b.WriteString("\t}\n")
+	}
+
+	b.WriteString("\tchain pfwd_dnat {\n")
+	for _, p := range pfs {
+		// rewrite the destination
+		b.WriteString("\t\t")
+		if p.HostIP != nil {
+			fmt.Fprintf(b, "%s daddr %s ", family, p.HostIP)
+		}
+		dst := net.JoinHostPort(p.podIP.String(), strconv.Itoa(int(p.PodPort)))
+		fmt.Fprintf(b, "%s dport %d dnat to %s\n", p.Protocol, p.HostPort, dst)
+	}
+	b.WriteString("\t}\n")
+	if family == "ip" {
+		b.WriteString("\tchain pfwd_snat {\n")
+		for _, p := range pfs {
+			// rewrite the source for connections to localhost on
+			// the host, the destination is already rewritten
+			fmt.Fprintf(b, "\t\tip daddr %s %s dport %d masquerade\n", p.podIP, p.Protocol, p.PodPort)
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+}
+
+// run runs the nft commands as a single transaction.
+func (f *nftablesPortForwarder) run(commands string) error {
+	cmd := exec.Command("nft", "-f", "/dev/stdin")
+	cmd.Stdin = strings.NewReader(commands)
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return errwrap.Wrap(fmt.Errorf("nft failed: %s", strings.TrimSpace(errOut.String())), err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestNftablesRuleset(t *testing.T) {
+	podID, err := types.NewUUID("6c2a4f1e-9b0d-4c1e-8a1e-0f3d5b7c9e21")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := newNftablesPortForwarder(*podID)
+	podIP := net.ParseIP("172.16.28.2")
+	pfs := []portForwarding{
+		{ForwardedPort{Protocol: "tcp", HostPort: 8888, PodPort: 80}, podIP},
+		{ForwardedPort{Protocol: "udp", HostPort: 5353, PodPort: 53}, podIP},
+		{ForwardedPort{Protocol: "tcp", HostIP: net.ParseIP("10.0.0.1"), HostPort: 8080, PodPort: 8080}, net.ParseIP("172.16.29.2")},
+		{ForwardedPort{Protocol: "tcp", HostPort: 8888, PodPort: 80}, net.ParseIP("fd00::2")},
+	}
+	ruleset := f.ruleset(pfs)
+
+	// the old tables must be deleted first, without failing if they
+	// do not exist
+	expectedPrefix := "add table ip rkt_pfwd_6c2a4f1e\ndelete table ip rkt_pfwd_6c2a4f1e\n" +
+		"add table ip6 rkt_pfwd_6c2a4f1e\ndelete table ip6 rkt_pfwd_6c2a4f1e\n" +
+		"table ip rkt_pfwd_6c2a4f1e {\n"
+	if !strings.HasPrefix(ruleset, expectedPrefix) {
+		t.Errorf("expected the ruleset to start with %q, got:\n%s", expectedPrefix, ruleset)
+	}
+	for _, rule := range []string{
+		"tcp dport 8888 dnat to 172.16.28.2:80",
+		"udp dport 5353 dnat to 172.16.28.2:53",
+		"ip daddr 172.16.28.2 tcp dport 80 masquerade",
+		"ip daddr 172.16.28.2 udp dport 53 masquerade",
+		"ip daddr 10.0.0.1 tcp dport 8080 dnat to 172.16.29.2:8080",
+		"ip daddr 172.16.29.2 tcp dport 8080 masquerade",
+		"table ip6 rkt_pfwd_6c2a4f1e {",
+		"tcp dport 8888 dnat to [fd00::2]:80",
+	} {
+		if !strings.Contains(ruleset, rule) {
+			t.Errorf("expected rule %q in the ruleset, got:\n%s", rule, ruleset)
+		}
+	}
+	// connections to ::1 cannot be forwarded
+	if strings.Contains(ruleset, "fd00::2 tcp dport 80 masquerade") {
+		t.Errorf("unexpected IPv6 localhost rule in the ruleset:\n%s", ruleset)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coreos/rkt/pkg/multicall"
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	portProxyMulticallName = "port-proxy"
+	portProxyFdNum         = 3
+
+	// udpSessionTimeout is the time after which a UDP session without
+	// traffic is closed
+	udpSessionTimeout = 30 * time.Second
+)
+
+var portProxyEntrypoint multicall.Entrypoint
+
+func init() {
+	portProxyEntrypoint = multicall.Add(portProxyMulticallName, portProxyCommand)
+}
+
+// userspacePortForwarder forwards the ports with a proxy process per
+// port, copying the traffic between the host and the pod. It does not
+// need netfilter, and the ports can be reached from localhost.
+type userspacePortForwarder struct{}
+
+func newUserspacePortForwarder() *userspacePortForwarder {
+	return &userspacePortForwarder{}
+}
+
+func (f *userspacePortForwarder) Name() string {
+	return portForwarderUserspace
+}
+
+func (f *userspacePortForwarder) Available() bool {
+	return true
+}
+
+// ForwardPorts binds the host ports and hands them over to the proxy
+// processes, so binding errors are reported here. The proxies get a
+// SIGTERM when their parent exits, and stage1 execs the pod process
+// from the thread starting them, so they stop with the pod.
+func (f *userspacePortForwarder) ForwardPorts(pfs []portForwarding) error {
+	for _, p := range pfs {
+		hostAddr := net.JoinHostPort(hostIPString(p.HostIP), strconv.Itoa(int(p.HostPort)))
+		podAddr := net.JoinHostPort(p.podIP.String(), strconv.Itoa(int(p.PodPort)))
+		file, err := listenFile(p.Protocol, p.podIP, hostAddr)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot listen on %s/%s", hostAddr, p.Protocol), err)
+		}
+		cmd := portProxyEntrypoint.Cmd(p.Protocol, podAddr)
+		cmd.ExtraFiles = []*os.File{file}
+		cmd.Stderr = os.Stderr
+		err = cmd.Start()
+		file.Close()
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot start the proxy for %s/%s", hostAddr, p.Protocol), err)
+		}
+	}
+	return nil
+}
+
+// UnforwardPorts does nothing, the proxies stop with the pod.
+func (f *userspacePortForwarder) UnforwardPorts() error {
+	return nil
+}
+
+func hostIPString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// listenFile listens on the address for the family of the pod IP only,
+// and returns the file of the socket. Without a host IP, a port of a
+// dual-stack pod is forwarded to both its addresses, so it is bound once
+// for each family.
+func listenFile(protocol string, podIP net.IP, addr string) (*os.File, error) {
+	network := protocol + "6"
+	if podIP.To4() != nil {
+		network = protocol + "4"
+	}
+	switch protocol {
+	case "tcp":
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		return l.(*net.TCPListener).File()
+	case "udp":
+		c, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+		return c.(*net.UDPConn).File()
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+// portProxyCommand is the proxy process, it copies the traffic of the
+// socket passed as fd 3 to the pod address.
+func portProxyCommand() error {
+	if len(os.Args) != 3 {
+		return fmt.Errorf("incorrect number of arguments. Usage: %s {tcp|udp} PODIP:PORT", portProxyMulticallName)
+	}
+	protocol, podAddr := os.Args[1], os.Args[2]
+	file := os.NewFile(portProxyFdNum, "listener")
+	defer file.Close()
+
+	switch protocol {
+	case "tcp":
+		l, err := net.FileListener(file)
+		if err != nil {
+			return errwrap.Wrap(errors.New("error getting the listener"), err)
+		}
+		return proxyTCP(l, podAddr)
+	case "udp":
+		c, err := net.FilePacketConn(file)
+		if err != nil {
+			return errwrap.Wrap(errors.New("error getting the packet connection"), err)
+		}
+		return proxyUDP(c, podAddr, udpSessionTimeout)
+	default:
+		return fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+// proxyTCP accepts the connections of the listener and copies them to
+// new connections to the pod address, until the listener is closed.
+func proxyTCP(l net.Listener, podAddr string) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			podConn, err := net.Dial("tcp", podAddr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: cannot connect to %s: %v\n", portProxyMulticallName, podAddr, err)
+				return
+			}
+			defer podConn.Close()
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go copyHalf(conn, podConn, &wg)
+			go copyHalf(podConn, conn, &wg)
+			wg.Wait()
+		}(conn)
+	}
+}
+
+// copyHalf copies src to dst, and closes dst for writing when src is
+// drained, so half-closed connections work.
+func copyHalf(dst, src net.Conn, wg *sync.WaitGroup) {
+	defer wg.Done()
+	io.Copy(dst, src)
+	if c, ok := dst.(*net.TCPConn); ok {
+		c.CloseWrite()
+	} else {
+		dst.Close()
+	}
+}
+
+// proxyUDP copies the datagrams of the packet connection to the pod
+// address, from a connection per client so the replies go back to the
+// right client. A client connection is closed after the timeout without
+// traffic.
+func proxyUDP(c net.PacketConn, podAddr string, timeout time.Duration) error {
+	var mu sync.Mutex
+	sessions := make(map[string]net.Conn)
+	buf := make([]byte, 65535)
+	for {
+		n, clientAddr, err := c.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		podConn, ok := sessions[clientAddr.String()]
+		if !ok {
+			podConn, err = net.Dial("udp", podAddr)
+			if err != nil {
+				mu.Unlock()
+				fmt.Fprintf(os.Stderr, "%s: cannot connect to %s: %v\n", portProxyMulticallName, podAddr, err)
+				continue
+			}
+			sessions[clientAddr.String()] = podConn
+			go func(podConn net.Conn, clientAddr net.Addr) {
+				defer func() {
+					mu.Lock()
+					delete(sessions, clientAddr.String())
+					mu.Unlock()
+					podConn.Close()
+				}()
+				replyBuf := make([]byte, 65535)
+				for {
+					podConn.SetReadDeadline(time.Now().Add(timeout))
+					n, err := podConn.Read(replyBuf)
+					if err != nil {
+						return
+					}
+					if _, err := c.WriteTo(replyBuf[:n], clientAddr); err != nil {
+						return
+					}
+				}
+			}(podConn, clientAddr)
+		}
+		mu.Unlock()
+		podConn.Write(buf[:n])
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/pkg/multicall"
+)
+
+func init() {
+	multicall.MaybeExec()
+}
+
+// serveEcho echoes the connections of the listener, until it is closed.
+func serveEcho(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			io.Copy(conn, conn)
+			conn.Close()
+		}()
+	}
+}
+
+// checkEcho checks that a line sent to the TCP address is echoed.
+func checkEcho(t *testing.T, addr string) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("HELO\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if answer != "HELO\n" {
+		t.Errorf("expected %q, got %q", "HELO\n", answer)
+	}
+}
+
+func TestProxyTCP(t *testing.T) {
+	// the pod side, an echo server
+	pod, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pod.Close()
+	go serveEcho(pod)
+
+	host, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer host.Close()
+	go proxyTCP(host, pod.Addr().String())
+
+	checkEcho(t, host.Addr().String())
+}
+
+func TestProxyUDP(t *testing.T) {
+	// the pod side, an echo server
+	pod, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pod.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pod.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pod.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	host, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer host.Close()
+	go proxyUDP(host, pod.LocalAddr().String(), time.Second)
+
+	// each client gets its own replies
+	for _, msg := range []string{"HELO", "EHLO"} {
+		conn, err := net.Dial("udp", host.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(buf[:n]) != msg {
+			t.Errorf("expected %q, got %q", msg, buf[:n])
+		}
+	}
+}
+
+func TestUserspacePortForwarder(t *testing.T) {
+	// the pod side, an echo server
+	pod, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pod.Close()
+	go serveEcho(pod)
+	podPort := pod.Addr().(*net.TCPAddr).Port
+
+	// get a free host port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hostPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	f := newUserspacePortForwarder()
+	pfs := []portForwarding{
+		{
+			ForwardedPort{Protocol: "tcp", HostIP: net.ParseIP("127.0.0.1"), HostPort: uint(hostPort), PodPort: uint(podPort)},
+			net.ParseIP("127.0.0.1"),
+		},
+	}
+	if err := f.ForwardPorts(pfs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkEcho(t, l.Addr().String())
+
+	// the host port is already bound by the proxy
+	if err := f.ForwardPorts(pfs); err == nil {
+		t.Errorf("expected an error forwarding the same port twice")
+	}
+}
+
+func TestUserspacePortForwarderDualStack(t *testing.T) {
+	// the pod side, an echo server on both addresses of the pod
+	pod4, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pod4.Close()
+	go serveEcho(pod4)
+	podPort := pod4.Addr().(*net.TCPAddr).Port
+	pod6, err := net.Listen("tcp6", net.JoinHostPort("::1", strconv.Itoa(podPort)))
+	if err != nil {
+		t.Skipf("cannot listen on the IPv6 loopback: %v", err)
+	}
+	defer pod6.Close()
+	go serveEcho(pod6)
+
+	// get a free host port
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hostPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// without a host IP, the port is forwarded to both pod addresses
+	fp := ForwardedPort{Protocol: "tcp", HostPort: uint(hostPort), PodPort: uint(podPort)}
+	pfs := []portForwarding{
+		{fp, net.ParseIP("127.0.0.1")},
+		{fp, net.ParseIP("::1")},
+	}
+	if err := newUserspacePortForwarder().ForwardPorts(pfs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkEcho(t, net.JoinHostPort("127.0.0.1", strconv.Itoa(hostPort)))
+	checkEcho(t, net.JoinHostPort("::1", strconv.Itoa(hostPort)))
+}
@@ -42,3 +42,7 @@ func Lgetxattr(path string, attr string) ([]byte, error) {
 func Lsetxattr(path string, attr string, data []byte, flags int) error {
 	return ErrNotSupportedPlatform
 }
+
+func Reflink(dest, src *os.File) error {
+	return ErrNotSupportedPlatform
+}
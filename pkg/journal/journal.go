@@ -0,0 +1,339 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journal implements a reader for the systemd journal file format.
+// It only supports what is needed to read the entries of the journal of a
+// pod without linking against libsystemd: it does not verify the hash
+// tables nor the seals, and it skips compressed fields.
+//
+// See https://www.freedesktop.org/wiki/Software/systemd/journal-files/
+package journal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	headerSignature = "LPKSHHRH"
+	// minHeaderSize is the size of the header of the oldest journal
+	// files we support, it contains every field we read.
+	minHeaderSize = 208
+
+	incompatibleCompact = 1 << 4
+
+	objectHeaderSize = 16
+
+	objectTypeData       = 1
+	objectTypeEntry      = 3
+	objectTypeEntryArray = 6
+
+	objectCompressedMask = 1<<0 | 1<<1 | 1<<2 // XZ, LZ4, ZSTD
+
+	dataPayloadOffset        = 64
+	dataPayloadOffsetCompact = 72
+	entryItemsOffset         = 64
+	entryArrayItemsOffset    = 24
+)
+
+var (
+	ErrInvalidSignature = errors.New("not a journal file")
+)
+
+// Entry is a journal entry.
+type Entry struct {
+	// Seqnum is the sequence number of the entry.
+	Seqnum uint64
+	// Realtime is the wallclock time of the entry.
+	Realtime time.Time
+	// Fields are the fields of the entry, compressed fields are omitted.
+	Fields map[string]string
+}
+
+// Before returns true if the entry e was written before the entry o.
+func (e *Entry) Before(o *Entry) bool {
+	if e.Realtime.Equal(o.Realtime) {
+		return e.Seqnum < o.Seqnum
+	}
+	return e.Realtime.Before(o.Realtime)
+}
+
+// File is an open journal file.
+type File struct {
+	f       *os.File
+	size    int64
+	compact bool
+
+	entryArrayOffset uint64
+	// fields caches the fields of the data objects by offset, as the
+	// same data object is shared by many entries.
+	fields map[uint64]string
+}
+
+// Open opens the journal file at path.
+// The file must be closed using File.Close()
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &File{
+		f:      f,
+		fields: make(map[uint64]string),
+	}
+	if err := j.readHeader(); err != nil {
+		f.Close()
+		return nil, errwrap.Wrap(fmt.Errorf("cannot read journal file %q", path), err)
+	}
+
+	return j, nil
+}
+
+// Close closes the journal file.
+func (j *File) Close() error {
+	return j.f.Close()
+}
+
+func (j *File) readHeader() error {
+	fi, err := j.f.Stat()
+	if err != nil {
+		return err
+	}
+	j.size = fi.Size()
+
+	h := make([]byte, minHeaderSize)
+	if _, err := j.f.ReadAt(h, 0); err != nil {
+		return errwrap.Wrap(errors.New("cannot read header"), err)
+	}
+	if string(h[0:8]) != headerSignature {
+		return ErrInvalidSignature
+	}
+
+	incompatibleFlags := binary.LittleEndian.Uint32(h[12:16])
+	j.compact = incompatibleFlags&incompatibleCompact != 0
+	j.entryArrayOffset = binary.LittleEndian.Uint64(h[176:184])
+
+	return nil
+}
+
+// readObject reads the whole object at offset and checks its type.
+func (j *File) readObject(offset uint64, typ byte) (flags byte, data []byte, err error) {
+	h := make([]byte, objectHeaderSize)
+	if offset == 0 || int64(offset)+objectHeaderSize > j.size {
+		return 0, nil, fmt.Errorf("object offset %d out of bounds", offset)
+	}
+	if _, err := j.f.ReadAt(h, int64(offset)); err != nil {
+		return 0, nil, err
+	}
+	if h[0] != typ {
+		return 0, nil, fmt.Errorf("object at offset %d has type %d, expected %d", offset, h[0], typ)
+	}
+	size := binary.LittleEndian.Uint64(h[8:16])
+	if size < objectHeaderSize || int64(offset+size) > j.size {
+		return 0, nil, fmt.Errorf("object at offset %d has invalid size %d", offset, size)
+	}
+
+	data = make([]byte, size)
+	if _, err := j.f.ReadAt(data, int64(offset)); err != nil {
+		return 0, nil, err
+	}
+	return h[1], data, nil
+}
+
+// readField returns the "FIELD=value" payload of the data object at offset.
+// ok is false if the payload is compressed.
+func (j *File) readField(offset uint64) (field string, ok bool, err error) {
+	if field, ok := j.fields[offset]; ok {
+		return field, true, nil
+	}
+
+	flags, data, err := j.readObject(offset, objectTypeData)
+	if err != nil {
+		return "", false, err
+	}
+	if flags&objectCompressedMask != 0 {
+		return "", false, nil
+	}
+
+	payloadOffset := dataPayloadOffset
+	if j.compact {
+		payloadOffset = dataPayloadOffsetCompact
+	}
+	if len(data) < payloadOffset {
+		return "", false, fmt.Errorf("data object at offset %d too small", offset)
+	}
+
+	field = string(data[payloadOffset:])
+	j.fields[offset] = field
+	return field, true, nil
+}
+
+func (j *File) readEntry(offset uint64) (*Entry, error) {
+	_, data, err := j.readObject(offset, objectTypeEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < entryItemsOffset {
+		return nil, fmt.Errorf("entry object at offset %d too small", offset)
+	}
+
+	e := &Entry{
+		Seqnum:   binary.LittleEndian.Uint64(data[16:24]),
+		Realtime: usecToTime(binary.LittleEndian.Uint64(data[24:32])),
+		Fields:   make(map[string]string),
+	}
+
+	itemSize := 16
+	if j.compact {
+		itemSize = 4
+	}
+	for i := entryItemsOffset; i+itemSize <= len(data); i += itemSize {
+		var dataOffset uint64
+		if j.compact {
+			dataOffset = uint64(binary.LittleEndian.Uint32(data[i : i+4]))
+		} else {
+			dataOffset = binary.LittleEndian.Uint64(data[i : i+8])
+		}
+
+		field, ok, err := j.readField(dataOffset)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		e.Fields[kv[0]] = kv[1]
+	}
+
+	return e, nil
+}
+
+// entryOffsets walks the chain of entry arrays.
+func (j *File) entryOffsets() ([]uint64, error) {
+	var offsets []uint64
+
+	itemSize := 8
+	if j.compact {
+		itemSize = 4
+	}
+
+	for offset := j.entryArrayOffset; offset != 0; {
+		_, data, err := j.readObject(offset, objectTypeEntryArray)
+		if err != nil {
+			return offsets, err
+		}
+		if len(data) < entryArrayItemsOffset {
+			return offsets, fmt.Errorf("entry array object at offset %d too small", offset)
+		}
+
+		for i := entryArrayItemsOffset; i+itemSize <= len(data); i += itemSize {
+			var entryOffset uint64
+			if j.compact {
+				entryOffset = uint64(binary.LittleEndian.Uint32(data[i : i+4]))
+			} else {
+				entryOffset = binary.LittleEndian.Uint64(data[i : i+8])
+			}
+			// Entry arrays are preallocated, the unused items are zero.
+			if entryOffset == 0 {
+				return offsets, nil
+			}
+			offsets = append(offsets, entryOffset)
+		}
+
+		offset = binary.LittleEndian.Uint64(data[16:24])
+	}
+
+	return offsets, nil
+}
+
+// Entries returns the entries of the journal file, in the order they were
+// written.
+//
+// A journal file can be read while journald is writing to it, in which case
+// the last entry can be incomplete. Reading stops at the first entry that
+// cannot be read and the entries read until then are returned.
+func (j *File) Entries() ([]*Entry, error) {
+	// The file may have grown since it was opened.
+	if err := j.readHeader(); err != nil {
+		return nil, err
+	}
+
+	offsets, _ := j.entryOffsets()
+
+	var entries []*Entry
+	for _, offset := range offsets {
+		e, err := j.readEntry(offset)
+		if err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// ReadDir reads the entries of all the journal files (active and archived)
+// in dir, sorted by time.
+func ReadDir(dir string) ([]*Entry, error) {
+	ls, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, fi := range ls {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".journal") {
+			continue
+		}
+
+		j, err := Open(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		fileEntries, err := j.Entries()
+		j.Close()
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot read entries of journal file %q", fi.Name()), err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	sort.Sort(byTime(entries))
+
+	return entries, nil
+}
+
+type byTime []*Entry
+
+func (b byTime) Len() int           { return len(b) }
+func (b byTime) Less(i, j int) bool { return b[i].Before(b[j]) }
+func (b byTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+func usecToTime(usec uint64) time.Time {
+	return time.Unix(int64(usec/1e6), int64(usec%1e6)*1e3)
+}
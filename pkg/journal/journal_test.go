@@ -0,0 +1,259 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEntry struct {
+	seqnum   uint64
+	realtime uint64 // usec
+	fields   []string
+	// compressed lists the indexes of the fields to write as compressed
+	// data objects.
+	compressed []int
+}
+
+// journalWriter writes minimal journal files: a header, the data and
+// entry objects, and a single entry array.
+type journalWriter struct {
+	buf     bytes.Buffer
+	compact bool
+}
+
+func (w *journalWriter) align() {
+	for w.buf.Len()%8 != 0 {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *journalWriter) le64(v uint64) {
+	binary.Write(&w.buf, binary.LittleEndian, v)
+}
+
+func (w *journalWriter) le32(v uint32) {
+	binary.Write(&w.buf, binary.LittleEndian, v)
+}
+
+func (w *journalWriter) objectHeader(typ, flags byte, size int) {
+	w.buf.Write([]byte{typ, flags, 0, 0, 0, 0, 0, 0})
+	w.le64(uint64(size))
+}
+
+func (w *journalWriter) writeData(field string, compressed bool) uint64 {
+	w.align()
+	offset := uint64(w.buf.Len())
+	payloadOffset := dataPayloadOffset
+	if w.compact {
+		payloadOffset = dataPayloadOffsetCompact
+	}
+	var flags byte
+	if compressed {
+		flags = 1
+	}
+	w.objectHeader(objectTypeData, flags, payloadOffset+len(field))
+	w.buf.Write(make([]byte, payloadOffset-objectHeaderSize))
+	w.buf.WriteString(field)
+	return offset
+}
+
+func (w *journalWriter) writeEntry(e testEntry, dataOffsets []uint64) uint64 {
+	w.align()
+	offset := uint64(w.buf.Len())
+	itemSize := 16
+	if w.compact {
+		itemSize = 4
+	}
+	w.objectHeader(objectTypeEntry, 0, entryItemsOffset+itemSize*len(dataOffsets))
+	w.le64(e.seqnum)
+	w.le64(e.realtime)
+	w.buf.Write(make([]byte, entryItemsOffset-32))
+	for _, o := range dataOffsets {
+		if w.compact {
+			w.le32(uint32(o))
+		} else {
+			w.le64(o)
+			w.le64(0)
+		}
+	}
+	return offset
+}
+
+func (w *journalWriter) writeEntryArray(entryOffsets []uint64, capacity int) uint64 {
+	w.align()
+	offset := uint64(w.buf.Len())
+	itemSize := 8
+	if w.compact {
+		itemSize = 4
+	}
+	w.objectHeader(objectTypeEntryArray, 0, entryArrayItemsOffset+itemSize*capacity)
+	w.le64(0)
+	for i := 0; i < capacity; i++ {
+		var o uint64
+		if i < len(entryOffsets) {
+			o = entryOffsets[i]
+		}
+		if w.compact {
+			w.le32(uint32(o))
+		} else {
+			w.le64(o)
+		}
+	}
+	return offset
+}
+
+func writeTestJournal(t *testing.T, path string, entries []testEntry, compact bool) {
+	w := &journalWriter{compact: compact}
+	w.buf.Write(make([]byte, minHeaderSize))
+
+	var entryOffsets []uint64
+	for _, e := range entries {
+		var dataOffsets []uint64
+		for i, f := range e.fields {
+			compressed := false
+			for _, c := range e.compressed {
+				if c == i {
+					compressed = true
+				}
+			}
+			dataOffsets = append(dataOffsets, w.writeData(f, compressed))
+		}
+		entryOffsets = append(entryOffsets, w.writeEntry(e, dataOffsets))
+	}
+	// Preallocate more items than needed, like journald does.
+	arrayOffset := w.writeEntryArray(entryOffsets, len(entryOffsets)+4)
+
+	b := w.buf.Bytes()
+	copy(b[0:8], headerSignature)
+	if compact {
+		binary.LittleEndian.PutUint32(b[12:16], incompatibleCompact)
+	}
+	binary.LittleEndian.PutUint64(b[88:96], minHeaderSize)
+	binary.LittleEndian.PutUint64(b[152:160], uint64(len(entries)))
+	binary.LittleEndian.PutUint64(b[176:184], arrayOffset)
+
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("error writing journal file: %v", err)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	entries := []testEntry{
+		{
+			seqnum:   1,
+			realtime: 1000000,
+			fields:   []string{"MESSAGE=hello", "_SYSTEMD_UNIT=foo.service"},
+		},
+		{
+			seqnum:     2,
+			realtime:   2500000,
+			fields:     []string{"MESSAGE=long message", "SYSLOG_IDENTIFIER=foo", "EMPTY="},
+			compressed: []int{0},
+		},
+	}
+	expected := []*Entry{
+		{
+			Seqnum:   1,
+			Realtime: time.Unix(1, 0),
+			Fields:   map[string]string{"MESSAGE": "hello", "_SYSTEMD_UNIT": "foo.service"},
+		},
+		{
+			Seqnum:   2,
+			Realtime: time.Unix(2, 500000000),
+			Fields:   map[string]string{"SYSLOG_IDENTIFIER": "foo", "EMPTY": ""},
+		},
+	}
+
+	for _, compact := range []bool{false, true} {
+		path := filepath.Join(dir, "system.journal")
+		writeTestJournal(t, path, entries, compact)
+
+		j, err := Open(path)
+		if err != nil {
+			t.Fatalf("compact=%v: unexpected error: %v", compact, err)
+		}
+		result, err := j.Entries()
+		j.Close()
+		if err != nil {
+			t.Fatalf("compact=%v: unexpected error: %v", compact, err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("compact=%v: got %v, want %v", compact, result, expected)
+		}
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "journal-test")
+	if err != nil {
+		t.Fatalf("error creating tempfile: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(make([]byte, minHeaderSize))
+	f.Close()
+
+	if _, err := Open(f.Name()); err == nil {
+		t.Errorf("expected an error opening a file without the journal signature")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestJournal(t, filepath.Join(dir, "system@0001.journal"), []testEntry{
+		{seqnum: 1, realtime: 1000000, fields: []string{"MESSAGE=one"}},
+		{seqnum: 3, realtime: 3000000, fields: []string{"MESSAGE=three"}},
+	}, false)
+	writeTestJournal(t, filepath.Join(dir, "system.journal"), []testEntry{
+		{seqnum: 2, realtime: 2000000, fields: []string{"MESSAGE=two"}},
+		{seqnum: 4, realtime: 3000000, fields: []string{"MESSAGE=four"}},
+	}, false)
+	// Not a journal file, must be ignored.
+	if err := ioutil.WriteFile(filepath.Join(dir, "system.journal~"), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	entries, err := ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.Fields["MESSAGE"])
+	}
+	expected := []string{"one", "two", "three", "four"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("got %v, want %v", messages, expected)
+	}
+}
@@ -24,7 +24,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/appc/spec/schema/types"
 	"github.com/coreos/rkt/common"
@@ -34,10 +36,12 @@ import (
 
 // A Config structure is used to configure a Keystore.
 type Config struct {
-	LocalRootPath    string
-	LocalPrefixPath  string
-	SystemRootPath   string
-	SystemPrefixPath string
+	LocalRootPath     string
+	LocalPrefixPath   string
+	LocalRevokedPath  string
+	SystemRootPath    string
+	SystemPrefixPath  string
+	SystemRevokedPath string
 }
 
 // A Keystore represents a repository of trusted public keys which can be
@@ -56,10 +60,12 @@ func New(config *Config) *Keystore {
 
 func NewConfig(systemPath, localPath string) *Config {
 	return &Config{
-		LocalRootPath:    filepath.Join(localPath, "trustedkeys", "root.d"),
-		LocalPrefixPath:  filepath.Join(localPath, "trustedkeys", "prefix.d"),
-		SystemRootPath:   filepath.Join(systemPath, "trustedkeys", "root.d"),
-		SystemPrefixPath: filepath.Join(systemPath, "trustedkeys", "prefix.d"),
+		LocalRootPath:     filepath.Join(localPath, "trustedkeys", "root.d"),
+		LocalPrefixPath:   filepath.Join(localPath, "trustedkeys", "prefix.d"),
+		LocalRevokedPath:  filepath.Join(localPath, "trustedkeys", "revoked.d"),
+		SystemRootPath:    filepath.Join(systemPath, "trustedkeys", "root.d"),
+		SystemPrefixPath:  filepath.Join(systemPath, "trustedkeys", "prefix.d"),
+		SystemRevokedPath: filepath.Join(systemPath, "trustedkeys", "revoked.d"),
 	}
 }
 
@@ -104,7 +110,23 @@ func checkSignature(ks *Keystore, prefix string, signed, signature io.ReadSeeker
 		// otherwise, the client failure is just "EOF", which is not helpful
 		return nil, fmt.Errorf("keystore: no valid signatures found in signature file")
 	}
-	return entities, err
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := signatureIssuer(signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.checkSignerValidity(entities, issuer, time.Now()); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// TrustedKeys returns the keys trusted for the given prefix, including
+// the root keys.
+func (ks *Keystore) TrustedKeys(prefix string) (openpgp.EntityList, error) {
+	return ks.loadKeyring(prefix)
 }
 
 // DeleteTrustedKeyPrefix deletes the prefix trusted key identified by fingerprint.
@@ -287,23 +309,216 @@ func entityFromFile(path string) (*openpgp.Entity, error) {
 	return entityList[0], nil
 }
 
-func (ks *Keystore) loadKeyring(prefix string) (openpgp.KeyRing, error) {
-	acidentifier, err := types.NewACIdentifier(prefix)
+// TrustedKeyPrefix returns the prefix the key with the given
+// fingerprint is trusted for, among the keys trusted for the given
+// prefix. The returned prefix is empty for a root key.
+func (ks *Keystore) TrustedKeyPrefix(prefix string, fingerprint [20]byte) (string, error) {
+	trustedKeys, err := ks.loadTrustedKeys(prefix)
+	if err != nil {
+		return "", err
+	}
+	key, ok := trustedKeys[fingerprintToFilename(fingerprint)]
+	if !ok {
+		return "", fmt.Errorf("key %x is not trusted for prefix %q", fingerprint, prefix)
+	}
+	return key.Prefix, nil
+}
+
+// A TrustedKey is a key of the keystore, trusted for the images with
+// the prefix, or for all the images if the prefix is empty.
+type TrustedKey struct {
+	Entity *openpgp.Entity
+	Prefix string
+	// System tells whether the key is in the system keystore.
+	System bool
+}
+
+// ListTrustedKeys returns the keys trusted for the given prefix,
+// including the root keys, sorted by prefix and fingerprint. All the
+// keys of the keystore are returned if prefix is empty.
+func (ks *Keystore) ListTrustedKeys(prefix string) ([]*TrustedKey, error) {
+	var trustedKeys map[string]*TrustedKey
+	var err error
+	if prefix == "" {
+		trustedKeys, err = ks.loadAllTrustedKeys()
+	} else {
+		trustedKeys, err = ks.loadTrustedKeys(prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*TrustedKey
+	for _, key := range trustedKeys {
+		keys = append(keys, key)
+	}
+	sort.Sort(trustedKeysByPrefix(keys))
+	return keys, nil
+}
+
+type trustedKeysByPrefix []*TrustedKey
+
+func (k trustedKeysByPrefix) Len() int      { return len(k) }
+func (k trustedKeysByPrefix) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k trustedKeysByPrefix) Less(i, j int) bool {
+	if k[i].Prefix != k[j].Prefix {
+		return k[i].Prefix < k[j].Prefix
+	}
+	return fingerprintToFilename(k[i].Entity.PrimaryKey.Fingerprint) < fingerprintToFilename(k[j].Entity.PrimaryKey.Fingerprint)
+}
+
+// RemoveTrustedKey removes the trust in the key with the given
+// fingerprint for prefix, or for all the images if prefix is empty.
+// The key is deleted from the local keystore, and masked if it is in
+// the system keystore.
+func (ks *Keystore) RemoveTrustedKey(prefix, fingerprint string) error {
+	localPath := path.Join(ks.LocalRootPath, fingerprint)
+	systemPath := path.Join(ks.SystemRootPath, fingerprint)
+	if prefix != "" {
+		acidentifier, err := types.NewACIdentifier(prefix)
+		if err != nil {
+			return err
+		}
+		localPath = path.Join(ks.LocalPrefixPath, acidentifier.String(), fingerprint)
+		systemPath = path.Join(ks.SystemPrefixPath, acidentifier.String(), fingerprint)
+	}
+	local, err := statKeyFile(localPath)
+	if err != nil {
+		return err
+	}
+	system, err := statKeyFile(systemPath)
+	if err != nil {
+		return err
+	}
+	inLocal := local != nil && local.Size() > 0
+	// a system key is trusted unless masked by an empty local file
+	inSystem := system != nil && system.Size() > 0 && (local == nil || inLocal)
+	if !inLocal && !inSystem {
+		if prefix == "" {
+			return fmt.Errorf("no root key with fingerprint %s", fingerprint)
+		}
+		return fmt.Errorf("no key with fingerprint %s trusted for prefix %q", fingerprint, prefix)
+	}
+	if inLocal {
+		if prefix == "" {
+			err = ks.DeleteTrustedKeyRoot(fingerprint)
+		} else {
+			err = ks.DeleteTrustedKeyPrefix(prefix, fingerprint)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if inSystem {
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			return err
+		}
+		if prefix == "" {
+			_, err = ks.MaskTrustedKeySystemRoot(fingerprint)
+		} else {
+			_, err = ks.MaskTrustedKeySystemPrefix(prefix, fingerprint)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statKeyFile returns the file info of the key file at path, or nil if
+// there is no such file.
+func statKeyFile(path string) (os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return info, err
+}
+
+func (ks *Keystore) loadKeyring(prefix string) (openpgp.EntityList, error) {
+	trustedKeys, err := ks.loadTrustedKeys(prefix)
 	if err != nil {
 		return nil, err
 	}
 	var keyring openpgp.EntityList
-	trustedKeys := make(map[string]*openpgp.Entity)
+	for _, v := range trustedKeys {
+		keyring = append(keyring, v.Entity)
+	}
+	return keyring, nil
+}
+
+// loadAllTrustedKeys returns all the keys of the keystore, keyed by
+// their prefix and fingerprint.
+func (ks *Keystore) loadAllTrustedKeys() (map[string]*TrustedKey, error) {
+	trustedKeys := make(map[string]*TrustedKey)
+	dirs := []struct {
+		dir string
+		// prefixPath is the directory of the prefixes, empty
+		// for the root keys
+		prefixPath string
+		system     bool
+	}{
+		{ks.SystemRootPath, "", true},
+		{ks.SystemPrefixPath, ks.SystemPrefixPath, true},
+		{ks.LocalRootPath, "", false},
+		{ks.LocalPrefixPath, ks.LocalPrefixPath, false},
+	}
+	for _, d := range dirs {
+		err := filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if info == nil || info.IsDir() {
+				return nil
+			}
+			key := &TrustedKey{System: d.system}
+			if d.prefixPath != "" {
+				key.Prefix, err = filepath.Rel(d.prefixPath, filepath.Dir(path))
+				if err != nil {
+					return err
+				}
+			}
+			id := key.Prefix + ":" + info.Name()
+			// Remove trust for default keys.
+			if info.Size() == 0 {
+				delete(trustedKeys, id)
+				return nil
+			}
+			key.Entity, err = entityFromFile(path)
+			if err != nil {
+				return err
+			}
+			trustedKeys[id] = key
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return trustedKeys, nil
+}
+
+// loadTrustedKeys returns the keys trusted for the given prefix, keyed
+// by their fingerprint.
+func (ks *Keystore) loadTrustedKeys(prefix string) (map[string]*TrustedKey, error) {
+	acidentifier, err := types.NewACIdentifier(prefix)
+	if err != nil {
+		return nil, err
+	}
+	trustedKeys := make(map[string]*TrustedKey)
 
 	prefixRoot := strings.Split(acidentifier.String(), "/")[0]
 	paths := []struct {
 		root     string
 		fullPath string
+		// prefixPath is the directory of the prefixes, empty
+		// for the root keys
+		prefixPath string
+		system     bool
 	}{
-		{ks.SystemRootPath, ks.SystemRootPath},
-		{ks.LocalRootPath, ks.LocalRootPath},
-		{path.Join(ks.SystemPrefixPath, prefixRoot), path.Join(ks.SystemPrefixPath, acidentifier.String())},
-		{path.Join(ks.LocalPrefixPath, prefixRoot), path.Join(ks.LocalPrefixPath, acidentifier.String())},
+		{ks.SystemRootPath, ks.SystemRootPath, "", true},
+		{ks.LocalRootPath, ks.LocalRootPath, "", false},
+		{path.Join(ks.SystemPrefixPath, prefixRoot), path.Join(ks.SystemPrefixPath, acidentifier.String()), ks.SystemPrefixPath, true},
+		{path.Join(ks.LocalPrefixPath, prefixRoot), path.Join(ks.LocalPrefixPath, acidentifier.String()), ks.LocalPrefixPath, false},
 	}
 	for _, p := range paths {
 		err := filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
@@ -330,18 +545,21 @@ func (ks *Keystore) loadKeyring(prefix string) (openpgp.KeyRing, error) {
 			if err != nil {
 				return err
 			}
-			trustedKeys[fingerprintToFilename(entity.PrimaryKey.Fingerprint)] = entity
+			key := &TrustedKey{Entity: entity, System: p.system}
+			if p.prefixPath != "" {
+				key.Prefix, err = filepath.Rel(p.prefixPath, filepath.Dir(path))
+				if err != nil {
+					return err
+				}
+			}
+			trustedKeys[fingerprintToFilename(entity.PrimaryKey.Fingerprint)] = key
 			return nil
 		})
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	for _, v := range trustedKeys {
-		keyring = append(keyring, v)
-	}
-	return keyring, nil
+	return trustedKeys, nil
 }
 
 func fingerprintToFilename(fp [20]byte) string {
@@ -359,7 +577,7 @@ func NewTestKeystore() (*Keystore, string, error) {
 	systemDir := filepath.Join(dir, common.DefaultSystemConfigDir)
 	localDir := filepath.Join(dir, common.DefaultLocalConfigDir)
 	c := NewConfig(systemDir, localDir)
-	for _, path := range []string{c.LocalRootPath, c.SystemRootPath, c.LocalPrefixPath, c.SystemPrefixPath} {
+	for _, path := range []string{c.LocalRootPath, c.SystemRootPath, c.LocalPrefixPath, c.SystemPrefixPath, c.LocalRevokedPath, c.SystemRevokedPath} {
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return nil, "", err
 		}
@@ -27,15 +27,20 @@ package keystore
 
 import (
 	"bytes"
+	"crypto"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/coreos/rkt/pkg/keystore/keystoretest"
 
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
 	"golang.org/x/crypto/openpgp/errors"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 func TestStoreTrustedKey(t *testing.T) {
@@ -172,6 +177,18 @@ func TestCheckSignature(t *testing.T) {
 			if fingerprint != key.Fingerprint {
 				t.Errorf("expected fingerprint == %v, got %v", key.Fingerprint, fingerprint)
 			}
+			expectedPrefix := tt.key
+			for _, root := range trustedRootKeys {
+				if tt.key == root {
+					expectedPrefix = ""
+				}
+			}
+			prefix, err := ks.TrustedKeyPrefix(tt.name, signer.PrimaryKey.Fingerprint)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			} else if prefix != expectedPrefix {
+				t.Errorf("expected key of %q trusted for prefix %q, got %q", tt.name, expectedPrefix, prefix)
+			}
 			continue
 		}
 		if err == nil {
@@ -183,3 +200,319 @@ func TestCheckSignature(t *testing.T) {
 		}
 	}
 }
+
+func TestListAndRemoveTrustedKeys(t *testing.T) {
+	ks, ksPath, err := NewTestKeystore()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(ksPath)
+
+	if _, err := ks.StoreTrustedKeyPrefix("example.com/app", bytes.NewBufferString(keystoretest.KeyMap["example.com/app"].ArmoredPublicKey)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := ks.StoreTrustedKeyRoot(bytes.NewBufferString(keystoretest.KeyMap["coreos.com"].ArmoredPublicKey)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dst := filepath.Join(ks.SystemRootPath, keystoretest.KeyMap["acme.com"].Fingerprint)
+	if err := ioutil.WriteFile(dst, []byte(keystoretest.KeyMap["acme.com"].ArmoredPublicKey), 0644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	checkKeys := func(prefix string, expected []string) {
+		keys, err := ks.ListTrustedKeys(prefix)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		var fingerprints []string
+		for _, key := range keys {
+			fingerprints = append(fingerprints, fmt.Sprintf("%s:%x", key.Prefix, key.Entity.PrimaryKey.Fingerprint))
+		}
+		if fmt.Sprint(fingerprints) != fmt.Sprint(expected) {
+			t.Errorf("expected keys %v for prefix %q, got %v", expected, prefix, fingerprints)
+		}
+	}
+	rootKeys := []string{
+		":" + keystoretest.KeyMap["acme.com"].Fingerprint,
+		":" + keystoretest.KeyMap["coreos.com"].Fingerprint,
+	}
+	if rootKeys[0] > rootKeys[1] {
+		rootKeys[0], rootKeys[1] = rootKeys[1], rootKeys[0]
+	}
+	appKey := "example.com/app:" + keystoretest.KeyMap["example.com/app"].Fingerprint
+	checkKeys("", append(rootKeys, appKey))
+	checkKeys("example.com/app/web", append(rootKeys, appKey))
+	checkKeys("acme.com", rootKeys)
+
+	if err := ks.RemoveTrustedKey("example.com/app", keystoretest.KeyMap["example.com/app"].Fingerprint); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	// the system key is masked
+	if err := ks.RemoveTrustedKey("", keystoretest.KeyMap["acme.com"].Fingerprint); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	checkKeys("", []string{":" + keystoretest.KeyMap["coreos.com"].Fingerprint})
+
+	if err := ks.RemoveTrustedKey("", keystoretest.KeyMap["acme.com"].Fingerprint); err == nil {
+		t.Errorf("expected an error removing a masked key")
+	}
+	if err := ks.RemoveTrustedKey("example.com", keystoretest.KeyMap["coreos.com"].Fingerprint); err == nil {
+		t.Errorf("expected an error removing a root key for a prefix")
+	}
+}
+
+func TestRevokedKey(t *testing.T) {
+	ks, ksPath, err := NewTestKeystore()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(ksPath)
+
+	key := keystoretest.KeyMap["example.com/app"]
+	if _, err := ks.StoreTrustedKeyPrefix("example.com/app", bytes.NewBufferString(key.ArmoredPublicKey)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	entity := readPrivateKey(t, key.ArmoredPrivateKey)
+
+	// a revocation certificate of an unknown key is rejected
+	otherEntity := readPrivateKey(t, keystoretest.KeyMap["coreos.com"].ArmoredPrivateKey)
+	if _, err := ks.StoreRevocation(newRevocation(t, otherEntity)); err == nil {
+		t.Errorf("expected an error storing the revocation of an unknown key")
+	}
+
+	if _, err := ks.StoreRevocation(newRevocation(t, entity)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	message, signature, err := keystoretest.NewMessageAndSignature(key.ArmoredPrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = ks.CheckSignature("example.com/app", message, signature)
+	if _, ok := err.(KeyRevokedError); !ok {
+		t.Errorf("expected KeyRevokedError, got %v", err)
+	}
+}
+
+func TestExpiredKey(t *testing.T) {
+	ks, ksPath, err := NewTestKeystore()
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(ksPath)
+
+	key := keystoretest.KeyMap["example.com/app"]
+	message, signature, err := keystoretest.NewMessageAndSignature(key.ArmoredPrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// set a one hour lifetime to the key, long expired since its
+	// creation
+	entity := readPrivateKey(t, key.ArmoredPrivateKey)
+	lifetime := uint32(3600)
+	for name, id := range entity.Identities {
+		id.SelfSignature.KeyLifetimeSecs = &lifetime
+		id.SelfSignature.CreationTime = time.Now()
+		if err := id.SelfSignature.SignUserId(name, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	buf := new(bytes.Buffer)
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Close()
+	if _, err := ks.StoreTrustedKeyPrefix("example.com/app", buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err = ks.CheckSignature("example.com/app", message, signature)
+	expiredErr, ok := err.(KeyExpiredError)
+	if !ok {
+		t.Fatalf("expected KeyExpiredError, got %v", err)
+	}
+	expiry := entity.PrimaryKey.CreationTime.Add(time.Hour)
+	if !expiredErr.Expiry.Equal(expiry) {
+		t.Errorf("expected expiry %v, got %v", expiry, expiredErr.Expiry)
+	}
+}
+
+func TestSigningSubkeyValidity(t *testing.T) {
+	key := keystoretest.KeyMap["example.com/app"]
+	tests := []struct {
+		name string
+		// change changes the binding signature of the signing
+		// subkey, after the signature was made
+		change func(entity *openpgp.Entity, subkey *openpgp.Subkey)
+		check  func(err error) bool
+	}{
+		{
+			"valid subkey",
+			func(entity *openpgp.Entity, subkey *openpgp.Subkey) {},
+			func(err error) bool { return err == nil },
+		},
+		{
+			"expired subkey",
+			func(entity *openpgp.Entity, subkey *openpgp.Subkey) {
+				lifetime := uint32(3600)
+				subkey.Sig.KeyLifetimeSecs = &lifetime
+				signSubkey(t, entity, subkey, subkey.Sig)
+			},
+			func(err error) bool {
+				_, ok := err.(KeyExpiredError)
+				return ok
+			},
+		},
+		{
+			"revoked subkey",
+			func(entity *openpgp.Entity, subkey *openpgp.Subkey) {
+				signSubkey(t, entity, subkey, &packet.Signature{
+					SigType:      packet.SigTypeSubkeyRevocation,
+					PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+					Hash:         crypto.SHA256,
+					CreationTime: time.Now(),
+					IssuerKeyId:  &entity.PrimaryKey.KeyId,
+				})
+			},
+			func(err error) bool {
+				revokedErr, ok := err.(KeyRevokedError)
+				return ok && revokedErr.Fingerprint != [20]byte{}
+			},
+		},
+	}
+	for _, tt := range tests {
+		ks, ksPath, err := NewTestKeystore()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		defer os.RemoveAll(ksPath)
+
+		// make the subkey, created long ago, a signing key and sign
+		// with it
+		entity := readPrivateKey(t, key.ArmoredPrivateKey)
+		if len(entity.Subkeys) == 0 {
+			t.Fatalf("expected the test key to have a subkey")
+		}
+		subkey := &entity.Subkeys[0]
+		subkey.Sig.FlagSign = true
+		subkey.Sig.FlagEncryptCommunications = false
+		subkey.Sig.FlagEncryptStorage = false
+		signSubkey(t, entity, subkey, subkey.Sig)
+		message := []byte("data")
+		signature := subkeySignature(t, subkey, message)
+		tt.change(entity, subkey)
+
+		buf := new(bytes.Buffer)
+		w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := entity.Serialize(w); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		w.Close()
+		if _, err := ks.StoreTrustedKeyPrefix("example.com/app", buf); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		_, err = ks.CheckSignature("example.com/app", bytes.NewReader(message), bytes.NewReader(signature))
+		if !tt.check(err) {
+			t.Errorf("%s: unexpected result %v", tt.name, err)
+		}
+	}
+}
+
+// signSubkey signs sig, a binding or revocation signature of the
+// subkey, with the primary key and makes it the signature of the subkey.
+func signSubkey(t *testing.T, entity *openpgp.Entity, subkey *openpgp.Subkey, sig *packet.Signature) {
+	if err := sig.SignKey(subkey.PublicKey, entity.PrivateKey, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	subkey.Sig = sig
+}
+
+// subkeySignature returns an armored detached signature of the message
+// made by the subkey. openpgp.DetachSign always signs with the primary
+// key.
+func subkeySignature(t *testing.T, subkey *openpgp.Subkey, message []byte) []byte {
+	sig := &packet.Signature{
+		SigType:      packet.SigTypeBinary,
+		PubKeyAlgo:   subkey.PrivateKey.PubKeyAlgo,
+		Hash:         crypto.SHA256,
+		CreationTime: time.Now(),
+		IssuerKeyId:  &subkey.PrivateKey.KeyId,
+	}
+	h := sig.Hash.New()
+	h.Write(message)
+	if err := sig.Sign(h, subkey.PrivateKey, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	buf := new(bytes.Buffer)
+	w, err := armor.Encode(buf, openpgp.SignatureType, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := sig.Serialize(w); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func readPrivateKey(t *testing.T, armoredPrivateKey string) *openpgp.Entity {
+	entityList, err := openpgp.ReadArmoredKeyRing(bytes.NewBufferString(armoredPrivateKey))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return entityList[0]
+}
+
+// newRevocation returns an armored revocation certificate of the key.
+func newRevocation(t *testing.T, entity *openpgp.Entity) *bytes.Buffer {
+	sig := &packet.Signature{
+		SigType:      packet.SigTypeKeyRevocation,
+		PubKeyAlgo:   entity.PrimaryKey.PubKeyAlgo,
+		Hash:         crypto.SHA256,
+		CreationTime: time.Now(),
+		IssuerKeyId:  &entity.PrimaryKey.KeyId,
+	}
+	// the revocation signature is made over the key packet body,
+	// without its header
+	keyPacket := new(bytes.Buffer)
+	if err := entity.PrimaryKey.Serialize(keyPacket); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	body := keyPacket.Bytes()
+	switch l := body[1]; {
+	case l < 192:
+		body = body[2:]
+	case l < 224:
+		body = body[3:]
+	default:
+		body = body[6:]
+	}
+	h := sig.Hash.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	if err := sig.Sign(h, entity.PrivateKey, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	w, err := armor.Encode(buf, openpgp.SignatureType, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := sig.Serialize(w); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w.Close()
+	return buf
+}
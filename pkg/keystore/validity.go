@@ -0,0 +1,295 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// KeyRevokedError is returned when a signature was made by a revoked
+// key.
+type KeyRevokedError struct {
+	Fingerprint [20]byte
+	// Reason is the reason given in the revocation certificate, it
+	// may be empty.
+	Reason string
+}
+
+func (e KeyRevokedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("keystore: key %x has been revoked", e.Fingerprint)
+	}
+	return fmt.Sprintf("keystore: key %x has been revoked: %s", e.Fingerprint, e.Reason)
+}
+
+// KeyExpiredError is returned when a signature was made by an expired
+// key.
+type KeyExpiredError struct {
+	Fingerprint [20]byte
+	Expiry      time.Time
+}
+
+func (e KeyExpiredError) Error() string {
+	return fmt.Sprintf("keystore: key %x expired on %s", e.Fingerprint, e.Expiry.UTC().Format(time.RFC3339))
+}
+
+// CheckKeyValidity returns a KeyRevokedError if the key has been
+// revoked, either by a revocation certificate of the keystore or by one
+// attached to the key, and a KeyExpiredError if the key has expired at
+// the given time.
+func (ks *Keystore) CheckKeyValidity(entity *openpgp.Entity, now time.Time) error {
+	fingerprint := entity.PrimaryKey.Fingerprint
+	if len(entity.Revocations) > 0 {
+		return KeyRevokedError{
+			Fingerprint: fingerprint,
+			Reason:      entity.Revocations[0].RevocationReasonText,
+		}
+	}
+	revocation, err := ks.loadRevocation(entity)
+	if err != nil {
+		return err
+	}
+	if revocation != nil {
+		return KeyRevokedError{
+			Fingerprint: fingerprint,
+			Reason:      revocation.RevocationReasonText,
+		}
+	}
+	if expiry, ok := KeyExpiry(entity); ok && !now.Before(expiry) {
+		return KeyExpiredError{
+			Fingerprint: fingerprint,
+			Expiry:      expiry,
+		}
+	}
+	return nil
+}
+
+// checkSignerValidity checks the validity of the key that made a
+// signature, like CheckKeyValidity. If the signature was made by the
+// subkey with ID issuer, the subkey is checked too: it may be revoked or
+// expired while the primary key is still valid.
+func (ks *Keystore) checkSignerValidity(entity *openpgp.Entity, issuer uint64, now time.Time) error {
+	if err := ks.CheckKeyValidity(entity, now); err != nil {
+		return err
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey.KeyId != issuer {
+			continue
+		}
+		fingerprint := subkey.PublicKey.Fingerprint
+		if revocation := subkeyRevocation(entity, subkey); revocation != nil {
+			return KeyRevokedError{
+				Fingerprint: fingerprint,
+				Reason:      revocation.RevocationReasonText,
+			}
+		}
+		if expiry, ok := subkeyExpiry(subkey); ok && !now.Before(expiry) {
+			return KeyExpiredError{
+				Fingerprint: fingerprint,
+				Expiry:      expiry,
+			}
+		}
+	}
+	return nil
+}
+
+// subkeyRevocation returns the revocation signature of the subkey made
+// by the primary key, or nil if the subkey has not been revoked. The
+// openpgp package keeps only the first signature following a subkey,
+// the other ones end up in the signatures of the last identity.
+func subkeyRevocation(entity *openpgp.Entity, subkey openpgp.Subkey) *packet.Signature {
+	if subkey.Sig.SigType == packet.SigTypeSubkeyRevocation {
+		return subkey.Sig
+	}
+	for _, id := range entity.Identities {
+		for _, sig := range id.Signatures {
+			if sig.SigType != packet.SigTypeSubkeyRevocation || sig.IssuerKeyId == nil || *sig.IssuerKeyId != entity.PrimaryKey.KeyId {
+				continue
+			}
+			if entity.PrimaryKey.VerifyKeySignature(subkey.PublicKey, sig) == nil {
+				return sig
+			}
+		}
+	}
+	return nil
+}
+
+// subkeyExpiry returns the expiry time of the subkey, as set by its
+// binding signature. The returned boolean is false if the subkey never
+// expires.
+func subkeyExpiry(subkey openpgp.Subkey) (time.Time, bool) {
+	sig := subkey.Sig
+	if sig.SigType != packet.SigTypeSubkeyBinding || sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+	lifetime := time.Duration(*sig.KeyLifetimeSecs) * time.Second
+	return subkey.PublicKey.CreationTime.Add(lifetime), true
+}
+
+// signatureIssuer returns the ID of the key that made the armored or
+// binary detached signature.
+func signatureIssuer(signature io.ReadSeeker) (uint64, error) {
+	if _, err := signature.Seek(0, 0); err != nil {
+		return 0, errwrap.Wrap(errors.New("error seeking signature file"), err)
+	}
+	var r io.Reader = signature
+	if block, err := armor.Decode(signature); err == nil {
+		r = block.Body
+	} else if _, err := signature.Seek(0, 0); err != nil {
+		return 0, errwrap.Wrap(errors.New("error seeking signature file"), err)
+	}
+	p, err := packet.Read(r)
+	if err != nil {
+		return 0, errwrap.Wrap(errors.New("error reading signature"), err)
+	}
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return 0, errors.New("signature without issuer")
+		}
+		return *sig.IssuerKeyId, nil
+	case *packet.SignatureV3:
+		return sig.IssuerKeyId, nil
+	}
+	return 0, errors.New("no signature found")
+}
+
+// KeyExpiry returns the expiry time of the key, as set by its most
+// recent identity self-signature. The returned boolean is false if the
+// key never expires.
+func KeyExpiry(entity *openpgp.Entity) (time.Time, bool) {
+	var selfSig *packet.Signature
+	for _, id := range entity.Identities {
+		if id.SelfSignature == nil {
+			continue
+		}
+		if selfSig == nil || id.SelfSignature.CreationTime.After(selfSig.CreationTime) {
+			selfSig = id.SelfSignature
+		}
+	}
+	if selfSig == nil || selfSig.KeyLifetimeSecs == nil || *selfSig.KeyLifetimeSecs == 0 {
+		return time.Time{}, false
+	}
+	lifetime := time.Duration(*selfSig.KeyLifetimeSecs) * time.Second
+	return entity.PrimaryKey.CreationTime.Add(lifetime), true
+}
+
+// loadRevocation returns the revocation certificate of the keystore
+// for the key, or nil if the key has not been revoked.
+func (ks *Keystore) loadRevocation(entity *openpgp.Entity) (*packet.Signature, error) {
+	filename := fingerprintToFilename(entity.PrimaryKey.Fingerprint)
+	for _, dir := range []string{ks.LocalRevokedPath, ks.SystemRevokedPath} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("keystore: error reading revocation certificate"), err)
+		}
+		revocation, err := readRevocation(bytes.NewReader(data))
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("keystore: invalid revocation certificate for key %s", filename), err)
+		}
+		if err := entity.PrimaryKey.VerifyRevocationSignature(revocation); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("keystore: revocation certificate for key %s not made by the key", filename), err)
+		}
+		return revocation, nil
+	}
+	return nil, nil
+}
+
+// StoreRevocation stores the revocation certificate r of a key of the
+// keystore. The signatures made by the revoked key are rejected from
+// then on.
+func (ks *Keystore) StoreRevocation(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	revocation, err := readRevocation(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	trustedKeys, err := ks.loadAllTrustedKeys()
+	if err != nil {
+		return "", err
+	}
+	var revoked *openpgp.Entity
+	for _, key := range trustedKeys {
+		if key.Entity.PrimaryKey.KeyId == *revocation.IssuerKeyId {
+			revoked = key.Entity
+			break
+		}
+	}
+	if revoked == nil {
+		return "", fmt.Errorf("no key with ID %X in the keystore", *revocation.IssuerKeyId)
+	}
+	if err := revoked.PrimaryKey.VerifyRevocationSignature(revocation); err != nil {
+		return "", errwrap.Wrap(errors.New("invalid revocation certificate"), err)
+	}
+	if err := os.MkdirAll(ks.LocalRevokedPath, 0755); err != nil {
+		return "", err
+	}
+	revocationPath := filepath.Join(ks.LocalRevokedPath, fingerprintToFilename(revoked.PrimaryKey.Fingerprint))
+	if err := ioutil.WriteFile(revocationPath, data, 0644); err != nil {
+		return "", err
+	}
+	return revocationPath, nil
+}
+
+// readRevocation reads the key revocation signature of an armored or
+// binary revocation certificate, like the ones generated by
+// "gpg --gen-revoke".
+func readRevocation(r io.ReadSeeker) (*packet.Signature, error) {
+	var pr io.Reader = r
+	if block, err := armor.Decode(r); err == nil {
+		pr = block.Body
+	} else {
+		// the certificate is not armored, read it as binary
+		if _, err := r.Seek(0, 0); err != nil {
+			return nil, err
+		}
+	}
+	packets := packet.NewReader(pr)
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			return nil, errors.New("no key revocation signature found")
+		}
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error parsing revocation certificate"), err)
+		}
+		sig, ok := p.(*packet.Signature)
+		if !ok || sig.SigType != packet.SigTypeKeyRevocation {
+			continue
+		}
+		if sig.IssuerKeyId == nil {
+			return nil, errors.New("key revocation signature without issuer")
+		}
+		return sig, nil
+	}
+}
@@ -17,7 +17,6 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	"path"
@@ -36,26 +35,44 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
 	supportedAPIVersion = "1.0.0-alpha"
 	cmdAPIService       = &cobra.Command{
-		Use:   `api-service [--listen="localhost:15441"]`,
+		Use:   `api-service [--listen="localhost:15441"] [--tls-cert=FILE --tls-key=FILE [--tls-client-ca=FILE]]`,
 		Short: "Run API service (experimental)",
 		Long: `The API service listens for gRPC requests on the address and port specified by
 the --listen option.
 
-Specify the address 0.0.0.0 to listen on all interfaces.`,
+Specify the address 0.0.0.0 to listen on all interfaces. Use unix:PATH to listen
+on a unix socket, which can only be accessed by its owner and the rkt group.
+
+With --tls-cert and --tls-key, the API service uses TLS. With --tls-client-ca,
+the clients must also authenticate with a certificate signed by one of the CAs
+in the given file.
+
+The API service is read-only unless --enable-mutating-api is set, which also
+serves the API to fetch images and to prepare, run, stop and remove pods. The
+mutating API requires root privileges.`,
 		Run: runWrapper(runAPIService),
 	}
 
-	flagAPIServiceListenAddr string
+	flagAPIServiceListenAddr  string
+	flagAPIServiceTLSCert     string
+	flagAPIServiceTLSKey      string
+	flagAPIServiceTLSClientCA string
+	flagAPIServiceMutating    bool
 )
 
 func init() {
 	cmdRkt.AddCommand(cmdAPIService)
-	cmdAPIService.Flags().StringVar(&flagAPIServiceListenAddr, "listen", common.APIServiceListenAddr, "address to listen for client API requests")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceListenAddr, "listen", common.APIServiceListenAddr, "address to listen for client API requests, unix:PATH to listen on a unix socket")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSCert, "tls-cert", "", "path to the TLS certificate of the API service")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSKey, "tls-key", "", "path to the key of the TLS certificate of the API service")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSClientCA, "tls-client-ca", "", "path to the CA certificates used to verify the client certificates, enables client authentication")
+	cmdAPIService.Flags().BoolVar(&flagAPIServiceMutating, "enable-mutating-api", false, "serve the API changing the state of the pods and of the images (requires root)")
 }
 
 // v1AlphaAPIServer implements v1Alpha.APIServer interface.
@@ -66,7 +83,7 @@ type v1AlphaAPIServer struct {
 var _ v1alpha.PublicAPIServer = &v1AlphaAPIServer{}
 
 func newV1AlphaAPIServer() (*v1AlphaAPIServer, error) {
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		return nil, err
 	}
@@ -275,11 +292,16 @@ func getApplist(p *pod) ([]*v1alpha.App, error) {
 func getNetworks(p *pod) []*v1alpha.Network {
 	var networks []*v1alpha.Network
 	for _, n := range p.nets {
-		networks = append(networks, &v1alpha.Network{
+		network := &v1alpha.Network{
 			Name: n.NetName,
-			// There will be IPv6 support soon so distinguish between v4 and v6
-			Ipv4: n.IP.String(),
-		})
+		}
+		if n.IP != nil {
+			network.Ipv4 = n.IP.String()
+		}
+		if ip6 := n.IP6Addr(); ip6 != nil {
+			network.Ipv6 = ip6.String()
+		}
+		networks = append(networks, network)
 	}
 	return networks
 }
@@ -704,14 +726,6 @@ func (s *v1AlphaAPIServer) InspectImage(ctx context.Context, request *v1alpha.In
 	return &v1alpha.InspectImageResponse{Image: image}, nil
 }
 
-func (s *v1AlphaAPIServer) GetLogs(request *v1alpha.GetLogsRequest, server v1alpha.PublicAPI_GetLogsServer) error {
-	return fmt.Errorf("not implemented yet")
-}
-
-func (s *v1AlphaAPIServer) ListenEvents(request *v1alpha.ListenEventsRequest, server v1alpha.PublicAPI_ListenEventsServer) error {
-	return fmt.Errorf("not implemented yet")
-}
-
 func runAPIService(cmd *cobra.Command, args []string) (exit int) {
 	// Set up the signal handler here so we can make sure the
 	// signals are caught after print the starting message.
@@ -719,14 +733,29 @@ func runAPIService(cmd *cobra.Command, args []string) (exit int) {
 
 	stderr.Print("API service starting...")
 
-	tcpl, err := net.Listen("tcp", flagAPIServiceListenAddr)
+	if flagAPIServiceMutating && os.Geteuid() != 0 {
+		stderr.Print("the mutating API requires root privileges")
+		return 1
+	}
+
+	var opts []grpc.ServerOption
+	if flagAPIServiceTLSCert != "" || flagAPIServiceTLSKey != "" || flagAPIServiceTLSClientCA != "" {
+		config, err := apiServiceTLSConfig(flagAPIServiceTLSCert, flagAPIServiceTLSKey, flagAPIServiceTLSClientCA)
+		if err != nil {
+			stderr.PrintE("failed to set up TLS", err)
+			return 1
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+
+	l, err := apiServiceListen(flagAPIServiceListenAddr)
 	if err != nil {
 		stderr.Error(err)
 		return 1
 	}
-	defer tcpl.Close()
+	defer l.Close()
 
-	publicServer := grpc.NewServer() // TODO(yifan): Add TLS credential option.
+	publicServer := grpc.NewServer(opts...)
 
 	v1AlphaAPIServer, err := newV1AlphaAPIServer()
 	if err != nil {
@@ -736,7 +765,14 @@ func runAPIService(cmd *cobra.Command, args []string) (exit int) {
 
 	v1alpha.RegisterPublicAPIServer(publicServer, v1AlphaAPIServer)
 
-	go publicServer.Serve(tcpl)
+	if flagAPIServiceMutating {
+		if flagAPIServiceTLSClientCA == "" && !strings.HasPrefix(flagAPIServiceListenAddr, unixListenPrefix) {
+			stderr.Print("warning: the mutating API is enabled without client authentication, anyone who can connect can run pods")
+		}
+		v1alpha.RegisterMutatingAPIServer(publicServer, newV1AlphaMutatingAPIServer(v1AlphaAPIServer.store))
+	}
+
+	go publicServer.Serve(l)
 
 	stderr.Printf("API service running on %v...", flagAPIServiceListenAddr)
 
@@ -0,0 +1,328 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coreos/rkt/api/v1alpha"
+	"github.com/coreos/rkt/pkg/set"
+	"github.com/coreos/rkt/store"
+)
+
+// eventsPollInterval is the interval between two scans of the pods
+// directories and of the image store in ListenEvents().
+//
+// Pod state transitions like running -> exited are not visible as
+// directory changes (they are signaled by releasing the lock on the pod
+// directory), so the state is polled instead of watched with inotify.
+var eventsPollInterval = time.Second
+
+// podLevel is how far a pod went in its lifecycle, as seen by ListenEvents().
+type podLevel int
+
+const (
+	podLevelNone podLevel = iota
+	podLevelPrepared
+	podLevelStarted
+	podLevelExited
+)
+
+// podSnapshot is the state of a pod observed during one scan.
+type podSnapshot struct {
+	level     podLevel
+	aborted   bool
+	apps      []string       // app names, in pod manifest order
+	exitCodes map[string]int // app name -> exit code, for the exited apps
+}
+
+// eventSnapshot is the state of the pods and of the images observed during one scan.
+type eventSnapshot struct {
+	pods   map[string]*podSnapshot // pod uuid -> snapshot
+	images map[string]string       // image ID -> image name
+}
+
+// podLevelOf returns the lifecycle level of a pod.
+// The exited pods moved to exited-garbage, or being deleted from there, are
+// known to have run and exited, so they stay at podLevelExited. The pods
+// garbage collected from the prepare stage never ran, and return
+// podLevelNone like the other deleting pods.
+func podLevelOf(p *pod) podLevel {
+	switch {
+	case p.isExited || p.isExitedGarbage || p.isExitedDeleting:
+		return podLevelExited
+	case p.isDeleting || p.isGarbage:
+		return podLevelNone
+	}
+	switch p.getState() {
+	case Prepared:
+		return podLevelPrepared
+	case Running:
+		return podLevelStarted
+	}
+	return podLevelNone
+}
+
+// takeEventSnapshot scans the pods directories and the image store.
+func takeEventSnapshot(s *store.Store) (*eventSnapshot, error) {
+	snap := &eventSnapshot{
+		pods:   make(map[string]*podSnapshot),
+		images: make(map[string]string),
+	}
+
+	if err := walkPods(includeAllDirs, func(p *pod) {
+		state := p.getState()
+		ps := &podSnapshot{
+			level:   podLevelOf(p),
+			aborted: state == AbortedPrepare,
+		}
+
+		if ps.level >= podLevelPrepared {
+			if apps, err := p.getApps(); err == nil {
+				for _, app := range apps {
+					ps.apps = append(ps.apps, app.Name.String())
+				}
+			}
+		}
+
+		// The status directory may not exist yet, in which
+		// case no app has exited.
+		if ps.level >= podLevelStarted {
+			if exitCodes, err := p.getExitStatuses(); err == nil {
+				ps.exitCodes = exitCodes
+			}
+		}
+
+		snap.pods[p.uuid.String()] = ps
+	}); err != nil {
+		return nil, err
+	}
+
+	aciInfos, err := s.GetAllACIInfos(nil, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, aciInfo := range aciInfos {
+		snap.images[aciInfo.BlobKey] = aciInfo.Name
+	}
+
+	return snap, nil
+}
+
+func newEvent(typ v1alpha.EventType, id, from string, now time.Time, data ...*v1alpha.KeyValue) *v1alpha.Event {
+	return &v1alpha.Event{
+		Type: typ,
+		Id:   id,
+		From: from,
+		Time: now.Unix(),
+		Data: data,
+	}
+}
+
+// diffEventSnapshots returns the events that happened between the previous
+// and the current snapshot. Events that were skipped between two scans (e.g.
+// a pod that was prepared and started in between) are generated too, in order.
+//
+// As pods never go back in their lifecycle, the current snapshot is updated
+// with what was already reported for pods whose current state does not
+// tell it (garbage and deleting pods).
+func diffEventSnapshots(prev, cur *eventSnapshot, now time.Time) []*v1alpha.Event {
+	var events []*v1alpha.Event
+
+	var podIDs []string
+	for id := range cur.pods {
+		podIDs = append(podIDs, id)
+	}
+	sort.Strings(podIDs)
+
+	for _, id := range podIDs {
+		curPod := cur.pods[id]
+		prevPod, ok := prev.pods[id]
+		if !ok {
+			prevPod = &podSnapshot{}
+		}
+
+		if curPod.aborted && !prevPod.aborted {
+			events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_POD_PREPARE_ABORTED, id, "", now))
+		}
+
+		for level := prevPod.level + 1; level <= curPod.level; level++ {
+			switch level {
+			case podLevelPrepared:
+				events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_POD_PREPARED, id, "", now))
+			case podLevelStarted:
+				events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_POD_STARTED, id, "", now))
+				for _, app := range curPod.apps {
+					events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_APP_STARTED, id, app, now))
+				}
+			case podLevelExited:
+				events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_POD_EXITED, id, "", now))
+			}
+		}
+
+		for _, app := range curPod.apps {
+			exitCode, ok := curPod.exitCodes[app]
+			if !ok {
+				continue
+			}
+			if _, ok := prevPod.exitCodes[app]; ok {
+				continue
+			}
+			events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_APP_EXITED, id, app, now,
+				&v1alpha.KeyValue{Key: "exit-code", Value: strconv.Itoa(exitCode)}))
+		}
+
+		if curPod.level < prevPod.level {
+			curPod.level = prevPod.level
+			curPod.apps = prevPod.apps
+			curPod.exitCodes = prevPod.exitCodes
+		}
+		curPod.aborted = curPod.aborted || prevPod.aborted
+	}
+
+	var gonePodIDs []string
+	for id := range prev.pods {
+		if _, ok := cur.pods[id]; !ok {
+			gonePodIDs = append(gonePodIDs, id)
+		}
+	}
+	sort.Strings(gonePodIDs)
+
+	for _, id := range gonePodIDs {
+		events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_POD_GARBAGE_COLLECTED, id, "", now))
+	}
+
+	var imported, removed []string
+	for id := range cur.images {
+		if _, ok := prev.images[id]; !ok {
+			imported = append(imported, id)
+		}
+	}
+	for id := range prev.images {
+		if _, ok := cur.images[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(imported)
+	sort.Strings(removed)
+
+	for _, id := range imported {
+		events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED, id, cur.images[id], now))
+	}
+	for _, id := range removed {
+		events = append(events, newEvent(v1alpha.EventType_EVENT_TYPE_IMAGE_REMOVED, id, prev.images[id], now))
+	}
+
+	return events
+}
+
+// satisfiesEventFilter returns true if the event satisfies the filter.
+// The event must not be nil, a nil filter is satisfied by every event.
+func satisfiesEventFilter(event *v1alpha.Event, filter *v1alpha.EventFilter) bool {
+	if filter == nil {
+		return true
+	}
+
+	// Filter according to the types.
+	if len(filter.Types) > 0 {
+		foundType := false
+		for _, typ := range filter.Types {
+			if event.Type == typ {
+				foundType = true
+				break
+			}
+		}
+		if !foundType {
+			return false
+		}
+	}
+
+	// Filter according to the IDs.
+	if len(filter.Ids) > 0 {
+		s := set.NewString(filter.Ids...)
+		if !s.Has(event.Id) {
+			return false
+		}
+	}
+
+	// Filter according to the names.
+	if len(filter.Names) > 0 {
+		s := set.NewString(filter.Names...)
+		if !s.Has(event.From) {
+			return false
+		}
+	}
+
+	// Filter according to the time.
+	if filter.SinceTime > 0 && event.Time < filter.SinceTime {
+		return false
+	}
+	if filter.UntilTime > 0 && event.Time > filter.UntilTime {
+		return false
+	}
+
+	return true
+}
+
+func (s *v1AlphaAPIServer) ListenEvents(request *v1alpha.ListenEventsRequest, server v1alpha.PublicAPI_ListenEventsServer) error {
+	filter := request.Filter
+
+	last, err := takeEventSnapshot(s.store)
+	if err != nil {
+		stderr.PrintE("failed to scan pods and images", err)
+		return err
+	}
+
+	// Close the stream at the until time.
+	var untilCh <-chan time.Time
+	if filter != nil && filter.UntilTime > 0 {
+		untilCh = time.After(time.Unix(filter.UntilTime, 0).Sub(time.Now()))
+	}
+
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-server.Context().Done():
+			return nil
+		case <-untilCh:
+			return nil
+		case now := <-ticker.C:
+			snap, err := takeEventSnapshot(s.store)
+			if err != nil {
+				stderr.PrintE("failed to scan pods and images", err)
+				return err
+			}
+
+			var events []*v1alpha.Event
+			for _, event := range diffEventSnapshots(last, snap, now) {
+				if satisfiesEventFilter(event, filter) {
+					events = append(events, event)
+				}
+			}
+			last = snap
+
+			if len(events) == 0 {
+				continue
+			}
+			if err := server.Send(&v1alpha.ListenEventsResponse{Events: events}); err != nil {
+				return err
+			}
+		}
+	}
+}
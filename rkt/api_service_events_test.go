@@ -0,0 +1,236 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/api/v1alpha"
+)
+
+func TestDiffEventSnapshots(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		prev   *eventSnapshot
+		cur    *eventSnapshot
+		events []v1alpha.EventType
+	}{
+		// Nothing changed.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelStarted, apps: []string{"app-foo"}}}},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelStarted, apps: []string{"app-foo"}}}},
+			nil,
+		},
+		// Pod prepared.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {}}},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelPrepared}}},
+			[]v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_POD_PREPARED},
+		},
+		// Prepare aborted.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {}}},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {aborted: true}}},
+			[]v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_POD_PREPARE_ABORTED},
+		},
+		// Pod prepared, started and exited between two scans.
+		{
+			&eventSnapshot{},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {
+				level:     podLevelExited,
+				apps:      []string{"app-foo"},
+				exitCodes: map[string]int{"app-foo": 0},
+			}}},
+			[]v1alpha.EventType{
+				v1alpha.EventType_EVENT_TYPE_POD_PREPARED,
+				v1alpha.EventType_EVENT_TYPE_POD_STARTED,
+				v1alpha.EventType_EVENT_TYPE_APP_STARTED,
+				v1alpha.EventType_EVENT_TYPE_POD_EXITED,
+				v1alpha.EventType_EVENT_TYPE_APP_EXITED,
+			},
+		},
+		// One app exited, the pod is still running.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {
+				level: podLevelStarted,
+				apps:  []string{"app-foo", "app-bar"},
+			}}},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {
+				level:     podLevelStarted,
+				apps:      []string{"app-foo", "app-bar"},
+				exitCodes: map[string]int{"app-bar": 1},
+			}}},
+			[]v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_APP_EXITED},
+		},
+		// Exited pod is being deleted.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelExited}}},
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {}}},
+			nil,
+		},
+		// Pod garbage collected.
+		{
+			&eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelExited}}},
+			&eventSnapshot{},
+			[]v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_POD_GARBAGE_COLLECTED},
+		},
+		// Image imported and removed.
+		{
+			&eventSnapshot{images: map[string]string{"sha512-foo": "example.com/foo"}},
+			&eventSnapshot{images: map[string]string{"sha512-bar": "example.com/bar"}},
+			[]v1alpha.EventType{
+				v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED,
+				v1alpha.EventType_EVENT_TYPE_IMAGE_REMOVED,
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		events := diffEventSnapshots(tt.prev, tt.cur, now)
+		if len(events) != len(tt.events) {
+			t.Errorf("#%d: got %d events (%v), want %d", i, len(events), events, len(tt.events))
+			continue
+		}
+		for j, event := range events {
+			if event.Type != tt.events[j] {
+				t.Errorf("#%d: event #%d: got %v, want %v", i, j, event.Type, tt.events[j])
+			}
+			if event.Time != now.Unix() {
+				t.Errorf("#%d: event #%d: got time %d, want %d", i, j, event.Time, now.Unix())
+			}
+		}
+	}
+}
+
+func TestDiffEventSnapshotsKeepsLevel(t *testing.T) {
+	prev := &eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelExited}}}
+	cur := &eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {}}}
+	diffEventSnapshots(prev, cur, time.Now())
+
+	// A deleting pod must not be reported as prepared, started and
+	// exited again at the next scan.
+	next := &eventSnapshot{pods: map[string]*podSnapshot{"pod-foo": {level: podLevelExited}}}
+	if events := diffEventSnapshots(cur, next, time.Now()); len(events) != 0 {
+		t.Errorf("got events %v, want none", events)
+	}
+}
+
+func TestFilterEvent(t *testing.T) {
+	tests := []struct {
+		event  *v1alpha.Event
+		filter *v1alpha.EventFilter
+		result bool
+	}{
+		// No filter.
+		{
+			&v1alpha.Event{Type: v1alpha.EventType_EVENT_TYPE_POD_STARTED},
+			nil,
+			true,
+		},
+		// Has the type.
+		{
+			&v1alpha.Event{Type: v1alpha.EventType_EVENT_TYPE_POD_STARTED},
+			&v1alpha.EventFilter{
+				Types: []v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_POD_STARTED, v1alpha.EventType_EVENT_TYPE_POD_EXITED},
+			},
+			true,
+		},
+		// Doesn't have the type.
+		{
+			&v1alpha.Event{Type: v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED},
+			&v1alpha.EventFilter{
+				Types: []v1alpha.EventType{v1alpha.EventType_EVENT_TYPE_POD_STARTED},
+			},
+			false,
+		},
+		// Has the ID and the name.
+		{
+			&v1alpha.Event{Id: "id-foo", From: "name-foo"},
+			&v1alpha.EventFilter{
+				Ids:   []string{"id-foo", "id-bar"},
+				Names: []string{"name-foo"},
+			},
+			true,
+		},
+		// Doesn't have the ID.
+		{
+			&v1alpha.Event{Id: "id-foo", From: "name-foo"},
+			&v1alpha.EventFilter{
+				Ids:   []string{"id-bar"},
+				Names: []string{"name-foo"},
+			},
+			false,
+		},
+		// Doesn't have the name.
+		{
+			&v1alpha.Event{Id: "id-foo", From: "name-foo"},
+			&v1alpha.EventFilter{
+				Names: []string{"name-bar"},
+			},
+			false,
+		},
+		// In the time range.
+		{
+			&v1alpha.Event{Time: 100},
+			&v1alpha.EventFilter{SinceTime: 50, UntilTime: 150},
+			true,
+		},
+		// Before the since time.
+		{
+			&v1alpha.Event{Time: 10},
+			&v1alpha.EventFilter{SinceTime: 50},
+			false,
+		},
+		// After the until time.
+		{
+			&v1alpha.Event{Time: 200},
+			&v1alpha.EventFilter{UntilTime: 150},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		result := satisfiesEventFilter(tt.event, tt.filter)
+		if result != tt.result {
+			t.Errorf("#%d: got %v, want %v", i, result, tt.result)
+		}
+	}
+}
+
+func TestPodLevelOf(t *testing.T) {
+	tests := []struct {
+		pod   *pod
+		level podLevel
+	}{
+		{&pod{isEmbryo: true}, podLevelNone},
+		{&pod{isPreparing: true}, podLevelNone},
+		{&pod{isAbortedPrepare: true}, podLevelNone},
+		{&pod{isPrepared: true}, podLevelPrepared},
+		{&pod{}, podLevelStarted},
+		{&pod{isExited: true}, podLevelExited},
+		// exited pods moved to exited-garbage, and deleted from there
+		{&pod{isExited: true, isExitedGarbage: true}, podLevelExited},
+		{&pod{isExited: true, isExitedGarbage: true, isExitedDeleting: true}, podLevelExited},
+		// pods garbage collected from the prepare stage never ran
+		{&pod{isGarbage: true}, podLevelNone},
+		{&pod{isGarbage: true, isDeleting: true}, podLevelNone},
+	}
+	for i, tt := range tests {
+		if level := podLevelOf(tt.pod); level != tt.level {
+			t.Errorf("#%d: expected level %d, got %d", i, tt.level, level)
+		}
+	}
+}
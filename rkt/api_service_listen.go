@@ -0,0 +1,120 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/rkt/common"
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// unixListenPrefix is the prefix of the --listen addresses that are
+	// paths of unix sockets.
+	unixListenPrefix = "unix:"
+	// apiServiceSocketMode gives access to the unix socket to its owner
+	// and to its group only.
+	apiServiceSocketMode = 0660
+)
+
+// apiServiceListen listens on the given address, either a TCP "host:port"
+// address or a "unix:PATH" unix socket path.
+//
+// The unix socket can only be accessed by its owner and by its group, which
+// is set to the rkt group if it exists.
+func apiServiceListen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixListenPrefix) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, unixListenPrefix)
+	if path == "" {
+		return nil, fmt.Errorf("no path given for the unix socket in %q", addr)
+	}
+
+	// A socket left by a previous instance would make the listen fail.
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%q exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot remove stale socket %q", path), err)
+		}
+	}
+
+	// Nobody else must be able to connect between the creation of the
+	// socket and the chmod.
+	oldMask := syscall.Umask(0177)
+	l, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	if gid, err := common.LookupGid(common.RktGroup); err == nil {
+		if err := os.Chown(path, -1, gid); err != nil {
+			stderr.PrintE(fmt.Sprintf("cannot change the group of %q to %q, only its owner can access it", path, common.RktGroup), err)
+		}
+	}
+	if err := os.Chmod(path, apiServiceSocketMode); err != nil {
+		l.Close()
+		return nil, errwrap.Wrap(fmt.Errorf("cannot change the permissions of %q", path), err)
+	}
+
+	return l, nil
+}
+
+// apiServiceTLSConfig returns the TLS configuration of the API service
+// using the given certificate and key. If clientCAFile is not empty, the
+// clients must present a certificate signed by one of the CAs it contains.
+func apiServiceTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a certificate and a key are needed to use TLS")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot load the certificate and the key"), err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("cannot read the client CA file"), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the client CA file %q", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/api/v1alpha"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCert is a certificate generated for the tests, with its key.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// generateTestCert generates a certificate signed by parent, or a self-signed
+// CA certificate if parent is nil.
+func generateTestCert(t *testing.T, name string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("cannot parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatalf("cannot load certificate: %v", err)
+	}
+	return cert
+}
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("cannot write %q: %v", path, err)
+	}
+	return path
+}
+
+// serveTestAPIService serves the API service on l, it only supports the
+// requests that do not use the store.
+func serveTestAPIService(l net.Listener, dataDir string, opts ...grpc.ServerOption) *grpc.Server {
+	// GetInfo() returns the data directory.
+	cachedDataDir = dataDir
+
+	server := grpc.NewServer(opts...)
+	v1alpha.RegisterPublicAPIServer(server, &v1AlphaAPIServer{})
+	go server.Serve(l)
+	return server
+}
+
+func getInfo(t *testing.T, addr string, opts ...grpc.DialOption) error {
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = v1alpha.NewPublicAPIClient(conn).GetInfo(ctx, &v1alpha.GetInfoRequest{})
+	return err
+}
+
+func TestAPIServiceTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-service-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	serverCA := generateTestCert(t, "server-ca", 1, nil)
+	clientCA := generateTestCert(t, "client-ca", 2, nil)
+	otherCA := generateTestCert(t, "other-ca", 3, nil)
+	serverCert := generateTestCert(t, "server", 4, serverCA)
+	clientCert := generateTestCert(t, "client", 5, clientCA)
+	otherClientCert := generateTestCert(t, "other-client", 6, otherCA)
+
+	certFile := writeTestFile(t, dir, "server.pem", serverCert.certPEM)
+	keyFile := writeTestFile(t, dir, "server-key.pem", serverCert.keyPEM)
+	clientCAFile := writeTestFile(t, dir, "client-ca.pem", clientCA.certPEM)
+
+	if _, err := apiServiceTLSConfig(certFile, "", ""); err == nil {
+		t.Errorf("expected an error without a key")
+	}
+	if _, err := apiServiceTLSConfig(certFile, keyFile, certFile+".missing"); err == nil {
+		t.Errorf("expected an error with a missing client CA file")
+	}
+
+	config, err := apiServiceTLSConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := apiServiceListen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := serveTestAPIService(l, dir, grpc.Creds(credentials.NewTLS(config)))
+	defer server.Stop()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(serverCA.cert)
+
+	tests := []struct {
+		certs     []tls.Certificate
+		expectErr bool
+	}{
+		// The client certificate is signed by the client CA.
+		{[]tls.Certificate{clientCert.tlsCertificate(t)}, false},
+		// No client certificate.
+		{nil, true},
+		// The client certificate is signed by another CA.
+		{[]tls.Certificate{otherClientCert.tlsCertificate(t)}, true},
+	}
+
+	for i, tt := range tests {
+		creds := credentials.NewTLS(&tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: tt.certs,
+		})
+		err := getInfo(t, l.Addr().String(), grpc.WithTransportCredentials(creds))
+		if tt.expectErr && err == nil {
+			t.Errorf("#%d: expected an error", i)
+		}
+		if !tt.expectErr && err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+
+	// Plain text clients are rejected.
+	if err := getInfo(t, l.Addr().String(), grpc.WithInsecure()); err == nil {
+		t.Errorf("expected an error with a plain text client")
+	}
+}
+
+func TestAPIServiceUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-service-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := apiServiceListen(unixListenPrefix); err == nil {
+		t.Errorf("expected an error without a socket path")
+	}
+
+	notSocket := writeTestFile(t, dir, "not-a-socket", nil)
+	if _, err := apiServiceListen(unixListenPrefix + notSocket); err == nil {
+		t.Errorf("expected an error listening on a regular file")
+	}
+
+	path := filepath.Join(dir, "api-service.sock")
+	// A stale socket is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := apiServiceListen(unixListenPrefix + path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := serveTestAPIService(l, dir)
+	defer server.Stop()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perm := fi.Mode().Perm(); perm != apiServiceSocketMode {
+		t.Errorf("socket has permissions %o, expected %o", perm, apiServiceSocketMode)
+	}
+
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}
+	if err := getInfo(t, path, grpc.WithInsecure(), grpc.WithDialer(dialer)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/rkt/api/v1alpha"
+	"github.com/coreos/rkt/pkg/journal"
+)
+
+const (
+	overlayJournalDirTemplate = "overlay/%s/upper/var/log/journal/%s"
+	regularJournalDirTemplate = "stage1/rootfs/var/log/journal/%s"
+)
+
+// logsPollInterval is the interval between two reads of the pod's journal
+// when following the logs in GetLogs().
+var logsPollInterval = time.Second
+
+// logsMatcher returns true if the journal entry must be returned.
+type logsMatcher func(*journal.Entry) bool
+
+// matchAllLogs matches the entries of all the apps in the pod.
+func matchAllLogs(*journal.Entry) bool {
+	return true
+}
+
+// appLogsMatcher returns a logsMatcher matching the entries written by the
+// app with the given name. stage1 runs each app in a "<app>.service" unit,
+// and journald records the unit of the entries from the cgroup of the
+// writer. The syslog identifier is not used: apps running the same
+// executable have the same one.
+func appLogsMatcher(p *pod, appName string) (logsMatcher, error) {
+	name, err := types.NewACName(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		return nil, err
+	}
+	if apps.Get(*name) == nil {
+		return nil, fmt.Errorf("app %q not found in pod %q", appName, p.uuid)
+	}
+
+	return unitLogsMatcher(fmt.Sprintf("%s.service", appName)), nil
+}
+
+// unitLogsMatcher returns a logsMatcher matching the entries of the unit.
+func unitLogsMatcher(unit string) logsMatcher {
+	return func(e *journal.Entry) bool {
+		return e.Fields["_SYSTEMD_UNIT"] == unit
+	}
+}
+
+// formatLogEntry formats the journal entry like 'journalctl -o short-iso'
+// without the hostname.
+func formatLogEntry(e *journal.Entry) string {
+	identifier := e.Fields["SYSLOG_IDENTIFIER"]
+	if identifier == "" {
+		identifier = e.Fields["_COMM"]
+	}
+	if pid := e.Fields["_PID"]; pid != "" {
+		identifier = fmt.Sprintf("%s[%s]", identifier, pid)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Realtime.Format("2006-01-02T15:04:05-0700"), identifier, e.Fields["MESSAGE"])
+}
+
+// filterLogEntries returns the formatted lines of the entries written after
+// the 'after' entry (if not nil) that satisfy the matcher and the time range
+// of the request. It also returns the last entry that was looked at, to be
+// passed as 'after' in the next call.
+func filterLogEntries(entries []*journal.Entry, after *journal.Entry, match logsMatcher, request *v1alpha.GetLogsRequest) ([]string, *journal.Entry) {
+	var lines []string
+	last := after
+	for _, e := range entries {
+		if after != nil && !after.Before(e) {
+			continue
+		}
+		last = e
+
+		if _, ok := e.Fields["MESSAGE"]; !ok {
+			continue
+		}
+		if request.SinceTime > 0 && e.Realtime.Unix() < request.SinceTime {
+			continue
+		}
+		if request.UntilTime > 0 && e.Realtime.Unix() > request.UntilTime {
+			continue
+		}
+		if !match(e) {
+			continue
+		}
+		lines = append(lines, formatLogEntry(e))
+	}
+	return lines, last
+}
+
+func (s *v1AlphaAPIServer) GetLogs(request *v1alpha.GetLogsRequest, server v1alpha.PublicAPI_GetLogsServer) error {
+	uuid, err := types.NewUUID(request.PodId)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("invalid pod id %q", request.PodId), err)
+		return err
+	}
+
+	p, err := getPod(uuid)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get pod %q", request.PodId), err)
+		return err
+	}
+	defer p.Close()
+
+	if !p.isRunning() && !p.afterRun() {
+		return fmt.Errorf("pod %q has not been started", request.PodId)
+	}
+
+	journalDir, err := p.getJournalDir()
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get journal directory for pod %q", request.PodId), err)
+		return err
+	}
+
+	match := logsMatcher(matchAllLogs)
+	if request.AppName != "" {
+		if match, err = appLogsMatcher(p, request.AppName); err != nil {
+			stderr.PrintE(fmt.Sprintf("failed to get app %q of pod %q", request.AppName, request.PodId), err)
+			return err
+		}
+	}
+
+	entries, err := journal.ReadDir(filepath.Join(p.path(), journalDir))
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to read journal for pod %q", request.PodId), err)
+		return err
+	}
+
+	lines, last := filterLogEntries(entries, nil, match, request)
+	if request.Lines > 0 && len(lines) > int(request.Lines) {
+		lines = lines[len(lines)-int(request.Lines):]
+	}
+	if err := server.Send(&v1alpha.GetLogsResponse{Lines: lines}); err != nil {
+		return err
+	}
+
+	if !request.Follow {
+		return nil
+	}
+
+	// Close the stream at the until time.
+	var untilCh <-chan time.Time
+	if request.UntilTime > 0 {
+		untilCh = time.After(time.Unix(request.UntilTime, 0).Sub(time.Now()))
+	}
+
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-server.Context().Done():
+			return nil
+		case <-untilCh:
+			return nil
+		case <-ticker.C:
+			if err := p.refreshState(); err != nil {
+				return err
+			}
+			if p.isGone {
+				return nil
+			}
+			// The journal can move with the pod directory
+			// when the pod is garbage collected.
+			entries, err := journal.ReadDir(filepath.Join(p.path(), journalDir))
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				stderr.PrintE(fmt.Sprintf("failed to read journal for pod %q", request.PodId), err)
+				return err
+			}
+
+			lines, last = filterLogEntries(entries, last, match, request)
+			if len(lines) > 0 {
+				if err := server.Send(&v1alpha.GetLogsResponse{Lines: lines}); err != nil {
+					return err
+				}
+			} else if !p.isRunning() {
+				// The pod exited and everything it logged was sent.
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/api/v1alpha"
+	"github.com/coreos/rkt/pkg/journal"
+)
+
+func TestFilterLogEntries(t *testing.T) {
+	newEntry := func(seqnum uint64, sec int64, ident, msg string) *journal.Entry {
+		return &journal.Entry{
+			Seqnum:   seqnum,
+			Realtime: time.Unix(sec, 0),
+			Fields: map[string]string{
+				"SYSLOG_IDENTIFIER": ident,
+				"MESSAGE":           msg,
+			},
+		}
+	}
+	entries := []*journal.Entry{
+		newEntry(1, 10, "foo", "foo-1"),
+		newEntry(2, 20, "bar", "bar-1"),
+		newEntry(3, 30, "foo", "foo-2"),
+		{Seqnum: 4, Realtime: time.Unix(40, 0), Fields: map[string]string{"SYSLOG_IDENTIFIER": "foo"}},
+	}
+	matchFoo := func(e *journal.Entry) bool {
+		return e.Fields["SYSLOG_IDENTIFIER"] == "foo"
+	}
+
+	tests := []struct {
+		after    *journal.Entry
+		match    logsMatcher
+		request  *v1alpha.GetLogsRequest
+		messages []string
+		last     uint64
+	}{
+		// All entries.
+		{
+			nil,
+			matchAllLogs,
+			&v1alpha.GetLogsRequest{},
+			[]string{"foo-1", "bar-1", "foo-2"},
+			4,
+		},
+		// Entries of one app.
+		{
+			nil,
+			matchFoo,
+			&v1alpha.GetLogsRequest{},
+			[]string{"foo-1", "foo-2"},
+			4,
+		},
+		// Entries in the time range.
+		{
+			nil,
+			matchAllLogs,
+			&v1alpha.GetLogsRequest{SinceTime: 15, UntilTime: 25},
+			[]string{"bar-1"},
+			4,
+		},
+		// Entries after the last one returned.
+		{
+			entries[1],
+			matchAllLogs,
+			&v1alpha.GetLogsRequest{},
+			[]string{"foo-2"},
+			4,
+		},
+		// No new entries.
+		{
+			entries[3],
+			matchAllLogs,
+			&v1alpha.GetLogsRequest{},
+			nil,
+			4,
+		},
+	}
+
+	for i, tt := range tests {
+		lines, last := filterLogEntries(entries, tt.after, tt.match, tt.request)
+
+		var expected []string
+		for _, e := range entries {
+			for _, m := range tt.messages {
+				if e.Fields["MESSAGE"] == m {
+					expected = append(expected, formatLogEntry(e))
+				}
+			}
+		}
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("#%d: got %v, want %v", i, lines, expected)
+		}
+		if last == nil || last.Seqnum != tt.last {
+			t.Errorf("#%d: got last entry %v, want seqnum %d", i, last, tt.last)
+		}
+	}
+}
+
+func TestUnitLogsMatcher(t *testing.T) {
+	match := unitLogsMatcher("foo.service")
+	tests := []struct {
+		fields map[string]string
+		match  bool
+	}{
+		{map[string]string{"_SYSTEMD_UNIT": "foo.service", "SYSLOG_IDENTIFIER": "sh"}, true},
+		// another app running the same executable
+		{map[string]string{"_SYSTEMD_UNIT": "bar.service", "SYSLOG_IDENTIFIER": "sh"}, false},
+		{map[string]string{"SYSLOG_IDENTIFIER": "sh"}, false},
+	}
+	for i, tt := range tests {
+		if m := match(&journal.Entry{Fields: tt.fields}); m != tt.match {
+			t.Errorf("#%d: expected match %t, got %t", i, tt.match, m)
+		}
+	}
+}
+
+func TestFormatLogEntry(t *testing.T) {
+	e := &journal.Entry{
+		Realtime: time.Date(2016, 4, 1, 12, 30, 0, 0, time.UTC),
+		Fields: map[string]string{
+			"SYSLOG_IDENTIFIER": "inspect",
+			"_PID":              "42",
+			"MESSAGE":           "hello",
+		},
+	}
+	expected := "2016-04-01T12:30:00+0000 inspect[42]: hello"
+	if line := formatLogEntry(e); line != expected {
+		t.Errorf("got %q, want %q", line, expected)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/appc/spec/schema"
+	"github.com/coreos/rkt/api/v1alpha"
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/common/apps"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/coreos/rkt/pkg/uid"
+	"github.com/coreos/rkt/rkt/image"
+	"github.com/coreos/rkt/stage0"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/net/context"
+)
+
+const (
+	// defaultAPIStopTimeout is the timeout of StopPod() when the request
+	// does not set one.
+	defaultAPIStopTimeout = 10 * time.Second
+)
+
+// runPodPollInterval is the interval between two checks of the state of the
+// pod started by RunPod().
+var runPodPollInterval = 100 * time.Millisecond
+
+// v1AlphaMutatingAPIServer implements v1alpha.MutatingAPIServer interface.
+type v1AlphaMutatingAPIServer struct {
+	store *store.Store
+}
+
+var _ v1alpha.MutatingAPIServer = &v1AlphaMutatingAPIServer{}
+
+func newV1AlphaMutatingAPIServer(s *store.Store) *v1AlphaMutatingAPIServer {
+	return &v1AlphaMutatingAPIServer{
+		store: s,
+	}
+}
+
+// FetchImage fetches an image like 'rkt fetch' and returns its ID.
+func (s *v1AlphaMutatingAPIServer) FetchImage(ctx context.Context, request *v1alpha.FetchImageRequest) (*v1alpha.FetchImageResponse, error) {
+	if request.StoreOnly && request.NoStore {
+		return nil, errors.New("both store_only and no_store specified")
+	}
+
+	config, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return nil, err
+	}
+
+	ft := &image.Fetcher{
+		S:                  s.store,
+		Ks:                 getKeystore(),
+		Headers:            config.AuthPerHost,
+		DockerAuth:         config.DockerCredentialsPerRegistry,
+		Registries:         config.RegistriesPerHost,
+		SignaturePolicy:    config.SignaturePolicyPerPrefix,
+		InsecureFlags:      globalFlags.InsecureFlags,
+		Debug:              globalFlags.Debug,
+		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
+
+		StoreOnly: request.StoreOnly,
+		NoStore:   request.NoStore,
+		WithDeps:  true,
+	}
+
+	hash, err := ft.FetchImage(request.Name, "", apps.AppImageGuess)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to fetch image %q", request.Name), err)
+		return nil, err
+	}
+
+	return &v1alpha.FetchImageResponse{Id: hash}, nil
+}
+
+// PreparePod prepares a pod from a pod manifest like 'rkt prepare --pod-manifest'
+// and returns its UUID.
+func (s *v1AlphaMutatingAPIServer) PreparePod(ctx context.Context, request *v1alpha.PreparePodRequest) (*v1alpha.PreparePodResponse, error) {
+	var pm schema.PodManifest
+	if err := pm.UnmarshalJSON(request.PodManifest); err != nil {
+		return nil, errwrap.Wrap(errors.New("invalid pod manifest"), err)
+	}
+
+	// stage0 reads the pod manifest from a file.
+	f, err := ioutil.TempFile("", "rkt-api-pod-manifest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(request.PodManifest)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return nil, err
+	}
+
+	s1img, err := getStage1Hash(s.store, config)
+	if err != nil {
+		stderr.Error(err)
+		return nil, err
+	}
+
+	p, err := newPod()
+	if err != nil {
+		stderr.PrintE("error creating new pod", err)
+		return nil, err
+	}
+	defer p.Close()
+
+	cfg := stage0.CommonConfig{
+		Store:       s.store,
+		Stage1Image: *s1img,
+		UUID:        p.uuid,
+		Debug:       globalFlags.Debug,
+	}
+
+	pcfg := stage0.PrepareConfig{
+		CommonConfig:       &cfg,
+		UseOverlay:         !request.NoOverlay && common.SupportsOverlay(),
+		PrivateUsers:       uid.NewBlankUidRange(),
+		SkipTreeStoreCheck: globalFlags.InsecureFlags.SkipOnDiskCheck(),
+		PodManifest:        f.Name(),
+	}
+
+	keyLock, err := lock.SharedKeyLock(lockDir(), common.PrepareLock)
+	if err != nil {
+		stderr.PrintE("cannot get shared prepare lock", err)
+		return nil, err
+	}
+	err = stage0.Prepare(pcfg, p.path(), p.uuid)
+	keyLock.Close()
+	if err != nil {
+		stderr.PrintE("error setting up stage0", err)
+		return nil, err
+	}
+
+	if err := p.sync(); err != nil {
+		stderr.PrintE("error syncing pod data", err)
+		return nil, err
+	}
+
+	if err := p.xToPrepared(); err != nil {
+		stderr.PrintE("error transitioning to prepared", err)
+		return nil, err
+	}
+
+	return &v1alpha.PreparePodResponse{Id: p.uuid.String()}, nil
+}
+
+// runPreparedArgs returns the arguments of the 'rkt run-prepared' command
+// running the prepared pod, using the same global flags as the API service.
+func runPreparedArgs(request *v1alpha.RunPodRequest) []string {
+	args := []string{
+		fmt.Sprintf("--dir=%s", getDataDir()),
+		fmt.Sprintf("--system-config=%s", globalFlags.SystemConfigDir),
+		fmt.Sprintf("--local-config=%s", globalFlags.LocalConfigDir),
+		fmt.Sprintf("--insecure-options=%s", globalFlags.InsecureFlags.String()),
+	}
+	if globalFlags.UserConfigDir != "" {
+		args = append(args, fmt.Sprintf("--user-config=%s", globalFlags.UserConfigDir))
+	}
+	if globalFlags.Debug {
+		args = append(args, "--debug")
+	}
+
+	args = append(args, cmdRunPreparedName)
+	if len(request.Networks) > 0 {
+		args = append(args, fmt.Sprintf("--net=%s", strings.Join(request.Networks, ",")))
+	}
+	args = append(args, request.Id)
+
+	return args
+}
+
+// RunPod runs a prepared pod. As stage0 execs the stage1, the pod is run by a
+// 'rkt run-prepared' child process, in its own session so the pod keeps
+// running if the API service exits.
+func (s *v1AlphaMutatingAPIServer) RunPod(ctx context.Context, request *v1alpha.RunPodRequest) (*v1alpha.RunPodResponse, error) {
+	p, err := getPodFromUUIDString(request.Id)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get pod %q", request.Id), err)
+		return nil, err
+	}
+	defer p.Close()
+
+	if !p.isPrepared {
+		return nil, fmt.Errorf("pod %q is not prepared", p.uuid)
+	}
+
+	cmd := exec.Command("/proc/self/exe", runPreparedArgs(request)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to run pod %q", p.uuid), err)
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(runPodPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-exited:
+			// The pod may have run and exited already.
+			if rerr := p.refreshState(); rerr != nil {
+				return nil, rerr
+			}
+			if p.isRunning() || p.afterRun() {
+				return &v1alpha.RunPodResponse{}, nil
+			}
+			if err == nil {
+				err = errors.New("run-prepared exited")
+			}
+			return nil, errwrap.Wrap(fmt.Errorf("failed to run pod %q", p.uuid), err)
+		case <-ticker.C:
+			if err := p.refreshState(); err != nil {
+				return nil, err
+			}
+			if p.isRunning() || p.afterRun() {
+				return &v1alpha.RunPodResponse{}, nil
+			}
+		}
+	}
+}
+
+// StopPod stops a running pod like 'rkt stop'.
+func (s *v1AlphaMutatingAPIServer) StopPod(ctx context.Context, request *v1alpha.StopPodRequest) (*v1alpha.StopPodResponse, error) {
+	p, err := getPodFromUUIDString(request.Id)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get pod %q", request.Id), err)
+		return nil, err
+	}
+	defer p.Close()
+
+	timeout := defaultAPIStopTimeout
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+	}
+
+	if err := stopPod(s.store, p, request.Force, timeout); err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to stop pod %q", p.uuid), err)
+		return nil, err
+	}
+
+	return &v1alpha.StopPodResponse{}, nil
+}
+
+// RemovePod removes a pod like 'rkt rm'.
+func (s *v1AlphaMutatingAPIServer) RemovePod(ctx context.Context, request *v1alpha.RemovePodRequest) (*v1alpha.RemovePodResponse, error) {
+	p, err := getPodFromUUIDString(request.Id)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get pod %q", request.Id), err)
+		return nil, err
+	}
+	defer p.Close()
+
+	if err := removePod(p); err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to remove pod %q", p.uuid), err)
+		return nil, err
+	}
+
+	return &v1alpha.RemovePodResponse{}, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/rkt/api/v1alpha"
+)
+
+func TestRunPreparedArgs(t *testing.T) {
+	cachedDataDir = "/var/lib/rkt"
+	globalFlags.SystemConfigDir = "/usr/lib/rkt"
+	globalFlags.LocalConfigDir = "/etc/rkt"
+
+	tests := []struct {
+		request *v1alpha.RunPodRequest
+		args    []string
+	}{
+		{
+			&v1alpha.RunPodRequest{Id: "uuid"},
+			[]string{"run-prepared", "uuid"},
+		},
+		{
+			&v1alpha.RunPodRequest{Id: "uuid", Networks: []string{"host"}},
+			[]string{"run-prepared", "--net=host", "uuid"},
+		},
+		{
+			&v1alpha.RunPodRequest{Id: "uuid", Networks: []string{"default", "mynet:IP=10.1.2.3"}},
+			[]string{"run-prepared", "--net=default,mynet:IP=10.1.2.3", "uuid"},
+		},
+	}
+
+	globalArgs := []string{
+		"--dir=/var/lib/rkt",
+		"--system-config=/usr/lib/rkt",
+		"--local-config=/etc/rkt",
+		"--insecure-options=" + globalFlags.InsecureFlags.String(),
+	}
+
+	for i, tt := range tests {
+		args := runPreparedArgs(tt.request)
+		expected := append(append([]string{}, globalArgs...), tt.args...)
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("#%d: got %v, want %v", i, args, expected)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/rkt/api/v1alpha"
+)
+
+// defaultStatsInterval is the interval between two samples sent by
+// GetPodStats() when following, if the request does not set one.
+var defaultStatsInterval = time.Second
+
+func (s *v1AlphaAPIServer) GetPodStats(request *v1alpha.GetPodStatsRequest, server v1alpha.PublicAPI_GetPodStatsServer) error {
+	uuid, err := types.NewUUID(request.Id)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("invalid pod id %q", request.Id), err)
+		return err
+	}
+
+	p, err := getPod(uuid)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get pod %q", request.Id), err)
+		return err
+	}
+	defer p.Close()
+
+	stats, err := getPodStats(p)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("failed to get stats of pod %q", request.Id), err)
+		return err
+	}
+	if err := server.Send(&v1alpha.GetPodStatsResponse{Stats: stats}); err != nil {
+		return err
+	}
+
+	if !request.Follow {
+		return nil
+	}
+
+	interval := defaultStatsInterval
+	if request.Interval > 0 {
+		interval = time.Duration(request.Interval) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-server.Context().Done():
+			return nil
+		case <-ticker.C:
+			if err := p.refreshState(); err != nil {
+				return err
+			}
+			if !p.isRunning() {
+				return nil
+			}
+
+			stats, err := getPodStats(p)
+			if err != nil {
+				// The pod may have exited since its state
+				// was refreshed.
+				if err := p.refreshState(); err == nil && !p.isRunning() {
+					return nil
+				}
+				stderr.PrintE(fmt.Sprintf("failed to get stats of pod %q", request.Id), err)
+				return err
+			}
+			if err := server.Send(&v1alpha.GetPodStatsResponse{Stats: stats}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/networking"
+	"github.com/coreos/rkt/networking/netinfo"
+	"github.com/coreos/rkt/pkg/fileutil"
+	"github.com/coreos/rkt/pkg/uid"
+	"github.com/coreos/rkt/stage0"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// checkpointInfoFilename is the name of the file describing the
+	// checkpointed pod in the checkpoint directory.
+	checkpointInfoFilename = "checkpoint.json"
+	// checkpointImagesDir is the directory of the CRIU images in the
+	// checkpoint directory.
+	checkpointImagesDir = "criu"
+	// checkpointPodDir is the directory of the copy of the pod directory
+	// in the checkpoint directory.
+	checkpointPodDir = "pod"
+)
+
+var (
+	cmdCheckpoint = &cobra.Command{
+		Use:   "checkpoint [--leave-running] UUID DIR",
+		Short: "Checkpoint a running pod to a directory",
+		Long: `Dumps the processes of the running pod with CRIU, and saves them in DIR
+together with the pod's directory and the information needed to restore it
+with 'rkt restore', on this host or on another one.
+
+The pod is stopped after the checkpoint, unless --leave-running is passed.
+Only the systemd-nspawn based stage1 flavors are supported.`,
+		Run: ensureSuperuser(runWrapper(runCheckpoint)),
+	}
+	flagLeaveRunning bool
+	flagCRIU         string
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdCheckpoint)
+	cmdCheckpoint.Flags().BoolVar(&flagLeaveRunning, "leave-running", false, "leave the pod running after the checkpoint")
+	cmdCheckpoint.Flags().StringVar(&flagCRIU, "criu", "criu", "path to the criu binary")
+}
+
+// checkpointInfo describes a checkpointed pod.
+type checkpointInfo struct {
+	// UUID is the uuid of the pod, it is kept on restore.
+	UUID string `json:"uuid"`
+	// Stage1Image is the hash of the stage1 image.
+	Stage1Image string `json:"stage1Image"`
+	// Stage1TreeStoreID is the tree store ID of the stage1 image.
+	Stage1TreeStoreID string `json:"stage1TreeStoreID"`
+	// AppsTreeStoreIDs are the tree store IDs of the app images by app
+	// name, empty if the pod does not use overlay.
+	AppsTreeStoreIDs map[string]string `json:"appsTreeStoreIDs,omitempty"`
+	// Networks are the networks the pod is in, empty if the pod uses
+	// the host's network namespace.
+	Networks []netinfo.NetInfo `json:"networks,omitempty"`
+	// NetNSInode is the inode of the pod's network namespace, 0 if the
+	// pod uses the host's network namespace.
+	NetNSInode uint64 `json:"netnsInode,omitempty"`
+}
+
+func runCheckpoint(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return 1
+	}
+
+	if globalFlags.Debug {
+		stage0.InitDebug()
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	p, err := getPodFromUUIDString(args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	if err := checkpointPod(s, p, args[1], flagLeaveRunning); err != nil {
+		stderr.PrintE(fmt.Sprintf("error checkpointing pod %q", p.uuid), err)
+		return 1
+	}
+
+	stdout.Printf("checkpointed pod %q to %q", p.uuid, args[1])
+	return 0
+}
+
+// checkpointPod dumps the processes of the pod with CRIU to dir, and saves
+// the pod's directory and its checkpointInfo next to them.
+func checkpointPod(s *store.Store, p *pod, dir string, leaveRunning bool) error {
+	if !p.isRunning() {
+		return fmt.Errorf("pod %q is not running", p.uuid)
+	}
+
+	flavor, err := os.Readlink(filepath.Join(p.path(), "flavor"))
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get stage1 flavor"), err)
+	}
+	if flavor == "kvm" || flavor == "fly" {
+		return fmt.Errorf("checkpoint is not supported by the %q stage1 flavor", flavor)
+	}
+
+	info, err := getCheckpointInfo(s, p)
+	if err != nil {
+		return err
+	}
+
+	pid, err := p.getContainerPID1()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get pod's pid"), err)
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errwrap.Wrap(errors.New("cannot create checkpoint directory"), err)
+	}
+	imagesDir := filepath.Join(dir, checkpointImagesDir)
+	if err := os.Mkdir(imagesDir, 0700); err != nil {
+		return errwrap.Wrap(errors.New("cannot create CRIU images directory"), err)
+	}
+
+	cfg := stage0.CheckpointConfig{
+		CRIUPath:     flagCRIU,
+		ImagesDir:    imagesDir,
+		PodPID:       pid,
+		NetNSInode:   info.NetNSInode,
+		LeaveRunning: leaveRunning,
+	}
+	if err := stage0.CheckpointPod(cfg, p.path()); err != nil {
+		return err
+	}
+
+	// The processes of the pod are dumped: the pod directory does not
+	// change anymore, unless the pod was left running.
+	useOverlay := p.usesOverlay()
+	if err := copyPodDir(p.path(), filepath.Join(dir, checkpointPodDir), useOverlay); err != nil {
+		return errwrap.Wrap(errors.New("cannot copy pod directory"), err)
+	}
+
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, checkpointInfoFilename), infoBytes, 0600); err != nil {
+		return errwrap.Wrap(errors.New("cannot write checkpoint info"), err)
+	}
+
+	return nil
+}
+
+// getCheckpointInfo collects what is needed to recreate the pod on restore.
+func getCheckpointInfo(s *store.Store, p *pod) (*checkpointInfo, error) {
+	info := &checkpointInfo{
+		UUID:             p.uuid.String(),
+		AppsTreeStoreIDs: make(map[string]string),
+	}
+
+	var err error
+	info.Stage1TreeStoreID, err = p.getStage1TreeStoreID()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get stage1 tree store ID"), err)
+	}
+	info.Stage1Image, err = s.GetTreeStoreImageHash(info.Stage1TreeStoreID)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get stage1 image"), err)
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get pod's apps"), err)
+	}
+	for _, a := range apps {
+		path, err := filepath.Rel("/", common.AppTreeStoreIDPath("", a.Name))
+		if err != nil {
+			return nil, err
+		}
+		treeStoreID, err := p.readFile(path)
+		// When not using overlayfs, apps don't have a treeStoreID file
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot get tree store ID of app %q", a.Name), err)
+		}
+		info.AppsTreeStoreIDs[a.Name.String()] = string(treeStoreID)
+	}
+
+	n, err := networking.Load(p.path(), p.uuid)
+	switch {
+	case err == nil:
+		info.Networks = n.NetInfos()
+	case os.IsNotExist(err):
+		// probably running with --net=host
+	default:
+		return nil, errwrap.Wrap(errors.New("cannot load networking state"), err)
+	}
+
+	if len(info.Networks) > 0 {
+		var st syscall.Stat_t
+		if err := syscall.Stat(filepath.Join(p.path(), "netns"), &st); err != nil {
+			return nil, errwrap.Wrap(errors.New("cannot stat pod's netns"), err)
+		}
+		info.NetNSInode = st.Ino
+	}
+
+	return info, nil
+}
+
+// readCheckpointInfo reads the checkpointInfo in the checkpoint directory.
+func readCheckpointInfo(dir string) (*checkpointInfo, error) {
+	infoBytes, err := ioutil.ReadFile(filepath.Join(dir, checkpointInfoFilename))
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot read checkpoint info"), err)
+	}
+	info := &checkpointInfo{}
+	if err := json.Unmarshal(infoBytes, info); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot parse checkpoint info"), err)
+	}
+	return info, nil
+}
+
+// podDirExcluded returns true if the file at path (relative to the pod
+// directory) must not be copied from or to a checkpoint: the files describing
+// the running processes and the network namespace are recreated on restore,
+// and with overlay the stage1 rootfs is a mount whose changes are in the
+// upper directories.
+func podDirExcluded(path string, useOverlay bool) bool {
+	switch path {
+	case "pid", "ppid", "netns":
+		return true
+	case common.Stage1RootfsPath("."):
+		return useOverlay
+	}
+	return false
+}
+
+// copyPodDir copies the pod directory src to dest, skipping the excluded
+// files. The directories in src are created in dest if needed.
+func copyPodDir(src, dest string, useOverlay bool) error {
+	return copyPodDirAt(src, dest, "", useOverlay)
+}
+
+func copyPodDirAt(src, dest, rel string, useOverlay bool) error {
+	fi, err := os.Stat(filepath.Join(src, rel))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(dest, rel), fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(src, rel))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		frel := filepath.Join(rel, f.Name())
+		if podDirExcluded(frel, useOverlay) {
+			continue
+		}
+
+		fsrc, fdest := filepath.Join(src, frel), filepath.Join(dest, frel)
+		switch {
+		case f.IsDir() && hasExcludedChild(frel, useOverlay):
+			if err := copyPodDirAt(src, dest, frel, useOverlay); err != nil {
+				return err
+			}
+		case f.IsDir():
+			if err := fileutil.CopyTree(fsrc, fdest, uid.NewBlankUidRange()); err != nil {
+				return err
+			}
+		case f.Mode()&os.ModeSymlink != 0:
+			if err := fileutil.CopySymlink(fsrc, fdest); err != nil {
+				return err
+			}
+		case f.Mode().IsRegular():
+			if err := fileutil.CopyRegularFile(fsrc, fdest); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasExcludedChild returns true if a file under the directory dir (relative
+// to the pod directory) is excluded from the copy.
+func hasExcludedChild(dir string, useOverlay bool) bool {
+	return useOverlay && strings.HasPrefix(common.Stage1RootfsPath("."), dir+"/")
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/rkt/networking/netinfo"
+)
+
+func TestCopyPodDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	files := []string{
+		"pod",
+		"pid",
+		"ppid",
+		"netns",
+		"stage1/manifest",
+		"stage1/rootfs/init",
+		"overlay/deps-sha512-1234/upper/etc/hosts",
+	}
+	for _, f := range files {
+		path := filepath.Join(src, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(f), 0644); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+	if err := os.Symlink("coreos", filepath.Join(src, "flavor")); err != nil {
+		t.Fatalf("error creating symlink: %v", err)
+	}
+
+	tests := []struct {
+		useOverlay bool
+		copied     []string
+		skipped    []string
+	}{
+		{
+			useOverlay: true,
+			copied:     []string{"pod", "flavor", "stage1/manifest", "overlay/deps-sha512-1234/upper/etc/hosts"},
+			skipped:    []string{"pid", "ppid", "netns", "stage1/rootfs"},
+		},
+		{
+			useOverlay: false,
+			copied:     []string{"pod", "flavor", "stage1/manifest", "stage1/rootfs/init"},
+			skipped:    []string{"pid", "ppid", "netns"},
+		},
+	}
+
+	for i, tt := range tests {
+		dest := filepath.Join(dir, "dest")
+		if err := copyPodDir(src, dest, tt.useOverlay); err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		for _, f := range tt.copied {
+			if _, err := os.Lstat(filepath.Join(dest, f)); err != nil {
+				t.Errorf("#%d: expected %q to be copied: %v", i, f, err)
+			}
+		}
+		for _, f := range tt.skipped {
+			if _, err := os.Lstat(filepath.Join(dest, f)); !os.IsNotExist(err) {
+				t.Errorf("#%d: expected %q to be skipped", i, f)
+			}
+		}
+		os.RemoveAll(dest)
+	}
+}
+
+func TestRestoredNetList(t *testing.T) {
+	tests := []struct {
+		in  netinfo.NetInfo
+		out string
+	}{
+		{
+			netinfo.NetInfo{
+				NetName: "dual",
+				IP:      net.ParseIP("10.1.0.2"),
+				Addresses: []netinfo.Address{
+					{IP: net.ParseIP("10.1.0.2")},
+					{IP: net.ParseIP("fd00::2")},
+				},
+			},
+			"dual:IP=10.1.0.2",
+		},
+		{
+			netinfo.NetInfo{
+				NetName: "ipv6",
+				Addresses: []netinfo.Address{
+					{IP: nil},
+					{IP: net.ParseIP("fd00::3")},
+				},
+			},
+			"ipv6:IP=fd00::3",
+		},
+		{
+			// checkpoint of an older version
+			netinfo.NetInfo{
+				NetName: "old",
+				IP:      net.ParseIP("10.2.0.4"),
+			},
+			"old:IP=10.2.0.4",
+		},
+		{
+			netinfo.NetInfo{
+				NetName: "noaddr",
+			},
+			"noaddr",
+		},
+	}
+	for _, tt := range tests {
+		netList, err := restoredNetList([]netinfo.NetInfo{tt.in})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.in.NetName, err)
+			continue
+		}
+		if s := netList.String(); s != tt.out {
+			t.Errorf("%s: expected %q, got %q", tt.in.NetName, tt.out, s)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"net"
 	"reflect"
 	"strings"
 	"testing"
@@ -138,3 +139,54 @@ func TestParsePortFlag(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePortFlagOptions(t *testing.T) {
+	tests := []struct {
+		in      string
+		hostIP  string
+		port    uint
+		count   uint
+		network string
+		err     bool
+	}{
+		{"foo:123", "", 123, 0, "", false},
+		{"foo:127.0.0.1:123", "127.0.0.1", 123, 0, "", false},
+		{"foo:[::1]:123", "::1", 123, 0, "", false},
+		{"foo:8000-8009", "", 8000, 10, "", false},
+		{"foo:10.0.0.1:8000-8000@backend", "10.0.0.1", 8000, 1, "backend", false},
+		{"foo:123@", "", 0, 0, "", true},
+		{"foo:localhost:123", "", 0, 0, "", true},
+		{"foo:0", "", 0, 0, "", true},
+		{"foo:8009-8000", "", 0, 0, "", true},
+		{"foo:8000-70000", "", 0, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		pl := portList{}
+		err := pl.Set(tt.in)
+		if err != nil {
+			if !tt.err {
+				t.Errorf("%q failed to parse: %v", tt.in, err)
+			}
+			continue
+		}
+		if tt.err {
+			t.Errorf("%q unexpectedly parsed", tt.in)
+			continue
+		}
+
+		p := pl[0]
+		if (tt.hostIP == "" && p.HostIP != nil) || (tt.hostIP != "" && !p.HostIP.Equal(net.ParseIP(tt.hostIP))) {
+			t.Errorf("%q parsed but HostIP mismatch: got %v, expected %v", tt.in, p.HostIP, tt.hostIP)
+		}
+		if p.HostPort != tt.port || p.Count != tt.count {
+			t.Errorf("%q parsed but host ports mismatch: got %v (%v ports), expected %v (%v ports)", tt.in, p.HostPort, p.Count, tt.port, tt.count)
+		}
+		if p.Network != tt.network {
+			t.Errorf("%q parsed but Network mismatch: got %q, expected %q", tt.in, p.Network, tt.network)
+		}
+		if s := pl.String(); s != tt.in {
+			t.Errorf("%q parsed but printed back as %q", tt.in, s)
+		}
+	}
+}
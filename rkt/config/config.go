@@ -56,13 +56,62 @@ type Stage1Data struct {
 	Location string
 }
 
+// StoreData holds the settings of the image store.
+type StoreData struct {
+	TreeStore string
+	DB        string
+}
+
+// RegistryData holds the network settings used when downloading from
+// a host (images, signatures, docker images).
+type RegistryData struct {
+	// Proxy is the URL of the HTTP proxy to use, the proxy
+	// environment variables are used if empty.
+	Proxy string
+	// CABundle is a path to a file with the PEM encoded
+	// certificates of the CAs trusted for the host, instead of
+	// the system ones.
+	CABundle string
+	// ClientCertificate and ClientKey are paths to the PEM
+	// encoded certificate and key to present to the host.
+	ClientCertificate string
+	ClientKey         string
+	// Mirror is a host to download from instead of this one.
+	Mirror string
+}
+
+// The actions of the signature policy rules.
+const (
+	// PolicyRequireSignature requires the images to be signed by
+	// a trusted key.
+	PolicyRequireSignature = "require-signature"
+	// PolicyAllowUnsignedLocal allows unsigned images fetched
+	// from local paths, the other images must be signed.
+	PolicyAllowUnsignedLocal = "allow-unsigned-local"
+	// PolicyDeny rejects the images.
+	PolicyDeny = "deny"
+)
+
+// SignaturePolicyRule is the signature policy applying to the images
+// with a name prefix.
+type SignaturePolicyRule struct {
+	// Action is one of the Policy* constants.
+	Action string
+	// Keys are the fingerprints of the keys allowed to sign the
+	// images, any trusted key is allowed if empty.
+	Keys []string
+}
+
 // Config is a single place where configuration for rkt frontend needs
 // resides.
 type Config struct {
 	AuthPerHost                  map[string]Headerer
 	DockerCredentialsPerRegistry map[string]BasicCredentials
+	RegistriesPerHost            map[string]RegistryData
+	SignaturePolicyPerPrefix     map[string]SignaturePolicyRule
 	Paths                        ConfigurablePaths
 	Stage1                       Stage1Data
+	Store                        StoreData
 }
 
 // MarshalJSON marshals the config for user output.
@@ -117,6 +166,48 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		stage0 = append(stage0, dockerAuth)
 	}
 
+	for host, registry := range c.RegistriesPerHost {
+		registries := struct {
+			RktVersion        string   `json:"rktVersion"`
+			RktKind           string   `json:"rktKind"`
+			Domains           []string `json:"domains"`
+			Proxy             string   `json:"proxy,omitempty"`
+			CABundle          string   `json:"caBundle,omitempty"`
+			ClientCertificate string   `json:"clientCertificate,omitempty"`
+			ClientKey         string   `json:"clientKey,omitempty"`
+			Mirror            string   `json:"mirror,omitempty"`
+		}{
+			RktVersion:        "v1",
+			RktKind:           "registries",
+			Domains:           []string{host},
+			Proxy:             registry.Proxy,
+			CABundle:          registry.CABundle,
+			ClientCertificate: registry.ClientCertificate,
+			ClientKey:         registry.ClientKey,
+			Mirror:            registry.Mirror,
+		}
+
+		stage0 = append(stage0, registries)
+	}
+
+	for prefix, rule := range c.SignaturePolicyPerPrefix {
+		policy := struct {
+			RktVersion string   `json:"rktVersion"`
+			RktKind    string   `json:"rktKind"`
+			Prefixes   []string `json:"prefixes"`
+			Action     string   `json:"action"`
+			Keys       []string `json:"keys,omitempty"`
+		}{
+			RktVersion: "v1",
+			RktKind:    "policy",
+			Prefixes:   []string{prefix},
+			Action:     rule.Action,
+			Keys:       rule.Keys,
+		}
+
+		stage0 = append(stage0, policy)
+	}
+
 	paths := struct {
 		RktVersion   string `json:"rktVersion"`
 		RktKind      string `json:"rktKind"`
@@ -143,7 +234,19 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		Location:   c.Stage1.Location,
 	}
 
-	stage0 = append(stage0, paths, stage1)
+	store := struct {
+		RktVersion string `json:"rktVersion"`
+		RktKind    string `json:"rktKind"`
+		TreeStore  string `json:"treeStore"`
+		DB         string `json:"db"`
+	}{
+		RktVersion: "v1",
+		RktKind:    "store",
+		TreeStore:  c.Store.TreeStore,
+		DB:         c.Store.DB,
+	}
+
+	stage0 = append(stage0, paths, stage1, store)
 
 	data := map[string]interface{}{"stage0": stage0}
 	return json.Marshal(data)
@@ -259,6 +362,8 @@ func newConfig() *Config {
 	return &Config{
 		AuthPerHost:                  make(map[string]Headerer),
 		DockerCredentialsPerRegistry: make(map[string]BasicCredentials),
+		RegistriesPerHost:            make(map[string]RegistryData),
+		SignaturePolicyPerPrefix:     make(map[string]SignaturePolicyRule),
 		Paths: ConfigurablePaths{
 			DataDir: "",
 		},
@@ -396,6 +501,12 @@ func mergeConfigs(config *Config, subconfig *Config) {
 	for registry, creds := range subconfig.DockerCredentialsPerRegistry {
 		config.DockerCredentialsPerRegistry[registry] = creds
 	}
+	for host, registry := range subconfig.RegistriesPerHost {
+		config.RegistriesPerHost[host] = registry
+	}
+	for prefix, rule := range subconfig.SignaturePolicyPerPrefix {
+		config.SignaturePolicyPerPrefix[prefix] = rule
+	}
 	if subconfig.Paths.DataDir != "" {
 		config.Paths.DataDir = subconfig.Paths.DataDir
 	}
@@ -409,4 +520,10 @@ func mergeConfigs(config *Config, subconfig *Config) {
 	if subconfig.Stage1.Location != "" {
 		config.Stage1.Location = subconfig.Stage1.Location
 	}
+	if subconfig.Store.TreeStore != "" {
+		config.Store.TreeStore = subconfig.Store.TreeStore
+	}
+	if subconfig.Store.DB != "" {
+		config.Store.DB = subconfig.Store.DB
+	}
 }
@@ -188,6 +188,114 @@ func TestStage1ConfigFormat(t *testing.T) {
 	}
 }
 
+func TestStoreConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected StoreData
+		fail     bool
+	}{
+		{"bogus contents", StoreData{}, true},
+		{`{"bogus": {"foo": "bar"}}`, StoreData{}, true},
+		{`{"rktKind": "foo"}`, StoreData{}, true},
+		{`{"rktKind": "store", "rktVersion": "foo"}`, StoreData{}, true},
+		{`{"rktKind": "store", "rktVersion": "v1"}`, StoreData{}, false},
+		{`{"rktKind": "store", "rktVersion": "v1", "treeStore": "dedup"}`, StoreData{TreeStore: "dedup"}, false},
+		{`{"rktKind": "store", "rktVersion": "v1", "treeStore": "copy"}`, StoreData{TreeStore: "copy"}, false},
+		{`{"rktKind": "store", "rktVersion": "v1", "treeStore": "bogus"}`, StoreData{}, true},
+		{`{"rktKind": "store", "rktVersion": "v1", "db": "kv"}`, StoreData{DB: "kv"}, false},
+		{`{"rktKind": "store", "rktVersion": "v1", "treeStore": "dedup", "db": "ql"}`, StoreData{TreeStore: "dedup", DB: "ql"}, false},
+		{`{"rktKind": "store", "rktVersion": "v1", "db": "bogus"}`, StoreData{}, true},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "store")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.Store
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+	}
+}
+
+func TestRegistriesConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected map[string]RegistryData
+		fail     bool
+	}{
+		{"bogus contents", nil, true},
+		{`{"bogus": {"foo": "bar"}}`, nil, true},
+		{`{"rktKind": "foo"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "foo"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": []}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"]}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "proxy": "proxy.example.com:3128"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "proxy": "ftp://proxy.example.com"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "proxy": "http://proxy.example.com:3128"}`, map[string]RegistryData{"coreos.com": RegistryData{Proxy: "http://proxy.example.com:3128"}}, false},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "caBundle": "ca.pem"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "caBundle": "/etc/ca.pem"}`, map[string]RegistryData{"coreos.com": RegistryData{CABundle: "/etc/ca.pem"}}, false},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "clientCertificate": "/etc/client.pem"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "clientKey": "/etc/client.key"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "clientCertificate": "client.pem", "clientKey": "/etc/client.key"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "clientCertificate": "/etc/client.pem", "clientKey": "/etc/client.key"}`, map[string]RegistryData{"coreos.com": RegistryData{ClientCertificate: "/etc/client.pem", ClientKey: "/etc/client.key"}}, false},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com"], "mirror": "https://mirror.example.com"}`, nil, true},
+		{`{"rktKind": "registries", "rktVersion": "v1", "domains": ["coreos.com", "quay.io"], "mirror": "mirror.example.com:5000"}`, map[string]RegistryData{"coreos.com": RegistryData{Mirror: "mirror.example.com:5000"}, "quay.io": RegistryData{Mirror: "mirror.example.com:5000"}}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "registries")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.RegistriesPerHost
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
+func TestPolicyConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected map[string]SignaturePolicyRule
+		fail     bool
+	}{
+		{"bogus contents", nil, true},
+		{`{"rktKind": "policy", "rktVersion": "foo"}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["coreos.com"]}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["coreos.com"], "action": "allow"}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["https://coreos.com"], "action": "deny"}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["coreos.com"], "action": "deny", "keys": ["8b86de38890ddb7291867b025210bd8888182190"]}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["coreos.com"], "action": "require-signature", "keys": ["8b86de38"]}`, nil, true},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["coreos.com/etcd", "coreos.com/flannel"], "action": "require-signature", "keys": ["8B86 DE38 890D DB72 9186  7B02 5210 BD88 8818 2190"]}`, map[string]SignaturePolicyRule{"coreos.com/etcd": SignaturePolicyRule{Action: PolicyRequireSignature, Keys: []string{"8b86de38890ddb7291867b025210bd8888182190"}}, "coreos.com/flannel": SignaturePolicyRule{Action: PolicyRequireSignature, Keys: []string{"8b86de38890ddb7291867b025210bd8888182190"}}}, false},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["example.com/dev"], "action": "allow-unsigned-local"}`, map[string]SignaturePolicyRule{"example.com/dev": SignaturePolicyRule{Action: PolicyAllowUnsignedLocal}}, false},
+		{`{"rktKind": "policy", "rktVersion": "v1", "prefixes": ["example.com"], "action": "deny"}`, map[string]SignaturePolicyRule{"example.com": SignaturePolicyRule{Action: PolicyDeny}}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "policy")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.SignaturePolicyPerPrefix
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
 func verifyFailure(shouldFail bool, contents string, err error) error {
 	var vErr error = nil
 	if err != nil {
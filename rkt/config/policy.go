@@ -0,0 +1,80 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+)
+
+type policyV1JsonParser struct{}
+
+type policyV1 struct {
+	Prefixes []string `json:"prefixes"`
+	Action   string   `json:"action"`
+	Keys     []string `json:"keys"`
+}
+
+func init() {
+	addParser("policy", "v1", &policyV1JsonParser{})
+	registerSubDir("policy.d", []string{"policy"})
+}
+
+func (p *policyV1JsonParser) parse(config *Config, raw []byte) error {
+	var policy policyV1
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return err
+	}
+	if len(policy.Prefixes) == 0 {
+		return fmt.Errorf("no prefixes specified")
+	}
+	rule := SignaturePolicyRule{
+		Action: policy.Action,
+	}
+	switch policy.Action {
+	case PolicyRequireSignature, PolicyAllowUnsignedLocal:
+	case PolicyDeny:
+		if len(policy.Keys) > 0 {
+			return fmt.Errorf("keys cannot be specified with the %q action", PolicyDeny)
+		}
+	case "":
+		return fmt.Errorf("no action specified")
+	default:
+		return fmt.Errorf("unknown action %q, expected %q, %q or %q", policy.Action, PolicyRequireSignature, PolicyAllowUnsignedLocal, PolicyDeny)
+	}
+	for _, key := range policy.Keys {
+		// accept fingerprints as printed by gpg, in groups of
+		// four uppercase digits
+		fingerprint := strings.ToLower(strings.Replace(key, " ", "", -1))
+		if b, err := hex.DecodeString(fingerprint); err != nil || len(b) != 20 {
+			return fmt.Errorf("invalid key fingerprint %q", key)
+		}
+		rule.Keys = append(rule.Keys, fingerprint)
+	}
+	for _, prefix := range policy.Prefixes {
+		if _, err := types.NewACIdentifier(prefix); err != nil {
+			return fmt.Errorf("invalid prefix %q: %v", prefix, err)
+		}
+		if _, ok := config.SignaturePolicyPerPrefix[prefix]; ok {
+			return fmt.Errorf("signature policy for prefix %q is already specified", prefix)
+		}
+		config.SignaturePolicyPerPrefix[prefix] = rule
+	}
+	return nil
+}
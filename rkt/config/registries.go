@@ -0,0 +1,92 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+type registriesV1JsonParser struct{}
+
+type registriesV1 struct {
+	Domains           []string `json:"domains"`
+	Proxy             string   `json:"proxy"`
+	CABundle          string   `json:"caBundle"`
+	ClientCertificate string   `json:"clientCertificate"`
+	ClientKey         string   `json:"clientKey"`
+	Mirror            string   `json:"mirror"`
+}
+
+func init() {
+	addParser("registries", "v1", &registriesV1JsonParser{})
+	registerSubDir("registries.d", []string{"registries"})
+}
+
+func (p *registriesV1JsonParser) parse(config *Config, raw []byte) error {
+	var registries registriesV1
+	if err := json.Unmarshal(raw, &registries); err != nil {
+		return err
+	}
+	if len(registries.Domains) == 0 {
+		return fmt.Errorf("no domains specified")
+	}
+	if registries.Proxy != "" {
+		u, err := url.Parse(registries.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL %q: %v", registries.Proxy, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q, expected an http or https URL", registries.Proxy)
+		}
+	}
+	if registries.CABundle != "" && !filepath.IsAbs(registries.CABundle) {
+		return fmt.Errorf("CA bundle must be an absolute path")
+	}
+	if (registries.ClientCertificate == "") != (registries.ClientKey == "") {
+		return fmt.Errorf("client certificate and client key must be specified together")
+	}
+	if registries.ClientCertificate != "" {
+		if !filepath.IsAbs(registries.ClientCertificate) {
+			return fmt.Errorf("client certificate must be an absolute path")
+		}
+		if !filepath.IsAbs(registries.ClientKey) {
+			return fmt.Errorf("client key must be an absolute path")
+		}
+	}
+	if registries.Mirror != "" && strings.ContainsAny(registries.Mirror, "/?#@") {
+		return fmt.Errorf("invalid mirror %q, expected a host with an optional port", registries.Mirror)
+	}
+	registry := RegistryData{
+		Proxy:             registries.Proxy,
+		CABundle:          registries.CABundle,
+		ClientCertificate: registries.ClientCertificate,
+		ClientKey:         registries.ClientKey,
+		Mirror:            registries.Mirror,
+	}
+	if registry == (RegistryData{}) {
+		return fmt.Errorf("no registry settings specified")
+	}
+	for _, domain := range registries.Domains {
+		if _, ok := config.RegistriesPerHost[domain]; ok {
+			return fmt.Errorf("registry settings for domain %q are already specified", domain)
+		}
+		config.RegistriesPerHost[domain] = registry
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type storeV1JsonParser struct{}
+
+type storeV1 struct {
+	TreeStore string `json:"treeStore"`
+	DB        string `json:"db"`
+}
+
+var (
+	allowedTreeStoreBackends = map[string]struct{}{
+		"copy":  struct{}{},
+		"dedup": struct{}{},
+	}
+	allowedDBs = map[string]struct{}{
+		"ql": struct{}{},
+		"kv": struct{}{},
+	}
+)
+
+func init() {
+	addParser("store", "v1", &storeV1JsonParser{})
+	registerSubDir("store.d", []string{"store"})
+}
+
+func (p *storeV1JsonParser) parse(config *Config, raw []byte) error {
+	var store storeV1
+	if err := json.Unmarshal(raw, &store); err != nil {
+		return err
+	}
+	if store.TreeStore != "" {
+		if _, ok := allowedTreeStoreBackends[store.TreeStore]; !ok {
+			backends := toArray(allowedTreeStoreBackends)
+			sort.Strings(backends)
+			return fmt.Errorf("invalid tree store backend %q, allowed backends are %q", store.TreeStore, strings.Join(backends, `", "`))
+		}
+		if config.Store.TreeStore != "" {
+			return fmt.Errorf("tree store backend is already specified")
+		}
+		config.Store.TreeStore = store.TreeStore
+	}
+	if store.DB != "" {
+		if _, ok := allowedDBs[store.DB]; !ok {
+			dbs := toArray(allowedDBs)
+			sort.Strings(dbs)
+			return fmt.Errorf("invalid store database %q, allowed databases are %q", store.DB, strings.Join(dbs, `", "`))
+		}
+		if config.Store.DB != "" {
+			return fmt.Errorf("store database is already specified")
+		}
+		config.Store.DB = store.DB
+	}
+	return nil
+}
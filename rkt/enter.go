@@ -25,7 +25,6 @@ import (
 	"github.com/appc/spec/schema/types"
 	"github.com/coreos/rkt/common"
 	"github.com/coreos/rkt/stage0"
-	"github.com/coreos/rkt/store"
 	"github.com/hashicorp/errwrap"
 	"github.com/spf13/cobra"
 )
@@ -94,7 +93,7 @@ func runEnter(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -149,7 +148,7 @@ func getAppName(p *pod) (*types.ACName, error) {
 		stderr.Printf("\t%v", ra.Name)
 	}
 
-	return nil, fmt.Errorf("specify app using \"rkt enter --app= ...\"")
+	return nil, fmt.Errorf("specify app using \"--app= ...\"")
 }
 
 // getEnterArgv returns the argv to use for entering the pod
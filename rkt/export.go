@@ -0,0 +1,460 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/appc/spec/aci"
+	"github.com/appc/spec/pkg/tarheader"
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// overlayOpaqueXattr is set to "y" by overlayfs on the directories of
+	// the upper directory hiding the content of the lower directory.
+	overlayOpaqueXattr = "trusted.overlay.opaque"
+)
+
+var (
+	cmdExport = &cobra.Command{
+		Use:   "export [--app=APPNAME] [--name=NAME] [--version=VERSION] UUID OUTPUT_ACI_FILE",
+		Short: "Export an app from an exited pod to an ACI file",
+		Long: `Packs the root filesystem of an app of an exited pod, including the changes
+made while the pod was running, in a new ACI.
+
+If the pod uses overlayfs, only the changes are packed and the image of the app
+is a dependency of the new image. Otherwise, the whole root filesystem of the
+app is packed.
+
+The new image must not have the name and version of the app's image, which
+is its dependency. Without --name and --version, it gets the name of the app's
+image, with the first part of the UUID of the pod appended to its version.
+
+The --app flag is only needed if the pod contains more than one app.`,
+		Run: ensureSuperuser(runWrapper(runExport)),
+	}
+	flagExportName    string
+	flagExportVersion string
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdExport)
+	cmdExport.Flags().StringVar(&flagAppName, "app", "", "name of the app to export within the specified pod")
+	cmdExport.Flags().BoolVar(&flagOverwriteACI, "overwrite", false, "overwrite output ACI")
+	cmdExport.Flags().StringVar(&flagExportName, "name", "", "name of the exported image, the name of the app's image if empty")
+	cmdExport.Flags().StringVar(&flagExportVersion, "version", "", "version label of the exported image, derived from the version of the app's image and the pod UUID if empty")
+}
+
+func runExport(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return 1
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	p, err := getPodFromUUIDString(args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	if !p.isExited && !p.isExitedGarbage {
+		stderr.Printf("pod %q is not exited, only exited pods can be exported", p.uuid)
+		return 1
+	}
+
+	appName, err := getAppName(p)
+	if err != nil {
+		stderr.PrintE("unable to determine app name", err)
+		return 1
+	}
+
+	mode := os.O_CREATE | os.O_WRONLY
+	if flagOverwriteACI {
+		mode |= os.O_TRUNC
+	} else {
+		mode |= os.O_EXCL
+	}
+	f, err := os.OpenFile(args[1], mode, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			stderr.Print("output ACI file exists (try --overwrite)")
+		} else {
+			stderr.PrintE(fmt.Sprintf("unable to open output ACI file %s", args[1]), err)
+		}
+		return 1
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			stderr.PrintE("error closing output ACI file", err)
+			exit = 1
+		}
+		if exit != 0 {
+			os.Remove(args[1])
+		}
+	}()
+
+	if err := exportApp(s, p, *appName, flagExportName, flagExportVersion, f); err != nil {
+		stderr.PrintE(fmt.Sprintf("error exporting app %q of pod %q", appName, p.uuid), err)
+		return 1
+	}
+
+	return 0
+}
+
+// exportApp writes an ACI of the root filesystem of the app to w, named
+// as given by exportedImageIdentity.
+func exportApp(s *store.Store, p *pod, appName types.ACName, name, version string, w io.Writer) error {
+	im, err := p.getAppImageManifest(appName)
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot read the image manifest of the app"), err)
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get pod's apps"), err)
+	}
+	app := apps.Get(appName)
+	if app == nil {
+		return fmt.Errorf("no app %q in the pod", appName)
+	}
+
+	m := *im
+	m.Name, m.Labels, err = exportedImageIdentity(im, p.uuid, name, version)
+	if err != nil {
+		return err
+	}
+	var rootfs string
+	var skip map[string]struct{}
+	if p.usesOverlay() {
+		path, err := filepath.Rel("/", common.AppTreeStoreIDPath("", appName))
+		if err != nil {
+			return err
+		}
+		treeStoreID, err := p.readFile(path)
+		if err != nil {
+			return errwrap.Wrap(errors.New("cannot get tree store ID of the app"), err)
+		}
+		rootfs = filepath.Join(p.path(), "overlay", string(treeStoreID), "upper", appName.String())
+
+		changes, err := getOverlayChanges(rootfs)
+		if err != nil {
+			return errwrap.Wrap(errors.New("cannot read the changes of the app"), err)
+		}
+		skip = changes.whiteoutsSet()
+
+		// The files of the app's image are removed from the
+		// exported image with a path whitelist: the changes must
+		// also be listed in it if the app's image has one.
+		m.PathWhitelist = nil
+		if changes.hasRemovals() || len(im.PathWhitelist) > 0 {
+			lowerFiles, err := listFiles(s.GetTreeStoreRootFS(string(treeStoreID)))
+			if err != nil {
+				return errwrap.Wrap(errors.New("cannot list the files of the app's image"), err)
+			}
+			m.PathWhitelist = changes.pathWhitelist(lowerFiles)
+		}
+
+		imageID := app.Image.ID
+		m.Dependencies = types.Dependencies{
+			{
+				ImageName: im.Name,
+				ImageID:   &imageID,
+				Labels:    im.Labels,
+			},
+		}
+	} else {
+		rootfs = common.AppRootfsPath(p.path(), appName)
+		// The dependencies of the app's image are already in its
+		// rendered root filesystem.
+		m.Dependencies = nil
+		m.PathWhitelist = nil
+	}
+
+	tw := tar.NewWriter(w)
+	aw := aci.NewImageWriter(m, tw)
+	if err := filepath.Walk(rootfs, rootfsWalker(rootfs, aw, skip)); err != nil {
+		aw.Close()
+		return errwrap.Wrap(errors.New("error packing the root filesystem"), err)
+	}
+
+	// Close writes the manifest and closes the tar writer.
+	return aw.Close()
+}
+
+// exportedImageIdentity returns the name and labels of the image exported
+// from an app whose image manifest is im. The name and the version label
+// are the given ones when not empty. Without a version, the first part of
+// the pod UUID is appended to the version of the app's image, so the new
+// image can be told apart from the app's image, its dependency, in the store.
+func exportedImageIdentity(im *schema.ImageManifest, podUUID *types.UUID, name, version string) (types.ACIdentifier, types.Labels, error) {
+	newName := im.Name
+	if name != "" {
+		n, err := types.NewACIdentifier(name)
+		if err != nil {
+			return "", nil, errwrap.Wrap(fmt.Errorf("invalid image name %q", name), err)
+		}
+		newName = *n
+	}
+
+	oldVersion, hasVersion := im.GetLabel("version")
+	if version == "" {
+		version = strings.Split(podUUID.String(), "-")[0]
+		if hasVersion {
+			version = oldVersion + "-" + version
+		}
+	}
+	if newName == im.Name && hasVersion && version == oldVersion {
+		return "", nil, fmt.Errorf("the exported image must not have the name and version of the app's image %q", im.Name)
+	}
+
+	var labels types.Labels
+	for _, l := range im.Labels {
+		if l.Name != "version" {
+			labels = append(labels, l)
+		}
+	}
+	labels = append(labels, types.Label{Name: "version", Value: version})
+	return newName, labels, nil
+}
+
+// rootfsWalker returns a filepath.WalkFunc adding the files under root to
+// the rootfs directory of the ACI written by aw. The files in skip, given
+// relative to root, are not added.
+func rootfsWalker(root string, aw aci.ArchiveWriter, skip map[string]struct{}) filepath.WalkFunc {
+	// cache of inode -> filepath, used to leverage hard links in the archive
+	inos := map[uint64]string{}
+	return func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relpath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := skip[relpath]; ok {
+			return nil
+		}
+
+		link := ""
+		var r io.Reader
+		switch info.Mode() & os.ModeType {
+		case os.ModeSocket:
+			return nil
+		case os.ModeNamedPipe:
+		case os.ModeCharDevice:
+		case os.ModeDevice:
+		case os.ModeDir:
+		case os.ModeSymlink:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			link = target
+		default:
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			r = file
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.Join(aci.RootfsDir, relpath)
+		tarheader.Populate(hdr, info, inos)
+		// If the file is a hard link to a file we've already seen, we
+		// don't need the contents
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Size = 0
+			r = nil
+		}
+
+		return aw.AddFile(hdr, r)
+	}
+}
+
+// overlayChanges describes the changes recorded in an overlayfs upper
+// directory. The paths are absolute paths in the merged filesystem.
+type overlayChanges struct {
+	// whiteouts are the files and directories removed from the lower
+	// directory.
+	whiteouts []string
+	// opaques are the directories whose content in the lower directory is
+	// hidden.
+	opaques []string
+	// files are the files of the upper directory, except directories and
+	// whiteouts.
+	files []string
+}
+
+// getOverlayChanges reads the changes recorded in the overlayfs upper
+// directory upper.
+func getOverlayChanges(upper string) (*overlayChanges, error) {
+	c := &overlayChanges{}
+	err := filepath.Walk(upper, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relpath, err := filepath.Rel(upper, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join("/", relpath)
+
+		switch {
+		case info.IsDir():
+			opaque, err := isOpaqueDir(path)
+			if err != nil {
+				return err
+			}
+			if opaque {
+				c.opaques = append(c.opaques, name)
+			}
+		case isWhiteout(info):
+			c.whiteouts = append(c.whiteouts, name)
+		default:
+			c.files = append(c.files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// hasRemovals returns true if files of the lower directory are removed.
+func (c *overlayChanges) hasRemovals() bool {
+	return len(c.whiteouts) > 0 || len(c.opaques) > 0
+}
+
+// whiteoutsSet returns the whiteouts, relative to the upper directory.
+func (c *overlayChanges) whiteoutsSet() map[string]struct{} {
+	s := make(map[string]struct{}, len(c.whiteouts))
+	for _, w := range c.whiteouts {
+		s[strings.TrimPrefix(w, "/")] = struct{}{}
+	}
+	return s
+}
+
+// removed returns true if the file of the lower directory at path is not in
+// the merged filesystem.
+func (c *overlayChanges) removed(path string) bool {
+	for _, w := range c.whiteouts {
+		if path == w || strings.HasPrefix(path, w+"/") {
+			return true
+		}
+	}
+	for _, o := range c.opaques {
+		if strings.HasPrefix(path, strings.TrimSuffix(o, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// pathWhitelist returns the sorted list of the files in the merged
+// filesystem, given the files of the lower directory. Like in the path
+// whitelists of the image manifests, directories are not listed.
+func (c *overlayChanges) pathWhitelist(lowerFiles []string) []string {
+	set := make(map[string]struct{})
+	for _, f := range lowerFiles {
+		if !c.removed(f) {
+			set[f] = struct{}{}
+		}
+	}
+	for _, f := range c.files {
+		set[f] = struct{}{}
+	}
+
+	pwl := make([]string, 0, len(set))
+	for f := range set {
+		pwl = append(pwl, f)
+	}
+	sort.Strings(pwl)
+	return pwl
+}
+
+// listFiles returns the absolute paths of the files in the filesystem at
+// root, except directories.
+func listFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relpath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.Join("/", relpath))
+		return nil
+	})
+	return files, err
+}
+
+// isWhiteout returns true if the file is an overlayfs whiteout, a character
+// device with 0/0 device number.
+func isWhiteout(info os.FileInfo) bool {
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
+
+// isOpaqueDir returns true if the directory at path is an overlayfs opaque
+// directory.
+func isOpaqueDir(path string) (bool, error) {
+	buf := make([]byte, 1)
+	n, err := syscall.Getxattr(path, overlayOpaqueXattr, buf)
+	switch err {
+	case nil:
+		return n == 1 && buf[0] == 'y', nil
+	case syscall.ENODATA, syscall.ENOTSUP:
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,160 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+func TestOverlayChangesPathWhitelist(t *testing.T) {
+	lowerFiles := []string{
+		"/bin/sh",
+		"/etc/hostname",
+		"/etc/passwd",
+		"/var/lib/a/1",
+		"/var/lib/a/2",
+		"/var/lib/ab",
+		"/var/log/old.log",
+	}
+
+	tests := []struct {
+		changes overlayChanges
+		pwl     []string
+	}{
+		{
+			// no changes
+			overlayChanges{},
+			lowerFiles,
+		},
+		{
+			// new and modified files
+			overlayChanges{
+				files: []string{"/etc/passwd", "/root/.bash_history"},
+			},
+			[]string{
+				"/bin/sh",
+				"/etc/hostname",
+				"/etc/passwd",
+				"/root/.bash_history",
+				"/var/lib/a/1",
+				"/var/lib/a/2",
+				"/var/lib/ab",
+				"/var/log/old.log",
+			},
+		},
+		{
+			// removed file and directory, /var/lib/ab is kept
+			overlayChanges{
+				whiteouts: []string{"/etc/hostname", "/var/lib/a"},
+			},
+			[]string{
+				"/bin/sh",
+				"/etc/passwd",
+				"/var/lib/ab",
+				"/var/log/old.log",
+			},
+		},
+		{
+			// directory removed and created again
+			overlayChanges{
+				opaques: []string{"/var/log"},
+				files:   []string{"/var/log/new.log"},
+			},
+			[]string{
+				"/bin/sh",
+				"/etc/hostname",
+				"/etc/passwd",
+				"/var/lib/a/1",
+				"/var/lib/a/2",
+				"/var/lib/ab",
+				"/var/log/new.log",
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		pwl := tt.changes.pathWhitelist(lowerFiles)
+		if !reflect.DeepEqual(pwl, tt.pwl) {
+			t.Errorf("#%d: expected path whitelist %v, got %v", i, tt.pwl, pwl)
+		}
+	}
+}
+
+func TestExportedImageIdentity(t *testing.T) {
+	podUUID, err := types.NewUUID("9b1bbaf7-8b4c-4c3b-9b1e-2e0c5f2e3c0a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	versioned := &schema.ImageManifest{
+		Name: "example.com/app",
+		Labels: types.Labels{
+			{Name: "version", Value: "1.0.0"},
+			{Name: "os", Value: "linux"},
+		},
+	}
+	unversioned := &schema.ImageManifest{
+		Name: "example.com/app",
+	}
+
+	tests := []struct {
+		im      *schema.ImageManifest
+		name    string
+		version string
+
+		expectedName    types.ACIdentifier
+		expectedVersion string
+		shouldFail      bool
+	}{
+		{versioned, "", "", "example.com/app", "1.0.0-9b1bbaf7", false},
+		{unversioned, "", "", "example.com/app", "9b1bbaf7", false},
+		{versioned, "example.com/app-debug", "", "example.com/app-debug", "1.0.0-9b1bbaf7", false},
+		{versioned, "", "1.0.1", "example.com/app", "1.0.1", false},
+		{versioned, "example.com/app-debug", "1.0.0", "example.com/app-debug", "1.0.0", false},
+		{versioned, "", "1.0.0", "", "", true},
+		{versioned, "example.com/app", "1.0.0", "", "", true},
+		{versioned, "Invalid Name", "", "", "", true},
+	}
+	for i, tt := range tests {
+		name, labels, err := exportedImageIdentity(tt.im, podUUID, tt.name, tt.version)
+		if err != nil {
+			if !tt.shouldFail {
+				t.Errorf("#%d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if tt.shouldFail {
+			t.Errorf("#%d: expected an error", i)
+			continue
+		}
+		if name != tt.expectedName {
+			t.Errorf("#%d: expected name %q, got %q", i, tt.expectedName, name)
+		}
+		m := schema.ImageManifest{Labels: labels}
+		if version, _ := m.GetLabel("version"); version != tt.expectedVersion {
+			t.Errorf("#%d: expected version %q, got %q", i, tt.expectedVersion, version)
+		}
+		if os, ok := tt.im.GetLabel("os"); ok {
+			if newOS, _ := m.GetLabel("os"); newOS != os {
+				t.Errorf("#%d: expected os label %q, got %q", i, os, newOS)
+			}
+		}
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"github.com/appc/spec/schema/types"
 	"github.com/coreos/rkt/common/apps"
 	"github.com/coreos/rkt/rkt/image"
-	"github.com/coreos/rkt/store"
 
 	"github.com/spf13/cobra"
 )
@@ -28,6 +27,8 @@ import (
 const (
 	defaultOS   = runtime.GOOS
 	defaultArch = runtime.GOARCH
+
+	defaultParallelFetches = 4
 )
 
 var (
@@ -40,7 +41,8 @@ If the ACI is available in the local store, the image will not be fetched
 again.`,
 		Run: runWrapper(runFetch),
 	}
-	flagFullHash bool
+	flagFullHash        bool
+	flagParallelFetches int
 )
 
 func init() {
@@ -53,6 +55,8 @@ func init() {
 	cmdFetch.Flags().Var((*appAsc)(&rktApps), "signature", "local signature file to use in validating the preceding image")
 	cmdFetch.Flags().BoolVar(&flagStoreOnly, "store-only", false, "use only available images in the store (do not discover or download from remote URLs)")
 	cmdFetch.Flags().BoolVar(&flagNoStore, "no-store", false, "fetch images ignoring the local store")
+	cmdFetch.Flags().BoolVar(&flagNoSquash, "no-squash", false, "store the layers of docker images as separate images")
+	cmdFetch.Flags().IntVar(&flagParallelFetches, "parallel-fetches", defaultParallelFetches, "maximum number of images fetched at the same time")
 	cmdFetch.Flags().BoolVar(&flagFullHash, "full", false, "print the full image hash after fetching")
 }
 
@@ -72,7 +76,7 @@ func runFetch(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -88,30 +92,31 @@ func runFetch(cmd *cobra.Command, args []string) (exit int) {
 		Ks:                 ks,
 		Headers:            config.AuthPerHost,
 		DockerAuth:         config.DockerCredentialsPerRegistry,
+		Registries:         config.RegistriesPerHost,
+		SignaturePolicy:    config.SignaturePolicyPerPrefix,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		Debug:              globalFlags.Debug,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
 
-		StoreOnly: flagStoreOnly,
-		NoStore:   flagNoStore,
-		WithDeps:  true,
+		StoreOnly:       flagStoreOnly,
+		NoStore:         flagNoStore,
+		WithDeps:        true,
+		NoSquash:        flagNoSquash,
+		ParallelFetches: flagParallelFetches,
 	}
 
-	err = rktApps.Walk(func(app *apps.App) error {
-		hash, err := ft.FetchImage(app.Image, app.Asc, app.ImType)
-		if err != nil {
-			return err
-		}
+	if err := ft.FetchImages(&rktApps); err != nil {
+		stderr.Error(err)
+		return 1
+	}
+	rktApps.Walk(func(app *apps.App) error {
+		hash := app.ImageID.String()
 		if !flagFullHash {
 			hash = types.ShortHash(hash)
 		}
 		stdout.Print(hash)
 		return nil
 	})
-	if err != nil {
-		stderr.Error(err)
-		return 1
-	}
 
 	return
 }
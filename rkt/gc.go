@@ -23,7 +23,6 @@ import (
 	"time"
 
 	"github.com/coreos/rkt/stage0"
-	"github.com/coreos/rkt/store"
 	"github.com/spf13/cobra"
 )
 
@@ -200,7 +199,7 @@ func deletePod(p *pod) {
 	}
 
 	if p.isExitedGarbage {
-		s, err := store.NewStore(getDataDir())
+		s, err := openStore()
 		if err != nil {
 			stderr.PrintE("cannot open store", err)
 			return
@@ -0,0 +1,388 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/coreos/rkt/common/apps"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// bundleVersion is the version of the format of the bundles.
+	bundleVersion = 1
+	// bundleIndexFile is the first file of a bundle, describing the
+	// images in the bundle.
+	bundleIndexFile = "index.json"
+	// bundleImagesDir is the directory of the ACIs and signatures in a
+	// bundle.
+	bundleImagesDir = "images"
+)
+
+// bundleIndex describes the images in a bundle.
+type bundleIndex struct {
+	Version int           `json:"version"`
+	Images  []bundleImage `json:"images"`
+}
+
+// bundleImage describes an image in a bundle. The ACI of the image is
+// stored in the bundle as images/KEY.aci and its signature, if any, as
+// images/KEY.aci.asc.
+type bundleImage struct {
+	Key     string         `json:"key"`
+	Name    string         `json:"name"`
+	Latest  bool           `json:"latest"`
+	Signed  bool           `json:"signed"`
+	Remotes []bundleRemote `json:"remotes,omitempty"`
+}
+
+// bundleRemote is a store.Remote of an image in a bundle.
+type bundleRemote struct {
+	ACIURL       string    `json:"aciURL"`
+	SigURL       string    `json:"sigURL,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	CacheMaxAge  int       `json:"cacheMaxAge,omitempty"`
+	DownloadTime time.Time `json:"downloadTime"`
+}
+
+// Bundler saves images of the store in a bundle, a tarball holding the
+// ACIs, their signatures and where they were fetched from, and loads the
+// images of a bundle into the store.
+type Bundler action
+
+// SaveImages writes a bundle with the passed images to w. The images
+// are hashes or names of images in the store. If b.WithDeps is true,
+// the dependencies of the images are saved too. The signatures of the
+// images are downloaded again from where the images were fetched, the
+// images which can't get a signature are saved unsigned. It returns the
+// keys of the saved images.
+func (b *Bundler) SaveImages(imgs []string, w io.Writer) ([]string, error) {
+	ensureLogger(b.Debug)
+	keys, err := b.getSaveKeys(imgs)
+	if err != nil {
+		return nil, err
+	}
+
+	allRemotes, err := b.S.GetAllRemotes()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get remotes"), err)
+	}
+	index := &bundleIndex{Version: bundleVersion}
+	aciinfos := make(map[string]*store.ACIInfo)
+	ascFiles := make(map[string]readSeekCloser)
+	defer func() {
+		for _, ascFile := range ascFiles {
+			ascFile.Close()
+		}
+	}()
+	for _, key := range keys {
+		aciinfo, err := b.S.GetACIInfoWithBlobKey(key)
+		if err != nil {
+			return nil, err
+		}
+		aciinfos[key] = aciinfo
+		img := bundleImage{
+			Key:    key,
+			Name:   aciinfo.Name,
+			Latest: aciinfo.Latest,
+		}
+		for _, rem := range allRemotes {
+			if rem.BlobKey != key {
+				continue
+			}
+			img.Remotes = append(img.Remotes, bundleRemote{
+				ACIURL:       rem.ACIURL,
+				SigURL:       rem.SigURL,
+				ETag:         rem.ETag,
+				CacheMaxAge:  rem.CacheMaxAge,
+				DownloadTime: rem.DownloadTime,
+			})
+		}
+		if ascFile := b.getSignature(&img); ascFile != nil {
+			ascFiles[key] = ascFile
+			img.Signed = true
+		}
+		index.Images = append(index.Images, img)
+	}
+
+	tw := tar.NewWriter(w)
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error marshalling bundle index"), err)
+	}
+	if err := writeBundleEntry(tw, bundleIndexFile, int64(len(indexJSON)), strings.NewReader(string(indexJSON))); err != nil {
+		return nil, err
+	}
+	for _, img := range index.Images {
+		if ascFile, ok := ascFiles[img.Key]; ok {
+			size, err := ascFile.Seek(0, os.SEEK_END)
+			if err != nil {
+				return nil, errwrap.Wrap(errors.New("error seeking signature file"), err)
+			}
+			if _, err := ascFile.Seek(0, os.SEEK_SET); err != nil {
+				return nil, errwrap.Wrap(errors.New("error seeking signature file"), err)
+			}
+			if err := writeBundleEntry(tw, bundleImagePath(img.Key)+".asc", size, ascFile); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.writeBundleACI(tw, img.Key, aciinfos[img.Key].Size); err != nil {
+			return nil, err
+		}
+		log.Printf("saved image %s (%s)", img.Key, img.Name)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errwrap.Wrap(errors.New("error closing bundle"), err)
+	}
+	return keys, nil
+}
+
+// getSaveKeys returns the keys of the passed images, and of their
+// dependencies if b.WithDeps is true.
+func (b *Bundler) getSaveKeys(imgs []string) ([]string, error) {
+	// the images to save are only looked for in the store, their
+	// dependencies are looked for below
+	a := action(*b)
+	a.StoreOnly = true
+	a.NoStore = false
+	a.WithDeps = false
+	finder := (*Finder)(&a)
+	fetcher := (*Fetcher)(&a)
+
+	var keys []string
+	seen := make(map[string]struct{})
+	add := func(key string) {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	for _, img := range imgs {
+		imgType := guessImageType(img)
+		if imgType != apps.AppImageHash && imgType != apps.AppImageName {
+			return nil, fmt.Errorf("cannot save %q, expected either an image hash or an image name", img)
+		}
+		h, err := finder.FindImage(img, "", imgType)
+		if err != nil {
+			return nil, err
+		}
+		key := h.String()
+		add(key)
+		if b.WithDeps {
+			deps, err := fetcher.fetchImageDeps(key)
+			if err != nil {
+				return nil, errwrap.Wrap(fmt.Errorf("cannot find the dependencies of image %q", img), err)
+			}
+			for _, dep := range deps {
+				add(dep)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// getSignature downloads the signature of the image from the remotes of
+// the image. It returns nil if no signature could be downloaded.
+func (b *Bundler) getSignature(img *bundleImage) readSeekCloser {
+	o := &httpOps{
+		InsecureSkipTLSVerify: b.InsecureFlags.SkipTLSCheck(),
+		S:                     b.S,
+		Headers:               b.Headers,
+		Registries:            b.Registries,
+		Debug:                 b.Debug,
+	}
+	var sigURLs []string
+	for _, rem := range img.Remotes {
+		sigURLs = append(sigURLs, rem.SigURL)
+	}
+	if ascFile := o.DownloadSignatureFromURLs(img.Key, sigURLs); ascFile != nil {
+		return ascFile
+	}
+	log.Printf("warning: no signature available for image %s (%s), saving it unsigned", img.Key, img.Name)
+	return nil
+}
+
+// writeBundleACI writes the ACI of the image with key, of the given size,
+// to the bundle.
+func (b *Bundler) writeBundleACI(tw *tar.Writer, key string, size int64) error {
+	rc, err := b.S.ReadStream(key)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("error reading image %q", key), err)
+	}
+	defer rc.Close()
+	return writeBundleEntry(tw, bundleImagePath(key), size, rc)
+}
+
+func writeBundleEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error writing %q to the bundle", name), err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error writing %q to the bundle", name), err)
+	}
+	return nil
+}
+
+func bundleImagePath(key string) string {
+	return path.Join(bundleImagesDir, key+".aci")
+}
+
+// LoadImages reads a bundle from r and imports its images into the
+// store, with their remotes. The images are verified against their
+// signatures in the bundle, unless the image verification is disabled.
+// It returns the keys of the loaded images.
+func (b *Bundler) LoadImages(r io.Reader) ([]string, error) {
+	ensureLogger(b.Debug)
+	if b.InsecureFlags.SkipImageCheck() && b.Ks != nil {
+		log.Printf("warning: image signature verification has been disabled")
+	}
+
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading bundle"), err)
+	}
+	if hdr.Name != bundleIndexFile {
+		return nil, fmt.Errorf("invalid bundle: expected %q, got %q", bundleIndexFile, hdr.Name)
+	}
+	index := &bundleIndex{}
+	if err := json.NewDecoder(tr).Decode(index); err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading bundle index"), err)
+	}
+	if index.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, expected %d", index.Version, bundleVersion)
+	}
+	images := make(map[string]*bundleImage)
+	for i := range index.Images {
+		images[bundleImagePath(index.Images[i].Key)] = &index.Images[i]
+	}
+
+	var keys []string
+	ascFiles := make(map[string]*os.File)
+	defer func() {
+		for _, ascFile := range ascFiles {
+			removeTmpFile(ascFile)
+		}
+	}()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading bundle"), err)
+		}
+		if img, ok := images[strings.TrimSuffix(hdr.Name, ".asc")]; ok && strings.HasSuffix(hdr.Name, ".asc") {
+			ascFile, err := copyToTmpFile(b.S, tr)
+			if err != nil {
+				return nil, err
+			}
+			ascFiles[img.Key] = ascFile
+			continue
+		}
+		img, ok := images[hdr.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle: unexpected file %q", hdr.Name)
+		}
+		if err := b.loadImage(img, tr, ascFiles[img.Key]); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot load image %s (%s)", img.Key, img.Name), err)
+		}
+		keys = append(keys, img.Key)
+		delete(images, hdr.Name)
+	}
+	for _, img := range images {
+		return nil, fmt.Errorf("invalid bundle: missing image %s (%s)", img.Key, img.Name)
+	}
+	return keys, nil
+}
+
+// loadImage verifies the ACI of img read from r against ascFile, and
+// imports it into the store with its remotes.
+func (b *Bundler) loadImage(img *bundleImage, r io.Reader, ascFile *os.File) error {
+	aciFile, err := copyToTmpFile(b.S, r)
+	if err != nil {
+		return err
+	}
+	defer removeTmpFile(aciFile)
+
+	if !b.InsecureFlags.SkipImageCheck() && b.Ks != nil {
+		v, err := newValidator(aciFile)
+		if err != nil {
+			return err
+		}
+		// the bundle is a local file
+		policy := signaturePolicy(b.SignaturePolicy)
+		if ascFile == nil {
+			if !policy.AllowUnsigned(v.GetImageName(), true) {
+				return errors.New("the image is not signed in the bundle, loading it requires disabling the image verification")
+			}
+		} else {
+			entity, err := v.ValidateWithPolicy(b.Ks, policy, ascFile)
+			if err != nil {
+				return errwrap.Wrap(fmt.Errorf("image %q verification failed", v.GetImageName()), err)
+			}
+			printIdentities(entity)
+		}
+	}
+	if _, err := aciFile.Seek(0, os.SEEK_SET); err != nil {
+		return errwrap.Wrap(errors.New("error seeking ACI file"), err)
+	}
+
+	key, err := b.S.WriteACI(aciFile, img.Latest)
+	if err != nil {
+		return err
+	}
+	if key != img.Key {
+		return fmt.Errorf("the image in the bundle has the key %q, expected %q", key, img.Key)
+	}
+
+	for _, brem := range img.Remotes {
+		rem, found, err := b.S.GetRemote(brem.ACIURL)
+		if err != nil {
+			return err
+		}
+		// keep the newest remote
+		if found && rem.DownloadTime.After(brem.DownloadTime) {
+			continue
+		}
+		rem = store.NewRemote(brem.ACIURL, brem.SigURL)
+		rem.ETag = brem.ETag
+		rem.BlobKey = key
+		rem.CacheMaxAge = brem.CacheMaxAge
+		rem.DownloadTime = brem.DownloadTime
+		if err := b.S.WriteRemote(rem); err != nil {
+			return err
+		}
+	}
+	log.Printf("loaded image %s (%s)", img.Key, img.Name)
+	return nil
+}
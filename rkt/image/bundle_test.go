@@ -0,0 +1,146 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/pkg/aci"
+	"github.com/coreos/rkt/pkg/keystore"
+	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
+)
+
+func writeTestBundleACI(t *testing.T, s *store.Store, dir, name, deps string) string {
+	manifest := fmt.Sprintf(`{
+		"acKind": "ImageManifest",
+		"acVersion": "0.7.4",
+		"name": %q,
+		"labels": [
+			{"name": "os", "value": %q},
+			{"name": "arch", "value": %q}
+		],
+		"dependencies": [%s]
+	}`, name, runtime.GOOS, runtime.GOARCH, deps)
+	aciFile, err := aci.NewACI(dir, manifest, nil)
+	if err != nil {
+		t.Fatalf("cannot create test ACI: %v", err)
+	}
+	defer aciFile.Close()
+	if _, err := aciFile.Seek(0, os.SEEK_SET); err != nil {
+		t.Fatalf("cannot seek test ACI: %v", err)
+	}
+	key, err := s.WriteACI(aciFile, true)
+	if err != nil {
+		t.Fatalf("cannot write test ACI: %v", err)
+	}
+	return key
+}
+
+func TestBundleSaveLoad(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-bundle-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	src, err := store.NewStore(filepath.Join(tmpDir, "src"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer src.Close()
+
+	depKey := writeTestBundleACI(t, src, tmpDir, "example.com/dep", "")
+	appKey := writeTestBundleACI(t, src, tmpDir, "example.com/app", `{"imageName": "example.com/dep"}`)
+	rem := store.NewRemote("file:///example.com/app.aci", "")
+	rem.BlobKey = appKey
+	rem.ETag = "etag"
+	rem.DownloadTime = time.Now().UTC().Truncate(time.Second)
+	if err := src.WriteRemote(rem); err != nil {
+		t.Fatalf("cannot write remote: %v", err)
+	}
+
+	sf, err := rktflag.NewSecFlags("image")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	bundle := &bytes.Buffer{}
+	sb := &Bundler{
+		S:             src,
+		InsecureFlags: sf,
+		WithDeps:      true,
+	}
+	keys, err := sb.SaveImages([]string{"example.com/app"}, bundle)
+	if err != nil {
+		t.Fatalf("cannot save images: %v", err)
+	}
+	if expected := []string{appKey, depKey}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected saved images %v, got %v", expected, keys)
+	}
+
+	dst, err := store.NewStore(filepath.Join(tmpDir, "dst"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer dst.Close()
+
+	// Unsigned images are refused when the image verification is enabled
+	noSkip, err := rktflag.NewSecFlags("none")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	ks := keystore.New(keystore.NewConfig(filepath.Join(tmpDir, "ks-system"), filepath.Join(tmpDir, "ks-local")))
+	db := &Bundler{
+		S:             dst,
+		Ks:            ks,
+		InsecureFlags: noSkip,
+	}
+	if _, err := db.LoadImages(bytes.NewReader(bundle.Bytes())); err == nil {
+		t.Fatalf("expected loading unsigned images to fail")
+	}
+
+	db.InsecureFlags = sf
+	keys, err = db.LoadImages(bytes.NewReader(bundle.Bytes()))
+	if err != nil {
+		t.Fatalf("cannot load images: %v", err)
+	}
+	sort.Strings(keys)
+	expected := []string{appKey, depKey}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected loaded images %v, got %v", expected, keys)
+	}
+	for _, key := range expected {
+		if _, err := dst.GetImageManifest(key); err != nil {
+			t.Errorf("cannot get the manifest of loaded image %q: %v", key, err)
+		}
+	}
+	got, found, err := dst.GetRemote(rem.ACIURL)
+	if err != nil || !found {
+		t.Fatalf("cannot get loaded remote: found %t, %v", found, err)
+	}
+	if got.BlobKey != appKey || got.ETag != rem.ETag || !got.DownloadTime.Equal(rem.DownloadTime) {
+		t.Errorf("expected remote %+v, got %+v", rem, got)
+	}
+}
@@ -47,6 +47,13 @@ type action struct {
 	// DockerAuth is used for authenticating when fetching docker
 	// images.
 	DockerAuth map[string]config.BasicCredentials
+	// Registries holds the network settings used when downloading
+	// from a host, like proxies, CA bundles or mirrors.
+	Registries map[string]config.RegistryData
+	// SignaturePolicy holds the signature policy rules per image
+	// name prefix, applied when the image signatures are
+	// verified.
+	SignaturePolicy map[string]config.SignaturePolicyRule
 	// InsecureFlags is a set of flags for enabling some insecure
 	// functionality. For now it is mostly skipping image
 	// signature verification and TLS certificate verification.
@@ -70,6 +77,13 @@ type action struct {
 	// WithDeps tells whether image dependencies should be
 	// downloaded too.
 	WithDeps bool
+	// NoSquash tells whether to keep each layer of docker images
+	// as a separate image, instead of squashing them in one image.
+	NoSquash bool
+	// ParallelFetches is the maximum number of images fetched at
+	// the same time. The images are fetched one by one if it is
+	// lower than 2.
+	ParallelFetches int
 }
 
 var (
@@ -150,6 +164,11 @@ func guessImageType(image string) apps.AppImageType {
 	if _, err := types.NewHash(image); err == nil {
 		return apps.AppImageHash
 	}
+	// oci-layout:PATH and oci-archive:PATH would be parsed as
+	// URLs with an opaque part.
+	if strings.HasPrefix(image, ociLayoutPrefix) || strings.HasPrefix(image, ociArchivePrefix) {
+		return apps.AppImageOCI
+	}
 	if u, err := url.Parse(image); err == nil && u.Scheme != "" {
 		return apps.AppImageURL
 	}
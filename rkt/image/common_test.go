@@ -75,6 +75,16 @@ func TestGuessImageType(t *testing.T) {
 			image:        "example.com/stage1,version=1.2.3,foo=bar",
 			expectedType: apps.AppImageName,
 		},
+		// guess OCI image layout references as OCI
+		{
+			image:        "oci-layout:/var/lib/images/busybox:1.0",
+			expectedType: apps.AppImageOCI,
+		},
+		// the same with a relative path
+		{
+			image:        "oci-archive:busybox.tar",
+			expectedType: apps.AppImageOCI,
+		},
 	}
 	for _, tt := range tests {
 		guessed := guessImageType(tt.image)
@@ -96,6 +106,8 @@ func imageTypeToString(imType apps.AppImageType) string {
 		return "path"
 	case apps.AppImageName:
 		return "name"
+	case apps.AppImageOCI:
+		return "OCI"
 	default:
 		return "unknown"
 	}
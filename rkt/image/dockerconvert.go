@@ -0,0 +1,117 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/coreos/rkt/pkg/multicall"
+	"github.com/coreos/rkt/rkt/config"
+	"github.com/hashicorp/errwrap"
+
+	docker2aci "github.com/appc/docker2aci/lib"
+	d2acommon "github.com/appc/docker2aci/lib/common"
+)
+
+const dockerConvertMulticallName = "docker2aci"
+
+var dockerConvertEntrypoint multicall.Entrypoint
+
+func init() {
+	dockerConvertEntrypoint = multicall.Add(dockerConvertMulticallName, dockerConvertCommand)
+}
+
+// dockerConvertRequest describes a conversion of a docker image to
+// ACI, done by the docker2aci multicall command.
+type dockerConvertRequest struct {
+	RegistryURL string
+	Username    string
+	Password    string
+	Insecure    bool
+	Squash      bool
+	OutputDir   string
+	TmpDir      string
+	// Registry holds the network settings of the registry, if
+	// any.
+	Registry *config.RegistryData
+}
+
+// dockerConvertCommand converts the docker image described by the
+// request read from stdin, and writes the paths of the generated ACIs
+// to stdout. docker2aci has no way to get an HTTP client, it uses
+// http.DefaultTransport, so the conversion is done in its own process
+// to use the transport of the registry settings without affecting the
+// other requests of rkt.
+func dockerConvertCommand() error {
+	if len(os.Args) != 1 {
+		return fmt.Errorf("incorrect number of arguments. Usage: %s", dockerConvertMulticallName)
+	}
+	var req dockerConvertRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		return errwrap.Wrap(errors.New("error decoding the conversion request"), err)
+	}
+	if req.Registry != nil {
+		// docker2aci takes care of skipping the TLS
+		// certificate validation for insecure registries.
+		transport, err := newRegistryTransport(*req.Registry, false)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("invalid registry settings for %q", docker2aci.GetIndexName(req.RegistryURL)), err)
+		}
+		http.DefaultTransport = transport
+	}
+	config := docker2aci.RemoteConfig{
+		Username: req.Username,
+		Password: req.Password,
+		Insecure: req.Insecure,
+		CommonConfig: docker2aci.CommonConfig{
+			Squash:      req.Squash,
+			OutputDir:   req.OutputDir,
+			TmpDir:      req.TmpDir,
+			Compression: d2acommon.NoCompression,
+		},
+	}
+	acis, err := docker2aci.ConvertRemoteRepo(req.RegistryURL, config)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(acis)
+}
+
+// convertDockerImage converts a docker image to ACI with the docker2aci
+// multicall command, and returns the paths of the generated ACIs.
+func convertDockerImage(req *dockerConvertRequest) ([]string, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error encoding the conversion request"), err)
+	}
+	var out bytes.Buffer
+	cmd := dockerConvertEntrypoint.Cmd()
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errwrap.Wrap(errors.New("docker2aci failed"), err)
+	}
+	var acis []string
+	if err := json.Unmarshal(out.Bytes(), &acis); err != nil {
+		return nil, errwrap.Wrap(errors.New("error decoding the converted ACIs"), err)
+	}
+	return acis, nil
+}
@@ -15,8 +15,11 @@
 package image
 
 import (
+	"archive/tar"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
@@ -31,6 +34,9 @@ import (
 
 	docker2aci "github.com/appc/docker2aci/lib"
 	d2acommon "github.com/appc/docker2aci/lib/common"
+	"github.com/appc/spec/aci"
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
 )
 
 // dockerFetcher is used to fetch images from docker:// URLs. It uses
@@ -42,8 +48,14 @@ type dockerFetcher struct {
 	// be enabled. No image verification must be true for now.
 	InsecureFlags *rktflag.SecFlags
 	DockerAuth    map[string]config.BasicCredentials
-	S             *store.Store
-	Debug         bool
+	// Registries holds the network settings of the registries,
+	// like proxies, CA bundles or mirrors.
+	Registries map[string]config.RegistryData
+	S          *store.Store
+	Debug      bool
+	// NoSquash tells whether to keep each layer of the image in
+	// its own ACI, instead of squashing the layers in one ACI.
+	NoSquash bool
 }
 
 // GetHash uses docker2aci to download the image and convert it to
@@ -67,15 +79,24 @@ func (f *dockerFetcher) fetchImageFrom(u *url.URL, latest bool) (string, error)
 		log.Printf("fetching image from %s", u.String())
 	}
 
-	aciFile, err := f.fetch(u)
+	aciFiles, err := f.fetch(u)
 	if err != nil {
 		return "", err
 	}
-	// At this point, the ACI file is removed, but it is kept
-	// alive, because we have an fd to it opened.
-	defer aciFile.Close()
+	// At this point, the ACI files are removed, but they are kept
+	// alive, because we have fds to them opened.
+	defer func() {
+		for _, aciFile := range aciFiles {
+			aciFile.Close()
+		}
+	}()
 
-	key, err := f.S.WriteACI(aciFile, latest)
+	var key string
+	if f.NoSquash {
+		key, err = f.writeLayers(aciFiles, latest)
+	} else {
+		key, err = f.S.WriteACI(aciFiles[0], latest)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -94,37 +115,216 @@ func (f *dockerFetcher) fetchImageFrom(u *url.URL, latest bool) (string, error)
 	return key, nil
 }
 
-func (f *dockerFetcher) fetch(u *url.URL) (*os.File, error) {
+// fetch converts the image to ACI, and returns the opened ACI files:
+// the squashed ACI, or the ACIs of the layers from the base one if
+// f.NoSquash is true.
+func (f *dockerFetcher) fetch(u *url.URL) ([]*os.File, error) {
 	tmpDir, err := f.getTmpDir()
 	if err != nil {
 		return nil, err
 	}
 	defer os.RemoveAll(tmpDir)
 
-	registryURL := strings.TrimPrefix(u.String(), "docker://")
+	registryURL := f.getMirrorURL(strings.TrimPrefix(u.String(), "docker://"))
 	user, password := f.getCreds(registryURL)
-	config := docker2aci.RemoteConfig{
-		Username: user,
-		Password: password,
-		Insecure: f.InsecureFlags.AllowHTTP(),
-		CommonConfig: docker2aci.CommonConfig{
-			Squash:      true,
-			OutputDir:   tmpDir,
-			TmpDir:      tmpDir,
-			Compression: d2acommon.NoCompression,
-		},
-	}
-	acis, err := docker2aci.ConvertRemoteRepo(registryURL, config)
+	req := &dockerConvertRequest{
+		RegistryURL: registryURL,
+		Username:    user,
+		Password:    password,
+		Insecure:    f.InsecureFlags.AllowHTTP(),
+		Squash:      !f.NoSquash,
+		OutputDir:   tmpDir,
+		TmpDir:      tmpDir,
+	}
+	if settings, ok := getRegistrySettings(f.Registries, docker2aci.GetIndexName(registryURL)); ok {
+		req.Registry = &settings
+	}
+	acis, err := convertDockerImage(req)
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error converting docker image to ACI"), err)
 	}
 
-	aciFile, err := os.Open(acis[0])
+	var aciFiles []*os.File
+	for _, aci := range acis {
+		aciFile, err := os.Open(aci)
+		if err != nil {
+			for _, f := range aciFiles {
+				f.Close()
+			}
+			return nil, errwrap.Wrap(errors.New("error opening ACI file"), err)
+		}
+		aciFiles = append(aciFiles, aciFile)
+	}
+
+	return aciFiles, nil
+}
+
+// writeLayers writes the ACIs of the layers, ordered from the base one,
+// in the store, and returns the key of the top one. The manifests of the
+// layers are rewritten so that a layer has the same ACI in all the
+// images sharing it, and each layer depends on the previous one by its
+// image ID. The layers already in the store have been downloaded and
+// converted anyway: docker2aci gives no way to skip them.
+func (f *dockerFetcher) writeLayers(aciFiles []*os.File, latest bool) (string, error) {
+	var key string
+	var parent *types.Dependency
+	for i, aciFile := range aciFiles {
+		im, err := aci.ManifestFromImage(aciFile)
+		if err != nil {
+			return "", errwrap.Wrap(errors.New("error reading the manifest of a layer"), err)
+		}
+		top := i == len(aciFiles)-1
+		layerIm, err := dockerLayerManifest(im, parent, top)
+		if err != nil {
+			return "", err
+		}
+
+		layerFile, err := f.S.TmpFile()
+		if err != nil {
+			return "", errwrap.Wrap(errors.New("error creating temporary file for a layer"), err)
+		}
+		// The file is removed now, it is kept alive by the fd.
+		os.Remove(layerFile.Name())
+		key, err = f.writeLayerACI(aciFile, layerIm, layerFile, latest && top)
+		layerFile.Close()
+		if err != nil {
+			return "", errwrap.Wrap(fmt.Errorf("error writing layer %q", im.Name), err)
+		}
+		if f.Debug {
+			log.Printf("layer %q stored with key %s", layerIm.Name, key)
+		}
+
+		imageID, err := types.NewHash(key)
+		if err != nil {
+			return "", err
+		}
+		parent = &types.Dependency{
+			ImageName: layerIm.Name,
+			ImageID:   imageID,
+			Labels:    layerIm.Labels,
+		}
+	}
+	return key, nil
+}
+
+// writeLayerACI writes the ACI read from aciFile with the manifest im to
+// layerFile, and writes it in the store.
+func (f *dockerFetcher) writeLayerACI(aciFile *os.File, im *schema.ImageManifest, layerFile *os.File, latest bool) (string, error) {
+	if _, err := aciFile.Seek(0, 0); err != nil {
+		return "", err
+	}
+	if err := replaceACIManifest(aciFile, im, layerFile); err != nil {
+		return "", err
+	}
+	if _, err := layerFile.Seek(0, 0); err != nil {
+		return "", err
+	}
+	return f.S.WriteACI(layerFile, latest)
+}
+
+// dockerLayerManifest returns the manifest to use in the store for the
+// layer with the manifest im generated by docker2aci. The intermediate
+// layers are not specific to the image: their name is derived from the
+// registry and the layer ID only, and they have no app. The top layer has
+// the name and the labels of the image, like a squashed image. The
+// dependency of the layer is replaced with parent, if any.
+func dockerLayerManifest(im *schema.ImageManifest, parent *types.Dependency, top bool) (*schema.ImageManifest, error) {
+	m := *im
+	m.Dependencies = nil
+	if parent != nil {
+		m.Dependencies = types.Dependencies{*parent}
+	}
+
+	m.Labels = nil
+	for _, l := range im.Labels {
+		switch l.Name {
+		case "layer":
+			continue
+		case "version":
+			if !top {
+				continue
+			}
+		}
+		m.Labels = append(m.Labels, l)
+	}
+
+	if top {
+		n := strings.LastIndex(im.Name.String(), "-")
+		if n < 0 {
+			return nil, fmt.Errorf("unexpected layer name %q", im.Name)
+		}
+		name, err := types.NewACIdentifier(im.Name.String()[:n])
+		if err != nil {
+			return nil, err
+		}
+		m.Name = *name
+		return &m, nil
+	}
+
+	layerID, ok := im.Labels.Get("layer")
+	if !ok {
+		return nil, fmt.Errorf("no layer label in the manifest of layer %q", im.Name)
+	}
+	registryURL, _ := im.Annotations.Get(d2acommon.AppcDockerRegistryURL)
+	nameStr, err := types.SanitizeACIdentifier(registryURL + "/layer-" + layerID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := types.NewACIdentifier(nameStr)
 	if err != nil {
-		return nil, errwrap.Wrap(errors.New("error opening squashed ACI file"), err)
+		return nil, err
 	}
+	m.Name = *name
+	m.App = nil
 
-	return aciFile, nil
+	m.Annotations = nil
+	for _, a := range im.Annotations {
+		switch a.Name.String() {
+		case d2acommon.AppcDockerRepository, d2acommon.AppcDockerTag, d2acommon.AppcDockerEntrypoint, d2acommon.AppcDockerCmd:
+			continue
+		}
+		m.Annotations = append(m.Annotations, a)
+	}
+
+	return &m, nil
+}
+
+// replaceACIManifest copies the uncompressed ACI read from r to w, with
+// the manifest replaced by im.
+func replaceACIManifest(r io.Reader, im *schema.ImageManifest, w io.Writer) error {
+	manifest, err := json.Marshal(im)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error marshalling image manifest"), err)
+	}
+
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errwrap.Wrap(errors.New("error reading ACI"), err)
+		}
+		if hdr.Name == aci.ManifestFile {
+			hdr.Size = int64(len(manifest))
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
 }
 
 func (f *dockerFetcher) getTmpDir() (string, error) {
@@ -135,6 +335,25 @@ func (f *dockerFetcher) getTmpDir() (string, error) {
 	return ioutil.TempDir(storeTmpDir, "docker2aci-")
 }
 
+// getMirrorURL returns the docker URL to fetch from instead of the
+// passed one, if its registry has a mirror.
+func (f *dockerFetcher) getMirrorURL(registryURL string) string {
+	p, err := d2acommon.ParseDockerURL(registryURL)
+	if err != nil {
+		return registryURL
+	}
+	mirror := getMirrorHost(f.Registries, p.IndexURL)
+	if mirror == p.IndexURL {
+		return registryURL
+	}
+	log.Printf("using mirror %s of %s", mirror, p.IndexURL)
+	mirrorURL := fmt.Sprintf("%s/%s", mirror, p.ImageName)
+	if p.Digest != "" {
+		return mirrorURL + "@" + p.Digest
+	}
+	return mirrorURL + ":" + p.Tag
+}
+
 func (f *dockerFetcher) getCreds(registryURL string) (string, string) {
 	indexName := docker2aci.GetIndexName(registryURL)
 	if creds, ok := f.DockerAuth[indexName]; ok {
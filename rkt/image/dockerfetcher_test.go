@@ -0,0 +1,253 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coreos/rkt/pkg/multicall"
+	"github.com/coreos/rkt/rkt/config"
+
+	d2acommon "github.com/appc/docker2aci/lib/common"
+	"github.com/appc/spec/aci"
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+func init() {
+	multicall.MaybeExec()
+}
+
+func newDockerLayerManifest(t *testing.T, layerID, parentID string) *schema.ImageManifest {
+	manifest := `{
+		"acKind": "ImageManifest",
+		"acVersion": "0.7.4",
+		"name": "registry-1.docker.io/library/debian-` + layerID + `",
+		"labels": [
+			{"name": "layer", "value": "` + layerID + `"},
+			{"name": "version", "value": "8"},
+			{"name": "os", "value": "linux"},
+			{"name": "arch", "value": "amd64"}
+		],
+		"app": {
+			"exec": ["/bin/bash"],
+			"user": "0",
+			"group": "0"
+		},
+		"annotations": [
+			{"name": "` + d2acommon.AppcDockerRegistryURL + `", "value": "registry-1.docker.io"},
+			{"name": "` + d2acommon.AppcDockerRepository + `", "value": "library/debian"},
+			{"name": "` + d2acommon.AppcDockerImageID + `", "value": "` + layerID + `"},
+			{"name": "` + d2acommon.AppcDockerParentImageID + `", "value": "` + parentID + `"},
+			{"name": "` + d2acommon.AppcDockerCmd + `", "value": "[\"/bin/bash\"]"}
+		],
+		"pathWhitelist": ["/bin/bash"]
+	}`
+	im := &schema.ImageManifest{}
+	if err := json.Unmarshal([]byte(manifest), im); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return im
+}
+
+func TestDockerLayerManifest(t *testing.T) {
+	im := newDockerLayerManifest(t, "aaaa", "")
+	base, err := dockerLayerManifest(im, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if base.Name != "registry-1.docker.io/layer-aaaa" {
+		t.Errorf("unexpected name of the base layer: %q", base.Name)
+	}
+	expectedLabels := types.Labels{
+		{Name: "os", Value: "linux"},
+		{Name: "arch", Value: "amd64"},
+	}
+	if !reflect.DeepEqual(base.Labels, expectedLabels) {
+		t.Errorf("unexpected labels of the base layer: %v", base.Labels)
+	}
+	if base.App != nil {
+		t.Errorf("unexpected app in the base layer")
+	}
+	if len(base.Dependencies) != 0 {
+		t.Errorf("unexpected dependencies of the base layer: %v", base.Dependencies)
+	}
+	if _, ok := base.Annotations.Get(d2acommon.AppcDockerRepository); ok {
+		t.Errorf("unexpected repository annotation in the base layer")
+	}
+	if id, _ := base.Annotations.Get(d2acommon.AppcDockerImageID); id != "aaaa" {
+		t.Errorf("unexpected image ID annotation in the base layer: %q", id)
+	}
+	if !reflect.DeepEqual(base.PathWhitelist, im.PathWhitelist) {
+		t.Errorf("unexpected path whitelist of the base layer: %v", base.PathWhitelist)
+	}
+
+	parentID, err := types.NewHash("sha512-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent := &types.Dependency{
+		ImageName: base.Name,
+		ImageID:   parentID,
+		Labels:    base.Labels,
+	}
+	im = newDockerLayerManifest(t, "bbbb", "aaaa")
+	top, err := dockerLayerManifest(im, parent, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if top.Name != "registry-1.docker.io/library/debian" {
+		t.Errorf("unexpected name of the top layer: %q", top.Name)
+	}
+	expectedLabels = types.Labels{
+		{Name: "version", Value: "8"},
+		{Name: "os", Value: "linux"},
+		{Name: "arch", Value: "amd64"},
+	}
+	if !reflect.DeepEqual(top.Labels, expectedLabels) {
+		t.Errorf("unexpected labels of the top layer: %v", top.Labels)
+	}
+	if top.App == nil {
+		t.Errorf("no app in the top layer")
+	}
+	if !reflect.DeepEqual(top.Dependencies, types.Dependencies{*parent}) {
+		t.Errorf("unexpected dependencies of the top layer: %v", top.Dependencies)
+	}
+	if _, ok := top.Annotations.Get(d2acommon.AppcDockerRepository); !ok {
+		t.Errorf("no repository annotation in the top layer")
+	}
+}
+
+func TestReplaceACIManifest(t *testing.T) {
+	im := newDockerLayerManifest(t, "aaaa", "")
+	manifest, err := json.Marshal(im)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries := []struct {
+		name     string
+		contents string
+	}{
+		{aci.ManifestFile, string(manifest)},
+		{"rootfs/bin/bash", "bash"},
+	}
+	src := &bytes.Buffer{}
+	tw := tar.NewWriter(src)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name: e.name,
+			Mode: 0644,
+			Size: int64(len(e.contents)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.contents)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newIm, err := dockerLayerManifest(im, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dst := &bytes.Buffer{}
+	if err := replaceACIManifest(src, newIm, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gotIm, err := aci.ManifestFromImage(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotIm.Name != newIm.Name {
+		t.Errorf("expected manifest of %q, got %q", newIm.Name, gotIm.Name)
+	}
+
+	tr := tar.NewReader(dst)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "rootfs/bin/bash" {
+			contents, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(contents) != "bash" {
+				t.Errorf("unexpected contents of rootfs/bin/bash: %q", contents)
+			}
+		}
+	}
+	if !reflect.DeepEqual(names, []string{aci.ManifestFile, "rootfs/bin/bash"}) {
+		t.Errorf("unexpected entries in the ACI: %v", names)
+	}
+}
+
+func TestConvertDockerImageRegistrySettings(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-docker-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// the registry is only reachable through its proxy
+	var lock sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		proxied = append(proxied, r.Host)
+		lock.Unlock()
+		http.Error(w, "no such registry", http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	req := &dockerConvertRequest{
+		RegistryURL: "registry.example.com/app:latest",
+		Insecure:    true,
+		Squash:      true,
+		OutputDir:   tmpDir,
+		TmpDir:      tmpDir,
+		Registry:    &config.RegistryData{Proxy: proxy.URL},
+	}
+	if _, err := convertDockerImage(req); err == nil {
+		t.Fatalf("expected the conversion to fail")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(proxied) == 0 || !strings.HasPrefix(proxied[0], "registry.example.com") {
+		t.Errorf("expected the registry to be reached through the proxy, got requests for %v", proxied)
+	}
+}
@@ -15,12 +15,17 @@
 package image
 
 import (
-	"container/list"
+	"crypto/sha512"
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sync"
 
 	"github.com/coreos/rkt/common/apps"
+	"github.com/coreos/rkt/pkg/lock"
 	"github.com/coreos/rkt/stage0"
 	"github.com/coreos/rkt/store"
 	"github.com/hashicorp/errwrap"
@@ -39,20 +44,55 @@ type Fetcher action
 // f.WithDeps is true also image dependencies are fetched.
 func (f *Fetcher) FetchImage(img string, ascPath string, imgType apps.AppImageType) (string, error) {
 	ensureLogger(f.Debug)
+	q := newFetchQueue(f)
 	a := f.getAsc(ascPath)
-	hash, err := f.fetchSingleImage(img, a, imgType)
+	hash, err := q.fetch(img, a, imgType)
 	if err != nil {
 		return "", err
 	}
 	if f.WithDeps {
-		err = f.fetchImageDeps(hash)
-		if err != nil {
+		if _, err := q.fetchDeps(hash); err != nil {
 			return "", err
 		}
 	}
 	return hash, nil
 }
 
+// FetchImages fetches the images of the passed apps into the store,
+// like FetchImage, and sets their image IDs. The images are fetched
+// concurrently, see f.ParallelFetches.
+func (f *Fetcher) FetchImages(al *apps.Apps) error {
+	ensureLogger(f.Debug)
+	var appl []*apps.App
+	al.Walk(func(app *apps.App) error {
+		appl = append(appl, app)
+		return nil
+	})
+	return f.fetchApps(appl)
+}
+
+func (f *Fetcher) fetchApps(appl []*apps.App) error {
+	q := newFetchQueue(f)
+	errs := make([]error, len(appl))
+	var wg sync.WaitGroup
+	for i, app := range appl {
+		wg.Add(1)
+		go func(i int, app *apps.App) {
+			defer wg.Done()
+			errs[i] = q.fetchApp(app)
+		}(i, app)
+	}
+	wg.Wait()
+	// report the error of the first app, like when fetching them
+	// one by one
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *Fetcher) getAsc(ascPath string) *asc {
 	if ascPath != "" {
 		return &asc{
@@ -63,42 +103,154 @@ func (f *Fetcher) getAsc(ascPath string) *asc {
 	return &asc{}
 }
 
-// fetchImageDeps will recursively fetch all the image dependencies
-func (f *Fetcher) fetchImageDeps(hash string) error {
-	imgsl := list.New()
+// fetchImageDeps will recursively fetch all the image dependencies,
+// and returns their hashes.
+func (f *Fetcher) fetchImageDeps(hash string) ([]string, error) {
+	return newFetchQueue(f).fetchDeps(hash)
+}
+
+// fetchQueue fetches images concurrently, with at most
+// f.ParallelFetches fetches at the same time. Each image is fetched
+// only once, the callers asking for an image already being fetched
+// wait for it.
+type fetchQueue struct {
+	f       *Fetcher
+	slots   chan struct{}
+	lock    sync.Mutex
+	fetches map[string]*queuedFetch
+}
+
+// queuedFetch is a fetch of a fetchQueue, done is closed when the
+// fetch is finished.
+type queuedFetch struct {
+	done chan struct{}
+	hash string
+	err  error
+}
+
+func newFetchQueue(f *Fetcher) *fetchQueue {
+	parallel := f.ParallelFetches
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &fetchQueue{
+		f:       f,
+		slots:   make(chan struct{}, parallel),
+		fetches: make(map[string]*queuedFetch),
+	}
+}
+
+// fetch fetches the image, waiting for a free slot, and returns its
+// hash.
+func (q *fetchQueue) fetch(img string, a *asc, imgType apps.AppImageType) (string, error) {
+	id := fmt.Sprintf("%d:%s", imgType, img)
+	q.lock.Lock()
+	if qf, ok := q.fetches[id]; ok {
+		q.lock.Unlock()
+		<-qf.done
+		return qf.hash, qf.err
+	}
+	qf := &queuedFetch{done: make(chan struct{})}
+	q.fetches[id] = qf
+	q.lock.Unlock()
+
+	q.slots <- struct{}{}
+	qf.hash, qf.err = q.f.fetchSingleImageLocked(img, a, imgType)
+	<-q.slots
+	close(qf.done)
+	return qf.hash, qf.err
+}
+
+// fetchAll fetches the images with the given names concurrently, and
+// returns their hashes in the same order.
+func (q *fetchQueue) fetchAll(imgs []string) ([]string, error) {
+	hashes := make([]string, len(imgs))
+	errs := make([]error, len(imgs))
+	var wg sync.WaitGroup
+	for i, img := range imgs {
+		wg.Add(1)
+		go func(i int, img string) {
+			defer wg.Done()
+			hashes[i], errs[i] = q.fetch(img, &asc{}, apps.AppImageName)
+		}(i, img)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// fetchDeps fetches the dependencies of the image, level by level, and
+// returns their hashes.
+func (q *fetchQueue) fetchDeps(hash string) ([]string, error) {
+	var hashes []string
 	seen := map[string]struct{}{}
-	f.addImageDeps(hash, imgsl, seen)
-	for el := imgsl.Front(); el != nil; el = el.Next() {
-		a := &asc{}
-		img := el.Value.(string)
-		hash, err := f.fetchSingleImage(img, a, apps.AppImageName)
+	level := []string{hash}
+	for len(level) > 0 {
+		var imgs []string
+		for _, h := range level {
+			deps, err := q.f.getNewImageDeps(h, seen)
+			if err != nil {
+				return nil, err
+			}
+			imgs = append(imgs, deps...)
+		}
+		var err error
+		level, err = q.fetchAll(imgs)
 		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, level...)
+	}
+	return hashes, nil
+}
+
+// fetchApp fetches the image of the app, and its dependencies if
+// q.f.WithDeps is true, and sets the image ID of the app.
+func (q *fetchQueue) fetchApp(app *apps.App) error {
+	hash, err := q.fetch(app.Image, q.f.getAsc(app.Asc), app.ImType)
+	if err != nil {
+		return err
+	}
+	if q.f.WithDeps {
+		if _, err := q.fetchDeps(hash); err != nil {
 			return err
 		}
-		f.addImageDeps(hash, imgsl, seen)
 	}
+	h, err := types.NewHash(hash)
+	if err != nil {
+		// should never happen
+		log.PanicE("got an invalid hash from the store, looks like it is corrupted", err)
+	}
+	app.ImageID = *h
 	return nil
 }
 
-func (f *Fetcher) addImageDeps(hash string, imgsl *list.List, seen map[string]struct{}) error {
+// getNewImageDeps returns the dependencies of the image which are not
+// in seen yet, and adds them to seen.
+func (f *Fetcher) getNewImageDeps(hash string, seen map[string]struct{}) ([]string, error) {
 	dependencies, err := f.getImageDeps(hash)
 	if err != nil {
-		return errwrap.Wrap(fmt.Errorf("failed to get dependencies for image ID %q", hash), err)
+		return nil, errwrap.Wrap(fmt.Errorf("failed to get dependencies for image ID %q", hash), err)
 	}
+	var imgs []string
 	for _, d := range dependencies {
 		imgName := d.ImageName.String()
 		app, err := discovery.NewApp(imgName, d.Labels.ToMap())
 		if err != nil {
-			return errwrap.Wrap(fmt.Errorf("one of image ID's %q dependencies (image %q) is invalid", hash, imgName), err)
+			return nil, errwrap.Wrap(fmt.Errorf("one of image ID's %q dependencies (image %q) is invalid", hash, imgName), err)
 		}
 		appStr := app.String()
 		if _, ok := seen[appStr]; ok {
 			continue
 		}
-		imgsl.PushBack(app.String())
+		imgs = append(imgs, appStr)
 		seen[appStr] = struct{}{}
 	}
-	return nil
+	return imgs, nil
 }
 
 func (f *Fetcher) getImageDeps(hash string) (types.Dependencies, error) {
@@ -113,24 +265,97 @@ func (f *Fetcher) getImageDeps(hash string) (types.Dependencies, error) {
 	return im.Dependencies, nil
 }
 
+// fetchSingleImageLocked fetches a single image like fetchSingleImage,
+// holding a lock for the image to serialize the concurrent fetches of
+// the same image, in this process or in other ones.
+func (f *Fetcher) fetchSingleImageLocked(img string, a *asc, imgType apps.AppImageType) (string, error) {
+	keyLock, err := f.lockFetch(img)
+	if err != nil {
+		return "", err
+	}
+	defer keyLock.Close()
+	return f.fetchSingleImage(img, a, imgType)
+}
+
+// lockFetch takes the fetch lock of the image. The lock key is based on
+// a hash of the image string.
+func (f *Fetcher) lockFetch(img string) (*lock.KeyLock, error) {
+	tmpDir, err := f.S.TmpDir()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error setting up temporary directory"), err)
+	}
+	lockDir := filepath.Join(tmpDir, "fetchlocks")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, errwrap.Wrap(errors.New("error setting up fetch locks directory"), err)
+	}
+	h := sha512.New()
+	h.Write([]byte(img))
+	key := f.S.HashToKey(h)
+
+	keyLock, err := lock.TryExclusiveKeyLock(lockDir, key)
+	if err == nil {
+		return keyLock, nil
+	}
+	if err != lock.ErrLocked {
+		return nil, errwrap.Wrap(fmt.Errorf("failed to lock the fetch of image %q", img), err)
+	}
+	log.Printf("another rkt instance is fetching image %q, waiting...", img)
+	keyLock, err = lock.ExclusiveKeyLock(lockDir, key)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("failed to lock the fetch of image %q", img), err)
+	}
+	return keyLock, nil
+}
+
 func (f *Fetcher) fetchSingleImage(img string, a *asc, imgType apps.AppImageType) (string, error) {
 	if imgType == apps.AppImageGuess {
 		imgType = guessImageType(img)
 	}
 	if imgType == apps.AppImageHash {
-		return "", fmt.Errorf("cannot fetch a hash %q, expected either a URL, a path or an image name", img)
+		return "", fmt.Errorf("cannot fetch a hash %q, expected either a URL, a path, an image name or an OCI image reference", img)
 	}
 
+	var hash string
+	var err error
 	switch imgType {
 	case apps.AppImageURL:
-		return f.fetchSingleImageByURL(img, a)
+		hash, err = f.fetchSingleImageByURL(img, a)
 	case apps.AppImagePath:
-		return f.fetchSingleImageByPath(img, a)
+		hash, err = f.fetchSingleImageByPath(img, a)
 	case apps.AppImageName:
-		return f.fetchSingleImageByName(img, a)
+		hash, err = f.fetchSingleImageByName(img, a)
+	case apps.AppImageOCI:
+		hash, err = f.fetchSingleImageByOCIRef(img)
 	default:
 		return "", fmt.Errorf("unknown image type %d", imgType)
 	}
+	if err != nil {
+		return "", err
+	}
+	// the image may come from the store, where it was possibly
+	// stored before the policy denied it
+	if err := f.checkPolicy(hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// checkPolicy returns an error if the image of the store with the hash
+// is denied by the signature policy. The policy is not applied if the
+// image verification is disabled.
+func (f *Fetcher) checkPolicy(hash string) error {
+	if len(f.SignaturePolicy) == 0 || f.Ks == nil || f.InsecureFlags.SkipImageCheck() {
+		return nil
+	}
+	key, err := f.S.ResolveKey(hash)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("could not resolve image %q", hash), err)
+	}
+	im, err := f.S.GetImageManifest(key)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("error getting the manifest of image %q", hash), err)
+	}
+	return signaturePolicy(f.SignaturePolicy).CheckName(im.Name.String())
 }
 
 type remoteCheck int
@@ -231,6 +456,8 @@ func (f *Fetcher) maybeFetchHTTPURLFromRemote(rem *store.Remote, u *url.URL, a *
 			Rem:           rem,
 			Debug:         f.Debug,
 			Headers:       f.Headers,
+			Registries:    f.Registries,
+			Policy:        f.SignaturePolicy,
 		}
 		return hf.GetHash(u, a)
 	}
@@ -243,8 +470,10 @@ func (f *Fetcher) maybeFetchDockerURLFromRemote(u *url.URL) (string, error) {
 		df := &dockerFetcher{
 			InsecureFlags: f.InsecureFlags,
 			DockerAuth:    f.DockerAuth,
+			Registries:    f.Registries,
 			S:             f.S,
 			Debug:         f.Debug,
+			NoSquash:      f.NoSquash,
 		}
 		return df.GetHash(u)
 	}
@@ -257,11 +486,22 @@ func (f *Fetcher) fetchSingleImageByPath(path string, a *asc) (string, error) {
 		InsecureFlags: f.InsecureFlags,
 		S:             f.S,
 		Ks:            f.Ks,
+		Policy:        f.SignaturePolicy,
 		Debug:         f.Debug,
 	}
 	return ff.GetHash(path, a)
 }
 
+func (f *Fetcher) fetchSingleImageByOCIRef(img string) (string, error) {
+	of := &ociFetcher{
+		InsecureFlags: f.InsecureFlags,
+		S:             f.S,
+		NoStore:       f.NoStore || f.NoCache,
+		Debug:         f.Debug,
+	}
+	return of.GetHash(img)
+}
+
 type appBundle struct {
 	App *discovery.App
 	Str string
@@ -344,6 +584,8 @@ func (f *Fetcher) maybeFetchImageFromRemote(app *appBundle, a *asc) (string, err
 			Ks:                 f.Ks,
 			Debug:              f.Debug,
 			Headers:            f.Headers,
+			Registries:         f.Registries,
+			Policy:             f.SignaturePolicy,
 			TrustKeysFromHTTPS: f.TrustKeysFromHTTPS,
 		}
 		return nf.GetHash(app.App, a)
@@ -0,0 +1,83 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/rkt/common/apps"
+	"github.com/coreos/rkt/store"
+)
+
+func TestFetchImagesWithDeps(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-fetcher-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s, err := store.NewStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer s.Close()
+
+	// app01 and app02 share the dependencies of dep01 and dep02
+	dep03 := writeTestBundleACI(t, s, tmpDir, "example.com/dep03", "")
+	dep01 := writeTestBundleACI(t, s, tmpDir, "example.com/dep01", `{"imageName": "example.com/dep03"}`)
+	dep02 := writeTestBundleACI(t, s, tmpDir, "example.com/dep02", `{"imageName": "example.com/dep03"}`)
+	deps := `{"imageName": "example.com/dep01"}, {"imageName": "example.com/dep02"}`
+	app01 := writeTestBundleACI(t, s, tmpDir, "example.com/app01", deps)
+	app02 := writeTestBundleACI(t, s, tmpDir, "example.com/app02", deps)
+
+	f := &Fetcher{
+		S:               s,
+		StoreOnly:       true,
+		WithDeps:        true,
+		ParallelFetches: 3,
+	}
+
+	hashes, err := f.fetchImageDeps(app01)
+	if err != nil {
+		t.Fatalf("cannot fetch dependencies: %v", err)
+	}
+	if expected := []string{dep01, dep02, dep03}; !reflect.DeepEqual(hashes, expected) {
+		t.Errorf("expected dependencies %v, got %v", expected, hashes)
+	}
+
+	var al apps.Apps
+	al.Create("example.com/app01")
+	al.Create("example.com/app02")
+	al.Create("example.com/app01")
+	if err := f.FetchImages(&al); err != nil {
+		t.Fatalf("cannot fetch images: %v", err)
+	}
+	var got []string
+	for _, h := range al.GetImageIDs() {
+		got = append(got, h.String())
+	}
+	if expected := []string{app01, app02, app01}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected image IDs %v, got %v", expected, got)
+	}
+
+	al.Create("example.com/missing")
+	if err := f.FetchImages(&al); err == nil {
+		t.Errorf("expected fetching a missing image to fail")
+	}
+}
@@ -31,6 +31,7 @@ type fileFetcher struct {
 	InsecureFlags *rktflag.SecFlags
 	S             *store.Store
 	Ks            *keystore.Keystore
+	Policy        signaturePolicy
 	Debug         bool
 }
 
@@ -76,15 +77,9 @@ func (f *fileFetcher) getFile(aciPath string, a *asc) (*os.File, error) {
 	return aciFile, nil
 }
 
-// fetch opens and verifies the ACI.
+// fetch opens and verifies the ACI. An unsigned ACI is accepted if
+// the signature policy allows it.
 func (f *fileFetcher) getVerifiedFile(aciPath string, a *asc) (*os.File, error) {
-	f.maybeOverrideAsc(aciPath, a)
-	ascFile, err := a.Get()
-	if err != nil {
-		return nil, errwrap.Wrap(errors.New("error opening signature file"), err)
-	}
-	defer func() { maybeClose(ascFile) }()
-
 	aciFile, err := os.Open(aciPath)
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error opening ACI file"), err)
@@ -96,7 +91,25 @@ func (f *fileFetcher) getVerifiedFile(aciPath string, a *asc) (*os.File, error)
 		return nil, err
 	}
 
-	entity, err := validator.ValidateWithSignature(f.Ks, ascFile)
+	if err := f.Policy.CheckName(validator.GetImageName()); err != nil {
+		return nil, err
+	}
+	f.maybeOverrideAsc(aciPath, a)
+	ascFile, err := a.Get()
+	if os.IsNotExist(err) && f.Policy.AllowUnsigned(validator.GetImageName(), true) {
+		if _, err := aciFile.Seek(0, 0); err != nil {
+			return nil, errwrap.Wrap(errors.New("error seeking ACI file"), err)
+		}
+		retAciFile := aciFile
+		aciFile = nil
+		return retAciFile, nil
+	}
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error opening signature file"), err)
+	}
+	defer func() { maybeClose(ascFile) }()
+
+	entity, err := validator.ValidateWithPolicy(f.Ks, f.Policy, ascFile)
 	if err != nil {
 		return nil, errwrap.Wrap(fmt.Errorf("image %q verification failed", validator.GetImageName()), err)
 	}
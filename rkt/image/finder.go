@@ -27,16 +27,34 @@ import (
 // try to fetch them.
 type Finder action
 
-// FindImages uses FindImage to attain a list of image hashes
+// FindImages finds the images of the apps like FindImage, and sets
+// their image IDs. The images are fetched concurrently, see
+// f.ParallelFetches.
 func (f *Finder) FindImages(al *apps.Apps) error {
-	return al.Walk(func(app *apps.App) error {
-		h, err := f.FindImage(app.Image, app.Asc, app.ImType)
+	ensureLogger(f.Debug)
+	// the images passed as hashes are looked for in the store, the
+	// other ones are fetched concurrently
+	var toFetch []*apps.App
+	err := al.Walk(func(app *apps.App) error {
+		imgType := app.ImType
+		if imgType == apps.AppImageGuess {
+			imgType = guessImageType(app.Image)
+		}
+		if imgType != apps.AppImageHash {
+			toFetch = append(toFetch, app)
+			return nil
+		}
+		h, err := f.getHashFromStore(app.Image)
 		if err != nil {
 			return err
 		}
 		app.ImageID = *h
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	return (*Fetcher)(f).fetchApps(toFetch)
 }
 
 // FindImage tries to get a hash of a passed image, ideally from
@@ -80,6 +98,9 @@ func (f *Finder) getHashFromStore(img string) (*types.Hash, error) {
 		// should never happen
 		log.PanicE("got an invalid hash from the store, looks like it is corrupted", err)
 	}
+	if err := (*Fetcher)(f).checkPolicy(fullKey); err != nil {
+		return nil, err
+	}
 	log.Printf("using image from the store with hash %s", h.String())
 	return h, nil
 }
@@ -35,6 +35,8 @@ type httpFetcher struct {
 	Rem           *store.Remote
 	Debug         bool
 	Headers       map[string]config.Headerer
+	Registries    map[string]config.RegistryData
+	Policy        signaturePolicy
 }
 
 // GetHash fetches the URL, optionally verifies it against passed asc,
@@ -151,9 +153,10 @@ func (f *httpFetcher) fetchVerifiedURL(u *url.URL, a *asc, etag string) (readSee
 func (f *httpFetcher) getHTTPOps() *httpOps {
 	return &httpOps{
 		InsecureSkipTLSVerify: f.InsecureFlags.SkipTLSCheck(),
-		S:       f.S,
-		Headers: f.Headers,
-		Debug:   f.Debug,
+		S:          f.S,
+		Headers:    f.Headers,
+		Registries: f.Registries,
+		Debug:      f.Debug,
 	}
 }
 
@@ -162,7 +165,7 @@ func (f *httpFetcher) validate(aciFile, ascFile io.ReadSeeker) error {
 	if err != nil {
 		return err
 	}
-	entity, err := v.ValidateWithSignature(f.Ks, ascFile)
+	entity, err := v.ValidateWithPolicy(f.Ks, f.Policy, ascFile)
 	if err != nil {
 		return err
 	}
@@ -34,7 +34,10 @@ type httpOps struct {
 	InsecureSkipTLSVerify bool
 	S                     *store.Store
 	Headers               map[string]config.Headerer
-	Debug                 bool
+	// Registries holds the network settings of the hosts, like
+	// proxies, CA bundles or mirrors.
+	Registries map[string]config.RegistryData
+	Debug      bool
 }
 
 // DownloadSignature takes an asc instance and tries to get the
@@ -69,6 +72,30 @@ func (o *httpOps) DownloadSignatureAgain(a *asc) (readSeekCloser, error) {
 	return ascFile, nil
 }
 
+// DownloadSignatureFromURLs tries to download the signature of the
+// image with key from each of the passed locations, and returns the
+// first one downloaded, or nil if none could be downloaded.
+func (o *httpOps) DownloadSignatureFromURLs(key string, sigURLs []string) readSeekCloser {
+	ensureLogger(o.Debug)
+	for _, sigURL := range sigURLs {
+		u, err := url.Parse(sigURL)
+		if sigURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		a := &asc{
+			Location: sigURL,
+			Fetcher:  o.GetAscRemoteFetcher(),
+		}
+		ascFile, err := o.DownloadSignatureAgain(a)
+		if err != nil {
+			log.PrintE(fmt.Sprintf("cannot download the signature of image %s", key), err)
+			continue
+		}
+		return ascFile
+	}
+	return nil
+}
+
 // DownloadImage download the image, duh. It expects to actually
 // receive the file, instead of being asked to use the cached version.
 func (o *httpOps) DownloadImage(u *url.URL) (readSeekCloser, *cacheData, error) {
@@ -93,6 +120,7 @@ func (o *httpOps) DownloadImageWithETag(u *url.URL, etag string) (readSeekCloser
 	}
 	defer func() { maybeClose(aciFile) }()
 
+	u = o.getMirrorURL(u)
 	session := o.getSession(u, aciFile.File, "ACI", etag)
 	dl := o.getDownloader(session)
 	if err := dl.Download(u, aciFile.File); err != nil {
@@ -115,6 +143,7 @@ func (o *httpOps) GetAscRemoteFetcher() *remoteAscFetcher {
 		default:
 			return fmt.Errorf("invalid signature location: expected %q scheme, got %q", "http(s)", u.Scheme)
 		}
+		u = o.getMirrorURL(u)
 		session := o.getSession(u, file, "signature", "")
 		dl := o.getDownloader(session)
 		err := dl.Download(u, file)
@@ -136,6 +165,7 @@ func (o *httpOps) getSession(u *url.URL, file *os.File, label, etag string) *res
 	eTagFilePath := fmt.Sprintf("%s.etag", file.Name())
 	return &resumableSession{
 		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
+		Registries:            o.Registries,
 		Headers:               o.getHeaders(u, etag),
 		File:                  file,
 		ETagFilePath:          eTagFilePath,
@@ -143,6 +173,16 @@ func (o *httpOps) getSession(u *url.URL, file *os.File, label, etag string) *res
 	}
 }
 
+// getMirrorURL returns the URL to download from instead of the passed
+// one, if its host has a mirror.
+func (o *httpOps) getMirrorURL(u *url.URL) *url.URL {
+	mu := getMirrorURL(o.Registries, u)
+	if mu != u {
+		log.Printf("using mirror %s of %s", mu.Host, u.Host)
+	}
+	return mu
+}
+
 func (o *httpOps) getDownloader(session downloadSession) *downloader {
 	return &downloader{
 		Session: session,
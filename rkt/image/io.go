@@ -21,6 +21,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/coreos/rkt/pkg/lock"
@@ -43,35 +44,155 @@ type readSeekCloser interface {
 	io.Closer
 }
 
+// copyToTmpFile copies the contents of r to a temporary file of the
+// store, and returns the file rewound.
+func copyToTmpFile(s *store.Store, r io.Reader) (*os.File, error) {
+	f, err := s.TmpFile()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error setting up temporary file"), err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		removeTmpFile(f)
+		return nil, errwrap.Wrap(errors.New("error copying to temporary file"), err)
+	}
+	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+		removeTmpFile(f)
+		return nil, errwrap.Wrap(errors.New("error seeking temporary file"), err)
+	}
+	return f, nil
+}
+
+func removeTmpFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// downloadProgress is the progress bar shared by the downloads.
+var downloadProgress = &progressBoard{w: os.Stderr}
+
 // getIoProgressReader returns a reader that wraps the HTTP response
-// body, so it prints a pretty progress bar when reading data from it.
+// body, so it prints a pretty progress bar when reading data from
+// it. The progress of concurrent downloads is combined in a single
+// progress bar.
 func getIoProgressReader(label string, res *http.Response) io.Reader {
-	prefix := "Downloading " + label
-	fmtBytesSize := 18
-	barSize := int64(80 - len(prefix) - fmtBytesSize)
-	bar := ioprogress.DrawTextFormatBarForW(barSize, os.Stderr)
-	fmtfunc := func(progress, total int64) string {
-		// Content-Length is set to -1 when unknown.
-		if total == -1 {
-			return fmt.Sprintf(
-				"%s: %v of an unknown total size",
-				prefix,
-				ioprogress.ByteUnitStr(progress),
-			)
+	return &progressReader{
+		Reader: res.Body,
+		board:  downloadProgress,
+		d: &progressDownload{
+			label: label,
+			// Content-Length is set to -1 when unknown.
+			size: res.ContentLength,
+		},
+	}
+}
+
+// progressDownload is the progress of a download.
+type progressDownload struct {
+	label    string
+	progress int64
+	size     int64
+	done     bool
+}
+
+// progressReader wraps the reader of a download, and reports the
+// progress of the download to a progressBoard.
+type progressReader struct {
+	io.Reader
+	board   *progressBoard
+	d       *progressDownload
+	started bool
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if !r.started {
+		r.board.add(r.d)
+		r.started = true
+	}
+	n, err := r.Reader.Read(p)
+	r.board.update(r.d, int64(n), err != nil)
+	return n, err
+}
+
+// progressBoard draws a single progress bar for the current downloads.
+// The downloads are kept until all of them are done, so the progress
+// bar does not go backwards.
+type progressBoard struct {
+	lock      sync.Mutex
+	w         io.Writer
+	downloads []*progressDownload
+	draw      ioprogress.DrawFunc
+	lastDraw  time.Time
+}
+
+func (b *progressBoard) add(d *progressDownload) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.downloads) == 0 {
+		b.draw = ioprogress.DrawTerminalf(b.w, b.format)
+	}
+	b.downloads = append(b.downloads, d)
+	b.drawLocked()
+}
+
+func (b *progressBoard) update(d *progressDownload, n int64, done bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	d.progress += n
+	if !done {
+		if time.Since(b.lastDraw) >= time.Second {
+			b.drawLocked()
+		}
+		return
+	}
+	if d.done {
+		return
+	}
+	d.done = true
+	for _, d := range b.downloads {
+		if !d.done {
+			return
+		}
+	}
+	b.drawLocked()
+	b.draw(-1, -1)
+	b.downloads = nil
+}
+
+func (b *progressBoard) drawLocked() {
+	var progress, total int64
+	for _, d := range b.downloads {
+		progress += d.progress
+		if total == -1 || d.size == -1 {
+			total = -1
+		} else {
+			total += d.size
 		}
+	}
+	b.draw(progress, total)
+	b.lastDraw = time.Now()
+}
+
+func (b *progressBoard) format(progress, total int64) string {
+	prefix := "Downloading " + b.downloads[0].label
+	if len(b.downloads) > 1 {
+		prefix = fmt.Sprintf("Downloading %d files", len(b.downloads))
+	}
+	if total == -1 {
 		return fmt.Sprintf(
-			"%s: %s %s",
+			"%s: %v of an unknown total size",
 			prefix,
-			bar(progress, total),
-			ioprogress.DrawTextFormatBytes(progress, total),
+			ioprogress.ByteUnitStr(progress),
 		)
 	}
-	return &ioprogress.Reader{
-		Reader:       res.Body,
-		Size:         res.ContentLength,
-		DrawFunc:     ioprogress.DrawTerminalf(os.Stderr, fmtfunc),
-		DrawInterval: time.Second,
-	}
+	fmtBytesSize := 18
+	barSize := int64(80 - len(prefix) - fmtBytesSize)
+	bar := ioprogress.DrawTextFormatBarForW(barSize, b.w)
+	return fmt.Sprintf(
+		"%s: %s %s",
+		prefix,
+		bar(progress, total),
+		ioprogress.DrawTextFormatBytes(progress, total),
+	)
 }
 
 // removeOnClose is a wrapper around os.File that removes the file
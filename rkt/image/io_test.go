@@ -0,0 +1,75 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProgressBoard(t *testing.T) {
+	out := &bytes.Buffer{}
+	board := &progressBoard{w: out}
+	newReader := func(label, data string) *progressReader {
+		return &progressReader{
+			Reader: strings.NewReader(data),
+			board:  board,
+			d: &progressDownload{
+				label: label,
+				size:  int64(len(data)),
+			},
+		}
+	}
+
+	// A single download is shown with its label
+	if _, err := ioutil.ReadAll(newReader("first", "0123456789")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "Downloading first: ") || !strings.Contains(last, "10 B/10 B") {
+		t.Errorf("unexpected progress %q", last)
+	}
+	if !strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("expected the progress to be terminated by a newline")
+	}
+
+	// Concurrent downloads are combined
+	out.Reset()
+	r1 := newReader("first", "0123456789")
+	r2 := newReader("second", "01234")
+	buf := make([]byte, 100)
+	r1.Read(buf)
+	r2.Read(buf)
+	if _, err := ioutil.ReadAll(r1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.HasSuffix(out.String(), "\n\n") {
+		t.Errorf("unexpected end of the progress with a download running")
+	}
+	if _, err := ioutil.ReadAll(r2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	last = lines[len(lines)-1]
+	if !strings.HasPrefix(last, "Downloading 2 files: ") || !strings.Contains(last, "15 B/15 B") {
+		t.Errorf("unexpected progress %q", last)
+	}
+	if len(board.downloads) != 0 {
+		t.Errorf("expected the downloads to be removed, got %d", len(board.downloads))
+	}
+}
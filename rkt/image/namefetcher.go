@@ -40,6 +40,8 @@ type nameFetcher struct {
 	Ks                 *keystore.Keystore
 	Debug              bool
 	Headers            map[string]config.Headerer
+	Registries         map[string]config.RegistryData
+	Policy             signaturePolicy
 	TrustKeysFromHTTPS bool
 }
 
@@ -70,6 +72,7 @@ func (f *nameFetcher) discoverApp(app *discovery.App) (*discovery.Endpoints, err
 		insecure = insecure | discovery.InsecureHttp
 	}
 	hostHeaders := config.ResolveAuthPerHost(f.Headers)
+	useRegistriesForDiscovery(f.Registries)
 	ep, attempts, err := discovery.DiscoverEndpoints(*app, hostHeaders, insecure)
 	if f.Debug {
 		for _, a := range attempts {
@@ -205,6 +208,7 @@ func (f *nameFetcher) maybeFetchPubKeys(appName string) {
 		allowHTTP = f.InsecureFlags.AllowHTTP()
 	}
 	if !f.InsecureFlags.SkipTLSCheck() || f.InsecureFlags.ConsiderInsecurePubKeys() {
+		useRegistriesForDiscovery(f.Registries)
 		m := &pubkey.Manager{
 			AuthPerHost:          f.Headers,
 			InsecureAllowHTTP:    allowHTTP,
@@ -212,6 +216,10 @@ func (f *nameFetcher) maybeFetchPubKeys(appName string) {
 			TrustKeysFromHTTPS:   f.TrustKeysFromHTTPS,
 			Ks:                   f.Ks,
 			Debug:                f.Debug,
+			Transport: &registriesTransport{
+				Registries:            f.Registries,
+				InsecureSkipTLSVerify: f.InsecureFlags.SkipTLSCheck(),
+			},
 		}
 		pkls, err := m.GetPubKeyLocations(appName)
 		// We do not bail out here, because if fetching the
@@ -263,7 +271,7 @@ func (f *nameFetcher) validate(app *discovery.App, aciFile, ascFile io.ReadSeeke
 		return err
 	}
 
-	entity, err := v.ValidateWithSignature(f.Ks, ascFile)
+	entity, err := v.ValidateWithPolicy(f.Ks, f.Policy, ascFile)
 	if err != nil {
 		return err
 	}
@@ -287,8 +295,9 @@ func (f *nameFetcher) maybeOverrideAscFetcherWithRemote(ascURL string, a *asc) {
 func (f *nameFetcher) getHTTPOps() *httpOps {
 	return &httpOps{
 		InsecureSkipTLSVerify: f.InsecureFlags.SkipTLSCheck(),
-		S:       f.S,
-		Headers: f.Headers,
-		Debug:   f.Debug,
+		S:          f.S,
+		Headers:    f.Headers,
+		Registries: f.Registries,
+		Debug:      f.Debug,
 	}
 }
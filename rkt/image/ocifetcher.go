@@ -0,0 +1,861 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+
+	"github.com/appc/spec/aci"
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+)
+
+const (
+	// ociLayoutPrefix prefixes the references to images in an OCI
+	// image layout directory.
+	ociLayoutPrefix = "oci-layout:"
+	// ociArchivePrefix prefixes the references to images in a tar
+	// archive of an OCI image layout.
+	ociArchivePrefix = "oci-archive:"
+
+	ociLayoutFile = "oci-layout"
+	ociIndexFile  = "index.json"
+	ociBlobsDir   = "blobs"
+
+	ociMediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+	ociWhiteoutPrefix = ".wh."
+	ociWhiteoutOpaque = ".wh..wh..opq"
+)
+
+// ociRef is a reference to an image in an OCI image layout, in the
+// form oci-layout:PATH[:NAME] or oci-archive:PATH[:NAME]. NAME is the
+// value of the org.opencontainers.image.ref.name annotation of the
+// image in the index of the layout, it can be omitted if the index
+// contains only one image.
+type ociRef struct {
+	// Archive tells whether Path is a tar archive of the layout
+	// instead of a directory.
+	Archive bool
+	// Path is the absolute path of the layout.
+	Path string
+	// Name is the name of the image in the layout, if any.
+	Name string
+}
+
+// parseOCIRef parses an oci-layout: or oci-archive: image reference.
+func parseOCIRef(img string) (*ociRef, error) {
+	ref := &ociRef{}
+	var rest string
+	switch {
+	case strings.HasPrefix(img, ociLayoutPrefix):
+		rest = strings.TrimPrefix(img, ociLayoutPrefix)
+	case strings.HasPrefix(img, ociArchivePrefix):
+		ref.Archive = true
+		rest = strings.TrimPrefix(img, ociArchivePrefix)
+	default:
+		return nil, fmt.Errorf("invalid OCI image reference %q, expected %sPATH[:NAME] or %sPATH[:NAME]", img, ociLayoutPrefix, ociArchivePrefix)
+	}
+
+	// The name cannot contain slashes, so a colon followed by
+	// a slash is a part of the path.
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i+1:], "/") {
+		ref.Name = rest[i+1:]
+		rest = rest[:i]
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("invalid OCI image reference %q, the path of the layout is empty", img)
+	}
+
+	absPath, err := filepath.Abs(rest)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("failed to get an absolute path for %q", rest), err)
+	}
+	ref.Path = absPath
+	return ref, nil
+}
+
+func (r *ociRef) String() string {
+	prefix := ociLayoutPrefix
+	if r.Archive {
+		prefix = ociArchivePrefix
+	}
+	if r.Name == "" {
+		return prefix + r.Path
+	}
+	return fmt.Sprintf("%s%s:%s", prefix, r.Path, r.Name)
+}
+
+// ociDescriptor is an OCI content descriptor.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+}
+
+// ociPlatform is the platform an image of an OCI index runs on.
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// ociIndex is an OCI image index, the index.json file of a layout is
+// one of them.
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is an OCI image manifest.
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+// ociImageConfig is an OCI image configuration, only the fields used
+// by rkt are listed.
+type ociImageConfig struct {
+	Created      *time.Time `json:"created,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	Architecture string     `json:"architecture"`
+	OS           string     `json:"os"`
+	Config       struct {
+		User         string              `json:"User,omitempty"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+		Env          []string            `json:"Env,omitempty"`
+		Entrypoint   []string            `json:"Entrypoint,omitempty"`
+		Cmd          []string            `json:"Cmd,omitempty"`
+		Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+		WorkingDir   string              `json:"WorkingDir,omitempty"`
+	} `json:"config"`
+}
+
+// ociFetcher is used to fetch images from OCI image layouts, given as
+// oci-layout: or oci-archive: references. The images are converted to
+// a single ACI, squashing their layers.
+type ociFetcher struct {
+	InsecureFlags *rktflag.SecFlags
+	S             *store.Store
+	// NoStore tells whether to convert the image even if it was
+	// already converted.
+	NoStore bool
+	Debug   bool
+}
+
+// GetHash converts the image referenced by img to an ACI, then stores
+// it in the store and returns the hash. A remote is written in the
+// store for the reference and the digest of the image, so the image is
+// converted again only if it changes in the layout.
+func (f *ociFetcher) GetHash(img string) (string, error) {
+	ensureLogger(f.Debug)
+	ref, err := parseOCIRef(img)
+	if err != nil {
+		return "", err
+	}
+
+	if !f.InsecureFlags.SkipImageCheck() {
+		return "", fmt.Errorf("signature verification for OCI images is not supported (try --insecure-options=image)")
+	}
+
+	layout, err := f.openLayout(ref)
+	if err != nil {
+		return "", err
+	}
+	defer layout.Close()
+
+	desc, err := layout.findImage(ref.Name)
+	if err != nil {
+		return "", errwrap.Wrap(fmt.Errorf("cannot find image in %q", ref), err)
+	}
+
+	remoteKey := fmt.Sprintf("%s@%s", ref, desc.Digest)
+	if !f.NoStore {
+		if rem, ok, err := f.S.GetRemote(remoteKey); err != nil {
+			return "", errwrap.Wrap(fmt.Errorf("failed to get the remote for %q", ref), err)
+		} else if ok {
+			log.Printf("using image from local store for OCI image %s", ref)
+			return rem.BlobKey, nil
+		}
+	}
+
+	log.Printf("converting OCI image %s", ref)
+	aciFile, err := f.convert(layout, desc, ref)
+	if err != nil {
+		return "", errwrap.Wrap(fmt.Errorf("error converting OCI image %q to ACI", ref), err)
+	}
+	defer aciFile.Close()
+
+	key, err := f.S.WriteACI(aciFile, false)
+	if err != nil {
+		return "", err
+	}
+
+	newRem := store.NewRemote(remoteKey, "")
+	newRem.BlobKey = key
+	newRem.DownloadTime = time.Now()
+	if err := f.S.WriteRemote(newRem); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// openLayout returns the layout referenced by ref. Archives are
+// extracted in a temporary directory of the store, removed when the
+// layout is closed.
+func (f *ociFetcher) openLayout(ref *ociRef) (*ociLayout, error) {
+	if !ref.Archive {
+		return newOCILayout(ref.Path, "")
+	}
+
+	storeTmpDir, err := f.S.TmpDir()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating temporary dir for OCI to ACI conversion"), err)
+	}
+	tmpDir, err := ioutil.TempDir(storeTmpDir, "oci2aci-")
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating temporary dir for OCI to ACI conversion"), err)
+	}
+	if err := extractOCIArchive(ref.Path, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, errwrap.Wrap(fmt.Errorf("error extracting OCI archive %q", ref.Path), err)
+	}
+	return newOCILayout(tmpDir, tmpDir)
+}
+
+// convert writes the image described by desc in a temporary ACI file,
+// and returns it.
+func (f *ociFetcher) convert(layout *ociLayout, desc *ociDescriptor, ref *ociRef) (*os.File, error) {
+	manifest := &ociManifest{}
+	if err := layout.readJSONBlob(desc, manifest); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot read image manifest"), err)
+	}
+	config := &ociImageConfig{}
+	if err := layout.readJSONBlob(&manifest.Config, config); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot read image configuration"), err)
+	}
+
+	im, err := ociImageManifest(ref, config)
+	if err != nil {
+		return nil, err
+	}
+
+	aciFile, err := f.S.TmpFile()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating temporary file for OCI to ACI conversion"), err)
+	}
+	// The file is removed now, it is kept alive by the returned fd.
+	os.Remove(aciFile.Name())
+
+	if err := squashOCILayers(layout, manifest.Layers, *im, aciFile); err != nil {
+		aciFile.Close()
+		return nil, err
+	}
+	if _, err := aciFile.Seek(0, 0); err != nil {
+		aciFile.Close()
+		return nil, errwrap.Wrap(errors.New("error seeking ACI file"), err)
+	}
+
+	return aciFile, nil
+}
+
+// ociLayout is an OCI image layout directory.
+type ociLayout struct {
+	dir    string
+	tmpDir string
+}
+
+func newOCILayout(dir, tmpDir string) (*ociLayout, error) {
+	if _, err := os.Stat(filepath.Join(dir, ociLayoutFile)); err != nil {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+		return nil, errwrap.Wrap(fmt.Errorf("%q is not an OCI image layout", dir), err)
+	}
+	return &ociLayout{dir: dir, tmpDir: tmpDir}, nil
+}
+
+// Close removes the temporary directory of the layout, if any.
+func (l *ociLayout) Close() error {
+	if l.tmpDir == "" {
+		return nil
+	}
+	return os.RemoveAll(l.tmpDir)
+}
+
+// findImage returns the descriptor of the image manifest named name in
+// the index of the layout. If name is empty, the index must contain
+// only one image. Nested indexes are resolved by picking the image for
+// the current platform.
+func (l *ociLayout) findImage(name string) (*ociDescriptor, error) {
+	indexBytes, err := ioutil.ReadFile(filepath.Join(l.dir, ociIndexFile))
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot read index"), err)
+	}
+	index := &ociIndex{}
+	if err := json.Unmarshal(indexBytes, index); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot parse index"), err)
+	}
+
+	var desc *ociDescriptor
+	switch {
+	case name != "":
+		for i, d := range index.Manifests {
+			if d.Annotations[ociRefNameAnnotation] == name {
+				desc = &index.Manifests[i]
+				break
+			}
+		}
+		if desc == nil {
+			return nil, fmt.Errorf("no image named %q in the index", name)
+		}
+	case len(index.Manifests) == 1:
+		desc = &index.Manifests[0]
+	case len(index.Manifests) == 0:
+		return nil, errors.New("the index is empty")
+	default:
+		var names []string
+		for _, d := range index.Manifests {
+			if n, ok := d.Annotations[ociRefNameAnnotation]; ok {
+				names = append(names, strconv.Quote(n))
+			}
+		}
+		return nil, fmt.Errorf("the index contains several images, specify one of them by name: %s", strings.Join(names, ", "))
+	}
+
+	if desc.MediaType != ociMediaTypeIndex {
+		return desc, nil
+	}
+
+	platformIndex := &ociIndex{}
+	if err := l.readJSONBlob(desc, platformIndex); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot read nested index"), err)
+	}
+	for i, d := range platformIndex.Manifests {
+		if d.MediaType != ociMediaTypeManifest || d.Platform == nil {
+			continue
+		}
+		if d.Platform.OS == runtime.GOOS && d.Platform.Architecture == runtime.GOARCH {
+			return &platformIndex.Manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no image for %s/%s in the nested index", runtime.GOOS, runtime.GOARCH)
+}
+
+// blobPath returns the path of the blob with the given digest.
+func (l *ociLayout) blobPath(digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" {
+		return "", fmt.Errorf("unsupported digest %q, only sha256 digests are supported", digest)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil || len(parts[1]) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(l.dir, ociBlobsDir, parts[0], parts[1]), nil
+}
+
+// openBlob opens the blob described by desc. The digest of the blob is
+// checked when its end is read.
+func (l *ociLayout) openBlob(desc *ociDescriptor) (io.ReadCloser, error) {
+	p, err := l.blobPath(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot open blob %q", desc.Digest), err)
+	}
+	return &digestReader{
+		f:      f,
+		h:      sha256.New(),
+		digest: desc.Digest,
+	}, nil
+}
+
+// readJSONBlob reads the blob described by desc and parses it in v.
+func (l *ociLayout) readJSONBlob(desc *ociDescriptor, v interface{}) error {
+	r, err := l.openBlob(desc)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// digestReader reads a blob, and fails at its end if its digest is
+// not the expected one.
+type digestReader struct {
+	f      *os.File
+	h      hash.Hash
+	digest string
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.f.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if d := "sha256:" + hex.EncodeToString(r.h.Sum(nil)); d != r.digest {
+			return n, fmt.Errorf("blob %q has digest %q", r.digest, d)
+		}
+	}
+	return n, err
+}
+
+func (r *digestReader) Close() error {
+	return r.f.Close()
+}
+
+// extractOCIArchive extracts the regular files and the directories of
+// the OCI image layout archive at archivePath to dir.
+func extractOCIArchive(archivePath, dir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Clean the name as an absolute path, so that it does
+		// not escape dir.
+		p := filepath.Join(dir, filepath.Clean("/"+hdr.Name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ociImageManifest generates the manifest of the ACI converted from the
+// image with the given configuration. The name of the ACI is the base
+// name of the layout, with the name of the image as version label.
+func ociImageManifest(ref *ociRef, config *ociImageConfig) (*schema.ImageManifest, error) {
+	base := strings.TrimSuffix(filepath.Base(ref.Path), ".tar")
+	sanitized, err := types.SanitizeACIdentifier(base)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get an image name from %q", base), err)
+	}
+	name, err := types.NewACIdentifier(sanitized)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get an image name from %q", base), err)
+	}
+
+	im := schema.BlankImageManifest()
+	im.Name = *name
+
+	version := ref.Name
+	if version == "" {
+		version = "latest"
+	}
+	im.Labels = types.Labels{{Name: *types.MustACIdentifier("version"), Value: version}}
+	if config.OS != "" {
+		im.Labels = append(im.Labels, types.Label{Name: *types.MustACIdentifier("os"), Value: config.OS})
+	}
+	if config.Architecture != "" {
+		im.Labels = append(im.Labels, types.Label{Name: *types.MustACIdentifier("arch"), Value: config.Architecture})
+	}
+
+	if config.Author != "" {
+		im.Annotations.Set(*types.MustACIdentifier("authors"), config.Author)
+	}
+	if config.Created != nil {
+		im.Annotations.Set(*types.MustACIdentifier("created"), config.Created.Format(time.RFC3339))
+	}
+
+	c := config.Config
+	exec := append(append([]string{}, c.Entrypoint...), c.Cmd...)
+	if len(exec) == 0 {
+		// no app, the image can only be used as a dependency
+		return im, nil
+	}
+
+	user, group := parseOCIUser(c.User)
+	app := &types.App{
+		Exec:             exec,
+		User:             user,
+		Group:            group,
+		WorkingDirectory: c.WorkingDir,
+	}
+	for _, e := range c.Env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid environment variable %q", e)
+		}
+		app.Environment.Set(parts[0], parts[1])
+	}
+	if app.Ports, err = ociPorts(c.ExposedPorts); err != nil {
+		return nil, err
+	}
+	if app.MountPoints, err = ociMountPoints(c.Volumes); err != nil {
+		return nil, err
+	}
+	im.App = app
+
+	return im, nil
+}
+
+// parseOCIUser parses the user of an OCI image configuration, in the
+// form user[:group], into an ACI user and group. The user and the group
+// default to root.
+func parseOCIUser(ociUser string) (string, string) {
+	user, group := "0", "0"
+	parts := strings.SplitN(ociUser, ":", 2)
+	if parts[0] != "" {
+		user = parts[0]
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		group = parts[1]
+	}
+	return user, group
+}
+
+// ociPorts converts the exposed ports of an OCI image configuration, in
+// the form port[/protocol], to ACI ports sorted by name.
+func ociPorts(exposedPorts map[string]struct{}) ([]types.Port, error) {
+	var ports []types.Port
+	for ep := range exposedPorts {
+		portStr, proto := ep, "tcp"
+		if i := strings.Index(ep, "/"); i >= 0 {
+			portStr, proto = ep[:i], ep[i+1:]
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid exposed port %q", ep), err)
+		}
+		name, err := types.SanitizeACName(fmt.Sprintf("%d-%s", port, proto))
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid exposed port %q", ep), err)
+		}
+		ports = append(ports, types.Port{
+			Name:     *types.MustACName(name),
+			Protocol: proto,
+			Port:     uint(port),
+			Count:    1,
+		})
+	}
+	sort.Sort(portsByName(ports))
+	return ports, nil
+}
+
+type portsByName []types.Port
+
+func (p portsByName) Len() int           { return len(p) }
+func (p portsByName) Less(i, j int) bool { return p[i].Name.String() < p[j].Name.String() }
+func (p portsByName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// ociMountPoints converts the volumes of an OCI image configuration to
+// ACI mount points sorted by path.
+func ociMountPoints(volumes map[string]struct{}) ([]types.MountPoint, error) {
+	var paths []string
+	for p := range volumes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var mps []types.MountPoint
+	seen := make(map[string]int)
+	for _, p := range paths {
+		name, err := types.SanitizeACName(path.Join("volume", p))
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid volume %q", p), err)
+		}
+		if n, ok := seen[name]; ok {
+			seen[name] = n + 1
+			name = fmt.Sprintf("%s-%d", name, n)
+		} else {
+			seen[name] = 1
+		}
+		mps = append(mps, types.MountPoint{
+			Name: *types.MustACName(name),
+			Path: p,
+		})
+	}
+	return mps, nil
+}
+
+// ociLayerReader reads the uncompressed content of a layer.
+type ociLayerReader struct {
+	*tar.Reader
+	blob io.ReadCloser
+	br   *bufio.Reader
+}
+
+// openOCILayer opens the layer described by desc, decompressing it if
+// it is gzipped.
+func openOCILayer(layout *ociLayout, desc *ociDescriptor) (*ociLayerReader, error) {
+	blob, err := layout.openBlob(desc)
+	if err != nil {
+		return nil, err
+	}
+	r := &ociLayerReader{
+		blob: blob,
+		br:   bufio.NewReader(blob),
+	}
+	magic, err := r.br.Peek(2)
+	if err != nil && err != io.EOF {
+		blob.Close()
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(r.br)
+		if err != nil {
+			blob.Close()
+			return nil, err
+		}
+		r.Reader = tar.NewReader(gr)
+	} else {
+		r.Reader = tar.NewReader(r.br)
+	}
+	return r, nil
+}
+
+// drain reads the layer to its end, so that its digest is checked.
+func (r *ociLayerReader) drain() error {
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.Copy(ioutil.Discard, r.br)
+	return err
+}
+
+func (r *ociLayerReader) Close() error {
+	return r.blob.Close()
+}
+
+// ociLayersIndex tells which layer provides each file of the squashed
+// image, applying the whiteouts of the upper layers.
+type ociLayersIndex struct {
+	// providers maps the paths of the files to the layer they
+	// come from.
+	providers map[string]int
+	// whiteouts maps the removed paths to the highest layer
+	// removing them.
+	whiteouts map[string]int
+	// opaques maps the directories whose content is hidden to the
+	// highest layer hiding it.
+	opaques map[string]int
+}
+
+func newOCILayersIndex() *ociLayersIndex {
+	return &ociLayersIndex{
+		providers: make(map[string]int),
+		whiteouts: make(map[string]int),
+		opaques:   make(map[string]int),
+	}
+}
+
+// add adds the file at name of the layer, the layers must be added
+// from the top one.
+func (idx *ociLayersIndex) add(name string, layer int) {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+	switch {
+	case base == ociWhiteoutOpaque:
+		if _, ok := idx.opaques[dir]; !ok {
+			idx.opaques[dir] = layer
+		}
+		return
+	case strings.HasPrefix(base, ociWhiteoutPrefix):
+		target := path.Join(dir, strings.TrimPrefix(base, ociWhiteoutPrefix))
+		if _, ok := idx.whiteouts[target]; !ok {
+			idx.whiteouts[target] = layer
+		}
+		return
+	}
+
+	if idx.hidden(name, layer) {
+		return
+	}
+	if _, ok := idx.providers[name]; !ok {
+		idx.providers[name] = layer
+	}
+}
+
+// hidden tells whether the file at name of the layer is removed by an
+// upper layer.
+func (idx *ociLayersIndex) hidden(name string, layer int) bool {
+	if l, ok := idx.whiteouts[name]; ok && l > layer {
+		return true
+	}
+	for p := path.Dir(name); ; p = path.Dir(p) {
+		if l, ok := idx.whiteouts[p]; ok && l > layer {
+			return true
+		}
+		if l, ok := idx.opaques[p]; ok && l > layer {
+			return true
+		}
+		if p == "/" {
+			return false
+		}
+	}
+}
+
+// provides tells whether the file at name comes from the layer.
+func (idx *ociLayersIndex) provides(name string, layer int) bool {
+	l, ok := idx.providers[name]
+	return ok && l == layer
+}
+
+// ociLayerPath returns the absolute path of a file in a layer.
+func ociLayerPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// squashOCILayers writes an ACI with the manifest im and the squashed
+// layers to w.
+func squashOCILayers(layout *ociLayout, layers []ociDescriptor, im schema.ImageManifest, w io.Writer) error {
+	// First pass, from the top layer: find the layer providing
+	// each file.
+	idx := newOCILayersIndex()
+	for i := len(layers) - 1; i >= 0; i-- {
+		lr, err := openOCILayer(layout, &layers[i])
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot open layer %q", layers[i].Digest), err)
+		}
+		err = func() error {
+			defer lr.Close()
+			for {
+				hdr, err := lr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				idx.add(ociLayerPath(hdr.Name), i)
+			}
+			return lr.drain()
+		}()
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot read layer %q", layers[i].Digest), err)
+		}
+	}
+
+	// Second pass, from the bottom layer: write the files, so
+	// that the targets of the hard links are written before them.
+	tw := tar.NewWriter(w)
+	aw := aci.NewImageWriter(im, tw)
+	rootfs := &tar.Header{
+		Name:     aci.RootfsDir,
+		Mode:     0755,
+		Typeflag: tar.TypeDir,
+		ModTime:  time.Now(),
+	}
+	if err := aw.AddFile(rootfs, nil); err != nil {
+		return err
+	}
+	written := make(map[string]struct{})
+	for i := range layers {
+		lr, err := openOCILayer(layout, &layers[i])
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot open layer %q", layers[i].Digest), err)
+		}
+		err = func() error {
+			defer lr.Close()
+			for {
+				hdr, err := lr.Next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				name := ociLayerPath(hdr.Name)
+				if name == "/" || !idx.provides(name, i) {
+					continue
+				}
+				if _, ok := written[name]; ok {
+					continue
+				}
+				written[name] = struct{}{}
+
+				hdr.Name = path.Join(aci.RootfsDir, name)
+				if hdr.Typeflag == tar.TypeLink {
+					hdr.Linkname = path.Join(aci.RootfsDir, ociLayerPath(hdr.Linkname))
+				}
+				if err := aw.AddFile(hdr, lr); err != nil {
+					return err
+				}
+			}
+		}()
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot write layer %q", layers[i].Digest), err)
+		}
+	}
+
+	// Close writes the manifest and closes the tar writer.
+	return aw.Close()
+}
@@ -0,0 +1,468 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestParseOCIRef(t *testing.T) {
+	tests := []struct {
+		img string
+		ref *ociRef
+	}{
+		{
+			img: "oci-layout:/var/lib/images/busybox",
+			ref: &ociRef{Path: "/var/lib/images/busybox"},
+		},
+		{
+			img: "oci-layout:/var/lib/images/busybox:1.0",
+			ref: &ociRef{Path: "/var/lib/images/busybox", Name: "1.0"},
+		},
+		{
+			img: "oci-archive:/var/lib/images/busybox.tar:latest",
+			ref: &ociRef{Archive: true, Path: "/var/lib/images/busybox.tar", Name: "latest"},
+		},
+		{
+			// the colon is a part of the path
+			img: "oci-layout:/var/lib/images:old/busybox",
+			ref: &ociRef{Path: "/var/lib/images:old/busybox"},
+		},
+		{
+			img: "oci-layout:",
+			ref: nil,
+		},
+		{
+			img: "oci-layout::1.0",
+			ref: nil,
+		},
+		{
+			img: "docker://busybox",
+			ref: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		ref, err := parseOCIRef(tt.img)
+		if tt.ref == nil {
+			if err == nil {
+				t.Errorf("expected %q to be invalid, got %+v", tt.img, ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", tt.img, err)
+			continue
+		}
+		if !reflect.DeepEqual(ref, tt.ref) {
+			t.Errorf("expected %q to be parsed as %+v, got %+v", tt.img, tt.ref, ref)
+		}
+		if ref.String() != tt.img {
+			t.Errorf("expected %+v to be printed as %q, got %q", ref, tt.img, ref.String())
+		}
+	}
+}
+
+type testOCIFile struct {
+	name     string
+	typeflag byte
+	contents string
+	linkname string
+}
+
+// writeTestOCIBlob writes data in the blobs of the layout and returns its
+// descriptor.
+func writeTestOCIBlob(t *testing.T, dir, mediaType string, data []byte) ociDescriptor {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	blobsDir := filepath.Join(dir, ociBlobsDir, "sha256")
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		t.Fatalf("cannot create blobs directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(blobsDir, digest), data, 0644); err != nil {
+		t.Fatalf("cannot write blob: %v", err)
+	}
+	return ociDescriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + digest,
+		Size:      int64(len(data)),
+	}
+}
+
+func writeTestOCIJSONBlob(t *testing.T, dir, mediaType string, v interface{}) ociDescriptor {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("cannot marshal blob: %v", err)
+	}
+	return writeTestOCIBlob(t, dir, mediaType, data)
+}
+
+func newTestOCILayer(t *testing.T, files []testOCIFile, compress bool) []byte {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(&buf)
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:     f.name,
+			Typeflag: f.typeflag,
+			Linkname: f.linkname,
+			Mode:     0644,
+			Size:     int64(len(f.contents)),
+		}
+		if f.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("cannot write layer: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.contents)); err != nil {
+			t.Fatalf("cannot write layer: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("cannot write layer: %v", err)
+	}
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			t.Fatalf("cannot write layer: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// newTestOCILayout writes an OCI image layout with an image named "1.0" in
+// dir. It returns the descriptor of its image manifest.
+func newTestOCILayout(t *testing.T, dir string) ociDescriptor {
+	layers := []ociDescriptor{
+		writeTestOCIBlob(t, dir, "application/vnd.oci.image.layer.v1.tar+gzip", newTestOCILayer(t, []testOCIFile{
+			{name: "./", typeflag: tar.TypeDir},
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/passwd", typeflag: tar.TypeReg, contents: "root"},
+			{name: "etc/hosts", typeflag: tar.TypeReg, contents: "localhost"},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/old", typeflag: tar.TypeReg, contents: "old"},
+			{name: "var/lib/a/1", typeflag: tar.TypeReg, contents: "1"},
+			{name: "var/lib/ab", typeflag: tar.TypeReg, contents: "ab"},
+		}, true)),
+		writeTestOCIBlob(t, dir, "application/vnd.oci.image.layer.v1.tar", newTestOCILayer(t, []testOCIFile{
+			{name: "etc/passwd", typeflag: tar.TypeReg, contents: "root\nuser"},
+			{name: "etc/.wh.hosts", typeflag: tar.TypeReg},
+			{name: "etc/passwd-", typeflag: tar.TypeLink, linkname: "etc/passwd"},
+			{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opt/new", typeflag: tar.TypeReg, contents: "new"},
+			{name: "var/lib/.wh.a", typeflag: tar.TypeReg},
+		}, false)),
+	}
+
+	config := &ociImageConfig{
+		Architecture: "amd64",
+		OS:           "linux",
+	}
+	config.Config.User = "1000:100"
+	config.Config.Env = []string{"PATH=/bin", "FOO=bar=baz"}
+	config.Config.Entrypoint = []string{"/bin/app"}
+	config.Config.Cmd = []string{"--flag"}
+	config.Config.WorkingDir = "/var"
+	config.Config.ExposedPorts = map[string]struct{}{"80/tcp": {}, "53/udp": {}}
+
+	manifest := &ociManifest{
+		SchemaVersion: 2,
+		Config:        writeTestOCIJSONBlob(t, dir, "application/vnd.oci.image.config.v1+json", config),
+		Layers:        layers,
+	}
+	desc := writeTestOCIJSONBlob(t, dir, ociMediaTypeManifest, manifest)
+	desc.Annotations = map[string]string{ociRefNameAnnotation: "1.0"}
+
+	index := &ociIndex{
+		SchemaVersion: 2,
+		Manifests:     []ociDescriptor{desc},
+	}
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		t.Fatalf("cannot marshal index: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ociIndexFile), indexBytes, 0644); err != nil {
+		t.Fatalf("cannot write index: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ociLayoutFile), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("cannot write layout file: %v", err)
+	}
+
+	return desc
+}
+
+// readTestACIRootfs returns the files of the rootfs of the image, with
+// the contents of the regular files and the targets of the links.
+func readTestACIRootfs(t *testing.T, s *store.Store, key string) map[string]string {
+	rs, err := s.ReadStream(key)
+	if err != nil {
+		t.Fatalf("cannot read image: %v", err)
+	}
+	defer rs.Close()
+
+	files := make(map[string]string)
+	tr := tar.NewReader(rs)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("cannot read image: %v", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("cannot read image: %v", err)
+			}
+			files[hdr.Name] = string(b)
+		case tar.TypeLink:
+			files[hdr.Name] = "link:" + hdr.Linkname
+		default:
+			files[hdr.Name] = ""
+		}
+	}
+	return files
+}
+
+func TestOCIFetcher(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-oci-fetcher-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s, err := store.NewStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer s.Close()
+
+	layoutDir := filepath.Join(tmpDir, "busybox")
+	desc := newTestOCILayout(t, layoutDir)
+
+	sf, err := rktflag.NewSecFlags("image")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	f := &ociFetcher{
+		InsecureFlags: sf,
+		S:             s,
+	}
+
+	img := "oci-layout:" + layoutDir + ":1.0"
+	key, err := f.GetHash(img)
+	if err != nil {
+		t.Fatalf("cannot fetch %q: %v", img, err)
+	}
+
+	im, err := s.GetImageManifest(key)
+	if err != nil {
+		t.Fatalf("cannot get image manifest: %v", err)
+	}
+	if im.Name.String() != "busybox" {
+		t.Errorf("expected image name %q, got %q", "busybox", im.Name)
+	}
+	expectedLabels := map[types.ACIdentifier]string{
+		"version": "1.0",
+		"os":      "linux",
+		"arch":    "amd64",
+	}
+	if labels := im.Labels.ToMap(); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, labels)
+	}
+	if im.App == nil {
+		t.Fatalf("expected an app in the image manifest")
+	}
+	if exec := []string(im.App.Exec); !reflect.DeepEqual(exec, []string{"/bin/app", "--flag"}) {
+		t.Errorf("unexpected exec %v", exec)
+	}
+	if im.App.User != "1000" || im.App.Group != "100" {
+		t.Errorf("expected user 1000 and group 100, got %q and %q", im.App.User, im.App.Group)
+	}
+	if im.App.WorkingDirectory != "/var" {
+		t.Errorf("expected working directory %q, got %q", "/var", im.App.WorkingDirectory)
+	}
+	if v, ok := im.App.Environment.Get("FOO"); !ok || v != "bar=baz" {
+		t.Errorf("expected FOO=bar=baz in the environment, got %v", im.App.Environment)
+	}
+	expectedPorts := []types.Port{
+		{Name: "53-udp", Protocol: "udp", Port: 53, Count: 1},
+		{Name: "80-tcp", Protocol: "tcp", Port: 80, Count: 1},
+	}
+	if !reflect.DeepEqual(im.App.Ports, expectedPorts) {
+		t.Errorf("expected ports %v, got %v", expectedPorts, im.App.Ports)
+	}
+
+	expectedFiles := map[string]string{
+		"rootfs":             "",
+		"rootfs/etc":         "",
+		"rootfs/etc/passwd":  "root\nuser",
+		"rootfs/etc/passwd-": "link:rootfs/etc/passwd",
+		"rootfs/opt":         "",
+		"rootfs/opt/new":     "new",
+		"rootfs/var/lib/ab":  "ab",
+		"manifest":           "",
+	}
+	files := readTestACIRootfs(t, s, key)
+	// the manifest is checked above
+	files["manifest"] = ""
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, files)
+	}
+
+	// The remote is used by the next fetches.
+	rem, ok, err := s.GetRemote(img + "@" + desc.Digest)
+	if err != nil || !ok {
+		t.Fatalf("expected a remote for %q, got %v", img, err)
+	}
+	if rem.BlobKey != key {
+		t.Errorf("expected the remote to point to %q, got %q", key, rem.BlobKey)
+	}
+	if key2, err := f.GetHash(img); err != nil || key2 != key {
+		t.Errorf("expected to get %q from the store, got %q, %v", key, key2, err)
+	}
+
+	// The image is not found if the name is wrong.
+	if _, err := f.GetHash("oci-layout:" + layoutDir + ":2.0"); err == nil {
+		t.Errorf("expected an error fetching a missing image")
+	}
+
+	// The same image in an archive, the name can be omitted as the
+	// index contains only one image.
+	archive := filepath.Join(tmpDir, "busybox.tar")
+	writeTestOCIArchive(t, layoutDir, archive)
+	key, err = f.GetHash("oci-archive:" + archive)
+	if err != nil {
+		t.Fatalf("cannot fetch archive %q: %v", archive, err)
+	}
+	files = readTestACIRootfs(t, s, key)
+	files["manifest"] = ""
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %v in the image of the archive, got %v", expectedFiles, files)
+	}
+}
+
+func TestOCIFetcherCorruptedBlob(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-oci-fetcher-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s, err := store.NewStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer s.Close()
+
+	layoutDir := filepath.Join(tmpDir, "busybox")
+	desc := newTestOCILayout(t, layoutDir)
+
+	layout, err := newOCILayout(layoutDir, "")
+	if err != nil {
+		t.Fatalf("cannot open layout: %v", err)
+	}
+	manifest := &ociManifest{}
+	if err := layout.readJSONBlob(&desc, manifest); err != nil {
+		t.Fatalf("cannot read manifest: %v", err)
+	}
+	p, err := layout.blobPath(manifest.Layers[1].Digest)
+	if err != nil {
+		t.Fatalf("cannot get layer path: %v", err)
+	}
+	if err := ioutil.WriteFile(p, newTestOCILayer(t, []testOCIFile{
+		{name: "etc/shadow", typeflag: tar.TypeReg, contents: "root"},
+	}, false), 0644); err != nil {
+		t.Fatalf("cannot corrupt layer: %v", err)
+	}
+
+	sf, err := rktflag.NewSecFlags("image")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	f := &ociFetcher{
+		InsecureFlags: sf,
+		S:             s,
+	}
+	if _, err := f.GetHash("oci-layout:" + layoutDir); err == nil {
+		t.Errorf("expected an error fetching an image with a corrupted layer")
+	}
+}
+
+func writeTestOCIArchive(t *testing.T, dir, archive string) {
+	out, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("cannot create archive: %v", err)
+	}
+	defer out.Close()
+
+	tw := tar.NewWriter(out)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if _, err := tw.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot write archive: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("cannot write archive: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/rkt/rkt/config"
+	"golang.org/x/crypto/openpgp"
+)
+
+// signaturePolicy holds the signature policy rules of the
+// configuration, per image name prefix. The images without a rule
+// must be signed by a trusted key. The policy decisions are logged
+// for auditing.
+type signaturePolicy map[string]config.SignaturePolicyRule
+
+// getRule returns the rule with the longest prefix matching the image
+// name, and its prefix. The prefix is empty if no rule matches.
+func (p signaturePolicy) getRule(name string) (string, config.SignaturePolicyRule) {
+	prefix := ""
+	rule := config.SignaturePolicyRule{Action: config.PolicyRequireSignature}
+	for rulePrefix, r := range p {
+		if name != rulePrefix && !strings.HasPrefix(name, rulePrefix+"/") {
+			continue
+		}
+		if len(rulePrefix) > len(prefix) {
+			prefix, rule = rulePrefix, r
+		}
+	}
+	return prefix, rule
+}
+
+// CheckName returns an error if the images with the name are denied.
+func (p signaturePolicy) CheckName(name string) error {
+	prefix, rule := p.getRule(name)
+	if rule.Action == config.PolicyDeny {
+		log.Printf("policy: image %q denied by the rule for prefix %q", name, prefix)
+		return fmt.Errorf("image %q is denied by the signature policy for prefix %q", name, prefix)
+	}
+	return nil
+}
+
+// CheckSigner returns an error if the signer is not allowed to sign
+// the images with the name.
+func (p signaturePolicy) CheckSigner(name string, signer *openpgp.Entity) error {
+	prefix, rule := p.getRule(name)
+	fingerprint := fmt.Sprintf("%x", signer.PrimaryKey.Fingerprint)
+	if len(rule.Keys) == 0 {
+		if prefix != "" {
+			log.Printf("policy: image %q signed by key %s accepted by the rule for prefix %q", name, fingerprint, prefix)
+		}
+		return nil
+	}
+	for _, key := range rule.Keys {
+		if key == fingerprint {
+			log.Printf("policy: image %q signed by allowed key %s accepted by the rule for prefix %q", name, fingerprint, prefix)
+			return nil
+		}
+	}
+	log.Printf("policy: image %q signed by key %s rejected by the rule for prefix %q", name, fingerprint, prefix)
+	return fmt.Errorf("image %q is signed by key %s, which is not allowed by the signature policy for prefix %q", name, fingerprint, prefix)
+}
+
+// AllowUnsigned tells whether the unsigned images with the name are
+// allowed. Only the images from local paths can be allowed.
+func (p signaturePolicy) AllowUnsigned(name string, local bool) bool {
+	prefix, rule := p.getRule(name)
+	if rule.Action != config.PolicyAllowUnsignedLocal || !local {
+		return false
+	}
+	log.Printf("policy: unsigned local image %q accepted by the rule for prefix %q", name, prefix)
+	return true
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"testing"
+
+	"github.com/coreos/rkt/rkt/config"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestSignaturePolicy(t *testing.T) {
+	ensureLogger(false)
+	allowedKey := "8b86de38890ddb7291867b025210bd8888182190"
+	policy := signaturePolicy{
+		"example.com":         config.SignaturePolicyRule{Action: config.PolicyDeny},
+		"example.com/dev":     config.SignaturePolicyRule{Action: config.PolicyAllowUnsignedLocal},
+		"coreos.com":          config.SignaturePolicyRule{Action: config.PolicyRequireSignature, Keys: []string{allowedKey}},
+		"coreos.com/etcd-dev": config.SignaturePolicyRule{Action: config.PolicyRequireSignature},
+	}
+	allowedSigner := &openpgp.Entity{
+		PrimaryKey: &packet.PublicKey{
+			Fingerprint: [20]byte{0x8b, 0x86, 0xde, 0x38, 0x89, 0x0d, 0xdb, 0x72, 0x91, 0x86, 0x7b, 0x02, 0x52, 0x10, 0xbd, 0x88, 0x88, 0x18, 0x21, 0x90},
+		},
+	}
+	otherSigner := &openpgp.Entity{
+		PrimaryKey: &packet.PublicKey{
+			Fingerprint: [20]byte{0x84, 0xaa},
+		},
+	}
+
+	tests := []struct {
+		name string
+		// prefix is the prefix of the expected rule
+		prefix        string
+		denied        bool
+		allowedSigner bool
+		otherSigner   bool
+		localUnsigned bool
+	}{
+		{"example.com/app", "example.com", true, true, true, false},
+		{"example.com/dev/app", "example.com/dev", false, true, true, true},
+		{"example.com/development", "example.com", true, true, true, false},
+		{"coreos.com/etcd", "coreos.com", false, true, false, false},
+		{"coreos.com/etcd-dev", "coreos.com/etcd-dev", false, true, true, false},
+		{"quay.io/app", "", false, true, true, false},
+	}
+	for _, tt := range tests {
+		if prefix, _ := policy.getRule(tt.name); prefix != tt.prefix {
+			t.Errorf("%s: expected the rule for prefix %q, got %q", tt.name, tt.prefix, prefix)
+		}
+		if err := policy.CheckName(tt.name); (err != nil) != tt.denied {
+			t.Errorf("%s: expected denied %v, got error %v", tt.name, tt.denied, err)
+		}
+		if err := policy.CheckSigner(tt.name, allowedSigner); (err == nil) != tt.allowedSigner {
+			t.Errorf("%s: expected allowed key to be accepted %v, got error %v", tt.name, tt.allowedSigner, err)
+		}
+		if err := policy.CheckSigner(tt.name, otherSigner); (err == nil) != tt.otherSigner {
+			t.Errorf("%s: expected other key to be accepted %v, got error %v", tt.name, tt.otherSigner, err)
+		}
+		if allowed := policy.AllowUnsigned(tt.name, true); allowed != tt.localUnsigned {
+			t.Errorf("%s: expected unsigned local image to be allowed %v, got %v", tt.name, tt.localUnsigned, allowed)
+		}
+		if policy.AllowUnsigned(tt.name, false) {
+			t.Errorf("%s: expected unsigned remote image to be rejected", tt.name)
+		}
+	}
+}
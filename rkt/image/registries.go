@@ -0,0 +1,185 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coreos/rkt/rkt/config"
+	"github.com/hashicorp/errwrap"
+
+	"github.com/appc/spec/discovery"
+)
+
+// registriesTransport is an http.RoundTripper sending each request
+// with the proxy, the CA bundle and the client certificate configured
+// for the host of the request. Redirections to other hosts get the
+// settings of these hosts.
+type registriesTransport struct {
+	// Registries holds the registry settings of the hosts.
+	Registries map[string]config.RegistryData
+	// InsecureSkipTLSVerify tells whether TLS certificate
+	// validation should be skipped.
+	InsecureSkipTLSVerify bool
+	// Fallback, if not nil, is used instead of
+	// http.DefaultTransport for the hosts without settings. It
+	// takes care of skipping the TLS certificate validation
+	// itself.
+	Fallback http.RoundTripper
+
+	lock       sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+func (t *registriesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.getTransport(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+func (t *registriesTransport) getTransport(host string) (http.RoundTripper, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if transport, ok := t.transports[host]; ok {
+		return transport, nil
+	}
+	registry, ok := getRegistrySettings(t.Registries, host)
+	if !ok && t.Fallback != nil {
+		return t.Fallback, nil
+	}
+	if !ok && !t.InsecureSkipTLSVerify {
+		return http.DefaultTransport, nil
+	}
+	transport, err := newRegistryTransport(registry, t.InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("invalid registry settings for %q", host), err)
+	}
+	if t.transports == nil {
+		t.transports = make(map[string]http.RoundTripper)
+	}
+	t.transports[host] = transport
+	return transport, nil
+}
+
+// newRegistryTransport returns an HTTP transport using the proxy, the
+// CA bundle and the client certificate of the passed registry
+// settings. Without a proxy in the settings, the proxy is taken from
+// the environment, like with http.DefaultTransport.
+func newRegistryTransport(registry config.RegistryData, insecureSkipTLSVerify bool) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if registry.Proxy != "" {
+		u, err := url.Parse(registry.Proxy)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("invalid proxy URL"), err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipTLSVerify,
+	}
+	if registry.CABundle != "" {
+		pem, err := ioutil.ReadFile(registry.CABundle)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading the CA bundle"), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA bundle %q", registry.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if registry.ClientCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(registry.ClientCertificate, registry.ClientKey)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error loading the client certificate"), err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}, nil
+}
+
+// getRegistrySettings returns the settings of the host, which may include
+// a port. The settings of the host without the port are used when there
+// are none for the host and port.
+func getRegistrySettings(registries map[string]config.RegistryData, host string) (config.RegistryData, bool) {
+	if registry, ok := registries[host]; ok {
+		return registry, true
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		registry, ok := registries[hostname]
+		return registry, ok
+	}
+	return config.RegistryData{}, false
+}
+
+// getMirrorHost returns the mirror configured for the host, or the
+// host itself if there is none.
+func getMirrorHost(registries map[string]config.RegistryData, host string) string {
+	if registry, ok := getRegistrySettings(registries, host); ok && registry.Mirror != "" {
+		return registry.Mirror
+	}
+	return host
+}
+
+// getMirrorURL returns a copy of the URL with the host replaced by its
+// mirror, or the URL itself if the host has no mirror.
+func getMirrorURL(registries map[string]config.RegistryData, u *url.URL) *url.URL {
+	mirror := getMirrorHost(registries, u.Host)
+	if mirror == u.Host {
+		return u
+	}
+	mu := *u
+	mu.Host = mirror
+	return &mu
+}
+
+var discoveryRegistriesOnce sync.Once
+
+// useRegistriesForDiscovery makes the meta discovery of images and
+// public keys use the registry settings of the hosts. The discovery
+// package sends its requests with global clients, so only the settings
+// passed the first time are used, rkt having the same settings during
+// its whole run.
+func useRegistriesForDiscovery(registries map[string]config.RegistryData) {
+	if len(registries) == 0 {
+		return
+	}
+	discoveryRegistriesOnce.Do(func() {
+		discovery.Client.Transport = &registriesTransport{
+			Registries: registries,
+			Fallback:   discovery.Client.Transport,
+		}
+		discovery.ClientInsecureTls.Transport = &registriesTransport{
+			Registries:            registries,
+			InsecureSkipTLSVerify: true,
+			Fallback:              discovery.ClientInsecureTls.Transport,
+		}
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/rkt/rkt/config"
+)
+
+func TestRegistriesTransport(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hostname, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "rkt-registries-test-")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	caBundle := filepath.Join(dir, "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: ts.TLS.Certificates[0].Certificate[0]}
+	if err := ioutil.WriteFile(caBundle, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatalf("error writing the CA bundle: %v", err)
+	}
+
+	secureTransport := &http.Transport{}
+	insecureTransport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+
+	tests := []struct {
+		registries map[string]config.RegistryData
+		insecure   bool
+		fallback   http.RoundTripper
+		fail       bool
+	}{
+		// the test server certificate is not trusted by default
+		{nil, false, nil, true},
+		{nil, true, nil, false},
+		{map[string]config.RegistryData{u.Host: config.RegistryData{CABundle: caBundle}}, false, nil, false},
+		// the settings of the host apply to all its ports
+		{map[string]config.RegistryData{hostname: config.RegistryData{CABundle: caBundle}}, false, nil, false},
+		{map[string]config.RegistryData{"example.com": config.RegistryData{CABundle: caBundle}}, false, nil, true},
+		{map[string]config.RegistryData{u.Host: config.RegistryData{CABundle: filepath.Join(dir, "missing.pem")}}, false, nil, true},
+		// the fallback is used only for the hosts without
+		// settings, and skips the TLS certificate validation
+		// itself
+		{nil, false, insecureTransport, false},
+		{nil, true, secureTransport, true},
+		{map[string]config.RegistryData{u.Host: config.RegistryData{CABundle: caBundle}}, false, secureTransport, false},
+	}
+	for i, tt := range tests {
+		client := &http.Client{
+			Transport: &registriesTransport{
+				Registries:            tt.registries,
+				InsecureSkipTLSVerify: tt.insecure,
+				Fallback:              tt.fallback,
+			},
+		}
+		res, err := client.Get(ts.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		if tt.fail && err == nil {
+			t.Errorf("#%d: expected the request to fail", i)
+		} else if !tt.fail && err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestMirrorURL(t *testing.T) {
+	ensureLogger(false)
+	registries := map[string]config.RegistryData{
+		"example.com":          config.RegistryData{Mirror: "mirror.example.com:8080"},
+		"registry-1.docker.io": config.RegistryData{Mirror: "docker-mirror.example.com"},
+		"quay.io":              config.RegistryData{Proxy: "http://proxy.example.com:3128"},
+	}
+
+	urls := []struct {
+		in  string
+		out string
+	}{
+		{"https://example.com/images/app.aci", "https://mirror.example.com:8080/images/app.aci"},
+		// the settings of the host apply to all its ports
+		{"https://example.com:443/images/app.aci", "https://mirror.example.com:8080/images/app.aci"},
+		{"https://quay.io:5000/images/app.aci", "https://quay.io:5000/images/app.aci"},
+		{"https://quay.io/images/app.aci", "https://quay.io/images/app.aci"},
+	}
+	for _, tt := range urls {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out := getMirrorURL(registries, u).String(); out != tt.out {
+			t.Errorf("expected %q to be mirrored as %q, got %q", tt.in, tt.out, out)
+		}
+	}
+
+	f := &dockerFetcher{Registries: registries}
+	dockerURLs := []struct {
+		in  string
+		out string
+	}{
+		{"busybox", "docker-mirror.example.com/library/busybox:latest"},
+		{"registry-1.docker.io/coreos/etcd:v2.3.0", "docker-mirror.example.com/coreos/etcd:v2.3.0"},
+		{"quay.io/coreos/etcd:v2.3.0", "quay.io/coreos/etcd:v2.3.0"},
+	}
+	for _, tt := range dockerURLs {
+		if out := f.getMirrorURL(tt.in); out != tt.out {
+			t.Errorf("expected %q to be mirrored as %q, got %q", tt.in, tt.out, out)
+		}
+	}
+}
@@ -15,7 +15,6 @@
 package image
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/coreos/rkt/rkt/config"
 	"github.com/coreos/rkt/version"
 )
 
@@ -57,6 +57,9 @@ type resumableSession struct {
 	// InsecureSkipTLSVerify tells whether TLS certificate
 	// validation should be skipped.
 	InsecureSkipTLSVerify bool
+	// Registries holds the network settings of the hosts, like
+	// proxies or CA bundles.
+	Registries map[string]config.RegistryData
 	// Headers are HTTP headers to be added to the HTTP
 	// request. Used for authentication.
 	Headers http.Header
@@ -272,11 +275,9 @@ func (s *resumableSession) reset() error {
 }
 
 func (s *resumableSession) getClient() *http.Client {
-	transport := http.DefaultTransport
-	if s.InsecureSkipTLSVerify {
-		transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
+	transport := &registriesTransport{
+		Registries:            s.Registries,
+		InsecureSkipTLSVerify: s.InsecureSkipTLSVerify,
 	}
 
 	return &http.Client{
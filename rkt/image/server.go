@@ -0,0 +1,430 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coreos/rkt/pkg/keystore"
+	"github.com/coreos/rkt/rkt/config"
+	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+const (
+	// serverImagesPath is the path under which the ACIs and their
+	// signatures are served. Image names can't start with an
+	// underscore, so it does not clash with discovery requests.
+	serverImagesPath = "/_images/"
+	// serverPubkeysPath is the path of the public keys.
+	serverPubkeysPath = "/_pubkeys.gpg"
+)
+
+// Server is an http.Handler publishing the images of the store over
+// the appc meta discovery. It serves the discovery templates, the
+// ACIs, their signatures and the keys trusted for their names.
+//
+// The signatures are not kept in the store, so they are downloaded
+// again from where the images were fetched, and kept in memory.
+type Server struct {
+	// S is the store the images are served from.
+	S *store.Store
+	// Ks is the keystore the public keys are served from. No keys
+	// are served if it is nil.
+	Ks *keystore.Keystore
+	// Headers are the headers used when downloading the
+	// signatures, per host.
+	Headers map[string]config.Headerer
+	// Registries holds the network settings used when downloading
+	// the signatures, per host.
+	Registries map[string]config.RegistryData
+	// InsecureFlags are the security options used when
+	// downloading the signatures.
+	InsecureFlags *rktflag.SecFlags
+	// Auth are the credentials required from the clients, per
+	// host. The clients are expected to use the same headers as
+	// rkt does with the auth configuration. No credentials are
+	// required if it is empty.
+	Auth map[string]config.Headerer
+	// Debug tells whether the requests should be logged.
+	Debug bool
+
+	sigsLock sync.Mutex
+	sigs     map[string]*sigCall
+}
+
+// sigCall is a signature download, done once per image and shared by
+// all the requests for the signature of that image.
+type sigCall struct {
+	done chan struct{}
+	sig  []byte
+	err  error
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ensureLogger(s.Debug)
+	if s.Debug {
+		log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL)
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="rkt"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, serverImagesPath):
+		s.serveImage(w, r)
+	case r.URL.Path == serverPubkeysPath:
+		s.servePubkeys(w, r)
+	default:
+		s.serveDiscovery(w, r)
+	}
+}
+
+// authorized checks the Authorization header of the request against
+// the credentials of the requested host.
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.Auth) == 0 {
+		return true
+	}
+	h, ok := s.Auth[r.Host]
+	if !ok {
+		h, ok = s.Auth[requestHostname(r)]
+	}
+	if !ok {
+		return false
+	}
+	expected := h.Header().Get("Authorization")
+	got := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(got)) == 1
+}
+
+// serveDiscovery serves the meta discovery templates for the requested
+// name, made of the requested host and path.
+func (s *Server) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSuffix(requestHostname(r)+r.URL.Path, "/")
+	if _, err := types.NewACIdentifier(prefix); err != nil {
+		http.Error(w, fmt.Sprintf("invalid image name %q", prefix), http.StatusNotFound)
+		return
+	}
+	base := requestBaseURL(r)
+	templates := []string{
+		base + serverImagesPath + "{name}.{ext}?version={version}&os={os}&arch={arch}",
+		base + serverImagesPath + "{name}.{ext}?version={version}",
+	}
+	var lines []string
+	for _, tpl := range templates {
+		lines = append(lines, fmt.Sprintf(`<meta name="ac-discovery" content="%s">`, html.EscapeString(prefix+" "+tpl)))
+	}
+	if s.Ks != nil {
+		pubkeys := base + serverPubkeysPath + "?prefix=" + url.QueryEscape(prefix)
+		lines = append(lines, fmt.Sprintf(`<meta name="ac-discovery-pubkeys" content="%s">`, html.EscapeString(prefix+" "+pubkeys)))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n%s\n</head>\n</html>\n", strings.Join(lines, "\n"))
+}
+
+// serveImage serves the ACI or the signature of the image matching the
+// requested name and labels.
+func (s *Server) serveImage(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, serverImagesPath)
+	isAsc := false
+	switch {
+	case strings.HasSuffix(name, ".aci.asc"):
+		name = strings.TrimSuffix(name, ".aci.asc")
+		isAsc = true
+	case strings.HasSuffix(name, ".aci"):
+		name = strings.TrimSuffix(name, ".aci")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	acname, labels, err := parseImageRequest(name, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	key, err := s.S.GetACI(*acname, labels)
+	if _, ok := err.(store.ACINotFoundError); ok {
+		key, err = s.findPortableImage(*acname, labels, err)
+	}
+	if err != nil {
+		if _, ok := err.(store.ACINotFoundError); ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.serverError(w, fmt.Sprintf("cannot find image %q", name), err)
+		return
+	}
+	if isAsc {
+		s.serveSignature(w, r, key)
+	} else {
+		s.serveACI(w, r, key)
+	}
+}
+
+// findPortableImage looks for an image without os and arch labels, which
+// runs on any os and arch, matching the other labels. It returns
+// notFoundErr if there is none.
+func (s *Server) findPortableImage(name types.ACIdentifier, labels types.Labels, notFoundErr error) (string, error) {
+	var otherLabels types.Labels
+	for _, l := range labels {
+		if l.Name != "os" && l.Name != "arch" {
+			otherLabels = append(otherLabels, l)
+		}
+	}
+	if len(otherLabels) == len(labels) {
+		return "", notFoundErr
+	}
+	key, err := s.S.GetACI(name, otherLabels)
+	if err != nil {
+		return "", err
+	}
+	im, err := s.S.GetImageManifest(key)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := im.Labels.Get("os"); ok {
+		return "", notFoundErr
+	}
+	if _, ok := im.Labels.Get("arch"); ok {
+		return "", notFoundErr
+	}
+	return key, nil
+}
+
+// parseImageRequest returns the image name and the labels of a request,
+// the labels are passed in the query.
+func parseImageRequest(name string, query url.Values) (*types.ACIdentifier, types.Labels, error) {
+	acname, err := types.NewACIdentifier(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid image name %q", name)
+	}
+	var labels types.Labels
+	for labelName := range query {
+		value := query.Get(labelName)
+		// the discovery passes "latest" when no version was
+		// requested
+		if labelName == "version" && value == "latest" {
+			continue
+		}
+		acLabelName, err := types.NewACIdentifier(labelName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid label name %q", labelName)
+		}
+		labels = append(labels, types.Label{Name: *acLabelName, Value: value})
+	}
+	return acname, labels, nil
+}
+
+func (s *Server) serveACI(w http.ResponseWriter, r *http.Request, key string) {
+	// the key is the hash of the ACI, so it is a strong etag
+	etag := strconv.Quote(key)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	aciinfo, err := s.S.GetACIInfoWithBlobKey(key)
+	if err != nil {
+		s.serverError(w, "cannot get image info", err)
+		return
+	}
+	rc, err := s.S.ReadStream(key)
+	if err != nil {
+		s.serverError(w, "cannot read image", err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(aciinfo.Size, 10))
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		log.PrintE(fmt.Sprintf("error serving image %s", key), err)
+	}
+}
+
+func (s *Server) serveSignature(w http.ResponseWriter, r *http.Request, key string) {
+	sig, err := s.getSignature(key)
+	if err != nil {
+		s.serverError(w, "cannot get signature", err)
+		return
+	}
+	if sig == nil {
+		http.Error(w, fmt.Sprintf("no signature available for image %s", key), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pgp-signature")
+	w.Header().Set("Content-Length", strconv.Itoa(len(sig)))
+	if r.Method == "HEAD" {
+		return
+	}
+	w.Write(sig)
+}
+
+// getSignature returns the signature of the image with key, downloading
+// it from the remotes of the image the first time. It returns nil if no
+// signature is available. Concurrent requests for the same image wait
+// for a single download, without blocking the requests for other
+// images.
+func (s *Server) getSignature(key string) ([]byte, error) {
+	s.sigsLock.Lock()
+	if c, ok := s.sigs[key]; ok {
+		s.sigsLock.Unlock()
+		<-c.done
+		return c.sig, c.err
+	}
+	if s.sigs == nil {
+		s.sigs = make(map[string]*sigCall)
+	}
+	// remember the images without signature too, so the remotes
+	// are not tried again on every request
+	c := &sigCall{done: make(chan struct{})}
+	s.sigs[key] = c
+	s.sigsLock.Unlock()
+
+	c.sig, c.err = s.downloadSignature(key)
+	if c.err != nil {
+		// let the next request try again
+		s.sigsLock.Lock()
+		delete(s.sigs, key)
+		s.sigsLock.Unlock()
+	}
+	close(c.done)
+	return c.sig, c.err
+}
+
+// downloadSignature downloads the signature of the image with key
+// from the remotes of the image. It returns nil if no signature is
+// available.
+func (s *Server) downloadSignature(key string) ([]byte, error) {
+	remotes, err := s.S.GetAllRemotes()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get remotes"), err)
+	}
+	o := &httpOps{
+		InsecureSkipTLSVerify: s.InsecureFlags.SkipTLSCheck(),
+		S:                     s.S,
+		Headers:               s.Headers,
+		Registries:            s.Registries,
+		Debug:                 s.Debug,
+	}
+	var sigURLs []string
+	for _, rem := range remotes {
+		if rem.BlobKey == key {
+			sigURLs = append(sigURLs, rem.SigURL)
+		}
+	}
+	ascFile := o.DownloadSignatureFromURLs(key, sigURLs)
+	if ascFile == nil {
+		return nil, nil
+	}
+	defer ascFile.Close()
+	if _, err := ascFile.Seek(0, os.SEEK_SET); err != nil {
+		return nil, errwrap.Wrap(errors.New("error seeking signature"), err)
+	}
+	sig, err := ioutil.ReadAll(ascFile)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading signature"), err)
+	}
+	return sig, nil
+}
+
+// servePubkeys serves the armored keys trusted for the requested prefix.
+func (s *Server) servePubkeys(w http.ResponseWriter, r *http.Request) {
+	if s.Ks == nil {
+		http.NotFound(w, r)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	if _, err := types.NewACIdentifier(prefix); err != nil {
+		http.Error(w, fmt.Sprintf("invalid prefix %q", prefix), http.StatusNotFound)
+		return
+	}
+	keys, err := s.Ks.TrustedKeys(prefix)
+	if err != nil {
+		s.serverError(w, "cannot get trusted keys", err)
+		return
+	}
+	if len(keys) == 0 {
+		http.Error(w, fmt.Sprintf("no trusted keys for prefix %q", prefix), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pgp-keys")
+	if r.Method == "HEAD" {
+		return
+	}
+	if err := writeArmoredKeys(w, keys); err != nil {
+		log.PrintE("error serving public keys", err)
+	}
+}
+
+func writeArmoredKeys(w io.Writer, keys openpgp.EntityList) error {
+	aw, err := armor.Encode(w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := key.Serialize(aw); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+func (s *Server) serverError(w http.ResponseWriter, msg string, err error) {
+	log.PrintE(msg, err)
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+// requestHostname returns the requested host, without port.
+func requestHostname(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
+
+// requestBaseURL returns the URL of the server, as requested.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
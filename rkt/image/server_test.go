@@ -0,0 +1,282 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/pkg/aci"
+	"github.com/coreos/rkt/pkg/keystore"
+	"github.com/coreos/rkt/pkg/keystore/keystoretest"
+	"github.com/coreos/rkt/rkt/config"
+	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+func doTestServerRequest(t *testing.T, srv *httptest.Server, path string, headers http.Header) (*http.Response, []byte) {
+	req, err := http.NewRequest("GET", srv.URL+path, nil)
+	if err != nil {
+		t.Fatalf("cannot create request: %v", err)
+	}
+	// the images are served for the names of the requested host
+	req.Host = "example.com"
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cannot get %q: %v", path, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("cannot read the response to %q: %v", path, err)
+	}
+	return res, body
+}
+
+func TestServer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-server-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s, err := store.NewStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer s.Close()
+	key := writeTestBundleACI(t, s, tmpDir, "example.com/app", "")
+
+	ks, ksPath, err := keystore.NewTestKeystore()
+	if err != nil {
+		t.Fatalf("cannot create keystore: %v", err)
+	}
+	defer os.RemoveAll(ksPath)
+	trustedKey := keystoretest.KeyMap["example.com/app"]
+	if _, err := ks.StoreTrustedKeyPrefix("example.com/app", bytes.NewBufferString(trustedKey.ArmoredPublicKey)); err != nil {
+		t.Fatalf("cannot store trusted key: %v", err)
+	}
+
+	sf, err := rktflag.NewSecFlags("none")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	handler := &Server{
+		S:             s,
+		Ks:            ks,
+		InsecureFlags: sf,
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	res, body := doTestServerRequest(t, srv, "/app?ac-discovery=1", nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status of the discovery: %d", res.StatusCode)
+	}
+	expectedMeta := `<meta name="ac-discovery" content="example.com/app http://example.com/_images/{name}.{ext}?version={version}&amp;os={os}&amp;arch={arch}">`
+	if !strings.Contains(string(body), expectedMeta) {
+		t.Errorf("expected %q in the discovery, got %q", expectedMeta, body)
+	}
+	expectedMeta = `<meta name="ac-discovery-pubkeys" content="example.com/app http://example.com/_pubkeys.gpg?prefix=example.com%2Fapp">`
+	if !strings.Contains(string(body), expectedMeta) {
+		t.Errorf("expected %q in the discovery, got %q", expectedMeta, body)
+	}
+
+	aciPath := fmt.Sprintf("/_images/example.com/app.aci?version=latest&os=%s&arch=%s", runtime.GOOS, runtime.GOARCH)
+	res, body = doTestServerRequest(t, srv, aciPath, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status of the image: %d", res.StatusCode)
+	}
+	etag := res.Header.Get("ETag")
+	if etag != strconv.Quote(key) {
+		t.Errorf("expected etag %q, got %q", strconv.Quote(key), etag)
+	}
+	if aciinfo, err := s.GetACIInfoWithBlobKey(key); err != nil || aciinfo.Size != int64(len(body)) {
+		t.Errorf("unexpected size of the image: %d", len(body))
+	}
+	res, _ = doTestServerRequest(t, srv, aciPath, http.Header{"If-None-Match": []string{etag}})
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected the image not to be modified, got status %d", res.StatusCode)
+	}
+
+	// Images without os and arch labels are served for any os and arch
+	aciFile, err := aci.NewBasicACI(tmpDir, "example.com/portable")
+	if err != nil {
+		t.Fatalf("cannot create test ACI: %v", err)
+	}
+	defer aciFile.Close()
+	if _, err := s.WriteACI(aciFile, true); err != nil {
+		t.Fatalf("cannot write test ACI: %v", err)
+	}
+	portablePath := fmt.Sprintf("/_images/example.com/portable.aci?version=latest&os=%s&arch=%s", runtime.GOOS, runtime.GOARCH)
+	if res, _ := doTestServerRequest(t, srv, portablePath, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status of the portable image: %d", res.StatusCode)
+	}
+
+	notFound := []string{
+		"/_images/example.com/app.aci?os=plan9",
+		"/_images/example.com/app.aci?version=1.0",
+		"/_images/example.com/other.aci",
+		"/_images/example.com/app.tar",
+		// the image was not fetched from a remote with a signature
+		"/_images/example.com/app.aci.asc",
+		"/_pubkeys.gpg?prefix=example.com/other",
+	}
+	for _, path := range notFound {
+		if res, _ := doTestServerRequest(t, srv, path, nil); res.StatusCode != http.StatusNotFound {
+			t.Errorf("expected %q not to be found, got status %d", path, res.StatusCode)
+		}
+	}
+
+	res, body = doTestServerRequest(t, srv, "/_pubkeys.gpg?prefix=example.com/app", nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status of the public keys: %d", res.StatusCode)
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("cannot read public keys: %v", err)
+	}
+	if len(keys) != 1 || fmt.Sprintf("%x", keys[0].PrimaryKey.Fingerprint) != trustedKey.Fingerprint {
+		t.Errorf("expected the key %s to be served", trustedKey.Fingerprint)
+	}
+
+	// Require authentication
+	authDir := filepath.Join(tmpDir, "auth.d")
+	if err := os.MkdirAll(authDir, 0755); err != nil {
+		t.Fatalf("cannot create auth directory: %v", err)
+	}
+	authConf := `{
+		"rktKind": "auth",
+		"rktVersion": "v1",
+		"domains": ["example.com"],
+		"type": "basic",
+		"credentials": {"user": "bar", "password": "baz"}
+	}`
+	if err := ioutil.WriteFile(filepath.Join(authDir, "auth.json"), []byte(authConf), 0644); err != nil {
+		t.Fatalf("cannot write auth configuration: %v", err)
+	}
+	cfg, err := config.GetConfigFromDir(tmpDir)
+	if err != nil {
+		t.Fatalf("cannot read auth configuration: %v", err)
+	}
+	handler.Auth = cfg.AuthPerHost
+	if res, _ := doTestServerRequest(t, srv, aciPath, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the request without credentials to be refused, got status %d", res.StatusCode)
+	}
+	badAuth := http.Header{"Authorization": []string{"Basic YmFyOnF1eA=="}}
+	if res, _ := doTestServerRequest(t, srv, aciPath, badAuth); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the request with wrong credentials to be refused, got status %d", res.StatusCode)
+	}
+	if res, _ := doTestServerRequest(t, srv, aciPath, cfg.AuthPerHost["example.com"].Header()); res.StatusCode != http.StatusOK {
+		t.Errorf("expected the request with credentials to succeed, got status %d", res.StatusCode)
+	}
+}
+
+func TestServerSignatureDownload(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-server-test-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s, err := store.NewStore(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatalf("cannot open store: %v", err)
+	}
+	defer s.Close()
+	slowKey := writeTestBundleACI(t, s, tmpDir, "example.com/slow", "")
+	otherKey := writeTestBundleACI(t, s, tmpDir, "example.com/other", "")
+
+	// the remote of the slow image answers once released
+	var downloads int32
+	release := make(chan struct{})
+	sigSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		<-release
+		w.Write([]byte("signature"))
+	}))
+	defer sigSrv.Close()
+	rem := store.NewRemote(sigSrv.URL+"/slow.aci", sigSrv.URL+"/slow.aci.asc")
+	rem.BlobKey = slowKey
+	if err := s.WriteRemote(rem); err != nil {
+		t.Fatalf("cannot write remote: %v", err)
+	}
+
+	sf, err := rktflag.NewSecFlags("none")
+	if err != nil {
+		t.Fatalf("cannot create security flags: %v", err)
+	}
+	handler := &Server{
+		S:             s,
+		InsecureFlags: sf,
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	slowPath := fmt.Sprintf("/_images/example.com/slow.aci.asc?version=latest&os=%s&arch=%s", runtime.GOOS, runtime.GOARCH)
+	otherPath := fmt.Sprintf("/_images/example.com/other.aci.asc?version=latest&os=%s&arch=%s", runtime.GOOS, runtime.GOARCH)
+	var wg sync.WaitGroup
+	bodies := make([][]byte, 2)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, bodies[i] = doTestServerRequest(t, srv, slowPath, nil)
+		}(i)
+	}
+
+	// the signature of the other image is served while the slow one
+	// is being downloaded
+	done := make(chan struct{})
+	go func() {
+		if res, _ := doTestServerRequest(t, srv, otherPath, nil); res.StatusCode != http.StatusNotFound {
+			t.Errorf("expected the signature of %s not to be found, got status %d", otherKey, res.StatusCode)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the signature download of an image blocked the requests for another image")
+	}
+
+	close(release)
+	wg.Wait()
+	for _, body := range bodies {
+		if string(body) != "signature" {
+			t.Errorf("unexpected signature %q", body)
+		}
+	}
+	if n := atomic.LoadInt32(&downloads); n != 1 {
+		t.Errorf("expected the signature to be downloaded once, got %d downloads", n)
+	}
+}
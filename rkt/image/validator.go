@@ -102,3 +102,24 @@ func (v *validator) ValidateWithSignature(ks *keystore.Keystore, sig io.ReadSeek
 	}
 	return entity, nil
 }
+
+// ValidateWithPolicy verifies the image against a given signature file
+// like ValidateWithSignature, and checks that the image and its signer
+// are allowed by the signature policy.
+func (v *validator) ValidateWithPolicy(ks *keystore.Keystore, policy signaturePolicy, sig io.ReadSeeker) (*openpgp.Entity, error) {
+	if ks == nil {
+		return nil, nil
+	}
+	name := v.GetImageName()
+	if err := policy.CheckName(name); err != nil {
+		return nil, err
+	}
+	entity, err := v.ValidateWithSignature(ks, sig)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.CheckSigner(name, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
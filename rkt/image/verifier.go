@@ -0,0 +1,118 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Verifier verifies images against their signatures with the keys
+// trusted in the keystore.
+type Verifier action
+
+// Verification describes the trusted key which made the signature of
+// an image.
+type Verification struct {
+	// Name is the name of the image.
+	Name string
+	// Signer is the key which made the signature.
+	Signer *openpgp.Entity
+	// Prefix is the prefix the key is trusted for. It is empty
+	// for a root key.
+	Prefix string
+}
+
+// VerifyImage verifies the image against the signature.
+func (v *Verifier) VerifyImage(image, sig io.ReadSeeker) (*Verification, error) {
+	ensureLogger(v.Debug)
+	if v.Ks == nil {
+		return nil, errors.New("no keystore to verify the image with")
+	}
+	val, err := newValidator(image)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := val.ValidateWithPolicy(v.Ks, signaturePolicy(v.SignaturePolicy), sig)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("image %q verification failed", val.GetImageName()), err)
+	}
+	prefix, err := v.Ks.TrustedKeyPrefix(val.GetImageName(), signer.PrimaryKey.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &Verification{
+		Name:   val.GetImageName(),
+		Signer: signer,
+		Prefix: prefix,
+	}, nil
+}
+
+// VerifyStoreImage verifies the image of the store with key against
+// the signature. If sig is nil, the signature is downloaded from where
+// the image was fetched.
+func (v *Verifier) VerifyStoreImage(key string, sig io.ReadSeeker) (*Verification, error) {
+	ensureLogger(v.Debug)
+	if sig == nil {
+		ascFile, err := v.getStoreSignature(key)
+		if err != nil {
+			return nil, err
+		}
+		defer ascFile.Close()
+		sig = ascFile
+	}
+	rc, err := v.S.ReadStream(key)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading image"), err)
+	}
+	defer rc.Close()
+	aciFile, err := copyToTmpFile(v.S, rc)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTmpFile(aciFile)
+	return v.VerifyImage(aciFile, sig)
+}
+
+// getStoreSignature downloads the signature of the image with key from
+// the locations it was fetched from.
+func (v *Verifier) getStoreSignature(key string) (readSeekCloser, error) {
+	remotes, err := v.S.GetAllRemotes()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot get remotes"), err)
+	}
+	var sigURLs []string
+	for _, rem := range remotes {
+		if rem.BlobKey == key {
+			sigURLs = append(sigURLs, rem.SigURL)
+		}
+	}
+	o := &httpOps{
+		InsecureSkipTLSVerify: v.InsecureFlags.SkipTLSCheck(),
+		S:                     v.S,
+		Headers:               v.Headers,
+		Registries:            v.Registries,
+		Debug:                 v.Debug,
+	}
+	ascFile := o.DownloadSignatureFromURLs(key, sigURLs)
+	if ascFile == nil {
+		return nil, fmt.Errorf("no signature available for image %s (try --signature)", key)
+	}
+	return ascFile, nil
+}
@@ -17,8 +17,6 @@ package main
 import (
 	"encoding/json"
 
-	"github.com/coreos/rkt/store"
-
 	"github.com/spf13/cobra"
 )
 
@@ -43,7 +41,7 @@ func runImageCatManifest(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -17,6 +17,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/coreos/rkt/store"
 	"github.com/hashicorp/errwrap"
@@ -61,3 +62,10 @@ func getStoreKeyFromAppOrHash(s *store.Store, input string) (string, error) {
 	}
 	return key, nil
 }
+
+// isACIFile tells whether the input is a path to an ACI file rather
+// than a reference to a stored image.
+func isACIFile(input string) bool {
+	fi, err := os.Stat(input)
+	return err == nil && fi.Mode().IsRegular()
+}
@@ -19,8 +19,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/coreos/rkt/store"
-
 	"github.com/spf13/cobra"
 )
 
@@ -48,7 +46,7 @@ func runImageExport(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -0,0 +1,97 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/coreos/rkt/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageFsck = &cobra.Command{
+		Use:   "fsck",
+		Short: "Check the consistency of the local store",
+		Long: `Verifies the contents of the images, the image database, the cached image
+manifests and the rendered images in the tree store.
+
+With --repair, broken images are removed, broken rendered images are
+re-rendered and the leftovers of interrupted operations are removed. The
+rendered images used by pods are never changed.`,
+		Run: runWrapper(runImageFsck),
+	}
+	flagImageFsckRepair bool
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageFsck)
+	cmdImageFsck.Flags().BoolVar(&flagImageFsckRepair, "repair", false, "repair the problems found")
+}
+
+func runImageFsck(cmd *cobra.Command, args []string) (exit int) {
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	opts := store.FsckOptions{Repair: flagImageFsckRepair}
+	if flagImageFsckRepair {
+		// Take an exclusive lock to block other pods being created
+		// while the tree stores are repaired, like image gc does.
+		keyLock, err := lock.ExclusiveKeyLock(lockDir(), common.PrepareLock)
+		if err != nil {
+			stderr.PrintE("cannot get exclusive prepare lock", err)
+			return 1
+		}
+		defer keyLock.Close()
+		opts.KeepTreeStoreIDs, err = getReferencedTreeStoreIDs()
+		if err != nil {
+			stderr.PrintE("cannot get referenced treestoreIDs", err)
+			return 1
+		}
+	}
+
+	problems, err := s.Fsck(opts)
+	if err != nil {
+		stderr.PrintE("cannot check the store", err)
+		return 1
+	}
+
+	repaired := 0
+	for _, p := range problems {
+		stderr.Print(p.String())
+		if p.Repaired() {
+			repaired++
+		}
+	}
+	if len(problems) == 0 {
+		stderr.Print("no problems found")
+		return 0
+	}
+	if flagImageFsckRepair {
+		stderr.Printf("%d problem(s) found, %d repaired", len(problems), repaired)
+	} else {
+		stderr.Printf("%d problem(s) found, use --repair to repair them", len(problems))
+	}
+	if repaired < len(problems) {
+		stderr.Error(errors.New("the store is not consistent"))
+		return 1
+	}
+	return 0
+}
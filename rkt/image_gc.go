@@ -49,7 +49,7 @@ func init() {
 }
 
 func runGCImage(cmd *cobra.Command, args []string) (exit int) {
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -65,6 +65,15 @@ func runGCImage(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
+	removed, err := s.GCTreeStoreBlobs()
+	if err != nil {
+		stderr.PrintE("failed to remove unreferenced treestore blobs", err)
+		return 1
+	}
+	if removed > 0 {
+		stderr.Printf("removed %d unreferenced treestore blobs", removed)
+	}
+
 	return 0
 }
 
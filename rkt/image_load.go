@@ -0,0 +1,89 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/rkt/rkt/image"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageLoad = &cobra.Command{
+		Use:   "load BUNDLE_FILE",
+		Short: "Load the images of a bundle file into the local store",
+		Long: `BUNDLE_FILE is a bundle created by "rkt image save".
+
+The images are verified against their signatures in the bundle using the
+trusted keys of the local keystore, unless --insecure-options=image is
+passed. The hashes of the loaded images are printed.`,
+		Run: runWrapper(runImageLoad),
+	}
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageLoad)
+	cmdImageLoad.Flags().BoolVar(&flagFullHash, "full", false, "print the full image hashes after loading")
+}
+
+func runImageLoad(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 1
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	config, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return 1
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("unable to open bundle file %s", args[0]), err)
+		return 1
+	}
+	defer f.Close()
+
+	b := &image.Bundler{
+		S:               s,
+		Ks:              getKeystore(),
+		SignaturePolicy: config.SignaturePolicyPerPrefix,
+		InsecureFlags:   globalFlags.InsecureFlags,
+		Debug:           globalFlags.Debug,
+	}
+	keys, err := b.LoadImages(f)
+	if err != nil {
+		stderr.PrintE("cannot load bundle", err)
+		return 1
+	}
+	for _, key := range keys {
+		if !flagFullHash {
+			key = types.ShortHash(key)
+		}
+		stdout.Print(key)
+	}
+
+	return 0
+}
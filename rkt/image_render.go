@@ -22,7 +22,6 @@ import (
 
 	"github.com/coreos/rkt/pkg/fileutil"
 	"github.com/coreos/rkt/pkg/uid"
-	"github.com/coreos/rkt/store"
 
 	"github.com/spf13/cobra"
 )
@@ -57,7 +56,7 @@ func runImageRender(cmd *cobra.Command, args []string) (exit int) {
 	}
 	outputDir := args[1]
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -0,0 +1,106 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coreos/rkt/rkt/image"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageSave = &cobra.Command{
+		Use:   "save IMAGE... --output=BUNDLE_FILE",
+		Short: "Save stored images and their dependencies to a bundle file",
+		Long: `IMAGE should be a string referencing an image: either a hash or an image name.
+
+The bundle holds the images, their dependencies, their signatures and the
+locations they were fetched from. It can be loaded into another store with
+"rkt image load". The signatures are downloaded again from where the images
+were fetched, images without a reachable signature are saved unsigned.`,
+		Run: runWrapper(runImageSave),
+	}
+	flagImageSaveOutput    string
+	flagImageSaveOverwrite bool
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageSave)
+	cmdImageSave.Flags().StringVarP(&flagImageSaveOutput, "output", "o", "", "output bundle file")
+	cmdImageSave.Flags().BoolVar(&flagImageSaveOverwrite, "overwrite", false, "overwrite output bundle file")
+}
+
+func runImageSave(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) < 1 || flagImageSaveOutput == "" {
+		cmd.Usage()
+		return 1
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+	config, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return 1
+	}
+
+	mode := os.O_CREATE | os.O_WRONLY
+	if flagImageSaveOverwrite {
+		mode |= os.O_TRUNC
+	} else {
+		mode |= os.O_EXCL
+	}
+	f, err := os.OpenFile(flagImageSaveOutput, mode, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			stderr.Print("output bundle file exists (try --overwrite)")
+		} else {
+			stderr.PrintE(fmt.Sprintf("unable to open output bundle file %s", flagImageSaveOutput), err)
+		}
+		return 1
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			stderr.PrintE("error closing output bundle file", err)
+			exit = 1
+		}
+		if exit != 0 {
+			os.Remove(flagImageSaveOutput)
+		}
+	}()
+
+	b := &image.Bundler{
+		S:             s,
+		Headers:       config.AuthPerHost,
+		Registries:    config.RegistriesPerHost,
+		InsecureFlags: globalFlags.InsecureFlags,
+		Debug:         globalFlags.Debug,
+
+		WithDeps: true,
+	}
+	keys, err := b.SaveImages(args, f)
+	if err != nil {
+		stderr.PrintE("cannot save images", err)
+		return 1
+	}
+	stderr.Printf("saved %d image(s) to %s", len(keys), flagImageSaveOutput)
+
+	return 0
+}
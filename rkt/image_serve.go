@@ -0,0 +1,115 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/coreos/rkt/rkt/config"
+	"github.com/coreos/rkt/rkt/image"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageServe = &cobra.Command{
+		Use:   "serve [--listen=ADDR] [--tls-cert=FILE --tls-key=FILE]",
+		Short: "Serve the images of the local store over the meta discovery",
+		Long: `Publishes the images of the local store, so other hosts can fetch them by
+name. The server answers the meta discovery requests for any name, and serves
+the images, their signatures and the keys trusted for their names.
+
+The image names must resolve to the address of the server on the fetching
+hosts. The discovery uses the default HTTPS port, or the default HTTP port
+when fetching with --insecure-options=http.
+
+With --auth-config-dir, the clients have to authenticate with the credentials
+of the auth configuration files in the auth.d subdirectory, in the same format
+as the rkt configuration.`,
+		Run: runWrapper(runImageServe),
+	}
+	flagImageServeListen        string
+	flagImageServeTLSCert       string
+	flagImageServeTLSKey        string
+	flagImageServeAuthConfigDir string
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageServe)
+	cmdImageServe.Flags().StringVar(&flagImageServeListen, "listen", "", `address to listen on (default ":443" with TLS, ":80" without)`)
+	cmdImageServe.Flags().StringVar(&flagImageServeTLSCert, "tls-cert", "", "TLS certificate file")
+	cmdImageServe.Flags().StringVar(&flagImageServeTLSKey, "tls-key", "", "TLS private key file")
+	cmdImageServe.Flags().StringVar(&flagImageServeAuthConfigDir, "auth-config-dir", "", "directory with the credentials required from the clients")
+}
+
+func runImageServe(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return 1
+	}
+	useTLS := flagImageServeTLSCert != "" || flagImageServeTLSKey != ""
+	if useTLS && (flagImageServeTLSCert == "" || flagImageServeTLSKey == "") {
+		stderr.Print("both --tls-cert and --tls-key must be specified")
+		return 1
+	}
+	addr := flagImageServeListen
+	if addr == "" {
+		if useTLS {
+			addr = ":443"
+		} else {
+			addr = ":80"
+		}
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+	cfg, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return 1
+	}
+	srv := &image.Server{
+		S:             s,
+		Ks:            getKeystore(),
+		Headers:       cfg.AuthPerHost,
+		Registries:    cfg.RegistriesPerHost,
+		InsecureFlags: globalFlags.InsecureFlags,
+		Debug:         globalFlags.Debug,
+	}
+	if flagImageServeAuthConfigDir != "" {
+		authCfg, err := config.GetConfigFromDir(flagImageServeAuthConfigDir)
+		if err != nil {
+			stderr.PrintE("cannot read the auth configuration", err)
+			return 1
+		}
+		if len(authCfg.AuthPerHost) == 0 {
+			stderr.Printf("no auth configuration found in %s", flagImageServeAuthConfigDir)
+			return 1
+		}
+		srv.Auth = authCfg.AuthPerHost
+	}
+
+	stderr.Printf("serving images on %s", addr)
+	if useTLS {
+		err = http.ListenAndServeTLS(addr, flagImageServeTLSCert, flagImageServeTLSKey, srv)
+	} else {
+		err = http.ListenAndServe(addr, srv)
+	}
+	stderr.PrintE(fmt.Sprintf("cannot serve images on %s", addr), err)
+	return 1
+}
@@ -0,0 +1,205 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+var (
+	cmdImageSign = &cobra.Command{
+		Use:   "sign --key=KEY_FILE IMAGE",
+		Short: "Sign a stored image or an ACI file",
+		Long: `IMAGE should be a path to an ACI file, or a string referencing a stored
+image: either a hash or an image name.
+
+KEY_FILE holds the private key, armored or binary, like the ones exported by
+"gpg --export-secret-keys". An armored detached signature is written to the
+--output file, which defaults to the ACI file path with the ".asc" extension.
+The --output flag is mandatory for stored images.`,
+		Run: runWrapper(runImageSign),
+	}
+	flagImageSignKey            string
+	flagImageSignKeyID          string
+	flagImageSignPassphraseFile string
+	flagImageSignOutput         string
+	flagImageSignOverwrite      bool
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageSign)
+	cmdImageSign.Flags().StringVar(&flagImageSignKey, "key", "", "file with the private key to sign with")
+	cmdImageSign.Flags().StringVar(&flagImageSignKeyID, "key-id", "", "ID or fingerprint of the key to sign with, if the key file holds several private keys")
+	cmdImageSign.Flags().StringVar(&flagImageSignPassphraseFile, "passphrase-file", "", "file with the passphrase of the private key")
+	cmdImageSign.Flags().StringVarP(&flagImageSignOutput, "output", "o", "", "output signature file")
+	cmdImageSign.Flags().BoolVar(&flagImageSignOverwrite, "overwrite", false, "overwrite output signature file")
+}
+
+func runImageSign(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 || flagImageSignKey == "" {
+		cmd.Usage()
+		return 1
+	}
+
+	signer, err := loadSigningKey(flagImageSignKey, flagImageSignKeyID, flagImageSignPassphraseFile)
+	if err != nil {
+		stderr.PrintE("cannot load the signing key", err)
+		return 1
+	}
+
+	var aci io.ReadCloser
+	output := flagImageSignOutput
+	if isACIFile(args[0]) {
+		aci, err = os.Open(args[0])
+		if err != nil {
+			stderr.PrintE(fmt.Sprintf("unable to open ACI file %s", args[0]), err)
+			return 1
+		}
+		if output == "" {
+			output = args[0] + ".asc"
+		}
+	} else {
+		if output == "" {
+			stderr.Print("the output signature file is required to sign a stored image (try --output)")
+			return 1
+		}
+		s, err := openStore()
+		if err != nil {
+			stderr.PrintE("cannot open store", err)
+			return 1
+		}
+		key, err := getStoreKeyFromAppOrHash(s, args[0])
+		if err != nil {
+			stderr.Error(err)
+			return 1
+		}
+		aci, err = s.ReadStream(key)
+		if err != nil {
+			stderr.PrintE("error reading image", err)
+			return 1
+		}
+	}
+	defer aci.Close()
+
+	mode := os.O_CREATE | os.O_WRONLY
+	if flagImageSignOverwrite {
+		mode |= os.O_TRUNC
+	} else {
+		mode |= os.O_EXCL
+	}
+	f, err := os.OpenFile(output, mode, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			stderr.Print("output signature file exists (try --overwrite)")
+		} else {
+			stderr.PrintE(fmt.Sprintf("unable to open output signature file %s", output), err)
+		}
+		return 1
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			stderr.PrintE("error closing output signature file", err)
+			exit = 1
+		}
+		if exit != 0 {
+			os.Remove(output)
+		}
+	}()
+
+	if err := openpgp.ArmoredDetachSign(f, signer, aci, nil); err != nil {
+		stderr.PrintE("error signing image", err)
+		return 1
+	}
+	stderr.Printf("signed %s with key %x, signature written to %s", args[0], signer.PrimaryKey.Fingerprint, output)
+
+	return 0
+}
+
+// loadSigningKey reads the private key from the key file, and decrypts
+// it with the passphrase from the passphrase file if needed. If keyID is
+// not empty, the key is the one whose fingerprint ends with keyID.
+func loadSigningKey(keyFile, keyID, passphraseFile string) (*openpgp.Entity, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading the key file"), err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		// the key is not armored, try reading a binary key
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error parsing the key file"), err)
+	}
+
+	var signers openpgp.EntityList
+	for _, entity := range keyring {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+		if keyID != "" && !strings.HasSuffix(fingerprint, strings.ToUpper(keyID)) {
+			continue
+		}
+		signers = append(signers, entity)
+	}
+	switch {
+	case len(signers) == 0 && keyID != "":
+		return nil, fmt.Errorf("no private key with ID %q in the key file", keyID)
+	case len(signers) == 0:
+		return nil, errors.New("no private key in the key file")
+	case len(signers) > 1:
+		return nil, errors.New("several private keys in the key file (try --key-id)")
+	}
+	signer := signers[0]
+
+	privateKeys := []*packet.PrivateKey{signer.PrivateKey}
+	for _, subkey := range signer.Subkeys {
+		if subkey.PrivateKey != nil {
+			privateKeys = append(privateKeys, subkey.PrivateKey)
+		}
+	}
+	var passphrase []byte
+	for _, pk := range privateKeys {
+		if !pk.Encrypted {
+			continue
+		}
+		if passphrase == nil {
+			if passphraseFile == "" {
+				return nil, errors.New("the private key is encrypted (try --passphrase-file)")
+			}
+			passphrase, err = ioutil.ReadFile(passphraseFile)
+			if err != nil {
+				return nil, errwrap.Wrap(errors.New("error reading the passphrase file"), err)
+			}
+			passphrase = bytes.TrimRight(passphrase, "\r\n")
+		}
+		if err := pk.Decrypt(passphrase); err != nil {
+			return nil, errwrap.Wrap(errors.New("error decrypting the private key"), err)
+		}
+	}
+	return signer, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/coreos/rkt/rkt/image"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageVerify = &cobra.Command{
+		Use:   "verify IMAGE",
+		Short: "Verify the signature of a stored image or an ACI file",
+		Long: `IMAGE should be a path to an ACI file, or a string referencing a stored
+image: either a hash or an image name.
+
+The image is verified against the --signature file with the trusted keys of
+the local keystore. The signature defaults to the ACI file path with the ".asc"
+extension, and for stored images, to the signature downloaded from where the
+image was fetched. The key which made the signature, and the prefix it is
+trusted for, are printed.`,
+		Run: runWrapper(runImageVerify),
+	}
+	flagImageVerifySignature string
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageVerify)
+	cmdImageVerify.Flags().StringVar(&flagImageVerifySignature, "signature", "", "signature file of the image")
+}
+
+func runImageVerify(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 1
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+	config, err := getConfig()
+	if err != nil {
+		stderr.PrintE("cannot get configuration", err)
+		return 1
+	}
+
+	v := &image.Verifier{
+		S:               s,
+		Ks:              getKeystore(),
+		Headers:         config.AuthPerHost,
+		Registries:      config.RegistriesPerHost,
+		SignaturePolicy: config.SignaturePolicyPerPrefix,
+		InsecureFlags:   globalFlags.InsecureFlags,
+		Debug:           globalFlags.Debug,
+	}
+
+	sigPath := flagImageVerifySignature
+	if sigPath == "" && isACIFile(args[0]) {
+		sigPath = args[0] + ".asc"
+	}
+	// a nil signature makes the verifier download the signature
+	// of a stored image
+	var sig io.ReadSeeker
+	if sigPath != "" {
+		sigFile, err := os.Open(sigPath)
+		if err != nil {
+			stderr.PrintE(fmt.Sprintf("unable to open signature file %s", sigPath), err)
+			return 1
+		}
+		defer sigFile.Close()
+		sig = sigFile
+	}
+
+	verification, err := verifyImage(v, s, args[0], sig)
+	if err != nil {
+		stderr.PrintE("cannot verify image", err)
+		return 1
+	}
+
+	trust := fmt.Sprintf("trusted for prefix %q", verification.Prefix)
+	if verification.Prefix == "" {
+		trust = "trusted as a root key"
+	}
+	stdout.Printf("image %q signed by key %x, %s:", verification.Name, verification.Signer.PrimaryKey.Fingerprint, trust)
+	var identities []string
+	for name := range verification.Signer.Identities {
+		identities = append(identities, name)
+	}
+	sort.Strings(identities)
+	for _, name := range identities {
+		stdout.Printf("  %s", name)
+	}
+
+	return 0
+}
+
+// verifyImage verifies the ACI file or the stored image referenced by
+// img against the signature.
+func verifyImage(v *image.Verifier, s *store.Store, img string, sig io.ReadSeeker) (*image.Verification, error) {
+	if isACIFile(img) {
+		aci, err := os.Open(img)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("unable to open ACI file %s", img), err)
+		}
+		defer aci.Close()
+		return v.VerifyImage(aci, sig)
+	}
+	key, err := getStoreKeyFromAppOrHash(s, img)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyStoreImage(key, sig)
+}
@@ -240,8 +240,14 @@ func appLine(app lastditch.RuntimeApp) string {
 func fmtNets(nis []netinfo.NetInfo) string {
 	var parts []string
 	for _, ni := range nis {
-		// there will be IPv6 support soon so distinguish between v4 and v6
-		parts = append(parts, fmt.Sprintf("%v:ip4=%v", ni.NetName, ni.IP))
+		var addrs []string
+		if ni.IP != nil || ni.IP6Addr() == nil {
+			addrs = append(addrs, fmt.Sprintf("ip4=%v", ni.IP))
+		}
+		if ip6 := ni.IP6Addr(); ip6 != nil {
+			addrs = append(addrs, fmt.Sprintf("ip6=%v", ip6))
+		}
+		parts = append(parts, fmt.Sprintf("%v:%s", ni.NetName, strings.Join(addrs, " ")))
 	}
 	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,74 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/rkt/networking"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNet = &cobra.Command{
+		Use:   "net [command]",
+		Short: "Operate on the networks of running pods",
+	}
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdNet)
+}
+
+// loadPodNetworking locks the networking state of the running pod and
+// loads it. The returned lock must be closed once the state is saved.
+func loadPodNetworking(p *pod) (*networking.Networking, *lock.FileLock, error) {
+	if !p.isRunning() {
+		return nil, nil, fmt.Errorf("pod %q isn't currently running", p.uuid)
+	}
+
+	flavor, err := os.Readlink(filepath.Join(p.path(), "flavor"))
+	if err != nil {
+		return nil, nil, errwrap.Wrap(errors.New("cannot get stage1 flavor"), err)
+	}
+	if flavor == "kvm" || flavor == "fly" {
+		return nil, nil, fmt.Errorf("changing the networks of a pod is not supported by the %q stage1 flavor", flavor)
+	}
+
+	l, err := lock.ExclusiveLock(filepath.Join(p.path(), "net"), lock.Dir)
+	if err == lock.ErrNotExist {
+		return nil, nil, fmt.Errorf("pod %q has no network namespace, it may be using the host networking", p.uuid)
+	}
+	if err != nil {
+		return nil, nil, errwrap.Wrap(errors.New("cannot lock the networking state"), err)
+	}
+
+	n, err := networking.Load(p.path(), p.uuid)
+	if err != nil {
+		l.Close()
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("pod %q has no network namespace, it may be using the host networking", p.uuid)
+		}
+		return nil, nil, errwrap.Wrap(errors.New("cannot load networking state"), err)
+	}
+	return n, l, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/networking/netinfo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNetAttach = &cobra.Command{
+		Use:   "attach UUID NETNAME[:ARGS]",
+		Short: "Attach a running pod to a network",
+		Long: `Add the given running pod to a network, configured like the networks of
+the --net flag of run. The optional ARGS are passed to the network plugin.
+
+The network is added to the network namespace of the pod, it is listed by
+status and removed when the pod is garbage collected.`,
+		Run: ensureSuperuser(runWrapper(runNetAttach)),
+	}
+)
+
+func init() {
+	cmdNet.AddCommand(cmdNetAttach)
+}
+
+func runNetAttach(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return 1
+	}
+
+	var netList common.NetList
+	if err := netList.Set(args[1]); err != nil {
+		stderr.PrintE("invalid network", err)
+		return 1
+	}
+	if netList.All() || netList.Host() || netList.None() || len(netList.StringsOnlyNames()) != 1 {
+		stderr.Printf("invalid network %q, expected a single network name", args[1])
+		return 1
+	}
+
+	p, err := getPodFromUUIDString(args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	n, l, err := loadPodNetworking(p)
+	if err != nil {
+		stderr.Error(err)
+		return 1
+	}
+	defer l.Close()
+
+	ni, err := n.Attach(netList, globalFlags.LocalConfigDir, globalFlags.Debug)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("cannot attach pod %q", p.uuid), err)
+		return 1
+	}
+
+	stdout.Print(fmtNets([]netinfo.NetInfo{*ni}))
+	return 0
+}
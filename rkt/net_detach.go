@@ -0,0 +1,66 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNetDetach = &cobra.Command{
+		Use:   "detach UUID NETNAME",
+		Short: "Detach a running pod from a network",
+		Long: `Remove the given running pod from a network. The default network of the
+pod, the last one it joined when it started, cannot be detached.`,
+		Run: ensureSuperuser(runWrapper(runNetDetach)),
+	}
+)
+
+func init() {
+	cmdNet.AddCommand(cmdNetDetach)
+}
+
+func runNetDetach(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return 1
+	}
+
+	p, err := getPodFromUUIDString(args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	n, l, err := loadPodNetworking(p)
+	if err != nil {
+		stderr.Error(err)
+		return 1
+	}
+	defer l.Close()
+
+	if err := n.Detach(args[1], globalFlags.Debug); err != nil {
+		stderr.PrintE(fmt.Sprintf("cannot detach pod %q", p.uuid), err)
+		return 1
+	}
+
+	stdout.Printf("detached pod %q from network %q", p.uuid, args[1])
+	return 0
+}
@@ -153,23 +153,29 @@ func walkPods(include includeMask, f func(*pod)) error {
 // The returned pod is always left in an exclusively locked state (preparing is locked in the prepared directory)
 // The pod must be closed using pod.Close()
 func newPod() (*pod, error) {
+	podUUID, err := types.NewUUID(uuid.New())
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating UUID"), err)
+	}
+
+	return newPodWithUUID(podUUID)
+}
+
+// newPodWithUUID creates a new pod directory in the "preparing" state for the given uuid, like newPod.
+// It is used to recreate a pod on restore, the caller must make sure that no pod with this uuid exists.
+func newPodWithUUID(podUUID *types.UUID) (*pod, error) {
 	if err := initPods(); err != nil {
 		return nil, err
 	}
 
 	p := &pod{
+		uuid:        podUUID,
 		createdByMe: true,
 		isEmbryo:    true, // starts as an embryo, then xToPreparing locks, renames, and sets isPreparing
 		// rest start false.
 	}
 
-	var err error
-	p.uuid, err = types.NewUUID(uuid.New())
-	if err != nil {
-		return nil, errwrap.Wrap(errors.New("error creating UUID"), err)
-	}
-
-	err = os.Mkdir(p.embryoPath(), 0750)
+	err := os.Mkdir(p.embryoPath(), 0750)
 	if err != nil {
 		return nil, err
 	}
@@ -1082,6 +1088,23 @@ func (p *pod) getStatusDir() (string, error) {
 	return regularStatusDir, nil
 }
 
+// getJournalDir returns the directory, relative to the pod directory, where
+// the pod's journald writes the journal files of the pod.
+func (p *pod) getJournalDir() (string, error) {
+	// stage1 sets the machine-id of the pod to its UUID without the dashes.
+	machineID := strings.Replace(p.uuid.String(), "-", "", -1)
+
+	if p.usesOverlay() {
+		stage1TreeStoreID, err := p.getStage1TreeStoreID()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(overlayJournalDirTemplate, stage1TreeStoreID, machineID), nil
+	}
+
+	return fmt.Sprintf(regularJournalDirTemplate, machineID), nil
+}
+
 // getExitStatuses returns a map of the statuses of the pod.
 func (p *pod) getExitStatuses() (map[string]int, error) {
 	statusDir, err := p.getStatusDir()
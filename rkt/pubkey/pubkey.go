@@ -43,6 +43,10 @@ type Manager struct {
 	TrustKeysFromHTTPS   bool
 	Ks                   *keystore.Keystore
 	Debug                bool
+	// Transport, if not nil, is used to download the keys instead
+	// of the default transport. It takes care of skipping the TLS
+	// certificate validation itself.
+	Transport http.RoundTripper
 }
 
 type AcceptOption int
@@ -187,14 +191,14 @@ func (m *Manager) getPubKey(u *url.URL) (*os.File, error) {
 		}
 		fallthrough
 	case "https":
-		return downloadKey(u, m.InsecureSkipTLSCheck)
+		return downloadKey(u, m.getClient())
 	}
 
 	return nil, fmt.Errorf("only local files and http or https URLs supported")
 }
 
 // downloadKey retrieves the file, storing it in a deleted tempfile
-func downloadKey(u *url.URL, skipTLSCheck bool) (*os.File, error) {
+func downloadKey(u *url.URL, client *http.Client) (*os.File, error) {
 	tf, err := ioutil.TempFile("", "")
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error creating tempfile"), err)
@@ -209,7 +213,6 @@ func downloadKey(u *url.URL, skipTLSCheck bool) (*os.File, error) {
 
 	// TODO(krnowak): we should probably apply credential headers
 	// from config here
-	client := getClient(skipTLSCheck)
 	res, err := client.Get(u.String())
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error getting key"), err)
@@ -233,6 +236,13 @@ func downloadKey(u *url.URL, skipTLSCheck bool) (*os.File, error) {
 	return retTf, nil
 }
 
+func (m *Manager) getClient() *http.Client {
+	if m.Transport != nil {
+		return &http.Client{Transport: m.Transport}
+	}
+	return getClient(m.InsecureSkipTLSCheck)
+}
+
 func getClient(skipTLSCheck bool) *http.Client {
 	if !skipTLSCheck {
 		return http.DefaultClient
@@ -0,0 +1,239 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/networking/netinfo"
+	"github.com/coreos/rkt/stage0"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdRestore = &cobra.Command{
+		Use:   "restore DIR",
+		Short: "Restore a pod checkpointed with 'rkt checkpoint'",
+		Long: `Recreates the pod saved in DIR by 'rkt checkpoint', with the same UUID, and
+restores its processes with CRIU.
+
+The images of the pod must be in the store, and no pod with the same UUID
+must exist: remove the checkpointed pod with 'rkt rm' first when restoring
+on the same host.`,
+		Run: ensureSuperuser(runWrapper(runRestore)),
+	}
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdRestore)
+	cmdRestore.Flags().StringVar(&flagCRIU, "criu", "criu", "path to the criu binary")
+}
+
+func runRestore(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 1
+	}
+
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		stderr.PrintE("cannot get checkpoint directory", err)
+		return 1
+	}
+
+	info, err := readCheckpointInfo(dir)
+	if err != nil {
+		stderr.PrintE("cannot load checkpoint", err)
+		return 1
+	}
+
+	podUUID, err := types.NewUUID(info.UUID)
+	if err != nil {
+		stderr.PrintE("invalid pod UUID in checkpoint", err)
+		return 1
+	}
+
+	if p, err := getPod(podUUID); err == nil {
+		p.Close()
+		stderr.Printf("pod %q already exists", podUUID)
+		return 1
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	p, err := newPodWithUUID(podUUID)
+	if err != nil {
+		stderr.PrintE("error creating new pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	podDir := filepath.Join(dir, checkpointPodDir)
+	_, err = os.Stat(filepath.Join(podDir, common.OverlayPreparedFilename))
+	useOverlay := err == nil
+	if err := copyPodDir(podDir, p.path(), useOverlay); err != nil {
+		stderr.PrintE("cannot copy pod directory", err)
+		return 1
+	}
+
+	if err := checkRestoredImages(s, p, info); err != nil {
+		stderr.PrintE("cannot restore the images of the pod", err)
+		return 1
+	}
+
+	if err := p.sync(); err != nil {
+		stderr.PrintE("error syncing pod data", err)
+		return 1
+	}
+
+	if err := p.xToPrepared(); err != nil {
+		stderr.PrintE("error setting pod to prepared", err)
+		return 1
+	}
+
+	if err := p.xToRun(); err != nil {
+		stderr.PrintE("cannot transition to run", err)
+		return 1
+	}
+
+	lfd, err := p.Fd()
+	if err != nil {
+		stderr.PrintE("unable to get lock fd", err)
+		return 1
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		stderr.PrintE("unable to get app list", err)
+		return 1
+	}
+
+	rktgid, err := common.LookupGid(common.RktGroup)
+	if err != nil {
+		stderr.Printf("group %q not found, will use default gid when rendering images", common.RktGroup)
+		rktgid = -1
+	}
+
+	netList, err := restoredNetList(info.Networks)
+	if err != nil {
+		stderr.PrintE("invalid network in checkpoint", err)
+		return 1
+	}
+
+	rcfg := stage0.RestoreConfig{
+		RunConfig: stage0.RunConfig{
+			CommonConfig: &stage0.CommonConfig{
+				Store: s,
+				UUID:  p.uuid,
+				Debug: globalFlags.Debug,
+			},
+			Net:         netList,
+			LockFd:      lfd,
+			Apps:        apps,
+			RktGid:      rktgid,
+			LocalConfig: globalFlags.LocalConfigDir,
+		},
+		CRIUPath:   flagCRIU,
+		ImagesDir:  filepath.Join(dir, checkpointImagesDir),
+		NetNSInode: info.NetNSInode,
+	}
+	if globalFlags.Debug {
+		stage0.InitDebug()
+	}
+	stage0.Restore(rcfg, p.path()) // execs, never returns
+	return 1
+}
+
+// restoredNetList returns the networks to put the restored pod in, asking
+// the network plugins for the IPs the pod had, so that its connections
+// survive the restore. The plugins take one IP per network, the IPv4 one
+// is requested if the pod had both.
+func restoredNetList(networks []netinfo.NetInfo) (common.NetList, error) {
+	var netList common.NetList
+	for _, n := range networks {
+		ips := n.IPs()
+		// the checkpoints of older versions only have the IPv4
+		// address
+		if len(ips) == 0 {
+			ips = []net.IP{n.IP}
+		}
+		spec := n.NetName
+		for _, ip := range ips {
+			if ip != nil {
+				spec += fmt.Sprintf(":IP=%s", ip)
+				break
+			}
+		}
+		if err := netList.Set(spec); err != nil {
+			return common.NetList{}, err
+		}
+	}
+	return netList, nil
+}
+
+// checkRestoredImages makes sure that the tree stores used by the pod are
+// rendered in the store, and that they are the same as the ones of the
+// checkpointed pod.
+func checkRestoredImages(s *store.Store, p *pod, info *checkpointInfo) error {
+	if err := checkRestoredTreeStore(s, info.Stage1Image, info.Stage1TreeStoreID); err != nil {
+		return errwrap.Wrap(errors.New("stage1 image"), err)
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		return err
+	}
+	for _, a := range apps {
+		treeStoreID, ok := info.AppsTreeStoreIDs[a.Name.String()]
+		if !ok {
+			// not using overlay, the app is in the stage1 rootfs
+			continue
+		}
+		if err := checkRestoredTreeStore(s, a.Image.ID.String(), treeStoreID); err != nil {
+			return errwrap.Wrap(fmt.Errorf("image of app %q", a.Name), err)
+		}
+	}
+
+	return nil
+}
+
+func checkRestoredTreeStore(s *store.Store, imageHash, treeStoreID string) error {
+	key, err := s.ResolveKey(imageHash)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("image %q not found in the store", imageHash), err)
+	}
+	id, _, err := s.RenderTreeStore(key, false)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error rendering tree image"), err)
+	}
+	if id != treeStoreID {
+		return fmt.Errorf("tree store ID %q differs from the checkpointed one %q, the dependencies of the image differ", id, treeStoreID)
+	}
+	return nil
+}
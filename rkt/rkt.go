@@ -26,6 +26,7 @@ import (
 	"github.com/coreos/rkt/pkg/log"
 	"github.com/coreos/rkt/rkt/config"
 	rktflag "github.com/coreos/rkt/rkt/flag"
+	"github.com/coreos/rkt/store"
 	"github.com/spf13/cobra"
 )
 
@@ -315,6 +316,34 @@ func getConfig() (*config.Config, error) {
 	return cachedConfig, nil
 }
 
+// openStore opens the store in the data directory with the settings of the
+// store configuration.
+func openStore() (*store.Store, error) {
+	config, err := getConfig()
+	if err != nil {
+		return nil, err
+	}
+	dbKind := store.DBDefault
+	if config.Store.DB != "" {
+		if dbKind, err = store.ParseDBKind(config.Store.DB); err != nil {
+			return nil, err
+		}
+	}
+	s, err := store.NewStoreWithDB(getDataDir(), dbKind)
+	if err != nil {
+		return nil, err
+	}
+	if config.Store.TreeStore != "" {
+		backend, err := store.ParseTreeStoreBackend(config.Store.TreeStore)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.SetTreeStoreBackend(backend)
+	}
+	return s, nil
+}
+
 func lockDir() string {
 	return filepath.Join(getDataDir(), "locks")
 }
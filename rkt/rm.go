@@ -17,9 +17,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
 	"github.com/spf13/cobra"
 )
 
@@ -73,12 +76,14 @@ func runRm(cmd *cobra.Command, args []string) (exit int) {
 		if err != nil {
 			ret = 1
 			stderr.PrintE("cannot get pod", err)
+			continue
 		}
 
-		if removePod(p) {
-			stdout.Printf("%q", p.uuid)
-		} else {
+		if err := removePod(p); err != nil {
 			ret = 1
+			stderr.Error(err)
+		} else {
+			stdout.Printf("%q", p.uuid)
 		}
 	}
 
@@ -89,32 +94,28 @@ func runRm(cmd *cobra.Command, args []string) (exit int) {
 	return ret
 }
 
-func removePod(p *pod) bool {
+// removePod removes a pod that is not running or being prepared or deleted.
+func removePod(p *pod) error {
 	switch {
 	case p.isRunning():
-		stderr.Printf("pod %q is currently running", p.uuid)
-		return false
+		return fmt.Errorf("pod %q is currently running", p.uuid)
 
 	case p.isEmbryo, p.isPreparing:
-		stderr.Printf("pod %q is currently being prepared", p.uuid)
-		return false
+		return fmt.Errorf("pod %q is currently being prepared", p.uuid)
 
 	case p.isExitedDeleting, p.isDeleting:
-		stderr.Printf("pod %q is currently being deleted", p.uuid)
-		return false
+		return fmt.Errorf("pod %q is currently being deleted", p.uuid)
 
 	case p.isAbortedPrepare:
 		stderr.Printf("moving failed prepare %q to garbage", p.uuid)
 		if err := p.xToGarbage(); err != nil && err != os.ErrNotExist {
-			stderr.PrintE("rename error", err)
-			return false
+			return errwrap.Wrap(errors.New("rename error"), err)
 		}
 
 	case p.isPrepared:
 		stderr.Printf("moving expired prepared pod %q to garbage", p.uuid)
 		if err := p.xToGarbage(); err != nil && err != os.ErrNotExist {
-			stderr.PrintE("rename error", err)
-			return false
+			return errwrap.Wrap(errors.New("rename error"), err)
 		}
 
 	// p.isExitedGarbage and p.isExited can be true at the same time. Test
@@ -123,17 +124,15 @@ func removePod(p *pod) bool {
 
 	case p.isExited:
 		if err := p.xToExitedGarbage(); err != nil && err != os.ErrNotExist {
-			stderr.PrintE("rename error", err)
-			return false
+			return errwrap.Wrap(errors.New("rename error"), err)
 		}
 	}
 
 	if err := p.ExclusiveLock(); err != nil {
-		stderr.PrintE("unable to acquire exclusive lock", err)
-		return false
+		return errwrap.Wrap(errors.New("unable to acquire exclusive lock"), err)
 	}
 
 	deletePod(p)
 
-	return true
+	return nil
 }
@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -28,7 +29,6 @@ import (
 	"github.com/coreos/rkt/pkg/uid"
 	"github.com/coreos/rkt/rkt/image"
 	"github.com/coreos/rkt/stage0"
-	"github.com/coreos/rkt/store"
 	"github.com/hashicorp/errwrap"
 	"github.com/spf13/cobra"
 )
@@ -64,6 +64,7 @@ image arguments with a lone "---" to resume argument parsing.`,
 	flagNoOverlay    bool
 	flagStoreOnly    bool
 	flagNoStore      bool
+	flagNoSquash     bool
 	flagPodManifest  string
 	flagMDSRegister  bool
 	flagUUIDFileSave string
@@ -74,7 +75,7 @@ func init() {
 	cmdRkt.AddCommand(cmdRun)
 
 	addStage1ImageFlags(cmdRun.Flags())
-	cmdRun.Flags().Var(&flagPorts, "port", "ports to expose on the host (requires contained network). Syntax: --port=NAME:HOSTPORT")
+	cmdRun.Flags().Var(&flagPorts, "port", "ports to expose on the host (requires contained network). Syntax: --port=NAME:[HOSTIP:]HOSTPORT[-HOSTPORT][@NETWORK]")
 	cmdRun.Flags().Var(&flagNet, "net", "configure the pod's networking. Optionally, pass a list of user-configured networks to load and set arguments to pass to each network, respectively. Syntax: --net[=n[:args], ...]")
 	cmdRun.Flags().Lookup("net").NoOptDefVal = "default"
 	cmdRun.Flags().BoolVar(&flagInheritEnv, "inherit-env", false, "inherit all environment variables not set by apps")
@@ -87,6 +88,8 @@ func init() {
 	cmdRun.Flags().Var(&flagDNSOpt, "dns-opt", "DNS options to write in /etc/resolv.conf")
 	cmdRun.Flags().BoolVar(&flagStoreOnly, "store-only", false, "use only available images in the store (do not discover or download from remote URLs)")
 	cmdRun.Flags().BoolVar(&flagNoStore, "no-store", false, "fetch images ignoring the local store")
+	cmdRun.Flags().BoolVar(&flagNoSquash, "no-squash", false, "store the layers of docker images as separate images")
+	cmdRun.Flags().IntVar(&flagParallelFetches, "parallel-fetches", defaultParallelFetches, "maximum number of images fetched at the same time")
 	cmdRun.Flags().StringVar(&flagPodManifest, "pod-manifest", "", "the path to the pod manifest. If it's non-empty, then only '--net', '--no-overlay' and '--interactive' will have effect")
 	cmdRun.Flags().BoolVar(&flagMDSRegister, "mds-register", false, "register pod with metadata service. needs network connectivity to the host (--net=(default|default-restricted|host)")
 	cmdRun.Flags().StringVar(&flagUUIDFileSave, "uuid-file-save", "", "write out pod UUID to specified file")
@@ -163,7 +166,7 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		return 1
 	}
 
-	s, err := store.NewStore(getDataDir())
+	s, err := openStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -186,13 +189,17 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		Ks:                 getKeystore(),
 		Headers:            config.AuthPerHost,
 		DockerAuth:         config.DockerCredentialsPerRegistry,
+		Registries:         config.RegistriesPerHost,
+		SignaturePolicy:    config.SignaturePolicyPerPrefix,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		Debug:              globalFlags.Debug,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
 
-		StoreOnly: flagStoreOnly,
-		NoStore:   flagNoStore,
-		WithDeps:  true,
+		StoreOnly:       flagStoreOnly,
+		NoStore:         flagNoStore,
+		WithDeps:        true,
+		NoSquash:        flagNoSquash,
+		ParallelFetches: flagParallelFetches,
 	}
 	if err := fn.FindImages(&rktApps); err != nil {
 		stderr.Error(err)
@@ -241,7 +248,7 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 	if len(flagPodManifest) > 0 {
 		pcfg.PodManifest = flagPodManifest
 	} else {
-		pcfg.Ports = []types.ExposedPort(flagPorts)
+		pcfg.Ports = []stage0.ExposedPort(flagPorts)
 		pcfg.InheritEnv = flagInheritEnv
 		pcfg.ExplicitEnv = flagExplicitEnv.Strings()
 		pcfg.Apps = &rktApps
@@ -309,13 +316,13 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 }
 
 // portList implements the flag.Value interface to contain a set of mappings
-// from port name --> host port
-type portList []types.ExposedPort
+// from port name --> host IP, host port range and network
+type portList []stage0.ExposedPort
 
 func (pl *portList) Set(s string) error {
 	parts := strings.SplitN(s, ":", 2)
 	if len(parts) != 2 {
-		return fmt.Errorf("%q is not in name:port format", s)
+		return fmt.Errorf("%q is not in name:[hostip:]port[-port][@network] format", s)
 	}
 
 	name, err := types.NewACName(parts[0])
@@ -323,14 +330,45 @@ func (pl *portList) Set(s string) error {
 		return errwrap.Wrap(fmt.Errorf("%q is not a valid port name", parts[0]), err)
 	}
 
-	port, err := strconv.ParseUint(parts[1], 10, 16)
-	if err != nil {
-		return fmt.Errorf("%q is not a valid port number", parts[1])
+	p := stage0.ExposedPort{}
+	p.Name = *name
+
+	hostPorts := parts[1]
+	if i := strings.LastIndex(hostPorts, "@"); i >= 0 {
+		p.Network = hostPorts[i+1:]
+		if p.Network == "" {
+			return fmt.Errorf("%q has an empty network name", s)
+		}
+		hostPorts = hostPorts[:i]
+	}
+
+	if i := strings.LastIndex(hostPorts, ":"); i >= 0 {
+		// IPv6 addresses are enclosed in brackets
+		hostIP := strings.TrimSuffix(strings.TrimPrefix(hostPorts[:i], "["), "]")
+		p.HostIP = net.ParseIP(hostIP)
+		if p.HostIP == nil {
+			return fmt.Errorf("%q is not a valid host IP", hostIP)
+		}
+		hostPorts = hostPorts[i+1:]
+	}
+
+	firstPort, lastPort := hostPorts, ""
+	if i := strings.Index(hostPorts, "-"); i >= 0 {
+		firstPort, lastPort = hostPorts[:i], hostPorts[i+1:]
+	}
+
+	port, err := strconv.ParseUint(firstPort, 10, 16)
+	if err != nil || port == 0 {
+		return fmt.Errorf("%q is not a valid port number", firstPort)
 	}
+	p.HostPort = uint(port)
 
-	p := types.ExposedPort{
-		Name:     *name,
-		HostPort: uint(port),
+	if lastPort != "" {
+		last, err := strconv.ParseUint(lastPort, 10, 16)
+		if err != nil || last < port {
+			return fmt.Errorf("%q is not a valid port range", hostPorts)
+		}
+		p.Count = uint(last-port) + 1
 	}
 
 	*pl = append(*pl, p)
@@ -339,8 +377,22 @@ func (pl *portList) Set(s string) error {
 
 func (pl *portList) String() string {
 	var ps []string
-	for _, p := range []types.ExposedPort(*pl) {
-		ps = append(ps, fmt.Sprintf("%v:%v", p.Name, p.HostPort))
+	for _, p := range []stage0.ExposedPort(*pl) {
+		s := fmt.Sprintf("%v:", p.Name)
+		switch {
+		case p.HostIP.To4() != nil:
+			s += fmt.Sprintf("%v:", p.HostIP)
+		case p.HostIP != nil:
+			s += fmt.Sprintf("[%v]:", p.HostIP)
+		}
+		s += fmt.Sprintf("%v", p.HostPort)
+		if p.Count > 0 {
+			s += fmt.Sprintf("-%v", p.HostPort+p.Count-1)
+		}
+		if p.Network != "" {
+			s += fmt.Sprintf("@%v", p.Network)
+		}
+		ps = append(ps, s)
 	}
 	return strings.Join(ps, " ")
 }
@@ -204,11 +204,11 @@ func getStage1Hash(s *store.Store, c *config.Config) (*types.Hash, error) {
 	imgDir := getStage1ImagesDirectory(c)
 	if overriddenStage1Location.kind != stage1ImageLocationUnset {
 		// we passed a --stage-{url,path,name,hash,from-dir} flag
-		return getStage1HashFromFlag(s, overriddenStage1Location, imgDir)
+		return getStage1HashFromFlag(s, c, overriddenStage1Location, imgDir)
 	}
 
 	imgRef, imgLoc, imgFileName := getStage1DataFromConfig(c)
-	return getConfiguredStage1Hash(s, imgRef, imgLoc, imgFileName)
+	return getConfiguredStage1Hash(s, c, imgRef, imgLoc, imgFileName)
 }
 
 func getStage1ImagesDirectory(c *config.Config) string {
@@ -218,7 +218,7 @@ func getStage1ImagesDirectory(c *config.Config) string {
 	return buildDefaultStage1ImagesDir
 }
 
-func getStage1HashFromFlag(s *store.Store, loc stage1ImageLocation, dir string) (*types.Hash, error) {
+func getStage1HashFromFlag(s *store.Store, c *config.Config, loc stage1ImageLocation, dir string) (*types.Hash, error) {
 	withKeystore := true
 	location := loc.location
 	if loc.kind == stage1ImageLocationFromDir {
@@ -252,7 +252,7 @@ func getStage1HashFromFlag(s *store.Store, loc stage1ImageLocation, dir string)
 		imgType = apps.AppImagePath
 	}
 
-	fn := getStage1Finder(s, withKeystore)
+	fn := getStage1Finder(s, c, withKeystore)
 	return fn.FindImage(location, "", imgType)
 }
 
@@ -297,12 +297,12 @@ func isTrustedLocation(location string) (bool, error) {
 	return false, nil
 }
 
-func getConfiguredStage1Hash(s *store.Store, imgRef, imgLoc, imgFileName string) (*types.Hash, error) {
+func getConfiguredStage1Hash(s *store.Store, c *config.Config, imgRef, imgLoc, imgFileName string) (*types.Hash, error) {
 	trusted, err := isTrustedLocation(imgLoc)
 	if err != nil {
 		return nil, err
 	}
-	fn := getStage1Finder(s, !trusted)
+	fn := getStage1Finder(s, c, !trusted)
 	if !strings.HasSuffix(imgRef, "-dirty") {
 		fn.StoreOnly = true
 		if hash, err := fn.FindImage(imgRef, "", apps.AppImageName); err == nil {
@@ -321,9 +321,13 @@ func getConfiguredStage1Hash(s *store.Store, imgRef, imgLoc, imgFileName string)
 	return getStage1HashFromPath(fn, imgLoc, imgFileName)
 }
 
-func getStage1Finder(s *store.Store, withKeystore bool) *image.Finder {
+// getStage1Finder returns a finder for the stage1 image. The images
+// are verified with the keystore and the signature policy only if
+// withKeystore is true.
+func getStage1Finder(s *store.Store, c *config.Config, withKeystore bool) *image.Finder {
 	fn := &image.Finder{
 		S:                  s,
+		SignaturePolicy:    c.SignaturePolicyPerPrefix,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
 
@@ -0,0 +1,307 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/rkt/api/v1alpha"
+	"github.com/coreos/rkt/common/cgroup"
+	"github.com/dustin/go-humanize"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdStats = &cobra.Command{
+		Use:   "stats [--follow] [--interval=DURATION] UUID",
+		Short: "Print the resource usage of a running pod",
+		Long: `Prints the CPU time and the memory used by the pod and by each of its apps,
+and the counters of the pod's interface in each of its networks.
+
+With --follow, a new sample is printed every interval until the pod exits.`,
+		Run: runWrapper(runStats),
+	}
+	flagStatsFollow   bool
+	flagStatsInterval time.Duration
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdStats)
+	cmdStats.Flags().BoolVar(&flagStatsFollow, "follow", false, "keep printing samples until the pod exits")
+	cmdStats.Flags().DurationVar(&flagStatsInterval, "interval", time.Second, "duration between two samples when following")
+	cmdStats.Flags().BoolVar(&flagNoLegend, "no-legend", false, "suppress a legend with the stats")
+	cmdStats.Flags().BoolVar(&flagFullOutput, "full", false, "print raw values instead of human readable ones")
+}
+
+func runStats(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 1
+	}
+	if flagStatsInterval <= 0 {
+		stderr.Print("the interval must be positive")
+		return 1
+	}
+
+	p, err := getPodFromUUIDString(args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return 1
+	}
+	defer p.Close()
+
+	for {
+		stats, err := getPodStats(p)
+		if err != nil {
+			stderr.PrintE(fmt.Sprintf("unable to get stats of pod %q", p.uuid), err)
+			return 1
+		}
+		printPodStats(stats)
+
+		if !flagStatsFollow {
+			return 0
+		}
+
+		time.Sleep(flagStatsInterval)
+		if err := p.refreshState(); err != nil {
+			stderr.PrintE("unable to refresh pod state", err)
+			return 1
+		}
+		if !p.isRunning() {
+			return 0
+		}
+		stdout.Print("")
+	}
+}
+
+// printPodStats prints a table with the resource usage of the pod and of its
+// apps, followed by a table with the counters of its networks if any.
+func printPodStats(stats *v1alpha.PodStats) {
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+
+	if !flagNoLegend {
+		fmt.Fprintf(tabOut, "NAME\tCPU\tMEMORY\n")
+	}
+	fmt.Fprintf(tabOut, "%s\t%s\t%s\n", stats.Id, formatCPUUsage(stats.CpuUsage), formatBytes(stats.MemoryUsage))
+	for _, app := range stats.Apps {
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\n", app.Name, formatCPUUsage(app.CpuUsage), formatBytes(app.MemoryUsage))
+	}
+
+	if len(stats.Networks) > 0 {
+		fmt.Fprintf(tabOut, "\n")
+		if !flagNoLegend {
+			fmt.Fprintf(tabOut, "NETWORK\tINTERFACE\tRX BYTES\tRX PACKETS\tRX ERRORS\tRX DROPPED\tTX BYTES\tTX PACKETS\tTX ERRORS\tTX DROPPED\n")
+		}
+		for _, n := range stats.Networks {
+			fmt.Fprintf(tabOut, "%s\t%s\t%s\t%d\t%d\t%d\t%s\t%d\t%d\t%d\n", n.Name, n.Interface,
+				formatBytes(n.RxBytes), n.RxPackets, n.RxErrors, n.RxDropped,
+				formatBytes(n.TxBytes), n.TxPackets, n.TxErrors, n.TxDropped)
+		}
+	}
+
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+}
+
+func formatCPUUsage(ns uint64) string {
+	if flagFullOutput {
+		return strconv.FormatUint(ns, 10)
+	}
+	return time.Duration(ns).String()
+}
+
+func formatBytes(b uint64) string {
+	if flagFullOutput {
+		return strconv.FormatUint(b, 10)
+	}
+	return humanize.IBytes(b)
+}
+
+// getPodStats takes a sample of the resource usage of a running pod.
+//
+// The CPU and memory usage are read from the cgroup accounting files of the
+// pod's machine subcgroup and of the "<app>.service" subcgroups of the apps.
+// The interface counters are read from /proc/PID/net/dev, which reflects the
+// network namespace of the pod's PID 1.
+func getPodStats(p *pod) (*v1alpha.PodStats, error) {
+	if !p.isRunning() {
+		return nil, fmt.Errorf("pod %q is not running", p.uuid)
+	}
+
+	pid, err := p.getContainerPID1()
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get pid of pod %q", p.uuid), err)
+	}
+
+	stats := &v1alpha.PodStats{
+		Id:        p.uuid.String(),
+		Timestamp: time.Now().UnixNano(),
+	}
+
+	cpuCgroup, err := podCgroupPath(pid, "cpuacct")
+	if err != nil {
+		return nil, err
+	}
+	if stats.CpuUsage, err = cgroup.GetCPUUsage(cpuCgroup); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get CPU usage of pod %q", p.uuid), err)
+	}
+
+	memoryCgroup, err := podCgroupPath(pid, "memory")
+	if err != nil {
+		return nil, err
+	}
+	if stats.MemoryUsage, err = cgroup.GetMemoryUsage(memoryCgroup); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get memory usage of pod %q", p.uuid), err)
+	}
+
+	apps, err := p.getApps()
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("cannot get apps of pod %q", p.uuid), err)
+	}
+	for _, app := range apps {
+		appStats, err := getAppStats(app.Name.String(), cpuCgroup, memoryCgroup)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot get stats of app %q", app.Name), err)
+		}
+		if appStats != nil {
+			stats.Apps = append(stats.Apps, appStats)
+		}
+	}
+
+	if len(p.nets) > 0 {
+		f, err := os.Open(fmt.Sprintf("/proc/%d/net/dev", pid))
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot read network counters of pod %q", p.uuid), err)
+		}
+		counters, err := parseNetDev(f)
+		f.Close()
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot read network counters of pod %q", p.uuid), err)
+		}
+		for _, n := range p.nets {
+			c, ok := counters[n.IfName]
+			if !ok {
+				continue
+			}
+			c.Name = n.NetName
+			stats.Networks = append(stats.Networks, c)
+		}
+	}
+
+	return stats, nil
+}
+
+// podCgroupPath returns the path of the pod's machine subcgroup in the given
+// controller hierarchy.
+func podCgroupPath(pid int, controller string) (string, error) {
+	path, err := cgroup.GetCgroupPathByPid(pid, controller)
+	if err != nil {
+		return "", errwrap.Wrap(fmt.Errorf("cannot get %s cgroup of pid %d", controller, pid), err)
+	}
+	// If the stage1 systemd > v226, it will put the PID1 into "init.scope"
+	// implicit scope unit in the root slice.
+	return strings.TrimSuffix(path, "/init.scope"), nil
+}
+
+// getAppStats returns the resource usage of the app with the given name, or
+// nil if the app has no subcgroup of its own (e.g. in the kvm and fly
+// flavors).
+func getAppStats(appName, cpuCgroup, memoryCgroup string) (*v1alpha.AppStats, error) {
+	service := appName + ".service"
+
+	cpu, err := cgroup.GetCPUUsage(filepath.Join(cpuCgroup, "system.slice", service))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := cgroup.GetMemoryUsage(filepath.Join(memoryCgroup, "system.slice", service))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1alpha.AppStats{
+		Name:        appName,
+		CpuUsage:    cpu,
+		MemoryUsage: memory,
+	}, nil
+}
+
+// parseNetDev parses the content of /proc/net/dev and returns the counters
+// of each interface, indexed by interface name.
+func parseNetDev(r io.Reader) (map[string]*v1alpha.NetworkStats, error) {
+	counters := make(map[string]*v1alpha.NetworkStats)
+
+	s := bufio.NewScanner(r)
+	for line := 0; s.Scan(); line++ {
+		// Skip the two header lines.
+		if line < 2 {
+			continue
+		}
+
+		parts := strings.SplitN(s.Text(), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid line %q", s.Text())
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("invalid line %q", s.Text())
+		}
+
+		var values [16]uint64
+		for i := range values {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, errwrap.Wrap(fmt.Errorf("invalid line %q", s.Text()), err)
+			}
+			values[i] = v
+		}
+
+		name := strings.TrimSpace(parts[0])
+		counters[name] = &v1alpha.NetworkStats{
+			Interface: name,
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDropped: values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDropped: values[11],
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/rkt/api/v1alpha"
+)
+
+func TestParseNetDev(t *testing.T) {
+	netDev := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     672       8    0    0    0     0          0         0      672       8    0    0    0     0       0          0
+  eth0:   15432     120    1    2    0     0          0         0     4096      40    3    4    0     0       0          0
+`
+	expected := map[string]*v1alpha.NetworkStats{
+		"lo": {
+			Interface: "lo",
+			RxBytes:   672,
+			RxPackets: 8,
+			TxBytes:   672,
+			TxPackets: 8,
+		},
+		"eth0": {
+			Interface: "eth0",
+			RxBytes:   15432,
+			RxPackets: 120,
+			RxErrors:  1,
+			RxDropped: 2,
+			TxBytes:   4096,
+			TxPackets: 40,
+			TxErrors:  3,
+			TxDropped: 4,
+		},
+	}
+
+	counters, err := parseNetDev(strings.NewReader(netDev))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(counters, expected) {
+		t.Errorf("got %v, want %v", counters, expected)
+	}
+
+	invalid := []string{
+		"header\nheader\n  eth0 15432 120\n",
+		"header\nheader\n  eth0: 15432 120 1 2\n",
+		"header\nheader\n  eth0: a b c d e f g h i j k l m n o p\n",
+	}
+	for i, in := range invalid {
+		if _, err := parseNetDev(strings.NewReader(in)); err == nil {
+			t.Errorf("#%d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/rkt/stage0"
+	"github.com/coreos/rkt/store"
+	"github.com/hashicorp/errwrap"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdStop = &cobra.Command{
+		Use:   "stop [--force] [--timeout=DURATION] UUID ...",
+		Short: "Stop a pod",
+		Long: `Stop the given running pods.
+
+By default the pods are asked to shut down cleanly, and are killed if they
+did not exit after the given timeout. With --force, the pods are killed
+right away.`,
+		Run: ensureSuperuser(runWrapper(runStop)),
+	}
+	flagForce       bool
+	flagStopTimeout time.Duration
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdStop)
+	cmdStop.Flags().BoolVar(&flagForce, "force", false, "forced stopping")
+	cmdStop.Flags().DurationVar(&flagStopTimeout, "timeout", 10*time.Second, "duration to wait for a pod to exit before killing it, 0 to wait forever")
+}
+
+func runStop(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) < 1 {
+		cmd.Usage()
+		return 1
+	}
+
+	if globalFlags.Debug {
+		stage0.InitDebug()
+	}
+
+	s, err := openStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 1
+	}
+
+	ret := 0
+	for _, podUUID := range args {
+		p, err := getPodFromUUIDString(podUUID)
+		if err != nil {
+			ret = 1
+			stderr.PrintE("cannot get pod", err)
+			continue
+		}
+
+		if err := stopPod(s, p, flagForce, flagStopTimeout); err != nil {
+			ret = 1
+			stderr.PrintE(fmt.Sprintf("error stopping pod %q", p.uuid), err)
+		} else {
+			stdout.Printf("%q", p.uuid)
+		}
+		p.Close()
+	}
+
+	if ret == 1 {
+		stderr.Print("failed to stop one or more pods")
+	}
+
+	return ret
+}
+
+// stopPod stops a running pod through the stage1's stop entrypoint and waits
+// for it to exit. If the pod did not exit after timeout (0 meaning no
+// timeout), it is stopped again, this time forcibly.
+func stopPod(s *store.Store, p *pod, force bool, timeout time.Duration) error {
+	if !p.isRunning() {
+		return fmt.Errorf("pod %q isn't currently running", p.uuid)
+	}
+
+	podPID, err := p.getContainerPID1()
+	if err != nil {
+		return errwrap.Wrap(errors.New("unable to determine the pid of the pod"), err)
+	}
+
+	stage1TreeStoreID, err := p.getStage1TreeStoreID()
+	if err != nil {
+		return errwrap.Wrap(errors.New("error getting stage1 treeStoreID"), err)
+	}
+	stage1RootFS := s.GetTreeStoreRootFS(stage1TreeStoreID)
+
+	// p is updated by waitExited() from now on.
+	pdir, uuid := p.path(), p.uuid
+	if err := stage0.StopPod(pdir, podPID, force, uuid, stage1RootFS); err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- p.waitExited()
+	}()
+
+	if force || timeout == 0 {
+		return <-exited
+	}
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(timeout):
+	}
+
+	stderr.Printf("pod %q did not exit after %v, killing it", uuid, timeout)
+	if err := stage0.StopPod(pdir, podPID, true, uuid, stage1RootFS); err != nil {
+		return err
+	}
+	return <-exited
+}
@@ -19,8 +19,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
 	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/coreos/rkt/pkg/keystore"
 	"github.com/coreos/rkt/rkt/pubkey"
 
 	"github.com/spf13/cobra"
@@ -28,7 +36,7 @@ import (
 
 var (
 	cmdTrust = &cobra.Command{
-		Use:   "trust [--prefix=PREFIX] [--insecure-allow-http] [--skip-fingerprint-review] [--root] [PUBKEY ...]",
+		Use:   "trust [--prefix=PREFIX] [--insecure-allow-http] [--skip-fingerprint-review] [--root] [PUBKEY ...] | --list | --remove=FINGERPRINT | --revoke=REVOCATION_CERT",
 		Short: "Trust a key for image verification",
 		Long: `Adds keys to the local keystore for use in verifying signed images.
 
@@ -39,13 +47,25 @@ PREFIX scopes the applicability of PUBKEY to image names sharing PREFIX.
 Meta discovery of PUBKEY at PREFIX will be attempted if no PUBKEY is specified.
 
 To trust a key for all images instead of for specific images, --root can be
-specified. Path to a key file must be given (no discovery).`,
+specified. Path to a key file must be given (no discovery).
+
+The trusted keys, restricted to the ones applying to PREFIX if specified, are
+listed with --list. A key is untrusted with --remove, for PREFIX, or as a root
+key if no PREFIX is specified. Keys of the system keystore are masked by the
+local keystore.
+
+A revocation certificate, like the ones generated by "gpg --gen-revoke", is
+imported with --revoke. The signatures made by revoked or expired keys are
+rejected.`,
 		Run: runWrapper(runTrust),
 	}
 	flagPrefix                string
 	flagRoot                  bool
 	flagAllowHTTP             bool
 	flagSkipFingerprintReview bool
+	flagTrustList             bool
+	flagTrustRemove           string
+	flagTrustRevoke           string
 )
 
 func init() {
@@ -54,9 +74,46 @@ func init() {
 	cmdTrust.Flags().BoolVar(&flagRoot, "root", false, "add root key from filesystem without a prefix")
 	cmdTrust.Flags().BoolVar(&flagSkipFingerprintReview, "skip-fingerprint-review", false, "accept key without fingerprint confirmation")
 	cmdTrust.Flags().BoolVar(&flagAllowHTTP, "insecure-allow-http", false, "allow HTTP use for key discovery and/or retrieval")
+	cmdTrust.Flags().BoolVar(&flagTrustList, "list", false, "list the trusted keys")
+	cmdTrust.Flags().StringVar(&flagTrustRemove, "remove", "", "fingerprint of the key to remove trust from")
+	cmdTrust.Flags().StringVar(&flagTrustRevoke, "revoke", "", "revocation certificate file to import")
 }
 
 func runTrust(cmd *cobra.Command, args []string) (exit int) {
+	modes := 0
+	for _, set := range []bool{flagTrustList, flagTrustRemove != "", flagTrustRevoke != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		stderr.Print("--list, --remove and --revoke usage mutually exclusive")
+		return 1
+	}
+	if modes > 0 {
+		if len(args) != 0 {
+			cmd.Usage()
+			return 1
+		}
+		if flagPrefix != "" && flagRoot {
+			stderr.Print("--root and --prefix usage mutually exclusive")
+			return 1
+		}
+		ks := getKeystore()
+		if ks == nil {
+			stderr.Print("could not get the keystore")
+			return 1
+		}
+		switch {
+		case flagTrustList:
+			return runTrustList(ks)
+		case flagTrustRemove != "":
+			return runTrustRemove(ks)
+		default:
+			return runTrustRevoke(ks)
+		}
+	}
+
 	if flagPrefix == "" && !flagRoot {
 		if len(args) != 0 {
 			stderr.Print("--root required for non-prefixed (root) keys")
@@ -111,3 +168,83 @@ func runTrust(cmd *cobra.Command, args []string) (exit int) {
 
 	return 0
 }
+
+func runTrustList(ks *keystore.Keystore) int {
+	keys, err := ks.ListTrustedKeys(flagPrefix)
+	if err != nil {
+		stderr.PrintE("error listing the trusted keys", err)
+		return 1
+	}
+
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+	fmt.Fprintf(tabOut, "PREFIX\tFINGERPRINT\tSTATUS\tIDENTITIES\n")
+	now := time.Now()
+	for _, key := range keys {
+		prefix := key.Prefix
+		if prefix == "" {
+			prefix = "(root)"
+		}
+		status := "valid"
+		switch err := ks.CheckKeyValidity(key.Entity, now); err.(type) {
+		case nil:
+		case keystore.KeyRevokedError:
+			status = "revoked"
+		case keystore.KeyExpiredError:
+			status = "expired"
+		default:
+			stderr.PrintE(fmt.Sprintf("error checking the validity of key %x", key.Entity.PrimaryKey.Fingerprint), err)
+			return 1
+		}
+		var identities []string
+		for name := range key.Entity.Identities {
+			identities = append(identities, name)
+		}
+		sort.Strings(identities)
+		fmt.Fprintf(tabOut, "%s\t%x\t%s\t%s\n", prefix, key.Entity.PrimaryKey.Fingerprint, status, strings.Join(identities, ", "))
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+
+	return 0
+}
+
+func runTrustRemove(ks *keystore.Keystore) int {
+	// accept fingerprints as printed by gpg, in groups of four
+	// uppercase digits
+	fingerprint := strings.ToLower(strings.Replace(flagTrustRemove, " ", "", -1))
+	if b, err := hex.DecodeString(fingerprint); err != nil || len(b) != 20 {
+		stderr.Printf("invalid key fingerprint %q", flagTrustRemove)
+		return 1
+	}
+
+	if err := ks.RemoveTrustedKey(flagPrefix, fingerprint); err != nil {
+		stderr.PrintE("error removing key", err)
+		return 1
+	}
+	if flagPrefix == "" {
+		stderr.Printf("removed trust in root key %s", fingerprint)
+	} else {
+		stderr.Printf("removed trust in key %s for prefix %q", fingerprint, flagPrefix)
+	}
+
+	return 0
+}
+
+func runTrustRevoke(ks *keystore.Keystore) int {
+	f, err := os.Open(flagTrustRevoke)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("unable to open revocation certificate %s", flagTrustRevoke), err)
+		return 1
+	}
+	defer f.Close()
+
+	path, err := ks.StoreRevocation(f)
+	if err != nil {
+		stderr.PrintE("error importing revocation certificate", err)
+		return 1
+	}
+	stderr.Printf("added revocation certificate at %q", path)
+
+	return 0
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package stage0
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/coreos/rkt/common"
+	"github.com/coreos/rkt/networking"
+	"github.com/coreos/rkt/pkg/sys"
+	"github.com/hashicorp/errwrap"
+)
+
+// CRIUNetNSKey identifies the network namespace of the pod in the CRIU
+// images. The namespace is dumped as an external one, as it is set up by
+// the network plugins and not by the processes of the pod.
+const CRIUNetNSKey = "rkt-pod-netns"
+
+// CheckpointConfig contains the configuration to checkpoint a pod.
+type CheckpointConfig struct {
+	CRIUPath     string // path to the criu binary
+	ImagesDir    string // directory where CRIU writes the images of the pod
+	PodPID       int    // PID of the pod's PID 1, root of the dumped process tree
+	NetNSInode   uint64 // inode of the pod's network namespace, 0 if the pod uses the host's
+	LeaveRunning bool   // leave the pod running after the dump
+}
+
+// RestoreConfig contains the configuration to restore a pod from a
+// checkpoint.
+type RestoreConfig struct {
+	RunConfig
+	CRIUPath   string // path to the criu binary
+	ImagesDir  string // directory containing the CRIU images of the pod
+	NetNSInode uint64 // inode of the pod's network namespace when it was checkpointed, 0 if it used the host's
+}
+
+// criuArgs returns the options shared by 'criu dump' and 'criu restore'.
+func criuArgs(criuPath, action, imagesDir string) []string {
+	args := []string{
+		criuPath, action,
+		"--images-dir", imagesDir,
+		"--log-file", action + ".log",
+		"--manage-cgroups",
+		"--tcp-established",
+		"--file-locks",
+		"--ext-unix-sk",
+		"--ext-mount-map", "auto",
+	}
+	if debugEnabled {
+		args = append(args, "-v4")
+	}
+	return args
+}
+
+// CheckpointPod dumps the process tree of the pod by fork/exec()ing CRIU.
+// Unless cfg.LeaveRunning is set, the processes of the pod are killed once
+// they are dumped.
+func CheckpointPod(cfg CheckpointConfig, dir string) error {
+	args := criuArgs(cfg.CRIUPath, "dump", cfg.ImagesDir)
+	args = append(args, "--tree", strconv.Itoa(cfg.PodPID))
+	if cfg.NetNSInode != 0 {
+		args = append(args, "--external", fmt.Sprintf("net[%d]:%s", cfg.NetNSInode, CRIUNetNSKey))
+	}
+	if cfg.LeaveRunning {
+		args = append(args, "--leave-running")
+	}
+
+	debug("Execing %v", args)
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Dir = dir
+	if err := c.Run(); err != nil {
+		return errwrap.Wrap(fmt.Errorf("criu dump failed, see %q", filepath.Join(cfg.ImagesDir, "dump.log")), err)
+	}
+
+	return nil
+}
+
+// Restore mounts the overlay filesystems of the pod, sets up the networks
+// the pod was in, and restores its processes by exec()ing CRIU.
+// CRIU stays the parent of the pod's PID 1 and holds the pod lock until
+// the pod exits, like the stage1 does for a pod started with Run.
+func Restore(cfg RestoreConfig, dir string) {
+	useOverlay, err := preparedWithOverlay(dir)
+	if err != nil {
+		log.FatalE("error preparing overlay", err)
+	}
+
+	debug("Setting up stage1")
+	if err := setupStage1Image(cfg.RunConfig, dir, useOverlay); err != nil {
+		log.FatalE("error setting up stage1", err)
+	}
+
+	for _, app := range cfg.Apps {
+		if err := setupAppImage(cfg.RunConfig, app.Name, app.Image.ID, dir, useOverlay); err != nil {
+			log.FatalE("error setting up app image", err)
+		}
+	}
+
+	destRootfs := common.Stage1RootfsPath(dir)
+
+	flavor, err := os.Readlink(filepath.Join(destRootfs, "flavor"))
+	if err != nil {
+		log.FatalE("error determining stage1 flavor", err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		log.FatalE("failed changing to dir", err)
+	}
+
+	args := criuArgs(cfg.CRIUPath, "restore", cfg.ImagesDir)
+	args = append(args,
+		"--root", destRootfs,
+		// rkt finds the pod's PID 1 with the pid file, see
+		// Documentation/devel/stage1-implementors-guide.md
+		"--pidfile", filepath.Join(dir, "pid"),
+	)
+
+	if cfg.NetNSInode != 0 {
+		debug("Setting up networking")
+		// this leaves the current thread in the pod's netns,
+		// that CRIU is going to be exec()ed into.
+		n, err := networking.Setup(".", *cfg.UUID, nil, cfg.Net, cfg.LocalConfig, flavor, cfg.Debug)
+		if err != nil {
+			log.FatalE("error setting up networking", err)
+		}
+		if err := n.Save(); err != nil {
+			log.FatalE("error saving networking info", err)
+		}
+
+		nsFile, err := os.Open("netns")
+		if err != nil {
+			log.FatalE("error opening pod's netns", err)
+		}
+		nsFd := int(nsFile.Fd())
+		if err := sys.CloseOnExec(nsFd, false); err != nil {
+			log.FatalE("error clearing FD_CLOEXEC on netns fd", err)
+		}
+		args = append(args, "--inherit-fd", fmt.Sprintf("fd[%d]:%s", nsFd, CRIUNetNSKey))
+	}
+
+	// make sure the lock fd stays open across exec
+	if err := sys.CloseOnExec(cfg.LockFd, false); err != nil {
+		log.Fatalf("error clearing FD_CLOEXEC on lock fd")
+	}
+
+	debug("Execing %v", args)
+	criuPath, err := exec.LookPath(args[0])
+	if err != nil {
+		log.FatalE("cannot find criu", err)
+	}
+	if err := syscall.Exec(criuPath, args, os.Environ()); err != nil {
+		log.FatalE("error execing criu", err)
+	}
+}
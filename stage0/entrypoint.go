@@ -31,6 +31,7 @@ const (
 	enterEntrypoint = "coreos.com/rkt/stage1/enter"
 	runEntrypoint   = "coreos.com/rkt/stage1/run"
 	gcEntrypoint    = "coreos.com/rkt/stage1/gc"
+	stopEntrypoint  = "coreos.com/rkt/stage1/stop"
 )
 
 // getStage1Entrypoint retrieves the named entrypoint from the stage1 manifest for a given pod
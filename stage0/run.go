@@ -69,14 +69,23 @@ var debugEnabled bool
 // configuration parameters required by Prepare
 type PrepareConfig struct {
 	*CommonConfig
-	Apps               *apps.Apps          // apps to prepare
-	InheritEnv         bool                // inherit parent environment into apps
-	ExplicitEnv        []string            // always set these environment variables for all the apps
-	Ports              []types.ExposedPort // list of ports that rkt will expose on the host
-	UseOverlay         bool                // prepare pod with overlay fs
-	SkipTreeStoreCheck bool                // skip checking the treestore before rendering
-	PodManifest        string              // use the pod manifest specified by the user, this will ignore flags such as '--volume', '--port', etc.
-	PrivateUsers       *uid.UidRange       // User namespaces
+	Apps               *apps.Apps    // apps to prepare
+	InheritEnv         bool          // inherit parent environment into apps
+	ExplicitEnv        []string      // always set these environment variables for all the apps
+	Ports              []ExposedPort // list of ports that rkt will expose on the host
+	UseOverlay         bool          // prepare pod with overlay fs
+	SkipTreeStoreCheck bool          // skip checking the treestore before rendering
+	PodManifest        string        // use the pod manifest specified by the user, this will ignore flags such as '--volume', '--port', etc.
+	PrivateUsers       *uid.UidRange // User namespaces
+}
+
+// ExposedPort is a port of the pod exposed on the host, with the options
+// which are not part of the pod manifest ports
+type ExposedPort struct {
+	types.ExposedPort
+	HostIP  net.IP // host IP the port is exposed on, all the host IPs if nil
+	Count   uint   // number of host ports, 0 to expose as many as the app ports
+	Network string // network the port is forwarded on, the default one if empty
 }
 
 // configuration parameters needed by Run
@@ -268,7 +277,18 @@ func generatePodManifest(cfg PrepareConfig, dir string) ([]byte, error) {
 	// TODO(jonboulle): check that app mountpoint expectations are
 	// satisfied here, rather than waiting for stage1
 	pm.Volumes = cfg.Apps.Volumes
-	pm.Ports = cfg.Ports
+	for _, ep := range cfg.Ports {
+		if err := validateExposedPort(pm.Apps, ep); err != nil {
+			return nil, err
+		}
+		pm.Ports = append(pm.Ports, ep.ExposedPort)
+		if ep.HostIP != nil {
+			pm.Annotations.Set(common.PortHostIPAnnotation(ep.Name), ep.HostIP.String())
+		}
+		if ep.Network != "" {
+			pm.Annotations.Set(common.PortNetworkAnnotation(ep.Name), ep.Network)
+		}
+	}
 
 	pmb, err := json.Marshal(pm)
 	if err != nil {
@@ -277,6 +297,34 @@ func generatePodManifest(cfg PrepareConfig, dir string) ([]byte, error) {
 	return pmb, nil
 }
 
+// validateExposedPort checks that the host port range of the exposed port
+// has the size of the app port range it maps onto. The ports not defined
+// by any apps are reported by stage1.
+func validateExposedPort(apps schema.AppList, ep ExposedPort) error {
+	for _, ra := range apps {
+		if ra.App == nil {
+			continue
+		}
+		for _, p := range ra.App.Ports {
+			if p.Name != ep.Name {
+				continue
+			}
+			count := p.Count
+			if count == 0 {
+				count = 1
+			}
+			if ep.Count != 0 && ep.Count != count {
+				return fmt.Errorf("port %q: %d host ports exposed for the %d ports of app %q", ep.Name, ep.Count, count, ra.Name)
+			}
+			if ep.HostPort+count > 65536 {
+				return fmt.Errorf("port %q: host port range starting at %d is out of the 1-65535 range", ep.Name, ep.HostPort)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
 // validatePodManifest reads the user-specified pod manifest, prepares the app images
 // and validates the pod manifest. If the pod manifest passes validation, it returns
 // the manifest as []byte.
@@ -0,0 +1,60 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package stage0
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+)
+
+// StopPod stops the given pod by fork/exec()ing the stage1's /stop.
+// /stop can expect to have its CWD set to the pod root.
+// podPID is the PID of the pod's PID 1, as used by enter.
+// If force is true, /stop must kill the pod instead of asking it to shut
+// down.
+// stage1Path is the path of the stage1 rootfs
+func StopPod(pdir string, podPID int, force bool, uuid *types.UUID, stage1Path string) error {
+	ep, err := getStage1Entrypoint(pdir, stopEntrypoint)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error determining 'stop' entrypoint"), err)
+	}
+
+	args := []string{filepath.Join(stage1Path, ep)}
+	if debugEnabled {
+		args = append(args, "--debug")
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, fmt.Sprintf("--pid=%d", podPID))
+	args = append(args, uuid.String())
+
+	c := exec.Cmd{
+		Path:   args[0],
+		Args:   args,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Dir:    pdir,
+	}
+	return c.Run()
+}
@@ -48,6 +48,7 @@ import (
 	"github.com/coreos/rkt/common/cgroup"
 	"github.com/coreos/rkt/networking"
 	rktlog "github.com/coreos/rkt/pkg/log"
+	"github.com/coreos/rkt/pkg/multicall"
 	"github.com/coreos/rkt/pkg/sys"
 	"github.com/coreos/rkt/stage1/init/kvm"
 )
@@ -439,6 +440,7 @@ NextPort:
 	for _, ep := range pod.Manifest.Ports {
 		n := ""
 		fp := networking.ForwardedPort{}
+		count := uint(1)
 
 		for _, a := range pod.Manifest.Apps {
 			for _, p := range a.App.Ports {
@@ -451,6 +453,9 @@ NextPort:
 						fp.Protocol = p.Protocol
 						fp.HostPort = ep.HostPort
 						fp.PodPort = p.Port
+						if p.Count > 1 {
+							count = p.Count
+						}
 						n = a.Name.String()
 					} else {
 						return nil, fmt.Errorf("ambiguous exposed port in PodManifest: %q and %q both define port %q", n, a.Name, p.Name)
@@ -463,14 +468,52 @@ NextPort:
 			return nil, fmt.Errorf("port name %q is not defined by any apps", ep.Name)
 		}
 
-		fps = append(fps, fp)
+		if ep.HostPort+count > 65536 {
+			return nil, fmt.Errorf("host port range of port %q starting at %d is out of the 1-65535 range", ep.Name, ep.HostPort)
+		}
+
+		if hostIP, ok := pod.Manifest.Annotations.Get(common.PortHostIPAnnotation(ep.Name).String()); ok {
+			fp.HostIP = net.ParseIP(hostIP)
+			if fp.HostIP == nil {
+				return nil, fmt.Errorf("invalid host IP %q of port %q", hostIP, ep.Name)
+			}
+		}
+		fp.Network, _ = pod.Manifest.Annotations.Get(common.PortNetworkAnnotation(ep.Name).String())
+
+		// port ranges are forwarded port by port, the host port
+		// range maps onto the app port range
+		for i := uint(0); i < count; i++ {
+			rfp := fp
+			rfp.HostPort += i
+			rfp.PodPort += i
+			fps = append(fps, rfp)
+		}
 	}
 
-	// TODO(eyakubovich): validate that there're no conflicts
+	if err := checkForwardedPortConflicts(fps); err != nil {
+		return nil, err
+	}
 
 	return fps, nil
 }
 
+// checkForwardedPortConflicts returns an error if a host port is forwarded
+// twice for the same protocol and host IP. TCP and UDP ports with the same
+// number do not conflict.
+func checkForwardedPortConflicts(fps []networking.ForwardedPort) error {
+	for i, a := range fps {
+		for _, b := range fps[:i] {
+			if a.Protocol != b.Protocol || a.HostPort != b.HostPort {
+				continue
+			}
+			if a.HostIP == nil || b.HostIP == nil || a.HostIP.Equal(b.HostIP) {
+				return fmt.Errorf("conflicting exposed ports in PodManifest: host port %d/%s is exposed twice", a.HostPort, a.Protocol)
+			}
+		}
+	}
+	return nil
+}
+
 func stage1() int {
 	uuid, err := types.NewUUID(flag.Arg(0))
 	if err != nil {
@@ -757,6 +800,9 @@ func getContainerSubCgroup(machineID string) (string, error) {
 }
 
 func main() {
+	// the userspace port forwarding proxies are multicall commands
+	multicall.MaybeExec()
+
 	flag.Parse()
 
 	stage1initcommon.InitDebug(debug)
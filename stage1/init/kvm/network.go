@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"net"
 	"path/filepath"
+	"strings"
 
 	"github.com/appc/cni/pkg/types"
 	"github.com/coreos/go-systemd/unit"
@@ -47,6 +48,11 @@ type netDescriber interface {
 	Name() string
 	Gateway() net.IP
 	Routes() []types.Route
+	// the IPv6 configuration, the addresses are nil if there is none
+	GuestIP6() net.IP
+	Mask6() net.IP
+	Gateway6() net.IP
+	Routes6() []types.Route
 }
 
 // GetKVMNetArgs returns additional arguments that need to be passed
@@ -59,7 +65,11 @@ func GetKVMNetArgs(nds []netDescriber) ([]string, error) {
 
 	for _, nd := range nds {
 		lkvmArgs = append(lkvmArgs, "--network")
-		lkvmArg := fmt.Sprintf("mode=tap,tapif=%s,host_ip=%s,guest_ip=%s", nd.IfName(), nd.Gateway(), nd.GuestIP())
+		lkvmArg := fmt.Sprintf("mode=tap,tapif=%s", nd.IfName())
+		// lkvm only takes IPv4 addresses, IPv6-only networks have none
+		if nd.GuestIP() != nil {
+			lkvmArg += fmt.Sprintf(",host_ip=%s,guest_ip=%s", nd.Gateway(), nd.GuestIP())
+		}
 		lkvmArgs = append(lkvmArgs, lkvmArg)
 	}
 
@@ -107,12 +117,6 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []netDescri
 
 	for i, netDescription := range netDescriptions {
 		ifName := fmt.Sprintf(networking.IfNamePattern, i)
-		netAddress := net.IPNet{
-			IP:   netDescription.GuestIP(),
-			Mask: net.IPMask(netDescription.Mask()),
-		}
-
-		address := netAddress.String()
 
 		mac, err := generateMacAddress()
 		if err != nil {
@@ -127,10 +131,29 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []netDescri
 			unit.NewUnitOption("Service", "ExecStartPre", downInterfaceCommand(ifName)),
 			unit.NewUnitOption("Service", "ExecStartPre", setMacCommand(ifName, mac.String())),
 			unit.NewUnitOption("Service", "ExecStartPre", upInterfaceCommand(ifName)),
-			unit.NewUnitOption("Service", "ExecStart", addAddressCommand(address, ifName)),
 			unit.NewUnitOption("Install", "RequiredBy", "default.target"),
 		}
 
+		// the network may have an IPv4 address, an IPv6 one or both
+		var addresses []string
+		if guestIP := netDescription.GuestIP(); guestIP != nil {
+			netAddress := net.IPNet{
+				IP:   guestIP,
+				Mask: net.IPMask(netDescription.Mask()),
+			}
+			addresses = append(addresses, netAddress.String())
+		}
+		if guestIP6 := netDescription.GuestIP6(); guestIP6 != nil {
+			netAddress6 := net.IPNet{
+				IP:   guestIP6,
+				Mask: net.IPMask(netDescription.Mask6()),
+			}
+			addresses = append(addresses, netAddress6.String())
+		}
+		for _, address := range addresses {
+			opts = append(opts, unit.NewUnitOption("Service", "ExecStart", addAddressCommand(address, ifName)))
+		}
+
 		for _, route := range netDescription.Routes() {
 			gw := route.GW
 			if gw == nil {
@@ -147,6 +170,22 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []netDescri
 			)
 		}
 
+		for _, route := range netDescription.Routes6() {
+			gw := route.GW
+			if gw == nil {
+				gw = netDescription.Gateway6()
+			}
+
+			opts = append(
+				opts,
+				unit.NewUnitOption(
+					"Service",
+					"ExecStartPost",
+					addRouteCommand(route.Dst.String(), gw.String()),
+				),
+			)
+		}
+
 		unitName := fmt.Sprintf("interface-%s", ifName) + ".service"
 		unitBytes, err := ioutil.ReadAll(unit.Serialize(opts))
 		if err != nil {
@@ -158,7 +197,7 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []netDescri
 			return errwrap.Wrap(fmt.Errorf("failed to create network unit file %q", unitName), err)
 		}
 
-		rlog.Printf("network unit created: %q in %q (iface=%q, addr=%q)", unitName, unitsPath, ifName, address)
+		rlog.Printf("network unit created: %q in %q (iface=%q, addr=%q)", unitName, unitsPath, ifName, strings.Join(addresses, ","))
 	}
 	return nil
 }
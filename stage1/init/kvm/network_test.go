@@ -15,29 +15,40 @@
 package kvm
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/appc/cni/pkg/types"
 )
 
 type testNetDescriber struct {
-	hostIP  net.IP
-	guestIP net.IP
-	mask    net.IP
-	name    string
-	ifName  string
-	ipMasq  bool
+	hostIP   net.IP
+	guestIP  net.IP
+	mask     net.IP
+	name     string
+	ifName   string
+	ipMasq   bool
+	guestIP6 net.IP
+	mask6    net.IP
+	routes6  []types.Route
 }
 
-func (t testNetDescriber) HostIP() net.IP        { return t.hostIP }
-func (t testNetDescriber) GuestIP() net.IP       { return t.guestIP }
-func (t testNetDescriber) Mask() net.IP          { return t.mask }
-func (t testNetDescriber) IfName() string        { return t.ifName }
-func (t testNetDescriber) IPMasq() bool          { return t.ipMasq }
-func (t testNetDescriber) Name() string          { return t.name }
-func (t testNetDescriber) Gateway() net.IP       { return net.IP{1, 1, 1, 1} }
-func (t testNetDescriber) Routes() []types.Route { return []types.Route{} }
+func (t testNetDescriber) HostIP() net.IP         { return t.hostIP }
+func (t testNetDescriber) GuestIP() net.IP        { return t.guestIP }
+func (t testNetDescriber) Mask() net.IP           { return t.mask }
+func (t testNetDescriber) IfName() string         { return t.ifName }
+func (t testNetDescriber) IPMasq() bool           { return t.ipMasq }
+func (t testNetDescriber) Name() string           { return t.name }
+func (t testNetDescriber) Gateway() net.IP        { return t.hostIP }
+func (t testNetDescriber) Routes() []types.Route  { return []types.Route{} }
+func (t testNetDescriber) GuestIP6() net.IP       { return t.guestIP6 }
+func (t testNetDescriber) Mask6() net.IP          { return t.mask6 }
+func (t testNetDescriber) Gateway6() net.IP       { return net.ParseIP("fd00::1") }
+func (t testNetDescriber) Routes6() []types.Route { return t.routes6 }
 
 func TestGetKVMNetArgs(t *testing.T) {
 	tests := []struct {
@@ -53,6 +64,7 @@ func TestGetKVMNetArgs(t *testing.T) {
 					"test-net",
 					"fooInt",
 					false,
+					nil, nil, nil,
 				},
 			},
 			expectedLkvm: []string{"--network", "mode=tap,tapif=fooInt,host_ip=1.1.1.1,guest_ip=2.2.2.2"},
@@ -66,6 +78,7 @@ func TestGetKVMNetArgs(t *testing.T) {
 					"test-net",
 					"barInt",
 					true,
+					nil, nil, nil,
 				},
 			},
 			expectedLkvm: []string{"--network", "mode=tap,tapif=barInt,host_ip=1.1.1.1,guest_ip=2.2.2.2"},
@@ -79,6 +92,7 @@ func TestGetKVMNetArgs(t *testing.T) {
 					"test-net",
 					"fooInt",
 					false,
+					nil, nil, nil,
 				},
 				testNetDescriber{
 					net.ParseIP("1.1.1.1"),
@@ -87,6 +101,7 @@ func TestGetKVMNetArgs(t *testing.T) {
 					"test-net",
 					"barInt",
 					true,
+					nil, nil, nil,
 				},
 			},
 			expectedLkvm: []string{
@@ -94,6 +109,22 @@ func TestGetKVMNetArgs(t *testing.T) {
 				"--network", "mode=tap,tapif=barInt,host_ip=1.1.1.1,guest_ip=2.2.2.2",
 			},
 		},
+		{ // IPv6-only network, no IPv4 addresses for lkvm
+			netDescriptions: []netDescriber{
+				testNetDescriber{
+					nil,
+					nil,
+					nil,
+					"test-net",
+					"fooInt",
+					false,
+					net.ParseIP("fd00::2"),
+					net.IP(net.CIDRMask(64, 128)),
+					nil,
+				},
+			},
+			expectedLkvm: []string{"--network", "mode=tap,tapif=fooInt"},
+		},
 	}
 
 	for i, tt := range tests {
@@ -112,3 +143,83 @@ func TestGetKVMNetArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateNetworkInterfaceUnitsIPv6(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rkt-kvm-network-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, defaultRoute6, _ := net.ParseCIDR("::/0")
+	nds := []netDescriber{
+		testNetDescriber{
+			net.ParseIP("1.1.1.1"),
+			net.ParseIP("2.2.2.2"),
+			net.ParseIP("255.255.255.0"),
+			"test-net",
+			"fooInt",
+			false,
+			net.ParseIP("fd00::2"),
+			net.IP(net.CIDRMask(64, 128)),
+			[]types.Route{{Dst: *defaultRoute6}},
+		},
+	}
+	if err := GenerateNetworkInterfaceUnits(dir, nds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "interface-eth0.service"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"ExecStart=/bin/ip address add 2.2.2.2/24 dev eth0",
+		"ExecStart=/bin/ip address add fd00::2/64 dev eth0",
+		"ExecStartPost=/bin/ip route add ::/0 via fd00::1",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("expected %q in the unit, got:\n%s", expected, b)
+		}
+	}
+}
+
+func TestGenerateNetworkInterfaceUnitsIPv6Only(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rkt-kvm-network-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	nds := []netDescriber{
+		testNetDescriber{
+			nil,
+			nil,
+			nil,
+			"test-net",
+			"fooInt",
+			false,
+			net.ParseIP("fd00::2"),
+			net.IP(net.CIDRMask(64, 128)),
+			nil,
+		},
+	}
+	if err := GenerateNetworkInterfaceUnits(dir, nds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "interface-eth0.service"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var addressCommands []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "ExecStart=") {
+			addressCommands = append(addressCommands, line)
+		}
+	}
+	expected := "ExecStart=/bin/ip address add fd00::2/64 dev eth0"
+	if len(addressCommands) != 1 || addressCommands[0] != expected {
+		t.Errorf("expected only %q in the unit, got:\n%s", expected, b)
+	}
+}
@@ -8,6 +8,7 @@ _S1_SS_SUBDIRS_ := \
 	net \
 	init \
 	gc \
+	stop \
 	reaper \
 	units \
 	aci
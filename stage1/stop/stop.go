@@ -0,0 +1,160 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"syscall"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+
+	"github.com/coreos/rkt/common"
+	rktlog "github.com/coreos/rkt/pkg/log"
+	"github.com/coreos/rkt/stage1/common/ssh"
+)
+
+const (
+	// sigRTMin is SIGRTMIN as seen by programs linked against glibc,
+	// which reserves the first two real-time signals for itself.
+	sigRTMin = 34
+	// sigHalt makes systemd start halt.target, see systemd(1).
+	sigHalt = syscall.Signal(sigRTMin + 3)
+)
+
+var (
+	debug  bool
+	force  bool
+	podPid int
+)
+
+func init() {
+	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
+	flag.BoolVar(&force, "force", false, "Forced stopping")
+	flag.IntVar(&podPid, "pid", 0, "Pod PID")
+}
+
+func main() {
+	flag.Parse()
+
+	log, diag, _ := rktlog.NewLogSet("stop", debug)
+	if !debug {
+		diag.SetOutput(ioutil.Discard)
+	}
+
+	podID, err := types.NewUUID(flag.Arg(0))
+	if err != nil {
+		log.Fatal("UUID is missing or malformed")
+	}
+
+	if podPid <= 0 {
+		log.Fatal("--pid is missing or invalid")
+	}
+
+	flavor, err := os.Readlink(filepath.Join(common.Stage1RootfsPath("."), "flavor"))
+	if err != nil {
+		log.FatalE("failed to get stage1 flavor", err)
+	}
+
+	switch {
+	case force && flavor == "kvm":
+		diag.Printf("stopping the virtual machine of pod %q", podID)
+		if err := stopKVM(podID); err != nil {
+			log.FatalE("error stopping the virtual machine", err)
+		}
+	case force:
+		diag.Printf("killing pod %q (pid %d)", podID, podPid)
+		if err := syscall.Kill(podPid, syscall.SIGKILL); err != nil {
+			log.FatalE("error killing the pod", err)
+		}
+	case flavor == "kvm":
+		diag.Printf("halting the virtual machine of pod %q", podID)
+		if err := haltKVM(); err != nil {
+			log.FatalE("error halting the virtual machine", err)
+		}
+	default:
+		// systemd is the pod's PID 1, start halt.target to stop the
+		// apps and shut the pod down cleanly.
+		diag.Printf("sending SIGRTMIN+3 to pod %q (pid %d)", podID, podPid)
+		if err := syscall.Kill(podPid, sigHalt); err != nil {
+			log.FatalE("error stopping the pod", err)
+		}
+	}
+}
+
+// haltKVM asks systemd in the virtual machine of the pod to start
+// halt.target, like sigHalt does with the other flavors, so the apps are
+// stopped and the guest shuts down cleanly. lkvm boots the guest without
+// ACPI, so there is no power button to press; the command is run over the
+// ssh server of the virtual machine, as with "rkt enter".
+func haltKVM() error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get working directory"), err)
+	}
+	podIP, err := ssh.GetPodDefaultIP(workDir)
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot load networking configuration"), err)
+	}
+	u, err := user.Current()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get current user"), err)
+	}
+	if err := ssh.CheckSSHSetup(workDir, u); err != nil {
+		return errwrap.Wrap(errors.New("error setting up ssh keys"), err)
+	}
+
+	args := append(ssh.Args(podIP, u, false), "/usr/bin/systemctl", "--no-block", "halt")
+	sshPath, err := exec.LookPath(args[0])
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot find 'ssh' binary in PATH"), err)
+	}
+	cmd := exec.Cmd{
+		Path:   sshPath,
+		Args:   args,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if err := cmd.Run(); err != nil {
+		return errwrap.Wrap(errors.New("systemctl halt failed"), err)
+	}
+	return nil
+}
+
+// stopKVM asks lkvm to stop the virtual machine of the pod, without
+// shutting the guest down.
+func stopKVM(podID *types.UUID) error {
+	lkvmPath := filepath.Join(common.Stage1RootfsPath("."), "lkvm")
+	cmd := exec.Command(lkvmPath, "stop", "--name", "rkt-"+podID.String())
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// lkvm requires $HOME to be defined to find the socket of the
+	// virtual machine, as in stage1/init.
+	if os.Getenv("HOME") == "" {
+		cmd.Env = append(os.Environ(), "HOME=/root")
+	}
+	if err := cmd.Run(); err != nil {
+		return errwrap.Wrap(errors.New("lkvm stop failed"), err)
+	}
+	return nil
+}
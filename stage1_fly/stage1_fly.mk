@@ -2,7 +2,7 @@ FLY_ACIDIR := $(BUILDDIR)/aci-for-fly-flavor
 FLY_ACIROOTFSDIR := $(FLY_ACIDIR)/rootfs
 FLY_TOOLSDIR := $(TOOLSDIR)/fly
 FLY_STAMPS :=
-FLY_SUBDIRS := run gc enter aci
+FLY_SUBDIRS := run gc enter stop aci
 FLY_STAGE1 := $(BINDIR)/stage1-fly.aci
 
 $(call setup-stamp-file,FLY_STAMP,aci-build)
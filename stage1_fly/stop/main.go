@@ -0,0 +1,62 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"syscall"
+
+	rktlog "github.com/coreos/rkt/pkg/log"
+)
+
+var (
+	debug  bool
+	force  bool
+	podPid int
+
+	log  *rktlog.Logger
+	diag *rktlog.Logger
+)
+
+func init() {
+	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
+	flag.BoolVar(&force, "force", false, "Forced stopping")
+	flag.IntVar(&podPid, "pid", 0, "Pod PID")
+}
+
+func main() {
+	flag.Parse()
+
+	log, diag, _ = rktlog.NewLogSet("stop", debug)
+	if !debug {
+		diag.SetOutput(ioutil.Discard)
+	}
+
+	if podPid <= 0 {
+		log.Fatal("--pid is missing or invalid")
+	}
+
+	// There is no init process in a fly pod, the app is the pod's PID 1.
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+
+	diag.Printf("sending %v to pid %d", sig, podPid)
+	if err := syscall.Kill(podPid, sig); err != nil {
+		log.FatalE("error stopping the pod", err)
+	}
+}
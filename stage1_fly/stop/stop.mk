@@ -0,0 +1 @@
+include stage1_fly/makelib/aci_binary.mk
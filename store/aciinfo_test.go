@@ -15,32 +15,38 @@
 package store
 
 import (
-	"database/sql"
 	"io/ioutil"
 	"os"
 	"testing"
 )
 
 func TestWriteACIInfo(t *testing.T) {
+	for _, kind := range []DBKind{DBQL, DBKV} {
+		testWriteACIInfo(t, kind)
+	}
+}
+
+func testWriteACIInfo(t *testing.T, kind DBKind) {
 	dir, err := ioutil.TempDir("", tstprefix)
 	if err != nil {
 		t.Fatalf("error creating tempdir: %v", err)
 	}
 	defer os.RemoveAll(dir)
-	s, err := NewStore(dir)
+	s, err := NewStoreWithDB(dir, kind)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
-	if err = s.db.Do(func(tx *sql.Tx) error {
+	defer s.Close()
+	if err = s.db.Do(func(tx DBTx) error {
 		aciinfo := &ACIInfo{
 			BlobKey: "key01",
 			Name:    "name01",
 		}
-		if err := WriteACIInfo(tx, aciinfo); err != nil {
+		if err := tx.WriteACIInfo(aciinfo); err != nil {
 			return err
 		}
 		// Insert it another time to check that is should be overwritten
-		if err := WriteACIInfo(tx, aciinfo); err != nil {
+		if err := tx.WriteACIInfo(aciinfo); err != nil {
 			return err
 		}
 		return nil
@@ -50,44 +56,44 @@ func TestWriteACIInfo(t *testing.T) {
 
 	var aciinfos []*ACIInfo
 	ok := false
-	if err = s.db.Do(func(tx *sql.Tx) error {
-		aciinfos, ok, err = GetACIInfosWithName(tx, "name01")
+	if err = s.db.Do(func(tx DBTx) error {
+		aciinfos, ok, err = tx.GetACIInfosWithName("name01")
 		return err
 	}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	if !ok {
-		t.Fatalf("expected some records but none found")
+		t.Fatalf("%v: expected some records but none found", kind)
 	}
 	if len(aciinfos) != 1 {
-		t.Fatalf("wrong number of records returned, wanted: 1, got: %d", len(aciinfos))
+		t.Fatalf("%v: wrong number of records returned, wanted: 1, got: %d", kind, len(aciinfos))
 	}
 
 	// Add another ACIInfo for the same app name
-	if err = s.db.Do(func(tx *sql.Tx) error {
+	if err = s.db.Do(func(tx DBTx) error {
 		aciinfo := &ACIInfo{
 			BlobKey: "key02",
 			Name:    "name01",
 		}
-		if err := WriteACIInfo(tx, aciinfo); err != nil {
+		if err := tx.WriteACIInfo(aciinfo); err != nil {
 			return err
 		}
 		return nil
 	}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if err = s.db.Do(func(tx *sql.Tx) error {
-		aciinfos, ok, err = GetACIInfosWithName(tx, "name01")
+	if err = s.db.Do(func(tx DBTx) error {
+		aciinfos, ok, err = tx.GetACIInfosWithName("name01")
 		return err
 	}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	if !ok {
-		t.Fatalf("expected some records but none found")
+		t.Fatalf("%v: expected some records but none found", kind)
 	}
 	if len(aciinfos) != 2 {
-		t.Fatalf("wrong number of records returned, wanted: 2, got: %d", len(aciinfos))
+		t.Fatalf("%v: wrong number of records returned, wanted: 2, got: %d", kind, len(aciinfos))
 	}
 }
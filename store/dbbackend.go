@@ -0,0 +1,377 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// DBKind is the kind of database used for the index of the store, holding
+// the ACI infos and the remotes.
+type DBKind int
+
+const (
+	// DBDefault is the kind of the database already used by the store,
+	// or DBQL for a new store.
+	DBDefault DBKind = iota
+	// DBQL is a ql SQL database.
+	DBQL
+	// DBKV is a bolt key-value database. Unlike DBQL, it allows
+	// concurrent read-only transactions.
+	DBKV
+)
+
+// String returns the name of the kind, as used in the configuration.
+func (k DBKind) String() string {
+	switch k {
+	case DBDefault:
+		return "default"
+	case DBQL:
+		return "ql"
+	case DBKV:
+		return "kv"
+	}
+	return fmt.Sprintf("DBKind(%d)", int(k))
+}
+
+// ParseDBKind returns the kind of database with the given name.
+func ParseDBKind(name string) (DBKind, error) {
+	for _, k := range []DBKind{DBDefault, DBQL, DBKV} {
+		if k.String() == name {
+			return k, nil
+		}
+	}
+	return DBDefault, fmt.Errorf("unknown store database kind %q", name)
+}
+
+// DBTx is a transaction on the index of the store. The semantics of the
+// methods are the ones of the functions with the same name operating on
+// the ql database.
+type DBTx interface {
+	GetACIInfosWithKeyPrefix(prefix string) ([]*ACIInfo, error)
+	GetACIInfosWithName(name string) ([]*ACIInfo, bool, error)
+	GetACIInfoWithBlobKey(blobKey string) (*ACIInfo, bool, error)
+	GetAllACIInfos(sortfields []string, ascending bool) ([]*ACIInfo, error)
+	WriteACIInfo(aciinfo *ACIInfo) error
+	RemoveACIInfo(blobKey string) error
+	GetRemote(aciURL string) (*Remote, bool, error)
+	GetAllRemotes() ([]*Remote, error)
+	WriteRemote(remote *Remote) error
+	RemoveRemote(blobKey string) error
+}
+
+type dbTxFunc func(DBTx) error
+
+// dbBackend is a database used for the index of the store.
+type dbBackend interface {
+	// kind returns the kind of the database.
+	kind() DBKind
+	// dir returns the directory of the database, which is backed up
+	// before migrations.
+	dir() string
+	// exists tells whether the database has been created.
+	exists() (bool, error)
+	// setup creates the database, or migrates it to the last version
+	// of its schema. It returns true if the image sizes have to be
+	// populated.
+	setup(s *Store) (bool, error)
+	// Do executes the provided functions inside a unique read-write
+	// transaction. If one of the functions returns an error the whole
+	// transaction is rolled back.
+	Do(fns ...dbTxFunc) error
+	// View executes the provided functions inside a unique read-only
+	// transaction.
+	View(fns ...dbTxFunc) error
+}
+
+// openDB opens the database of the store, migrating the data of the
+// database used by the store to a database of the requested kind if
+// needed.
+func (s *Store) openDB(kind DBKind) error {
+	qldb, err := NewDB(filepath.Join(s.dir, "db"))
+	if err != nil {
+		return err
+	}
+	backends := map[DBKind]dbBackend{
+		DBQL: &qlBackend{db: qldb},
+		DBKV: newKVDB(filepath.Join(s.dir, "kvdb")),
+	}
+
+	current, err := currentDBKind(backends)
+	if err != nil {
+		return err
+	}
+	if kind == DBDefault {
+		kind = current
+	}
+	db, ok := backends[kind]
+	if !ok {
+		return fmt.Errorf("unknown store database kind %v", kind)
+	}
+
+	if kind != current {
+		currentExists, err := backends[current].exists()
+		if err != nil {
+			return err
+		}
+		if currentExists {
+			if err := s.migrateDB(backends[current], db); err != nil {
+				return errwrap.Wrap(fmt.Errorf("failed to migrate the store database from %v to %v", current, kind), err)
+			}
+		}
+	}
+
+	s.db = db
+	needsSizePopulation, err := db.setup(s)
+	if err != nil {
+		return err
+	}
+	if needsSizePopulation {
+		if err := s.populateSize(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentDBKind returns the kind of the database used by the store. The
+// kv database is the current one if it exists, as it is the database
+// created last during a migration.
+func currentDBKind(backends map[DBKind]dbBackend) (DBKind, error) {
+	exists, err := backends[DBKV].exists()
+	if err != nil {
+		return DBDefault, err
+	}
+	if exists {
+		return DBKV, nil
+	}
+	return DBQL, nil
+}
+
+// migrateDB copies the data of the src database to the dst database, then
+// backs up and removes the src database.
+func (s *Store) migrateDB(src, dst dbBackend) error {
+	// Take an exclusive store lock
+	if err := s.storeLock.ExclusiveLock(); err != nil {
+		return err
+	}
+	// Another process could have migrated the database in the meantime
+	exists, err := src.exists()
+	if err != nil || !exists {
+		return err
+	}
+
+	needsSizePopulation, err := src.setup(s)
+	if err != nil {
+		return err
+	}
+
+	var aciinfos []*ACIInfo
+	var remotes []*Remote
+	if err := src.View(func(tx DBTx) error {
+		var err error
+		if aciinfos, err = tx.GetAllACIInfos(nil, false); err != nil {
+			return err
+		}
+		remotes, err = tx.GetAllRemotes()
+		return err
+	}); err != nil {
+		return errwrap.Wrap(errors.New("error reading the database"), err)
+	}
+
+	if _, err := dst.setup(s); err != nil {
+		return err
+	}
+	if err := dst.Do(func(tx DBTx) error {
+		for _, aciinfo := range aciinfos {
+			if err := tx.WriteACIInfo(aciinfo); err != nil {
+				return err
+			}
+		}
+		for _, remote := range remotes {
+			if err := tx.WriteRemote(remote); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errwrap.Wrap(errors.New("error writing the database"), err)
+	}
+
+	if err := createBackup(src.dir(), filepath.Join(s.dir, "db-backups"), backupsNumber); err != nil {
+		return errwrap.Wrap(errors.New("error backing up the database"), err)
+	}
+	if err := os.RemoveAll(src.dir()); err != nil {
+		return err
+	}
+
+	if needsSizePopulation {
+		s.db = dst
+		return s.populateSize()
+	}
+	return nil
+}
+
+// qlBackend is the index of the store in a ql database.
+type qlBackend struct {
+	db *DB
+}
+
+func (b *qlBackend) kind() DBKind {
+	return DBQL
+}
+
+func (b *qlBackend) dir() string {
+	return b.db.dbdir
+}
+
+func (b *qlBackend) exists() (bool, error) {
+	_, err := os.Stat(filepath.Join(b.db.dbdir, DbFilename))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *qlBackend) setup(s *Store) (bool, error) {
+	needsMigrate := false
+	needsSizePopulation := false
+	fn := func(tx *sql.Tx) error {
+		var err error
+		ok, err := dbIsPopulated(tx)
+		if err != nil {
+			return err
+		}
+		// populate the db
+		if !ok {
+			for _, stmt := range dbCreateStmts {
+				_, err = tx.Exec(stmt)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// if db is populated check its version
+		version, err := getDBVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version < dbVersion {
+			needsMigrate = true
+		}
+		if version > dbVersion {
+			return fmt.Errorf("current store db version: %d (greater than the current rkt expected version: %d)", version, dbVersion)
+		}
+		if version < 5 {
+			needsSizePopulation = true
+		}
+		return nil
+	}
+	if err := b.db.Do(fn); err != nil {
+		return false, err
+	}
+
+	// migration is done in another transaction as it must take an exclusive
+	// store lock. If, in the meantime, another process has already done the
+	// migration, between the previous db version check and the below
+	// migration code, the migration will do nothing as it'll start
+	// migration from the current version.
+	if !needsMigrate {
+		return false, nil
+	}
+	// Take an exclusive store lock
+	if err := s.storeLock.ExclusiveLock(); err != nil {
+		return false, err
+	}
+	if err := s.backupDB(b); err != nil {
+		return false, err
+	}
+	fn = func(tx *sql.Tx) error {
+		return migrate(tx, dbVersion)
+	}
+	if err := b.db.Do(fn); err != nil {
+		return false, err
+	}
+	return needsSizePopulation, nil
+}
+
+func (b *qlBackend) Do(fns ...dbTxFunc) error {
+	return b.db.Do(func(tx *sql.Tx) error {
+		qtx := &qlTx{tx: tx}
+		for _, fn := range fns {
+			if err := fn(qtx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// View is the same as Do, as ql transactions are always exclusive.
+func (b *qlBackend) View(fns ...dbTxFunc) error {
+	return b.Do(fns...)
+}
+
+// qlTx is a transaction on the ql database.
+type qlTx struct {
+	tx *sql.Tx
+}
+
+func (t *qlTx) GetACIInfosWithKeyPrefix(prefix string) ([]*ACIInfo, error) {
+	return GetACIInfosWithKeyPrefix(t.tx, prefix)
+}
+
+func (t *qlTx) GetACIInfosWithName(name string) ([]*ACIInfo, bool, error) {
+	return GetACIInfosWithName(t.tx, name)
+}
+
+func (t *qlTx) GetACIInfoWithBlobKey(blobKey string) (*ACIInfo, bool, error) {
+	return GetACIInfoWithBlobKey(t.tx, blobKey)
+}
+
+func (t *qlTx) GetAllACIInfos(sortfields []string, ascending bool) ([]*ACIInfo, error) {
+	return GetAllACIInfos(t.tx, sortfields, ascending)
+}
+
+func (t *qlTx) WriteACIInfo(aciinfo *ACIInfo) error {
+	return WriteACIInfo(t.tx, aciinfo)
+}
+
+func (t *qlTx) RemoveACIInfo(blobKey string) error {
+	return RemoveACIInfo(t.tx, blobKey)
+}
+
+func (t *qlTx) GetRemote(aciURL string) (*Remote, bool, error) {
+	return GetRemote(t.tx, aciURL)
+}
+
+func (t *qlTx) GetAllRemotes() ([]*Remote, error) {
+	return GetAllRemotes(t.tx)
+}
+
+func (t *qlTx) WriteRemote(remote *Remote) error {
+	return WriteRemote(t.tx, remote)
+}
+
+func (t *qlTx) RemoveRemote(blobKey string) error {
+	return RemoveRemote(t.tx, blobKey)
+}
@@ -0,0 +1,238 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type migrateDBTest struct {
+	from     DBKind
+	to       DBKind
+	aciinfos []*ACIInfo
+	remotes  []*Remote
+}
+
+func testMigrateDB(tt migrateDBTest) error {
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		return fmt.Errorf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewStoreWithDB(dir, tt.from)
+	if err != nil {
+		return err
+	}
+	if kind := s.db.kind(); kind != tt.from {
+		return fmt.Errorf("wrong db kind: got %v, want %v", kind, tt.from)
+	}
+	if err := s.db.Do(func(tx DBTx) error {
+		for _, aciinfo := range tt.aciinfos {
+			if err := tx.WriteACIInfo(aciinfo); err != nil {
+				return err
+			}
+		}
+		for _, remote := range tt.remotes {
+			if err := tx.WriteRemote(remote); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	fromDir := s.db.dir()
+	s.Close()
+
+	s, err = NewStoreWithDB(dir, tt.to)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if kind := s.db.kind(); kind != tt.to {
+		return fmt.Errorf("wrong db kind: got %v, want %v", kind, tt.to)
+	}
+	if _, err := os.Stat(fromDir); !os.IsNotExist(err) {
+		return fmt.Errorf("db %q not removed after the migration", fromDir)
+	}
+	backups, err := ioutil.ReadDir(filepath.Join(s.dir, "db-backups"))
+	if err != nil {
+		return err
+	}
+	if len(backups) != 1 {
+		return fmt.Errorf("wrong number of db backups: got %d, want 1", len(backups))
+	}
+
+	// The default db is now the migrated one
+	s.Close()
+	s, err = NewStore(dir)
+	if err != nil {
+		return err
+	}
+	if kind := s.db.kind(); kind != tt.to {
+		return fmt.Errorf("wrong default db kind: got %v, want %v", kind, tt.to)
+	}
+
+	var aciinfos []*ACIInfo
+	var remotes []*Remote
+	if err := s.db.View(func(tx DBTx) error {
+		var err error
+		if aciinfos, err = tx.GetAllACIInfos([]string{"blobkey"}, true); err != nil {
+			return err
+		}
+		remotes, err = tx.GetAllRemotes()
+		return err
+	}); err != nil {
+		return err
+	}
+	if len(aciinfos) != len(tt.aciinfos) {
+		return fmt.Errorf("wrong number of aciinfos: got %d, want %d", len(aciinfos), len(tt.aciinfos))
+	}
+	for i, aciinfo := range aciinfos {
+		if !compareACIInfos(aciinfo, tt.aciinfos[i]) {
+			return fmt.Errorf("got %#v, want %#v", aciinfo, tt.aciinfos[i])
+		}
+	}
+	if len(remotes) != len(tt.remotes) {
+		return fmt.Errorf("wrong number of remotes: got %d, want %d", len(remotes), len(tt.remotes))
+	}
+	for _, remote := range tt.remotes {
+		found := false
+		for _, r := range remotes {
+			if compareRemotes(r, remote) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("remote %#v not found", remote)
+		}
+	}
+	return nil
+}
+
+func compareACIInfos(a, b *ACIInfo) bool {
+	return a.BlobKey == b.BlobKey && a.Name == b.Name &&
+		a.ImportTime.Equal(b.ImportTime) && a.LastUsed.Equal(b.LastUsed) &&
+		a.Latest == b.Latest && a.Size == b.Size && a.TreeStoreSize == b.TreeStoreSize
+}
+
+func compareRemotes(a, b *Remote) bool {
+	return a.ACIURL == b.ACIURL && a.SigURL == b.SigURL && a.ETag == b.ETag &&
+		a.BlobKey == b.BlobKey && a.CacheMaxAge == b.CacheMaxAge &&
+		a.DownloadTime.Equal(b.DownloadTime)
+}
+
+func TestMigrateDB(t *testing.T) {
+	// ql stores times with a nanosecond precision but without the
+	// monotonic clock reading and the location
+	now := time.Unix(time.Now().Unix(), 0)
+	aciinfos := []*ACIInfo{
+		{"sha512-aaaaaaaa", "example.com/app01", now, now, 10, 20, false},
+		{"sha512-bbbbbbbb", "example.com/app02", now, now.Add(time.Hour), 30, 0, true},
+	}
+	remotes := []*Remote{
+		{"http://example.com/app01.aci", "http://example.com/app01.aci.asc", "", "sha512-aaaaaaaa", 0, now},
+		{"http://example.com/app02.aci", "http://example.com/app02.aci.asc", "etag02", "sha512-bbbbbbbb", 100, now},
+	}
+	tests := []migrateDBTest{
+		// Empty dbs
+		{DBQL, DBKV, nil, nil},
+		{DBKV, DBQL, nil, nil},
+
+		{DBQL, DBKV, aciinfos, remotes},
+		{DBKV, DBQL, aciinfos, remotes},
+	}
+
+	for i, tt := range tests {
+		if err := testMigrateDB(tt); err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestGetAllACIInfosSorted(t *testing.T) {
+	now := time.Now()
+	aciinfos := []*ACIInfo{
+		{BlobKey: "sha512-aaaaaaaa", Name: "example.com/app02", ImportTime: now},
+		{BlobKey: "sha512-bbbbbbbb", Name: "example.com/app01", ImportTime: now.Add(time.Hour)},
+		{BlobKey: "sha512-cccccccc", Name: "example.com/app01", ImportTime: now.Add(-time.Hour)},
+	}
+	tests := []struct {
+		sortfields []string
+		ascending  bool
+		keys       []string
+	}{
+		{
+			[]string{"importtime"},
+			true,
+			[]string{"sha512-cccccccc", "sha512-aaaaaaaa", "sha512-bbbbbbbb"},
+		},
+		{
+			[]string{"importtime"},
+			false,
+			[]string{"sha512-bbbbbbbb", "sha512-aaaaaaaa", "sha512-cccccccc"},
+		},
+		{
+			[]string{"name", "importtime"},
+			true,
+			[]string{"sha512-cccccccc", "sha512-bbbbbbbb", "sha512-aaaaaaaa"},
+		},
+	}
+
+	for _, kind := range []DBKind{DBQL, DBKV} {
+		dir, err := ioutil.TempDir("", tstprefix)
+		if err != nil {
+			t.Fatalf("error creating tempdir: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		s, err := NewStoreWithDB(dir, kind)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer s.Close()
+		if err := s.db.Do(func(tx DBTx) error {
+			for _, aciinfo := range aciinfos {
+				if err := tx.WriteACIInfo(aciinfo); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for i, tt := range tests {
+			got, err := s.GetAllACIInfos(tt.sortfields, tt.ascending)
+			if err != nil {
+				t.Fatalf("%v: #%d: unexpected error: %v", kind, i, err)
+			}
+			keys := make([]string, len(got))
+			for j, aciinfo := range got {
+				keys[j] = aciinfo.BlobKey
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("%v: #%d: got %v, want %v", kind, i, keys, tt.keys)
+			}
+		}
+	}
+}
@@ -0,0 +1,448 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	specaci "github.com/appc/spec/aci"
+	"github.com/appc/spec/schema"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/hashicorp/errwrap"
+)
+
+// FsckProblemKind is the kind of an inconsistency found in the store.
+type FsckProblemKind int
+
+const (
+	// FsckBlobCorrupted is a blob whose contents don't match its key.
+	FsckBlobCorrupted FsckProblemKind = iota
+	// FsckBlobOrphaned is a blob without ACI info.
+	FsckBlobOrphaned
+	// FsckACIInfoDangling is an ACI info without blob.
+	FsckACIInfoDangling
+	// FsckRemoteDangling is a remote referencing a missing image.
+	FsckRemoteDangling
+	// FsckImageManifestMismatch is a missing cached image manifest, or
+	// one not matching the manifest in the blob.
+	FsckImageManifestMismatch
+	// FsckImageManifestOrphaned is a cached image manifest without blob.
+	FsckImageManifestOrphaned
+	// FsckTreeStorePartial is a tree store not fully rendered.
+	FsckTreeStorePartial
+	// FsckTreeStoreOrphaned is a tree store of a missing image.
+	FsckTreeStoreOrphaned
+	// FsckTreeStoreCorrupted is a tree store whose files don't match
+	// its hash.
+	FsckTreeStoreCorrupted
+)
+
+func (k FsckProblemKind) String() string {
+	switch k {
+	case FsckBlobCorrupted:
+		return "corrupted blob"
+	case FsckBlobOrphaned:
+		return "orphaned blob"
+	case FsckACIInfoDangling:
+		return "dangling aciinfo"
+	case FsckRemoteDangling:
+		return "dangling remote"
+	case FsckImageManifestMismatch:
+		return "mismatched image manifest"
+	case FsckImageManifestOrphaned:
+		return "orphaned image manifest"
+	case FsckTreeStorePartial:
+		return "partial treestore"
+	case FsckTreeStoreOrphaned:
+		return "orphaned treestore"
+	case FsckTreeStoreCorrupted:
+		return "corrupted treestore"
+	}
+	return fmt.Sprintf("FsckProblemKind(%d)", int(k))
+}
+
+// FsckProblem is an inconsistency found in the store.
+type FsckProblem struct {
+	Kind FsckProblemKind
+	// ID is the key of the image, the ACI URL of the remote, or the
+	// ID of the tree store.
+	ID string
+	// Err describes the problem, it can be nil.
+	Err error
+	// Repair describes the change done to repair the problem, it's empty
+	// if the problem was not repaired.
+	Repair string
+	// RepairErr is the error of a failed repair.
+	RepairErr error
+}
+
+func (p *FsckProblem) String() string {
+	s := fmt.Sprintf("%s %s", p.Kind, p.ID)
+	if p.Err != nil {
+		s += fmt.Sprintf(": %v", p.Err)
+	}
+	switch {
+	case p.RepairErr != nil:
+		s += fmt.Sprintf(" (repair failed: %v)", p.RepairErr)
+	case p.Repair != "":
+		s += fmt.Sprintf(" (%s)", p.Repair)
+	}
+	return s
+}
+
+// Repaired returns true if the problem has been repaired.
+func (p *FsckProblem) Repaired() bool {
+	return p.Repair != "" && p.RepairErr == nil
+}
+
+// FsckOptions are the options of Fsck.
+type FsckOptions struct {
+	// Repair enables the repair of the problems found.
+	Repair bool
+	// KeepTreeStoreIDs are the IDs of the tree stores which must not be
+	// removed nor re-rendered, like the ones used by the pods. They are
+	// still checked.
+	KeepTreeStoreIDs map[string]struct{}
+}
+
+// Fsck verifies the consistency of the store: the contents of the blobs,
+// the ACI infos and remotes in the database, the cached image manifests and
+// the tree stores. With opts.Repair the broken entries are removed, and the
+// broken tree stores of the images in the store are re-rendered.
+// It returns the problems found.
+func (s *Store) Fsck(opts FsckOptions) ([]*FsckProblem, error) {
+	var problems []*FsckProblem
+	report := func(p *FsckProblem, repair func() (string, error)) {
+		if opts.Repair && repair != nil {
+			desc, err := repair()
+			if err != nil {
+				p.RepairErr = err
+			} else {
+				p.Repair = desc
+			}
+		}
+		problems = append(problems, p)
+	}
+	removeImage := func(key string) func() (string, error) {
+		return func() (string, error) {
+			return "removed image", s.removeImageEntries(key)
+		}
+	}
+
+	blobKeys := diskvKeys(s, blobType)
+	blobs := make(map[string]struct{})
+	for _, key := range blobKeys {
+		blobs[key] = struct{}{}
+		kind, err := s.checkBlob(key)
+		if err == nil {
+			continue
+		}
+		if kind == FsckImageManifestMismatch {
+			k := key
+			report(&FsckProblem{Kind: kind, ID: key, Err: err}, func() (string, error) {
+				return "rewritten image manifest", s.rewriteImageManifest(k)
+			})
+			continue
+		}
+		report(&FsckProblem{Kind: kind, ID: key, Err: err}, removeImage(key))
+	}
+
+	for _, key := range diskvKeys(s, imageManifestType) {
+		if _, ok := blobs[key]; !ok {
+			k := key
+			report(&FsckProblem{Kind: FsckImageManifestOrphaned, ID: key}, func() (string, error) {
+				return "removed image manifest", s.eraseImageEntry(imageManifestType, k)
+			})
+		}
+	}
+
+	var aciinfos []*ACIInfo
+	var remotes []*Remote
+	if err := s.db.View(func(tx DBTx) error {
+		var err error
+		if aciinfos, err = tx.GetAllACIInfos(nil, false); err != nil {
+			return err
+		}
+		remotes, err = tx.GetAllRemotes()
+		return err
+	}); err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading the database"), err)
+	}
+	images := make(map[string]struct{})
+	for _, ai := range aciinfos {
+		if _, ok := blobs[ai.BlobKey]; !ok {
+			report(&FsckProblem{Kind: FsckACIInfoDangling, ID: ai.BlobKey}, removeImage(ai.BlobKey))
+			continue
+		}
+		images[ai.BlobKey] = struct{}{}
+	}
+	for _, remote := range remotes {
+		if _, ok := images[remote.BlobKey]; ok {
+			continue
+		}
+		r := remote
+		report(&FsckProblem{Kind: FsckRemoteDangling, ID: remote.ACIURL, Err: fmt.Errorf("missing image %q", remote.BlobKey)}, func() (string, error) {
+			return "removed remote", s.db.Do(func(tx DBTx) error {
+				return tx.RemoveRemote(r.BlobKey)
+			})
+		})
+	}
+
+	treeStoreIDs, err := s.GetTreeStoreIDs()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(treeStoreIDs)
+	for _, id := range treeStoreIDs {
+		kind, key, err := s.checkTreeStore(id, images)
+		if err == nil {
+			continue
+		}
+		p := &FsckProblem{Kind: kind, ID: id, Err: err}
+		if _, ok := opts.KeepTreeStoreIDs[id]; ok {
+			report(p, nil)
+			continue
+		}
+		treeStoreID := id
+		if kind == FsckTreeStorePartial {
+			report(p, func() (string, error) {
+				return s.removePartialTreeStore(treeStoreID)
+			})
+			continue
+		}
+		if kind != FsckTreeStoreCorrupted {
+			report(p, func() (string, error) {
+				return "removed treestore", s.RemoveTreeStore(treeStoreID)
+			})
+			continue
+		}
+		report(p, func() (string, error) {
+			newID, _, err := s.RenderTreeStore(key, true)
+			if err != nil {
+				return "", err
+			}
+			if newID != treeStoreID {
+				// the dependencies of the image changed
+				return "removed treestore", s.RemoveTreeStore(treeStoreID)
+			}
+			return "re-rendered treestore", nil
+		})
+	}
+
+	return problems, nil
+}
+
+// diskvKeys returns all the keys of the diskv store of the given type.
+func diskvKeys(s *Store, storeType int64) []string {
+	var keys []string
+	for key := range s.stores[storeType].Keys(nil) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkBlob verifies that the contents of the blob with key match the key,
+// that the blob has an ACI info and that the cached image manifest matches
+// the one in the blob. It returns the kind of the problem found.
+func (s *Store) checkBlob(key string) (FsckProblemKind, error) {
+	keyLock, err := lock.SharedKeyLock(s.imageLockDir, key)
+	if err != nil {
+		return FsckBlobCorrupted, errwrap.Wrap(errors.New("error locking image"), err)
+	}
+	defer keyLock.Close()
+
+	rc, err := s.stores[blobType].ReadStream(key, false)
+	if err != nil {
+		return FsckBlobCorrupted, errwrap.Wrap(errors.New("error reading blob"), err)
+	}
+	defer rc.Close()
+
+	// Hash the whole blob while looking for its manifest
+	h := sha512.New()
+	r := io.TeeReader(rc, h)
+	imj, merr := manifestFromTar(r)
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return FsckBlobCorrupted, errwrap.Wrap(errors.New("error reading blob"), err)
+	}
+	if hash := s.HashToKey(h); hash != key {
+		return FsckBlobCorrupted, fmt.Errorf("wrong blob hash: %s", hash)
+	}
+	if merr != nil {
+		return FsckBlobCorrupted, merr
+	}
+
+	// WriteACI writes the ACI info with the image key lock held
+	var found bool
+	if err := s.db.View(func(tx DBTx) error {
+		var err error
+		_, found, err = tx.GetACIInfoWithBlobKey(key)
+		return err
+	}); err != nil {
+		return FsckBlobOrphaned, errwrap.Wrap(errors.New("error getting aciinfo"), err)
+	}
+	if !found {
+		return FsckBlobOrphaned, errors.New("missing aciinfo")
+	}
+
+	cached, err := s.stores[imageManifestType].Read(key)
+	if err != nil {
+		return FsckImageManifestMismatch, errwrap.Wrap(errors.New("error reading image manifest"), err)
+	}
+	if !bytes.Equal(cached, imj) {
+		return FsckImageManifestMismatch, errors.New("image manifest doesn't match the one in the blob")
+	}
+	return 0, nil
+}
+
+// manifestFromTar returns the image manifest in the ACI read from r, in
+// the format used to cache it.
+func manifestFromTar(r io.Reader) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("missing image manifest")
+		}
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading tarball"), err)
+		}
+		if hdr.Name != specaci.ManifestFile {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading image manifest"), err)
+		}
+		im := &schema.ImageManifest{}
+		if err := im.UnmarshalJSON(data); err != nil {
+			return nil, errwrap.Wrap(errors.New("error unmarshalling image manifest"), err)
+		}
+		return json.Marshal(im)
+	}
+}
+
+// rewriteImageManifest replaces the cached image manifest of the image with
+// key with the manifest in its blob.
+func (s *Store) rewriteImageManifest(key string) error {
+	keyLock, err := lock.ExclusiveKeyLock(s.imageLockDir, key)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking image"), err)
+	}
+	defer keyLock.Close()
+
+	rc, err := s.stores[blobType].ReadStream(key, false)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error reading blob"), err)
+	}
+	defer rc.Close()
+	imj, err := manifestFromTar(rc)
+	if err != nil {
+		return err
+	}
+	return s.stores[imageManifestType].Write(key, imj)
+}
+
+// removeImageEntries removes all the entries of the image with key: the ACI
+// info and remotes, the blob and the cached image manifest. Unlike
+// RemoveACI, the entries don't need to exist.
+func (s *Store) removeImageEntries(key string) error {
+	keyLock, err := lock.ExclusiveKeyLock(s.imageLockDir, key)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking image"), err)
+	}
+	defer keyLock.Close()
+
+	if err := s.db.Do(func(tx DBTx) error {
+		if err := tx.RemoveACIInfo(key); err != nil {
+			return err
+		}
+		return tx.RemoveRemote(key)
+	}); err != nil {
+		return errwrap.Wrap(errors.New("cannot remove image from db"), err)
+	}
+	for _, t := range []int64{blobType, imageManifestType} {
+		if err := s.eraseImageEntry(t, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eraseImageEntry removes the entry with key from the diskv store of the
+// given type, if it exists.
+func (s *Store) eraseImageEntry(storeType int64, key string) error {
+	if err := s.stores[storeType].Erase(key); err != nil && !os.IsNotExist(err) {
+		return errwrap.Wrap(fmt.Errorf("cannot remove %s entry", diskvStores[storeType]), err)
+	}
+	return nil
+}
+
+// removePartialTreeStore removes the tree store with id, found partially
+// rendered. A tree store being rendered is partial too, so the removal is
+// done with the tree store lock held, and only if the tree store is still
+// not rendered once the lock is taken.
+func (s *Store) removePartialTreeStore(id string) (string, error) {
+	treeStoreKeyLock, err := lock.ExclusiveKeyLock(s.treeStoreLockDir, id)
+	if err != nil {
+		return "", errwrap.Wrap(errors.New("error locking tree store"), err)
+	}
+	defer treeStoreKeyLock.Close()
+
+	rendered, err := s.treestore.IsRendered(id)
+	if err != nil {
+		return "", errwrap.Wrap(errors.New("cannot determine if tree is rendered"), err)
+	}
+	if rendered {
+		return "rendered meanwhile, kept treestore", nil
+	}
+	if err := s.treestore.Remove(id, s); err != nil {
+		return "", errwrap.Wrap(errors.New("error removing the tree store"), err)
+	}
+	return "removed treestore", nil
+}
+
+// checkTreeStore verifies the tree store with id, which must be the tree
+// store of one of the images. It returns the kind of the problem found and
+// the key of the image of the tree store.
+func (s *Store) checkTreeStore(id string, images map[string]struct{}) (FsckProblemKind, string, error) {
+	rendered, err := s.treestore.IsRendered(id)
+	if err != nil {
+		return FsckTreeStorePartial, "", errwrap.Wrap(errors.New("cannot determine if tree is rendered"), err)
+	}
+	if !rendered {
+		return FsckTreeStorePartial, "", errors.New("not rendered")
+	}
+	key, err := s.treestore.GetImageHash(id)
+	if err != nil {
+		return FsckTreeStorePartial, "", err
+	}
+	if _, ok := images[key]; !ok {
+		return FsckTreeStoreOrphaned, key, fmt.Errorf("missing image %q", key)
+	}
+	if _, err := s.CheckTreeStore(id); err != nil {
+		return FsckTreeStoreCorrupted, key, err
+	}
+	return 0, key, nil
+}
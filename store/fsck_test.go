@@ -0,0 +1,273 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/pkg/aci"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/coreos/rkt/pkg/sys"
+)
+
+func writeTestACI(t *testing.T, s *Store, dir, name string) string {
+	imj := `
+		{
+		    "acKind": "ImageManifest",
+		    "acVersion": "0.7.4",
+		    "name": "` + name + `"
+		}
+	`
+	entries := []*aci.ACIEntry{
+		{
+			Header: &tar.Header{
+				Name:     "rootfs/a",
+				Typeflag: tar.TypeDir,
+			},
+		},
+		{
+			Contents: "hello",
+			Header: &tar.Header{
+				Name: "rootfs/hello.txt",
+				Size: 5,
+			},
+		},
+	}
+	aciFile, err := aci.NewACI(dir, imj, entries)
+	if err != nil {
+		t.Fatalf("error creating test tar: %v", err)
+	}
+	defer aciFile.Close()
+	if _, err := aciFile.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	key, err := s.WriteACI(aciFile, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return key
+}
+
+func fsckProblemsString(problems []*FsckProblem) []string {
+	var ps []string
+	for _, p := range problems {
+		ps = append(ps, p.Kind.String()+" "+p.ID)
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+func TestFsck(t *testing.T) {
+	if !sys.HasChrootCapability() {
+		t.Skipf("chroot capability not available. Disabling test.")
+	}
+
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	key01 := writeTestACI(t, s, dir, "example.com/test01")
+	key02 := writeTestACI(t, s, dir, "example.com/test02")
+	id, _, err := s.RenderTreeStore(key01, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	problems, err := s.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems in a consistent store: %v", fsckProblemsString(problems))
+	}
+
+	// Corrupt the blob of the second image
+	ds := s.stores[blobType]
+	blobPath := filepath.Join(append([]string{ds.BasePath}, append(ds.Transform(key02), key02)...)...)
+	f, err := os.OpenFile(blobPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Write([]byte("garbage")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+	// Remove the cached manifest of the first image
+	if err := s.stores[imageManifestType].Erase(key01); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Add entries of a missing image
+	missingKey := "sha512-0000000000000000000000000000000000000000000000000000000000000000"
+	if err := s.db.Do(func(tx DBTx) error {
+		if err := tx.WriteACIInfo(&ACIInfo{BlobKey: missingKey, Name: "example.com/missing"}); err != nil {
+			return err
+		}
+		return tx.WriteRemote(NewRemote("http://example.com/missing.aci", ""))
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Add a half-written tree store
+	if err := os.MkdirAll(s.GetTreeStoreRootFS("deps-partial"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Change a file of the tree store of the first image
+	if err := os.Chmod(filepath.Join(s.GetTreeStoreRootFS(id), "a"), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"corrupted blob " + key02,
+		"corrupted treestore " + id,
+		"dangling aciinfo " + missingKey,
+		"dangling remote http://example.com/missing.aci",
+		"mismatched image manifest " + key01,
+		"partial treestore deps-partial",
+	}
+	sort.Strings(expected)
+
+	problems, err = s.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fsckProblemsString(problems); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected problems %v, got %v", expected, got)
+	}
+	for _, p := range problems {
+		if p.Repaired() {
+			t.Errorf("unexpected repair without the repair option: %v", p)
+		}
+	}
+
+	// The corrupted tree store is used by a pod
+	problems, err = s.Fsck(FsckOptions{
+		Repair:           true,
+		KeepTreeStoreIDs: map[string]struct{}{id: struct{}{}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fsckProblemsString(problems); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected problems %v, got %v", expected, got)
+	}
+	for _, p := range problems {
+		if p.ID == id {
+			if p.Repaired() {
+				t.Errorf("unexpected repair of a kept tree store: %v", p)
+			}
+		} else if !p.Repaired() {
+			t.Errorf("expected problem to be repaired: %v", p)
+		}
+	}
+
+	problems, err = s.Fsck(FsckOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fsckProblemsString(problems); !reflect.DeepEqual(got, []string{"corrupted treestore " + id}) {
+		t.Fatalf("unexpected problems: %v", got)
+	}
+	if !problems[0].Repaired() {
+		t.Errorf("expected problem to be repaired: %v", problems[0])
+	}
+
+	problems, err = s.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems after the repair: %v", fsckProblemsString(problems))
+	}
+	if _, err := s.GetImageManifest(key01); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := s.ResolveKey(key02); err == nil {
+		t.Errorf("expected corrupted image %q to be removed", key02)
+	}
+}
+
+func TestFsckPartialTreeStoreBeingRendered(t *testing.T) {
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	// A render in progress holds the tree store lock
+	id := "deps-rendering"
+	if err := os.MkdirAll(s.GetTreeStoreRootFS(id), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	renderLock, err := lock.ExclusiveKeyLock(s.treeStoreLockDir, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type result struct {
+		problems []*FsckProblem
+		err      error
+	}
+	done := make(chan result)
+	go func() {
+		problems, err := s.Fsck(FsckOptions{Repair: true})
+		done <- result{problems, err}
+	}()
+
+	select {
+	case r := <-done:
+		t.Fatalf("fsck did not wait for the render to finish: %v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The render finishes
+	f, err := os.Create(filepath.Join(s.GetTreeStorePath(id), renderedfilename))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+	renderLock.Close()
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("unexpected error: %v", r.err)
+	}
+	if got := fsckProblemsString(r.problems); !reflect.DeepEqual(got, []string{"partial treestore " + id}) {
+		t.Fatalf("unexpected problems: %v", got)
+	}
+	if r.problems[0].RepairErr != nil {
+		t.Errorf("unexpected repair error: %v", r.problems[0].RepairErr)
+	}
+	if _, err := os.Stat(s.GetTreeStoreRootFS(id)); err != nil {
+		t.Errorf("expected the rendered tree store to be kept: %v", err)
+	}
+}
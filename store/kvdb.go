@@ -0,0 +1,357 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	KVDbFilename = "kv.db"
+
+	// Incremental kv db version at the current code revision.
+	kvDBVersion = 1
+)
+
+var (
+	// kvVersionBucket holds the kv db version under kvVersionKey.
+	kvVersionBucket = []byte("version")
+	kvVersionKey    = []byte("version")
+	// kvACIInfoBucket holds the json encoded ACIInfos keyed by blob key.
+	kvACIInfoBucket = []byte("aciinfo")
+	// kvRemoteBucket holds the json encoded Remotes keyed by ACI URL.
+	kvRemoteBucket = []byte("remote")
+
+	// kvMigrateTable is a map of migrate functions. The key is the kv db
+	// version to migrate to.
+	kvMigrateTable = map[int]func(*bolt.Tx) error{}
+)
+
+// kvDB is the index of the store in a bolt database. The database file is
+// locked by bolt: read-write transactions are exclusive, while read-only
+// transactions can run concurrently.
+type kvDB struct {
+	dbdir string
+}
+
+func newKVDB(dbdir string) *kvDB {
+	return &kvDB{dbdir: dbdir}
+}
+
+func (db *kvDB) kind() DBKind {
+	return DBKV
+}
+
+func (db *kvDB) dir() string {
+	return db.dbdir
+}
+
+func (db *kvDB) path() string {
+	return filepath.Join(db.dbdir, KVDbFilename)
+}
+
+func (db *kvDB) exists() (bool, error) {
+	_, err := os.Stat(db.path())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (db *kvDB) setup(s *Store) (bool, error) {
+	if err := os.MkdirAll(db.dbdir, defaultPathPerm); err != nil {
+		return false, err
+	}
+	bdb, err := bolt.Open(db.path(), defaultFilePerm, nil)
+	if err != nil {
+		return false, err
+	}
+	defer bdb.Close()
+
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		vb := tx.Bucket(kvVersionBucket)
+		// populate the db
+		if vb == nil {
+			for _, name := range [][]byte{kvVersionBucket, kvACIInfoBucket, kvRemoteBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return putKVDBVersion(tx, kvDBVersion)
+		}
+		version, err := strconv.Atoi(string(vb.Get(kvVersionKey)))
+		if err != nil {
+			return errwrap.Wrap(errors.New("invalid kv db version"), err)
+		}
+		if version > kvDBVersion {
+			return fmt.Errorf("current store kv db version: %d (greater than the current rkt expected version: %d)", version, kvDBVersion)
+		}
+		// bolt transactions are exclusive, so there is no need
+		// to take the exclusive store lock to migrate.
+		for v := version + 1; v <= kvDBVersion; v++ {
+			f, ok := kvMigrateTable[v]
+			if !ok {
+				return fmt.Errorf("missing migrate function for version %d", v)
+			}
+			if err := f(tx); err != nil {
+				return errwrap.Wrap(fmt.Errorf("failed to migrate kv db to version %d", v), err)
+			}
+			if err := putKVDBVersion(tx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return false, err
+}
+
+func putKVDBVersion(tx *bolt.Tx, version int) error {
+	return tx.Bucket(kvVersionBucket).Put(kvVersionKey, []byte(strconv.Itoa(version)))
+}
+
+func (db *kvDB) Do(fns ...dbTxFunc) error {
+	bdb, err := bolt.Open(db.path(), defaultFilePerm, nil)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	return bdb.Update(func(tx *bolt.Tx) error {
+		return doKVTx(tx, fns)
+	})
+}
+
+func (db *kvDB) View(fns ...dbTxFunc) error {
+	bdb, err := bolt.Open(db.path(), defaultFilePerm, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	return bdb.View(func(tx *bolt.Tx) error {
+		return doKVTx(tx, fns)
+	})
+}
+
+func doKVTx(tx *bolt.Tx, fns []dbTxFunc) error {
+	ktx := &kvTx{tx: tx}
+	for _, fn := range fns {
+		if err := fn(ktx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvTx is a transaction on the kv database.
+type kvTx struct {
+	tx *bolt.Tx
+}
+
+// forEachACIInfo calls fn for each ACIInfo, in blob key order.
+func (t *kvTx) forEachACIInfo(fn func(*ACIInfo) error) error {
+	return t.tx.Bucket(kvACIInfoBucket).ForEach(func(k, v []byte) error {
+		aciinfo := &ACIInfo{}
+		if err := json.Unmarshal(v, aciinfo); err != nil {
+			return errwrap.Wrap(fmt.Errorf("invalid aciinfo for blob key %q", k), err)
+		}
+		return fn(aciinfo)
+	})
+}
+
+func (t *kvTx) GetACIInfosWithKeyPrefix(prefix string) ([]*ACIInfo, error) {
+	var aciinfos []*ACIInfo
+	c := t.tx.Bucket(kvACIInfoBucket).Cursor()
+	p := []byte(prefix)
+	for k, v := c.Seek(p); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+		aciinfo := &ACIInfo{}
+		if err := json.Unmarshal(v, aciinfo); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid aciinfo for blob key %q", k), err)
+		}
+		aciinfos = append(aciinfos, aciinfo)
+	}
+	return aciinfos, nil
+}
+
+func (t *kvTx) GetACIInfosWithName(name string) ([]*ACIInfo, bool, error) {
+	var aciinfos []*ACIInfo
+	err := t.forEachACIInfo(func(aciinfo *ACIInfo) error {
+		if aciinfo.Name == name {
+			aciinfos = append(aciinfos, aciinfo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return aciinfos, len(aciinfos) > 0, nil
+}
+
+func (t *kvTx) GetACIInfoWithBlobKey(blobKey string) (*ACIInfo, bool, error) {
+	aciinfo := &ACIInfo{}
+	v := t.tx.Bucket(kvACIInfoBucket).Get([]byte(blobKey))
+	if v == nil {
+		return aciinfo, false, nil
+	}
+	if err := json.Unmarshal(v, aciinfo); err != nil {
+		return nil, false, errwrap.Wrap(fmt.Errorf("invalid aciinfo for blob key %q", blobKey), err)
+	}
+	return aciinfo, true, nil
+}
+
+func (t *kvTx) GetAllACIInfos(sortfields []string, ascending bool) ([]*ACIInfo, error) {
+	var aciinfos []*ACIInfo
+	err := t.forEachACIInfo(func(aciinfo *ACIInfo) error {
+		aciinfos = append(aciinfos, aciinfo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sortfields) > 0 {
+		sorter := &aciinfoSorter{aciinfos: aciinfos, fields: sortfields}
+		for _, f := range sortfields {
+			if _, ok := aciinfoLessFuncs[f]; !ok {
+				return nil, fmt.Errorf("unknown aciinfo field %q", f)
+			}
+		}
+		if ascending {
+			sort.Stable(sorter)
+		} else {
+			sort.Stable(sort.Reverse(sorter))
+		}
+	}
+	return aciinfos, nil
+}
+
+func (t *kvTx) WriteACIInfo(aciinfo *ACIInfo) error {
+	v, err := json.Marshal(aciinfo)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(kvACIInfoBucket).Put([]byte(aciinfo.BlobKey), v)
+}
+
+func (t *kvTx) RemoveACIInfo(blobKey string) error {
+	return t.tx.Bucket(kvACIInfoBucket).Delete([]byte(blobKey))
+}
+
+func (t *kvTx) GetRemote(aciURL string) (*Remote, bool, error) {
+	remote := &Remote{}
+	v := t.tx.Bucket(kvRemoteBucket).Get([]byte(aciURL))
+	if v == nil {
+		return remote, false, nil
+	}
+	if err := json.Unmarshal(v, remote); err != nil {
+		return nil, false, errwrap.Wrap(fmt.Errorf("invalid remote for %q", aciURL), err)
+	}
+	return remote, true, nil
+}
+
+func (t *kvTx) GetAllRemotes() ([]*Remote, error) {
+	var remotes []*Remote
+	err := t.tx.Bucket(kvRemoteBucket).ForEach(func(k, v []byte) error {
+		remote := &Remote{}
+		if err := json.Unmarshal(v, remote); err != nil {
+			return errwrap.Wrap(fmt.Errorf("invalid remote for %q", k), err)
+		}
+		remotes = append(remotes, remote)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return remotes, nil
+}
+
+func (t *kvTx) WriteRemote(remote *Remote) error {
+	v, err := json.Marshal(remote)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(kvRemoteBucket).Put([]byte(remote.ACIURL), v)
+}
+
+func (t *kvTx) RemoveRemote(blobKey string) error {
+	remotes, err := t.GetAllRemotes()
+	if err != nil {
+		return err
+	}
+	b := t.tx.Bucket(kvRemoteBucket)
+	for _, remote := range remotes {
+		if remote.BlobKey != blobKey {
+			continue
+		}
+		if err := b.Delete([]byte(remote.ACIURL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aciinfoLessFuncs are the functions comparing the ACIInfos for each
+// field, named like the columns of the aciinfo table of the ql database.
+var aciinfoLessFuncs = map[string]func(a, b *ACIInfo) (less bool, equal bool){
+	"blobkey": func(a, b *ACIInfo) (bool, bool) {
+		return a.BlobKey < b.BlobKey, a.BlobKey == b.BlobKey
+	},
+	"name": func(a, b *ACIInfo) (bool, bool) {
+		return a.Name < b.Name, a.Name == b.Name
+	},
+	"importtime": func(a, b *ACIInfo) (bool, bool) {
+		return a.ImportTime.Before(b.ImportTime), a.ImportTime.Equal(b.ImportTime)
+	},
+	"lastused": func(a, b *ACIInfo) (bool, bool) {
+		return a.LastUsed.Before(b.LastUsed), a.LastUsed.Equal(b.LastUsed)
+	},
+	"latest": func(a, b *ACIInfo) (bool, bool) {
+		return !a.Latest && b.Latest, a.Latest == b.Latest
+	},
+	"size": func(a, b *ACIInfo) (bool, bool) {
+		return a.Size < b.Size, a.Size == b.Size
+	},
+	"treestoresize": func(a, b *ACIInfo) (bool, bool) {
+		return a.TreeStoreSize < b.TreeStoreSize, a.TreeStoreSize == b.TreeStoreSize
+	},
+}
+
+// aciinfoSorter implements sort.Interface for ACIInfos, sorting by the
+// given fields.
+type aciinfoSorter struct {
+	aciinfos []*ACIInfo
+	fields   []string
+}
+
+func (s *aciinfoSorter) Len() int      { return len(s.aciinfos) }
+func (s *aciinfoSorter) Swap(i, j int) { s.aciinfos[i], s.aciinfos[j] = s.aciinfos[j], s.aciinfos[i] }
+func (s *aciinfoSorter) Less(i, j int) bool {
+	for _, f := range s.fields {
+		less, equal := aciinfoLessFuncs[f](s.aciinfos[i], s.aciinfos[j])
+		if !equal {
+			return less
+		}
+	}
+	return false
+}
@@ -61,6 +61,26 @@ func GetRemote(tx *sql.Tx, aciURL string) (remote *Remote, found bool, err error
 	return remote, found, err
 }
 
+// GetAllRemotes returns all the remotes.
+func GetAllRemotes(tx *sql.Tx) ([]*Remote, error) {
+	var remotes []*Remote
+	rows, err := tx.Query("SELECT * FROM remote")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		remote := &Remote{}
+		if err := rows.Scan(&remote.ACIURL, &remote.SigURL, &remote.ETag, &remote.BlobKey, &remote.CacheMaxAge, &remote.DownloadTime); err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, remote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return remotes, nil
+}
+
 // WriteRemote adds or updates the provided Remote.
 func WriteRemote(tx *sql.Tx, remote *Remote) error {
 	// ql doesn't have an INSERT OR UPDATE function so
@@ -16,7 +16,6 @@ package store
 
 import (
 	"crypto/sha512"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -99,7 +98,7 @@ func (e *StoreRemovalError) Error() string {
 type Store struct {
 	dir       string
 	stores    []*diskv.Diskv
-	db        *DB
+	db        dbBackend
 	treestore *TreeStore
 	// storeLock is a lock on the whole store. It's used for store migration. If
 	// a previous version of rkt is using the store and in the meantime a
@@ -114,24 +113,35 @@ type Store struct {
 }
 
 func (s *Store) UpdateSize(key string, newSize int64) error {
-	return s.db.Do(func(tx *sql.Tx) error {
-		_, err := tx.Exec("UPDATE aciinfo SET size = $1 WHERE blobkey == $2", newSize, key)
-		return err
+	return s.updateACIInfo(key, func(aciinfo *ACIInfo) {
+		aciinfo.Size = newSize
 	})
 }
 
 func (s *Store) UpdateTreeStoreSize(key string, newSize int64) error {
-	return s.db.Do(func(tx *sql.Tx) error {
-		_, err := tx.Exec("UPDATE aciinfo SET treestoresize = $1 WHERE blobkey == $2", newSize, key)
-		return err
+	return s.updateACIInfo(key, func(aciinfo *ACIInfo) {
+		aciinfo.TreeStoreSize = newSize
+	})
+}
+
+// updateACIInfo applies update to the ACIInfo with the given blob key, if
+// it exists.
+func (s *Store) updateACIInfo(key string, update func(*ACIInfo)) error {
+	return s.db.Do(func(tx DBTx) error {
+		aciinfo, found, err := tx.GetACIInfoWithBlobKey(key)
+		if err != nil || !found {
+			return err
+		}
+		update(aciinfo)
+		return tx.WriteACIInfo(aciinfo)
 	})
 }
 
 func (s *Store) populateSize() error {
 	var ais []*ACIInfo
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		ais, err = GetACIInfosWithKeyPrefix(tx, "")
+		ais, err = tx.GetACIInfosWithKeyPrefix("")
 		return err
 	})
 	if err != nil {
@@ -189,7 +199,16 @@ func (s *Store) populateSize() error {
 	return nil
 }
 
+// NewStore opens the store in baseDir, creating it if needed, with the
+// database already used by the store.
 func NewStore(baseDir string) (*Store, error) {
+	return NewStoreWithDB(baseDir, DBDefault)
+}
+
+// NewStoreWithDB opens the store in baseDir, creating it if needed, with a
+// database of the given kind. The data of the database already used by the
+// store is migrated if it is of another kind.
+func NewStoreWithDB(baseDir string, dbKind DBKind) (*Store, error) {
 	// We need to allow the store's setgid bits (if any) to propagate, so
 	// disable umask
 	um := syscall.Umask(0)
@@ -228,80 +247,15 @@ func NewStore(baseDir string) (*Store, error) {
 			Transform: blockTransform,
 		})
 	}
-	db, err := NewDB(filepath.Join(storeDir, "db"))
-	if err != nil {
-		return nil, err
+	s.treestore = &TreeStore{
+		path:      filepath.Join(storeDir, "tree"),
+		blobsPath: filepath.Join(storeDir, "treeblobs"),
 	}
-	s.db = db
-
-	s.treestore = &TreeStore{path: filepath.Join(storeDir, "tree")}
 
-	needsMigrate := false
-	needsSizePopulation := false
-	fn := func(tx *sql.Tx) error {
-		var err error
-		ok, err := dbIsPopulated(tx)
-		if err != nil {
-			return err
-		}
-		// populate the db
-		if !ok {
-			for _, stmt := range dbCreateStmts {
-				_, err = tx.Exec(stmt)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		}
-		// if db is populated check its version
-		version, err := getDBVersion(tx)
-		if err != nil {
-			return err
-		}
-		if version < dbVersion {
-			needsMigrate = true
-		}
-		if version > dbVersion {
-			return fmt.Errorf("current store db version: %d (greater than the current rkt expected version: %d)", version, dbVersion)
-		}
-		if version < 5 {
-			needsSizePopulation = true
-		}
-		return nil
-	}
-	if err = db.Do(fn); err != nil {
+	if err := s.openDB(dbKind); err != nil {
 		return nil, err
 	}
 
-	// migration is done in another transaction as it must take an exclusive
-	// store lock. If, in the meantime, another process has already done the
-	// migration, between the previous db version check and the below
-	// migration code, the migration will do nothing as it'll start
-	// migration from the current version.
-	if needsMigrate {
-		// Take an exclusive store lock
-		err := s.storeLock.ExclusiveLock()
-		if err != nil {
-			return nil, err
-		}
-		if err := s.backupDB(); err != nil {
-			return nil, err
-		}
-		fn := func(tx *sql.Tx) error {
-			return migrate(tx, dbVersion)
-		}
-		if err = db.Do(fn); err != nil {
-			return nil, err
-		}
-
-		if needsSizePopulation {
-			if err := s.populateSize(); err != nil {
-				return nil, err
-			}
-		}
-	}
-
 	return s, nil
 }
 
@@ -310,10 +264,10 @@ func (s *Store) Close() error {
 	return s.storeLock.Close()
 }
 
-// backupDB backs up current database.
-func (s *Store) backupDB() error {
+// backupDB backs up the given database.
+func (s *Store) backupDB(db dbBackend) error {
 	backupsDir := filepath.Join(s.dir, "db-backups")
-	return createBackup(s.db.dbdir, backupsDir, backupsNumber)
+	return createBackup(db.dir(), backupsDir, backupsNumber)
 }
 
 // TmpFile returns an *os.File local to the same filesystem as the Store, or
@@ -371,9 +325,9 @@ func (s *Store) ResolveKey(key string) (string, error) {
 	}
 
 	var aciInfos []*ACIInfo
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		aciInfos, err = GetACIInfosWithKeyPrefix(tx, key)
+		aciInfos, err = tx.GetACIInfosWithKeyPrefix(key)
 		return err
 	})
 	if err != nil {
@@ -397,9 +351,9 @@ func (s *Store) ResolveName(name string) ([]string, bool, error) {
 		aciInfos []*ACIInfo
 		found    bool
 	)
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		aciInfos, found, err = GetACIInfosWithName(tx, name)
+		aciInfos, found, err = tx.GetACIInfosWithName(name)
 		return err
 	})
 	if err != nil {
@@ -425,8 +379,8 @@ func (s *Store) ReadStream(key string) (io.ReadCloser, error) {
 	}
 	defer keyLock.Close()
 
-	err = s.db.Do(func(tx *sql.Tx) error {
-		aciinfo, found, err := GetACIInfoWithBlobKey(tx, key)
+	err = s.db.Do(func(tx DBTx) error {
+		aciinfo, found, err := tx.GetACIInfoWithBlobKey(key)
 		if err != nil {
 			return errwrap.Wrap(errors.New("error getting aciinfo"), err)
 		} else if !found {
@@ -435,7 +389,7 @@ func (s *Store) ReadStream(key string) (io.ReadCloser, error) {
 
 		aciinfo.LastUsed = time.Now()
 
-		return WriteACIInfo(tx, aciinfo)
+		return tx.WriteACIInfo(aciinfo)
 	})
 	if err != nil {
 		return nil, errwrap.Wrap(fmt.Errorf("cannot get image info for %q from db", key), err)
@@ -503,7 +457,7 @@ func (s *Store) WriteACI(r io.ReadSeeker, latest bool) (string, error) {
 	}
 
 	// Save aciinfo
-	if err = s.db.Do(func(tx *sql.Tx) error {
+	if err = s.db.Do(func(tx DBTx) error {
 		aciinfo := &ACIInfo{
 			BlobKey:    key,
 			Name:       im.Name.String(),
@@ -512,7 +466,7 @@ func (s *Store) WriteACI(r io.ReadSeeker, latest bool) (string, error) {
 			Latest:     latest,
 			Size:       sz,
 		}
-		return WriteACIInfo(tx, aciinfo)
+		return tx.WriteACIInfo(aciinfo)
 	}); err != nil {
 		return "", errwrap.Wrap(errors.New("error writing ACI Info"), err)
 	}
@@ -555,17 +509,17 @@ func (s *Store) RemoveACI(key string) error {
 	// Firstly remove aciinfo and remote from the db in an unique transaction.
 	// remote needs to be removed or a GetRemote will return a blobKey not
 	// referenced by any ACIInfo.
-	err = s.db.Do(func(tx *sql.Tx) error {
-		if _, found, err := GetACIInfoWithBlobKey(tx, key); err != nil {
+	err = s.db.Do(func(tx DBTx) error {
+		if _, found, err := tx.GetACIInfoWithBlobKey(key); err != nil {
 			return errwrap.Wrap(errors.New("error getting aciinfo"), err)
 		} else if !found {
 			return fmt.Errorf("cannot find image with key: %s", key)
 		}
 
-		if err := RemoveACIInfo(tx, key); err != nil {
+		if err := tx.RemoveACIInfo(key); err != nil {
 			return err
 		}
-		if err := RemoveRemote(tx, key); err != nil {
+		if err := tx.RemoveRemote(key); err != nil {
 			return err
 		}
 		return nil
@@ -657,6 +611,12 @@ func (s *Store) RenderTreeStore(key string, rebuild bool) (id string, hash strin
 	return id, hash, nil
 }
 
+// SetTreeStoreBackend sets the backend used to render the trees. The trees
+// already rendered are not changed.
+func (s *Store) SetTreeStoreBackend(backend TreeStoreBackend) {
+	s.treestore.backend = backend
+}
+
 // CheckTreeStore verifies the treestore consistency for the specified id.
 func (s *Store) CheckTreeStore(id string) (string, error) {
 	treeStoreKeyLock, err := lock.SharedKeyLock(s.treeStoreLockDir, id)
@@ -668,6 +628,12 @@ func (s *Store) CheckTreeStore(id string) (string, error) {
 	return s.treestore.Check(id)
 }
 
+// GetTreeStoreImageHash returns the key of the image rendered in the
+// treestore with the given id.
+func (s *Store) GetTreeStoreImageHash(id string) (string, error) {
+	return s.treestore.GetImageHash(id)
+}
+
 // GetTreeStorePath returns the absolute path of the treestore for the specified id.
 // It doesn't ensure that the path exists and is fully rendered. This should
 // be done calling IsRendered()
@@ -723,18 +689,29 @@ func (s *Store) GetTreeStoreIDs() ([]string, error) {
 func (s *Store) GetRemote(aciURL string) (*Remote, bool, error) {
 	var remote *Remote
 	found := false
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		remote, found, err = GetRemote(tx, aciURL)
+		remote, found, err = tx.GetRemote(aciURL)
 		return err
 	})
 	return remote, found, err
 }
 
+// GetAllRemotes returns all the remotes.
+func (s *Store) GetAllRemotes() ([]*Remote, error) {
+	var remotes []*Remote
+	err := s.db.View(func(tx DBTx) error {
+		var err error
+		remotes, err = tx.GetAllRemotes()
+		return err
+	})
+	return remotes, err
+}
+
 // WriteRemote adds or updates the provided Remote.
 func (s *Store) WriteRemote(remote *Remote) error {
-	err := s.db.Do(func(tx *sql.Tx) error {
-		return WriteRemote(tx, remote)
+	err := s.db.Do(func(tx DBTx) error {
+		return tx.WriteRemote(remote)
 	})
 	return err
 }
@@ -786,9 +763,9 @@ func (s *Store) GetACI(name types.ACIdentifier, labels types.Labels) (string, er
 	}
 
 	var aciinfos []*ACIInfo
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		aciinfos, _, err = GetACIInfosWithName(tx, name.String())
+		aciinfos, _, err = tx.GetACIInfosWithName(name.String())
 		return err
 	})
 	if err != nil {
@@ -844,9 +821,9 @@ nextKey:
 
 func (s *Store) GetAllACIInfos(sortfields []string, ascending bool) ([]*ACIInfo, error) {
 	var aciInfos []*ACIInfo
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		aciInfos, err = GetAllACIInfos(tx, sortfields, ascending)
+		aciInfos, err = tx.GetAllACIInfos(sortfields, ascending)
 		return err
 	})
 	return aciInfos, err
@@ -855,9 +832,9 @@ func (s *Store) GetAllACIInfos(sortfields []string, ascending bool) ([]*ACIInfo,
 func (s *Store) GetACIInfoWithBlobKey(blobKey string) (*ACIInfo, error) {
 	var aciInfo *ACIInfo
 	var found bool
-	err := s.db.Do(func(tx *sql.Tx) error {
+	err := s.db.View(func(tx DBTx) error {
 		var err error
-		aciInfo, found, err = GetACIInfoWithBlobKey(tx, blobKey)
+		aciInfo, found, err = tx.GetACIInfoWithBlobKey(blobKey)
 		return err
 	})
 	if !found {
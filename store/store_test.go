@@ -17,7 +17,6 @@ package store
 import (
 	"archive/tar"
 	"bytes"
-	"database/sql"
 	"encoding/hex"
 	"io/ioutil"
 	"os"
@@ -86,13 +85,13 @@ func TestResolveKey(t *testing.T) {
 	}
 	for _, d := range data {
 		// Save aciinfo
-		err := s.db.Do(func(tx *sql.Tx) error {
+		err := s.db.Do(func(tx DBTx) error {
 			aciinfo := &ACIInfo{
 				BlobKey:    d.String(),
 				Name:       "example.com/app",
 				ImportTime: time.Now(),
 			}
-			return WriteACIInfo(tx, aciinfo)
+			return tx.WriteACIInfo(aciinfo)
 		})
 		if err != nil {
 			t.Fatalf("error writing to store: %v", err)
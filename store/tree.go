@@ -46,6 +46,10 @@ const (
 // TreeStore represents a store of rendered ACIs
 type TreeStore struct {
 	path string
+	// blobsPath is the directory of the blobs shared by the trees
+	// rendered with the TreeStoreDedup backend.
+	blobsPath string
+	backend   TreeStoreBackend
 }
 
 // Write renders the ACI with the provided key in the treestore. id references
@@ -70,6 +74,11 @@ func (ts *TreeStore) Write(id string, key string, s *Store) (string, error) {
 	if err != nil {
 		return "", errwrap.Wrap(errors.New("cannot render aci"), err)
 	}
+	if ts.backend == TreeStoreDedup {
+		if err := ts.dedup(id, s); err != nil {
+			return "", errwrap.Wrap(errors.New("cannot share the files of the tree"), err)
+		}
+	}
 	hash, err := ts.Hash(id)
 	if err != nil {
 		return "", errwrap.Wrap(errors.New("cannot calculate tree hash"), err)
@@ -308,7 +317,7 @@ func FileInfoFromHeader(hdr *tar.Header) *fileInfo {
 }
 
 // TODO(sgotti) this func is copied from appcs/spec/aci/build.go but also
-// removes the hash, rendered and image files. Find a way to reuse it.
+// removes the hash, rendered, image and blobs files. Find a way to reuse it.
 func buildWalker(root string, aw specaci.ArchiveWriter) filepath.WalkFunc {
 	// cache of inode -> filepath, used to leverage hard links in the archive
 	inos := map[uint64]string{}
@@ -326,7 +335,8 @@ func buildWalker(root string, aw specaci.ArchiveWriter) filepath.WalkFunc {
 		if relpath == specaci.ManifestFile ||
 			relpath == hashfilename ||
 			relpath == renderedfilename ||
-			relpath == imagefilename {
+			relpath == imagefilename ||
+			relpath == blobsfilename {
 			// ignore; this will be written by the archive writer
 			// TODO(jonboulle): does this make sense? maybe just remove from archivewriter?
 			return nil
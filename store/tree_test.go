@@ -163,3 +163,110 @@ func TestTreeStoreRemove(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func treeStoreWriteSharingACI(dir string, name string, s *Store) (string, error) {
+	imj := `
+		{
+		    "acKind": "ImageManifest",
+		    "acVersion": "0.7.4",
+		    "name": "` + name + `"
+		}
+	`
+
+	entries := []*aci.ACIEntry{
+		{
+			Contents: "shared",
+			Header: &tar.Header{
+				Name: "rootfs/shared.txt",
+				Size: 6,
+			},
+		},
+		{
+			Contents: name,
+			Header: &tar.Header{
+				Name: "rootfs/name.txt",
+				Size: int64(len(name)),
+			},
+		},
+	}
+	aci, err := aci.NewACI(dir, imj, entries)
+	if err != nil {
+		return "", err
+	}
+	defer aci.Close()
+
+	// Rewind the ACI
+	if _, err := aci.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	return s.WriteACI(aci, false)
+}
+
+func TestTreeStoreDedup(t *testing.T) {
+	if !sys.HasChrootCapability() {
+		t.Skipf("chroot capability not available. Disabling test.")
+	}
+
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.SetTreeStoreBackend(TreeStoreDedup)
+
+	ids := []string{"treestoreid01", "treestoreid02"}
+	names := []string{"example.com/test01", "example.com/test02"}
+	var blobs [][]string
+	for i, id := range ids {
+		key, err := treeStoreWriteSharingACI(dir, names[i], s)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.treestore.Write(id, key, s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.treestore.Check(id); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := s.treestore.getBlobs(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(b) != 2 {
+			t.Fatalf("expected 2 blobs for tree %q, got %v", id, b)
+		}
+		blobs = append(blobs, b)
+	}
+
+	// Only the shared file has the same blob in both trees
+	shared := 0
+	for _, b1 := range blobs[0] {
+		for _, b2 := range blobs[1] {
+			if b1 == b2 {
+				shared++
+			}
+		}
+	}
+	if shared != 1 {
+		t.Fatalf("expected 1 shared blob, got %d", shared)
+	}
+
+	expectedRemoved := []int{1, 2}
+	for i, id := range ids {
+		if err := s.treestore.Remove(id, s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		removed, err := s.GCTreeStoreBlobs()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if removed != expectedRemoved[i] {
+			t.Fatalf("expected %d removed blobs, got %d", expectedRemoved[i], removed)
+		}
+	}
+}
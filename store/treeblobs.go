@@ -0,0 +1,325 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"bufio"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/rkt/pkg/fileutil"
+	"github.com/coreos/rkt/pkg/lock"
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// blobsfilename is the file of a rendered tree listing the blobs
+	// used by the tree.
+	blobsfilename = "blobs"
+	// blobsLockKey is the key of the lock on the blobs in the tree store
+	// lock directory. Trees sharing their files with the blobs take a
+	// shared lock, the blobs garbage collection takes an exclusive lock.
+	blobsLockKey = "blobs"
+)
+
+// TreeStoreBackend is the way the tree store renders the images.
+type TreeStoreBackend int
+
+const (
+	// TreeStoreCopy renders a full copy of the files of the images in
+	// each tree.
+	TreeStoreCopy TreeStoreBackend = iota
+	// TreeStoreDedup stores the contents of the files of the trees once,
+	// in blobs keyed by their hash, and shares the blobs with the trees
+	// with reflinks or, on filesystems not supporting them, hardlinks.
+	TreeStoreDedup
+)
+
+// String returns the name of the backend, as used in the configuration.
+func (b TreeStoreBackend) String() string {
+	switch b {
+	case TreeStoreCopy:
+		return "copy"
+	case TreeStoreDedup:
+		return "dedup"
+	}
+	return fmt.Sprintf("TreeStoreBackend(%d)", int(b))
+}
+
+// ParseTreeStoreBackend returns the backend with the given name.
+func ParseTreeStoreBackend(name string) (TreeStoreBackend, error) {
+	for _, b := range []TreeStoreBackend{TreeStoreCopy, TreeStoreDedup} {
+		if b.String() == name {
+			return b, nil
+		}
+	}
+	return TreeStoreCopy, fmt.Errorf("unknown tree store backend %q", name)
+}
+
+// dedup replaces the regular files of the rendered tree with id with the
+// blobs having the same contents and metadata, adding the missing blobs,
+// and writes the list of the blobs used by the tree.
+// Files with extended attributes are not shared, nor are empty files.
+func (ts *TreeStore) dedup(id string, s *Store) error {
+	if err := os.MkdirAll(ts.blobsPath, defaultPathPerm); err != nil {
+		return errwrap.Wrap(errors.New("cannot create blobs directory"), err)
+	}
+	blobsKeyLock, err := lock.SharedKeyLock(s.treeStoreLockDir, blobsLockKey)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking tree store blobs"), err)
+	}
+	defer blobsKeyLock.Close()
+
+	rootfs := ts.GetRootFS(id)
+	blobs := make(map[string]struct{})
+	// once cleared, only hardlinks are tried
+	reflink := true
+	err = filepath.Walk(rootfs, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() == 0 {
+			return nil
+		}
+		hasXattrs, err := hasXattrs(path)
+		if err != nil {
+			return err
+		}
+		if hasXattrs {
+			return nil
+		}
+
+		key, err := blobKey(path, info)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot hash file %q", path), err)
+		}
+		blobPath := filepath.Join(ts.blobsPath, key)
+		shared, err := ts.shareBlob(blobPath, path, id, info, &reflink)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot share file %q", path), err)
+		}
+		if shared {
+			blobs[key] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(blobs))
+	for key := range blobs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	content := strings.Join(keys, "\n")
+	if len(keys) > 0 {
+		content += "\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(ts.GetPath(id), blobsfilename), []byte(content), 0644); err != nil {
+		return errwrap.Wrap(errors.New("cannot write blobs file"), err)
+	}
+	return nil
+}
+
+// shareBlob replaces the file at path in the tree with id with the blob at
+// blobPath, creating the blob from the file if it does not exist yet. It
+// returns false if the file could not be shared because of the hardlinks
+// limit of the filesystem.
+func (ts *TreeStore) shareBlob(blobPath, path, id string, info os.FileInfo, reflink *bool) (bool, error) {
+	_, err := os.Stat(blobPath)
+	switch {
+	case os.IsNotExist(err):
+		// other trees can be creating the same blob
+		tmpPath := blobPath + ".tmp-" + id
+		shared, err := shareFile(path, tmpPath, info, reflink)
+		if err != nil || !shared {
+			return shared, err
+		}
+		return true, os.Rename(tmpPath, blobPath)
+	case err != nil:
+		return false, err
+	}
+
+	tmpPath := path + ".rkt-blob"
+	shared, err := shareFile(blobPath, tmpPath, info, reflink)
+	if err != nil || !shared {
+		return shared, err
+	}
+	return true, os.Rename(tmpPath, path)
+}
+
+// shareFile creates the file dest sharing the contents of src, with the
+// metadata in info. It makes a reflink if *reflink is true and the
+// filesystem supports them, and a hardlink otherwise, clearing *reflink.
+// It returns false if src has too many links.
+func shareFile(src, dest string, info os.FileInfo, reflink *bool) (bool, error) {
+	os.Remove(dest)
+	if *reflink {
+		ok, err := reflinkFile(src, dest, info)
+		if ok || err != nil {
+			return ok, err
+		}
+		*reflink = false
+	}
+
+	err := os.Link(src, dest)
+	if lerr, ok := err.(*os.LinkError); ok && lerr.Err == syscall.EMLINK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// reflinkFile creates dest as a reflink of src, with the metadata in info.
+// It returns false if reflinks are not supported.
+func reflinkFile(src, dest string, info os.FileInfo) (bool, error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer srcFile.Close()
+	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return false, err
+	}
+	defer destFile.Close()
+
+	if err := fileutil.Reflink(destFile, srcFile); err != nil {
+		os.Remove(dest)
+		switch err {
+		case syscall.EOPNOTSUPP, syscall.EXDEV, syscall.EINVAL, syscall.ENOTTY:
+			return false, nil
+		}
+		return false, err
+	}
+
+	st := info.Sys().(*syscall.Stat_t)
+	// chown before chmod, it clears the setuid and setgid bits
+	if err := destFile.Chown(int(st.Uid), int(st.Gid)); err != nil {
+		return false, err
+	}
+	if err := destFile.Chmod(info.Mode()); err != nil {
+		return false, err
+	}
+	if err := os.Chtimes(dest, info.ModTime(), info.ModTime()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// blobKey returns the key of the blob of the file at path: the hash of
+// its contents and of the metadata shared by hardlinks.
+func blobKey(path string, info os.FileInfo) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	st := info.Sys().(*syscall.Stat_t)
+	hash := sha512.New()
+	fmt.Fprintf(hash, "%o %d %d\n", info.Mode(), st.Uid, st.Gid)
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hashToKey(hash), nil
+}
+
+// hasXattrs returns true if the file at path has extended attributes.
+func hasXattrs(path string) (bool, error) {
+	sz, err := syscall.Listxattr(path, nil)
+	switch err {
+	case nil:
+		return sz > 0, nil
+	case syscall.ENOTSUP:
+		return false, nil
+	}
+	return false, err
+}
+
+// getBlobs returns the blobs used by the tree with id.
+func (ts *TreeStore) getBlobs(id string) ([]string, error) {
+	f, err := os.Open(filepath.Join(ts.GetPath(id), blobsfilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var blobs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			blobs = append(blobs, line)
+		}
+	}
+	return blobs, scanner.Err()
+}
+
+// GCTreeStoreBlobs removes the tree store blobs not used by any tree,
+// and returns the number of removed blobs.
+func (s *Store) GCTreeStoreBlobs() (int, error) {
+	blobsKeyLock, err := lock.ExclusiveKeyLock(s.treeStoreLockDir, blobsLockKey)
+	if err != nil {
+		return 0, errwrap.Wrap(errors.New("error locking tree store blobs"), err)
+	}
+	defer blobsKeyLock.Close()
+
+	ls, err := ioutil.ReadDir(s.treestore.blobsPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errwrap.Wrap(errors.New("cannot read blobs directory"), err)
+	}
+
+	treeStoreIDs, err := s.GetTreeStoreIDs()
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]struct{})
+	for _, id := range treeStoreIDs {
+		blobs, err := s.treestore.getBlobs(id)
+		if err != nil {
+			return 0, errwrap.Wrap(fmt.Errorf("cannot get the blobs of tree store %q", id), err)
+		}
+		for _, b := range blobs {
+			referenced[b] = struct{}{}
+		}
+	}
+
+	removed := 0
+	for _, fi := range ls {
+		// leftovers of interrupted renders are removed too
+		if _, ok := referenced[fi.Name()]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.treestore.blobsPath, fi.Name())); err != nil {
+			return removed, errwrap.Wrap(fmt.Errorf("cannot remove blob %q", fi.Name()), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
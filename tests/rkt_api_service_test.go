@@ -130,6 +130,9 @@ func checkPodNetworks(t *testing.T, rawNets map[string]*networkInfo, apiNets []*
 			if netInfo.ipv4 != net.Ipv4 {
 				t.Errorf("Expected %q, saw %q", netInfo.ipv4, net.Ipv4)
 			}
+			if netInfo.ipv6 != net.Ipv6 {
+				t.Errorf("Expected %q, saw %q", netInfo.ipv6, net.Ipv6)
+			}
 		} else {
 			t.Errorf("Expected network (name: %q, ipv4: %q) in networks", netInfo.name, netInfo.ipv4)
 		}
@@ -543,3 +546,268 @@ func TestAPIServiceCgroup(t *testing.T) {
 		t.Errorf("Unexpected cgroup returned by pods: %v", cgroups)
 	}
 }
+
+// waitForEvents reads the events from the channel until it has seen an
+// event of each of the given types for the given ID, or fails after a timeout.
+// The events are returned by type.
+func waitForEvents(t *testing.T, events <-chan *v1alpha.Event, id string, types ...v1alpha.EventType) map[v1alpha.EventType]*v1alpha.Event {
+	seen := make(map[v1alpha.EventType]*v1alpha.Event)
+	timeout := time.After(time.Second * 30)
+	for len(seen) < len(types) {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("Event stream closed while waiting for events %v for %q", types, id)
+			}
+			if event.Id != id {
+				continue
+			}
+			for _, typ := range types {
+				if event.Type == typ {
+					seen[typ] = event
+				}
+			}
+		case <-timeout:
+			t.Fatalf("Timeout while waiting for events %v for %q, saw %v", types, id, seen)
+		}
+	}
+	return seen
+}
+
+func TestAPIServiceListenEvents(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	svc := startAPIService(t, ctx)
+	defer stopAPIService(t, svc)
+
+	c, conn := newAPIClientOrFail(t, "localhost:15441")
+	defer conn.Close()
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.ListenEvents(streamCtx, &v1alpha.ListenEventsRequest{
+		Filter: &v1alpha.EventFilter{
+			Types: []v1alpha.EventType{
+				v1alpha.EventType_EVENT_TYPE_POD_PREPARED,
+				v1alpha.EventType_EVENT_TYPE_POD_STARTED,
+				v1alpha.EventType_EVENT_TYPE_POD_EXITED,
+				v1alpha.EventType_EVENT_TYPE_POD_GARBAGE_COLLECTED,
+				v1alpha.EventType_EVENT_TYPE_APP_EXITED,
+				v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED,
+				v1alpha.EventType_EVENT_TYPE_IMAGE_REMOVED,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events := make(chan *v1alpha.Event, 100)
+	go func() {
+		defer close(events)
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			for _, event := range resp.Events {
+				events <- event
+			}
+		}
+	}()
+
+	patches := []string{"--exec=/inspect --print-msg=HELLO_API --exit-code=0"}
+	imageHash := patchImportAndFetchHash("rkt-inspect-print.aci", patches, t, ctx)
+	seen := waitForEvents(t, events, imageHash, v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED)
+	if event := seen[v1alpha.EventType_EVENT_TYPE_IMAGE_IMPORTED]; event.From != "coreos.com/rkt-inspect" {
+		t.Errorf("Expected image name %q, saw %q", "coreos.com/rkt-inspect", event.From)
+	}
+
+	prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), imageHash)
+	uuid := runRktAndGetUUID(t, prepareCmd)
+	waitForEvents(t, events, uuid, v1alpha.EventType_EVENT_TYPE_POD_PREPARED)
+
+	runCmd := fmt.Sprintf("%s run-prepared --mds-register=false %s", ctx.Cmd(), uuid)
+	waitOrFail(t, spawnOrFail(t, runCmd), 0)
+	seen = waitForEvents(t, events, uuid,
+		v1alpha.EventType_EVENT_TYPE_POD_STARTED,
+		v1alpha.EventType_EVENT_TYPE_POD_EXITED,
+		v1alpha.EventType_EVENT_TYPE_APP_EXITED)
+
+	event := seen[v1alpha.EventType_EVENT_TYPE_APP_EXITED]
+	if event.From != "rkt-inspect" {
+		t.Errorf("Expected app name %q, saw %q", "rkt-inspect", event.From)
+	}
+	if len(event.Data) != 1 || event.Data[0].Key != "exit-code" || event.Data[0].Value != "0" {
+		t.Errorf("Expected exit code 0 in the event data, saw %v", event.Data)
+	}
+
+	runGC(t, ctx)
+	waitForEvents(t, events, uuid, v1alpha.EventType_EVENT_TYPE_POD_GARBAGE_COLLECTED)
+
+	removeFromCas(t, ctx, imageHash)
+	waitForEvents(t, events, imageHash, v1alpha.EventType_EVENT_TYPE_IMAGE_REMOVED)
+}
+
+func TestAPIServiceGetLogs(t *testing.T) {
+	// stage1 links the pod's journal only if the host runs systemd.
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		t.Skip("Systemd is not running on the host.")
+	}
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	svc := startAPIService(t, ctx)
+	defer stopAPIService(t, svc)
+
+	c, conn := newAPIClientOrFail(t, "localhost:15441")
+	defer conn.Close()
+
+	patches := []string{"--exec=/inspect --print-msg=HELLO_API_LOGS --exit-code=0"}
+	imageHash := patchImportAndFetchHash("rkt-inspect-print.aci", patches, t, ctx)
+
+	prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), imageHash)
+	uuid := runRktAndGetUUID(t, prepareCmd)
+
+	runCmd := fmt.Sprintf("%s run-prepared --mds-register=false %s", ctx.Cmd(), uuid)
+	waitOrFail(t, spawnOrFail(t, runCmd), 0)
+
+	for _, appName := range []string{"", "rkt-inspect"} {
+		stream, err := c.GetLogs(context.Background(), &v1alpha.GetLogsRequest{
+			PodId:   uuid,
+			AppName: appName,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var lines []string
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				break
+			}
+			lines = append(lines, resp.Lines...)
+		}
+
+		found := false
+		for _, line := range lines {
+			if strings.Contains(line, "HELLO_API_LOGS") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected the app output in the logs (app name %q), saw %v", appName, lines)
+		}
+	}
+
+	// Only one line.
+	stream, err := c.GetLogs(context.Background(), &v1alpha.GetLogsRequest{
+		PodId: uuid,
+		Lines: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Lines) != 1 {
+		t.Errorf("Expected 1 line, saw %v", resp.Lines)
+	}
+
+	// Unknown app.
+	stream, err = c.GetLogs(context.Background(), &v1alpha.GetLogsRequest{
+		PodId:   uuid,
+		AppName: "no-such-app",
+	})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if err == nil {
+		t.Errorf("Expected an error getting the logs of an unknown app")
+	}
+}
+
+func TestAPIServiceMutating(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	// The mutating API is not served by default.
+	svc := startAPIService(t, ctx)
+	_, conn := newAPIClientOrFail(t, "localhost:15441")
+	if _, err := v1alpha.NewMutatingAPIClient(conn).RemovePod(context.Background(), &v1alpha.RemovePodRequest{}); err == nil {
+		t.Errorf("Expected the mutating API to be disabled")
+	}
+	conn.Close()
+	stopAPIService(t, svc)
+
+	apisvcCmd := fmt.Sprintf("%s api-service --enable-mutating-api", ctx.Cmd())
+	svc = startRktAndCheckOutput(t, apisvcCmd, "API service running")
+	defer stopAPIService(t, svc)
+
+	_, conn = newAPIClientOrFail(t, "localhost:15441")
+	defer conn.Close()
+	c := v1alpha.NewMutatingAPIClient(conn)
+
+	image := patchTestACI("rkt-inspect-mutating.aci", "--exec=/inspect --sleep=1000")
+	defer os.Remove(image)
+
+	fetchResp, err := c.FetchImage(context.Background(), &v1alpha.FetchImageRequest{Name: image})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imgID, err := types.NewHash(fetchResp.Id)
+	if err != nil {
+		t.Fatalf("Cannot generate types.Hash from %v: %v", fetchResp.Id, err)
+	}
+	pm := schema.BlankPodManifest()
+	pm.Apps = []schema.RuntimeApp{
+		{
+			Name:  *types.MustACName("rkt-inspect"),
+			Image: schema.RuntimeImage{ID: *imgID},
+		},
+	}
+	pmb, err := json.Marshal(pm)
+	if err != nil {
+		t.Fatalf("Cannot marshal pod manifest: %v", err)
+	}
+
+	prepareResp, err := c.PreparePod(context.Background(), &v1alpha.PreparePodRequest{PodManifest: pmb})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	podID := prepareResp.Id
+	if podInfo := getPodInfo(t, ctx, podID); podInfo.state != "prepared" {
+		t.Fatalf("Pod %q is %q, expected it to be prepared", podID, podInfo.state)
+	}
+
+	if _, err := c.RunPod(context.Background(), &v1alpha.RunPodRequest{Id: podID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if podInfo := getPodInfo(t, ctx, podID); podInfo.state != "running" {
+		t.Fatalf("Pod %q is %q, expected it to be running", podID, podInfo.state)
+	}
+
+	// A running pod cannot be removed.
+	if _, err := c.RemovePod(context.Background(), &v1alpha.RemovePodRequest{Id: podID}); err == nil {
+		t.Errorf("Expected an error removing a running pod")
+	}
+
+	if _, err := c.StopPod(context.Background(), &v1alpha.StopPodRequest{Id: podID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if podInfo := getPodInfo(t, ctx, podID); podInfo.state != "exited" {
+		t.Fatalf("Pod %q is %q, expected it to be exited", podID, podInfo.state)
+	}
+
+	if _, err := c.RemovePod(context.Background(), &v1alpha.RemovePodRequest{Id: podID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
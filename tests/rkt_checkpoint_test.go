@@ -0,0 +1,92 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build host coreos src
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestCheckpointRestore checkpoints a running pod, removes it, and restores
+// it from the checkpoint.
+func TestCheckpointRestore(t *testing.T) {
+	if _, err := exec.LookPath("criu"); err != nil {
+		t.Skip("criu is not installed on the host.")
+	}
+
+	image := patchTestACI("rkt-inspect-checkpoint.aci", "--exec=/inspect --print-msg=HelloCheckpoint --sleep=1000")
+	defer os.Remove(image)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	tmpDir, err := ioutil.TempDir("", "rkt-checkpoint-test")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	checkpointDir := filepath.Join(tmpDir, "checkpoint")
+
+	prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), image)
+	podUUID := runRktAndGetUUID(t, prepareCmd)
+
+	runCmd := fmt.Sprintf("%s run-prepared --mds-register=false %s", ctx.Cmd(), podUUID)
+	runChild := spawnOrFail(t, runCmd)
+	if err := expectWithOutput(runChild, "HelloCheckpoint"); err != nil {
+		t.Fatalf("Expected the pod to print its message: %v", err)
+	}
+
+	checkpointCmd := fmt.Sprintf("%s checkpoint %s %s", ctx.Cmd(), podUUID, checkpointDir)
+	spawnAndWaitOrFail(t, checkpointCmd, 0)
+	runChild.Wait()
+
+	if podInfo := getPodInfo(t, ctx, podUUID); podInfo.state != "exited" {
+		t.Fatalf("Expected the checkpointed pod to be exited, got %q", podInfo.state)
+	}
+
+	// The pod is restored with the same UUID.
+	restoreCmd := fmt.Sprintf("%s restore %s", ctx.Cmd(), checkpointDir)
+	spawnAndWaitOrFail(t, restoreCmd, 1)
+
+	rmCmd := fmt.Sprintf("%s rm %s", ctx.Cmd(), podUUID)
+	spawnAndWaitOrFail(t, rmCmd, 0)
+
+	restoreChild := spawnOrFail(t, restoreCmd)
+
+	running := false
+	for i := 0; i < 30; i++ {
+		if podInfo := getPodInfo(t, ctx, podUUID); podInfo.state == "running" {
+			running = true
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	if !running {
+		t.Fatalf("Expected the restored pod to be running")
+	}
+
+	stopCmd := fmt.Sprintf("%s stop --force %s", ctx.Cmd(), podUUID)
+	spawnAndWaitOrFail(t, stopCmd, 0)
+	restoreChild.Wait()
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build host coreos src kvm
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestExport tests 'rkt export': it runs a pod writing a file in its app, exports
+// the app, and checks that the file is in the exported image, with and without
+// overlay.
+func TestExport(t *testing.T) {
+	image := patchTestACI("rkt-inspect-export.aci", "--exec=/inspect --write-file --file-name=/exported --content=EXPORTED")
+	defer os.Remove(image)
+
+	tmpDir := createTempDirOrPanic("rkt-TestExport-")
+	defer os.RemoveAll(tmpDir)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	for i, flags := range []string{"", "--no-overlay"} {
+		prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s %s", ctx.Cmd(), flags, image)
+		podUUID := runRktAndGetUUID(t, prepareCmd)
+
+		// A pod that did not run cannot be exported.
+		exported := filepath.Join(tmpDir, fmt.Sprintf("exported-%d.aci", i))
+		exportCmd := fmt.Sprintf("%s export %s %s", ctx.Cmd(), podUUID, exported)
+		spawnAndWaitOrFail(t, exportCmd, 1)
+
+		runCmd := fmt.Sprintf("%s run-prepared --mds-register=false %s", ctx.Cmd(), podUUID)
+		spawnAndWaitOrFail(t, runCmd, 0)
+
+		spawnAndWaitOrFail(t, exportCmd, 0)
+
+		// The output file is not overwritten without --overwrite.
+		spawnAndWaitOrFail(t, exportCmd, 1)
+
+		readCmd := fmt.Sprintf("%s --insecure-options=image run --mds-register=false %s --exec /inspect -- --read-file --file-name=/exported", ctx.Cmd(), exported)
+		runRktAndCheckOutput(t, readCmd, "<<<EXPORTED>>>", false)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+func TestImageFsck(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	hash := importImageAndFetchHash(t, ctx, "", getEmptyImagePath())
+
+	fsckCmd := fmt.Sprintf("%s image fsck", ctx.Cmd())
+	runRktAndCheckOutput(t, fsckCmd, "no problems found", false)
+
+	// Corrupt the blob of the image
+	blobs, err := filepath.Glob(filepath.Join(ctx.DataDir(), "cas", "blob", "sha512", "*", hash+"*"))
+	if err != nil || len(blobs) != 1 {
+		t.Fatalf("cannot find the blob of image %q: %v", hash, err)
+	}
+	f, err := os.OpenFile(blobs[0], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("cannot open the blob of image %q: %v", hash, err)
+	}
+	if _, err := f.Write([]byte("garbage")); err != nil {
+		t.Fatalf("cannot write the blob of image %q: %v", hash, err)
+	}
+	f.Close()
+
+	runRktAndCheckOutput(t, fsckCmd, "corrupted blob "+hash, true)
+	runRktAndCheckOutput(t, fsckCmd+" --repair", "removed image", false)
+	runRktAndCheckOutput(t, fsckCmd, "no problems found", false)
+
+	imageListCmd := fmt.Sprintf("%s image list --fields=id --no-legend --full", ctx.Cmd())
+	child := spawnOrFail(t, imageListCmd)
+	if err := expectWithOutput(child, hash); err == nil {
+		t.Fatalf("image %q not removed by the repair", hash)
+	}
+	waitOrFail(t, child, 0)
+}
@@ -0,0 +1,56 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+func TestImageSaveLoad(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	tmpDir, err := ioutil.TempDir("", "rkt-TestImageSaveLoad-")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	bundle := filepath.Join(tmpDir, "bundle.tar")
+
+	hash := importImageAndFetchHash(t, ctx, "", getEmptyImagePath())
+
+	saveCmd := fmt.Sprintf("%s image save --output=%s %s", ctx.Cmd(), bundle, hash)
+	runRktAndCheckOutput(t, saveCmd, "saved 1 image(s)", false)
+	// The bundle is not overwritten by default
+	runRktAndCheckOutput(t, saveCmd, "output bundle file exists", true)
+
+	removeFromCas(t, ctx, hash)
+
+	// The image in the bundle is not signed
+	loadCmd := fmt.Sprintf("%s image load --full %s", ctx.Cmd(), bundle)
+	runRktAndCheckOutput(t, loadCmd, "not signed in the bundle", true)
+
+	loadCmd = fmt.Sprintf("%s --insecure-options=image image load --full %s", ctx.Cmd(), bundle)
+	runRktAndCheckOutput(t, loadCmd, hash, false)
+
+	imageListCmd := fmt.Sprintf("%s image list --fields=id --no-legend --full", ctx.Cmd())
+	runRktAndCheckOutput(t, imageListCmd, hash, false)
+}
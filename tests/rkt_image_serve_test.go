@@ -0,0 +1,49 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestImageServe fetches an image by name from another store served with
+// rkt image serve.
+func TestImageServe(t *testing.T) {
+	serverCtx := testutils.NewRktRunCtx()
+	defer serverCtx.Cleanup()
+
+	imageName := "localhost/rkt-inspect-image-serve"
+	imagePath := patchTestACI("rkt-inspect-image-serve.aci", "--name="+imageName)
+	defer os.Remove(imagePath)
+	hash := importImageAndFetchHash(t, serverCtx, "", imagePath)
+
+	// The discovery over plain HTTP uses the default port
+	serveCmd := fmt.Sprintf("%s image serve --listen=:80", serverCtx.Cmd())
+	child := startRktAndCheckOutput(t, serveCmd, "serving images on :80")
+	defer func() {
+		child.Cmd.Process.Kill()
+		child.Cmd.Wait()
+	}()
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	fetchCmd := fmt.Sprintf("%s --insecure-options=image,http fetch --full %s", ctx.Cmd(), imageName)
+	runRktAndCheckOutput(t, fetchCmd, hash, false)
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+func TestImageSignVerify(t *testing.T) {
+	imageFile := patchTestACI("rkt-inspect-image-sign.aci", "--name=rkt-prefix.com/my-app")
+	defer os.Remove(imageFile)
+	ascFile := fmt.Sprintf("%s.asc", imageFile)
+	defer os.Remove(ascFile)
+	rootAscFile := fmt.Sprintf("%s.root.asc", imageFile)
+	defer os.Remove(rootAscFile)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	t.Logf("Sign the image with a key of a keyring holding several keys: it should fail\n")
+	signCmd := fmt.Sprintf("%s image sign --key=./secring.gpg %s", ctx.Cmd(), imageFile)
+	runRktAndCheckOutput(t, signCmd, "several private keys in the key file", true)
+
+	t.Logf("Sign the image\n")
+	// keys stored in tests/secring.gpg, tests/key1.gpg, tests/key2.gpg
+	signCmd = fmt.Sprintf("%s image sign --key=./secring.gpg --key-id=D9DCEF41 %s", ctx.Cmd(), imageFile)
+	runRktAndCheckOutput(t, signCmd, "signature written to "+ascFile, false)
+
+	t.Logf("Verify the image without trusting the key: it should fail\n")
+	verifyCmd := fmt.Sprintf("%s image verify %s", ctx.Cmd(), imageFile)
+	runRktAndCheckOutput(t, verifyCmd, "openpgp: signature made by unknown entity", true)
+
+	t.Logf("Trust the key and verify the image\n")
+	runRktTrust(t, ctx, "rkt-prefix.com/my-app", 1)
+	runRktAndCheckOutput(t, verifyCmd, `trusted for prefix "rkt-prefix.com/my-app"`, false)
+
+	t.Logf("Verify the stored image with the signature\n")
+	hash := importImageAndFetchHash(t, ctx, "", imageFile)
+	verifyCmd = fmt.Sprintf("%s image verify --signature=%s %s", ctx.Cmd(), ascFile, hash)
+	runRktAndCheckOutput(t, verifyCmd, `trusted for prefix "rkt-prefix.com/my-app"`, false)
+
+	t.Logf("Sign the stored image with the second key, trusted as a root key, and verify it\n")
+	signCmd = fmt.Sprintf("%s image sign --key=./secring.gpg --key-id=585091E3 --output=%s %s", ctx.Cmd(), rootAscFile, hash)
+	runRktAndCheckOutput(t, signCmd, "signature written to "+rootAscFile, false)
+	runRktTrust(t, ctx, "", 2)
+	verifyCmd = fmt.Sprintf("%s image verify --signature=%s %s", ctx.Cmd(), rootAscFile, imageFile)
+	runRktAndCheckOutput(t, verifyCmd, "trusted as a root key", false)
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build host coreos src
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestNetAttachDetach tests that a running pod can be attached to a
+// network and detached from it, and that the default network of the pod
+// cannot be detached.
+func TestNetAttachDetach(t *testing.T) {
+	image := patchTestACI("rkt-inspect-net-attach.aci", "--exec=/inspect --read-stdin")
+	defer os.Remove(image)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	nt := networkTemplateT{
+		Name:   "attached",
+		Type:   "ptp",
+		IpMasq: true,
+		Ipam: ipamTemplateT{
+			Type:   "host-local",
+			Subnet: "11.11.6.0/24",
+		},
+	}
+	netdir := prepareTestNet(t, ctx, nt)
+	defer os.RemoveAll(netdir)
+
+	prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), image)
+	podUUID := runRktAndGetUUID(t, prepareCmd)
+
+	runCmd := fmt.Sprintf("%s run-prepared --mds-register=false --interactive %s", ctx.Cmd(), podUUID)
+	runChild := spawnOrFail(t, runCmd)
+	defer waitOrFail(t, runChild, 0)
+
+	if err := expectWithOutput(runChild, "Enter text:"); err != nil {
+		t.Fatalf("Waited for the prompt but not found: %v", err)
+	}
+
+	attachCmd := fmt.Sprintf("%s net attach %s %s", ctx.Cmd(), podUUID, nt.Name)
+	runRktAndCheckRegexOutput(t, attachCmd, `attached:ip4=11\.11\.6\.\d+`)
+
+	podInfo := getPodInfo(t, ctx, podUUID)
+	ni, ok := podInfo.networks[nt.Name]
+	if !ok {
+		t.Fatalf("pod %q is not attached to network %q: %v", podUUID, nt.Name, podInfo.networks)
+	}
+	if !strings.HasPrefix(ni.ipv4, "11.11.6.") {
+		t.Fatalf("unexpected IPv4 address %q on network %q", ni.ipv4, nt.Name)
+	}
+	if _, ok := podInfo.networks["default"]; !ok {
+		t.Fatalf("pod %q is not attached to the default network anymore: %v", podUUID, podInfo.networks)
+	}
+
+	// The pod cannot be attached twice to a network.
+	spawnAndWaitOrFail(t, attachCmd, 1)
+
+	// The default network cannot be detached.
+	detachDefaultCmd := fmt.Sprintf("%s net detach %s default", ctx.Cmd(), podUUID)
+	spawnAndWaitOrFail(t, detachDefaultCmd, 1)
+
+	detachCmd := fmt.Sprintf("%s net detach %s %s", ctx.Cmd(), podUUID, nt.Name)
+	spawnAndWaitOrFail(t, detachCmd, 0)
+
+	podInfo = getPodInfo(t, ctx, podUUID)
+	if _, ok := podInfo.networks[nt.Name]; ok {
+		t.Fatalf("pod %q is still attached to network %q", podUUID, nt.Name)
+	}
+
+	if err := runChild.SendLine("Bye"); err != nil {
+		t.Fatalf("Failed to send to the pod: %v", err)
+	}
+	if err := expectWithOutput(runChild, "Received text: Bye"); err != nil {
+		t.Fatalf("Expected Bye but not found: %v", err)
+	}
+}
@@ -349,67 +349,95 @@ func TestNetDefaultPortFwdConnectivity(t *testing.T) {
 	defer ctx.Cleanup()
 
 	bannedPorts := make(map[int]struct{}, 0)
-	f := func(httpGetIP string, rktArg string, shouldSucceed bool) {
-		httpPort, err := testutils.GetNextFreePort4Banned(bannedPorts)
-		if err != nil {
-			t.Fatalf("%v", err)
-		}
-		bannedPorts[httpPort] = struct{}{}
-
-		httpServeAddr := fmt.Sprintf("0.0.0.0:%d", httpPort)
-		testImageArgs := []string{
-			fmt.Sprintf("--ports=http,protocol=tcp,port=%d", httpPort),
-			fmt.Sprintf("--exec=/inspect --serve-http=%v", httpServeAddr),
-		}
-		testImage := patchTestACI("rkt-inspect-networking.aci", testImageArgs...)
-		defer os.Remove(testImage)
+	testNetPortFwdConnectivity(t, ctx, bannedPorts, "172.16.28.1", "--net=default", true)
+	testNetPortFwdConnectivity(t, ctx, bannedPorts, "127.0.0.1", "--net=default", true)
 
-		cmd := fmt.Sprintf(
-			"%s --debug --insecure-options=image run --port=http:%d %s --mds-register=false %s",
-			ctx.Cmd(), httpPort, rktArg, testImage)
-		child := spawnOrFail(t, cmd)
+	// TODO: ensure that default-restricted is not accessible from non-host
+	// testNetPortFwdConnectivity(t, ctx, bannedPorts, "172.16.28.1", "--net=default-restricted", true)
+	// testNetPortFwdConnectivity(t, ctx, bannedPorts, "127.0.0.1", "--net=default-restricted", true)
+}
 
-		httpGetAddr := fmt.Sprintf("http://%v:%v", httpGetIP, httpPort)
+/*
+ * Default-restricted net userspace port forwarding connectivity
+ * ---
+ * Container launches http server on all its interfaces
+ * Host must be able to connect to container's http server through the
+ * userspace proxy, without any NAT rules
+ */
+func TestNetDefaultRestrictedUserspacePortFwdConnectivity(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
 
-		ga := testutils.NewGoroutineAssistant(t)
-		ga.Add(2)
+	nt := networkTemplateT{
+		Name:          "default-restricted",
+		Type:          "ptp",
+		PortForwarder: "userspace",
+		Ipam: ipamTemplateT{
+			Type:   "host-local",
+			Subnet: "172.16.28.0/24",
+		},
+	}
+	netdir := prepareTestNet(t, ctx, nt)
+	defer os.RemoveAll(netdir)
 
-		// Child opens the server
-		go func() {
-			defer ga.Done()
-			ga.WaitOrFail(child)
-		}()
+	bannedPorts := make(map[int]struct{}, 0)
+	testNetPortFwdConnectivity(t, ctx, bannedPorts, "127.0.0.1", "--net=default-restricted", true)
+}
 
-		// Host connects to the child via the forward port on localhost
-		go func() {
-			defer ga.Done()
-			expectedRegex := `serving on`
-			_, out, err := expectRegexWithOutput(child, expectedRegex)
-			if err != nil {
-				ga.Fatalf("Error: %v\nOutput: %v", err, out)
-			}
-			body, err := testutils.HTTPGet(httpGetAddr)
-			switch {
-			case err != nil && shouldSucceed:
-				ga.Fatalf("%v\n", err)
-			case err == nil && !shouldSucceed:
-				ga.Fatalf("HTTP-Get to %q should have failed! But received %q", httpGetAddr, body)
-			case err != nil && !shouldSucceed:
-				child.Close()
-				fallthrough
-			default:
-				t.Logf("HTTP-Get received: %s", body)
-			}
-		}()
+func testNetPortFwdConnectivity(t *testing.T, ctx *testutils.RktRunCtx, bannedPorts map[int]struct{}, httpGetIP string, rktArg string, shouldSucceed bool) {
+	httpPort, err := testutils.GetNextFreePort4Banned(bannedPorts)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	bannedPorts[httpPort] = struct{}{}
 
-		ga.Wait()
+	httpServeAddr := fmt.Sprintf("0.0.0.0:%d", httpPort)
+	testImageArgs := []string{
+		fmt.Sprintf("--ports=http,protocol=tcp,port=%d", httpPort),
+		fmt.Sprintf("--exec=/inspect --serve-http=%v", httpServeAddr),
 	}
-	f("172.16.28.1", "--net=default", true)
-	f("127.0.0.1", "--net=default", true)
+	testImage := patchTestACI("rkt-inspect-networking.aci", testImageArgs...)
+	defer os.Remove(testImage)
 
-	// TODO: ensure that default-restricted is not accessible from non-host
-	// f("172.16.28.1", "--net=default-restricted", true)
-	// f("127.0.0.1", "--net=default-restricted", true)
+	cmd := fmt.Sprintf(
+		"%s --debug --insecure-options=image run --port=http:%d %s --mds-register=false %s",
+		ctx.Cmd(), httpPort, rktArg, testImage)
+	child := spawnOrFail(t, cmd)
+
+	httpGetAddr := fmt.Sprintf("http://%v:%v", httpGetIP, httpPort)
+
+	ga := testutils.NewGoroutineAssistant(t)
+	ga.Add(2)
+
+	// Child opens the server
+	go func() {
+		defer ga.Done()
+		ga.WaitOrFail(child)
+	}()
+
+	// Host connects to the child via the forward port on localhost
+	go func() {
+		defer ga.Done()
+		expectedRegex := `serving on`
+		_, out, err := expectRegexWithOutput(child, expectedRegex)
+		if err != nil {
+			ga.Fatalf("Error: %v\nOutput: %v", err, out)
+		}
+		body, err := testutils.HTTPGet(httpGetAddr)
+		switch {
+		case err != nil && shouldSucceed:
+			ga.Fatalf("%v\n", err)
+		case err == nil && !shouldSucceed:
+			ga.Fatalf("HTTP-Get to %q should have failed! But received %q", httpGetAddr, body)
+		case err != nil && !shouldSucceed:
+			child.Close()
+			fallthrough
+		default:
+			t.Logf("HTTP-Get received: %s", body)
+		}
+	}()
+
+	ga.Wait()
 }
 
 func writeNetwork(t *testing.T, net networkTemplateT, netd string) error {
@@ -435,12 +463,13 @@ func writeNetwork(t *testing.T, net networkTemplateT, netd string) error {
 }
 
 type networkTemplateT struct {
-	Name      string
-	Type      string
-	Master    string `json:"master,omitempty"`
-	IpMasq    bool
-	IsGateway bool
-	Ipam      ipamTemplateT
+	Name          string
+	Type          string
+	Master        string `json:"master,omitempty"`
+	IpMasq        bool
+	IsGateway     bool
+	PortForwarder string `json:"portForwarder,omitempty"`
+	Ipam          ipamTemplateT
 }
 
 type ipamTemplateT struct {
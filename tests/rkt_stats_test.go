@@ -0,0 +1,56 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build host coreos src kvm
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestStats tests that rkt stats prints the resource usage of a running pod
+// and fails for a pod that is not running.
+func TestStats(t *testing.T) {
+	image := patchTestACI("rkt-inspect-stats.aci", "--exec=/inspect --read-stdin")
+	defer os.Remove(image)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), image)
+	podUUID := runRktAndGetUUID(t, prepareCmd)
+
+	statsCmd := fmt.Sprintf("%s stats --no-legend --full %s", ctx.Cmd(), podUUID)
+	spawnAndWaitOrFail(t, statsCmd, 1)
+
+	runCmd := fmt.Sprintf("%s run-prepared --mds-register=false --interactive %s", ctx.Cmd(), podUUID)
+	runChild := spawnOrFail(t, runCmd)
+
+	if err := expectWithOutput(runChild, "Enter text:"); err != nil {
+		t.Fatalf("Waited for the prompt but not found: %v", err)
+	}
+
+	runRktAndCheckRegexOutput(t, statsCmd, fmt.Sprintf(`%s\s+[0-9]+\s+[0-9]+`, podUUID))
+
+	stopCmd := fmt.Sprintf("%s stop --force %s", ctx.Cmd(), podUUID)
+	spawnAndWaitOrFail(t, stopCmd, 0)
+	runChild.Wait()
+
+	spawnAndWaitOrFail(t, statsCmd, 1)
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build host coreos src kvm
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/coreos/rkt/tests/testutils"
+)
+
+// TestStop tests that rkt stop stops a running pod, with and without --force.
+func TestStop(t *testing.T) {
+	image := patchTestACI("rkt-inspect-stop.aci", "--exec=/inspect --read-stdin")
+	defer os.Remove(image)
+
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	for _, flags := range []string{"", "--force"} {
+		prepareCmd := fmt.Sprintf("%s --insecure-options=image prepare %s", ctx.Cmd(), image)
+		podUUID := runRktAndGetUUID(t, prepareCmd)
+
+		runCmd := fmt.Sprintf("%s run-prepared --mds-register=false --interactive %s", ctx.Cmd(), podUUID)
+		runChild := spawnOrFail(t, runCmd)
+
+		if err := expectWithOutput(runChild, "Enter text:"); err != nil {
+			t.Fatalf("Waited for the prompt but not found: %v", err)
+		}
+
+		stopCmd := fmt.Sprintf("%s stop %s %s", ctx.Cmd(), flags, podUUID)
+		spawnAndWaitOrFail(t, stopCmd, 0)
+
+		// The exit status of the pod depends on how it was stopped.
+		runChild.Wait()
+
+		podInfo := getPodInfo(t, ctx, podUUID)
+		if podInfo.state != "exited" {
+			t.Fatalf("pod %q stopped with %q is %q, expected it to be exited", podUUID, flags, podInfo.state)
+		}
+
+		// A stopped pod cannot be stopped again.
+		spawnAndWaitOrFail(t, stopCmd, 1)
+	}
+}
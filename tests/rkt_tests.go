@@ -407,6 +407,7 @@ type appInfo struct {
 type networkInfo struct {
 	name string
 	ipv4 string
+	ipv6 string
 }
 
 type podInfo struct {
@@ -423,7 +424,7 @@ type podInfo struct {
 // parsePodInfo parses the 'rkt status $UUID' result into podInfo struct.
 // For example, the 'result' can be:
 // state=running
-// networks=default:ip4=172.16.28.103
+// networks=default:ip4=172.16.28.103 ip6=fd00::103
 // pid=14352
 // exited=false
 // created=2016-04-01 19:12:03.447 -0700 PDT
@@ -445,21 +446,31 @@ func parsePodInfoOutput(t *testing.T, result string, p *podInfo) {
 			}
 			networks := strings.Split(tuples[1], ",")
 			for _, n := range networks {
-				fields := strings.Split(n, ":")
+				// IPv6 addresses contain colons
+				fields := strings.SplitN(strings.TrimSpace(n), ":", 2)
 				if len(fields) != 2 {
 					t.Fatalf("Unexpected network info format: %v", n)
 				}
 
-				ip4 := strings.Split(fields[1], "=")
-				if len(ip4) != 2 {
-					t.Fatalf("Unexpected network info format: %v", n)
-				}
-
 				networkName := fields[0]
-				p.networks[networkName] = &networkInfo{
+				ni := &networkInfo{
 					name: networkName,
-					ipv4: ip4[1],
 				}
+				for _, addr := range strings.Fields(fields[1]) {
+					ip := strings.SplitN(addr, "=", 2)
+					if len(ip) != 2 {
+						t.Fatalf("Unexpected network info format: %v", n)
+					}
+					switch ip[0] {
+					case "ip4":
+						ni.ipv4 = ip[1]
+					case "ip6":
+						ni.ipv6 = ip[1]
+					default:
+						t.Fatalf("Unexpected network info format: %v", n)
+					}
+				}
+				p.networks[networkName] = ni
 			}
 		case "pid":
 			pid, err := strconv.Atoi(tuples[1])
@@ -83,3 +83,29 @@ func TestTrust(t *testing.T) {
 	runImage(t, ctx, imageFile, "Hello", false)
 	runImage(t, ctx, imageFile2, "Hello", false)
 }
+
+func TestTrustListRemove(t *testing.T) {
+	ctx := testutils.NewRktRunCtx()
+	defer ctx.Cleanup()
+
+	// fingerprints of tests/key1.gpg and tests/key2.gpg
+	fingerprint1 := "36b287add1095db1afd9cbf125d5d9fad9dcef41"
+	fingerprint2 := "84aa0bf58e75fd757b506fe4379e3d70585091e3"
+
+	runRktTrust(t, ctx, "rkt-prefix.com/my-app", 1)
+	runRktTrust(t, ctx, "", 2)
+
+	t.Logf("List the trusted keys\n")
+	listCmd := fmt.Sprintf("%s trust --list", ctx.Cmd())
+	runRktAndCheckOutput(t, listCmd, fingerprint1+"\tvalid", false)
+	runRktAndCheckOutput(t, listCmd, fingerprint2+"\tvalid", false)
+
+	t.Logf("Remove the key trusted for the prefix\n")
+	removeCmd := fmt.Sprintf("%s trust --prefix=rkt-prefix.com/my-app --remove=%s", ctx.Cmd(), fingerprint1)
+	runRktAndCheckOutput(t, removeCmd, "removed trust in key "+fingerprint1, false)
+	runRktAndCheckOutput(t, removeCmd, "no key with fingerprint "+fingerprint1, true)
+
+	t.Logf("Remove the root key\n")
+	removeCmd = fmt.Sprintf("%s trust --remove=%s", ctx.Cmd(), fingerprint2)
+	runRktAndCheckOutput(t, removeCmd, "removed trust in root key "+fingerprint2, false)
+}